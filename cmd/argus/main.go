@@ -11,13 +11,14 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/nahuelsantos/argus/internal/config"
 	"github.com/nahuelsantos/argus/internal/handlers"
 	"github.com/nahuelsantos/argus/internal/metrics"
 	"github.com/nahuelsantos/argus/internal/middleware"
 	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
 )
 
 func main() {
@@ -39,14 +40,28 @@ func main() {
 	// Register Prometheus metrics
 	metrics.RegisterMetrics()
 
+	// Optionally push the registered metrics over OTLP as well
+	metricExporter := services.NewMetricExporter(prometheus.DefaultGatherer)
+	if err := metricExporter.Configure(types.MetricExportSettingsFromEnv()); err != nil {
+		fmt.Printf("Failed to configure OTLP metric export: %v\n", err)
+	}
+
+	// Pattern series are evaluated whenever the registry is gathered
+	patternEngine := services.NewMetricPatternEngine()
+	prometheus.MustRegister(patternEngine)
+
 	// Initialize handlers
-	basicHandlers := handlers.NewBasicHandlers(loggingService, tracingService)
+	basicHandlers := handlers.NewBasicHandlers(loggingService, tracingService, metricExporter, patternEngine)
 	simulationHandlers := handlers.NewSimulationHandlers(loggingService, tracingService)
 	alertingHandlers := handlers.NewAlertingHandlers(loggingService, alertingService)
 	testingHandlers := handlers.NewTestingHandlers(loggingService, tracingService)
 	integrationHandlers := handlers.NewIntegrationHandlers(loggingService, tracingService)
 	performanceHandlers := handlers.NewPerformanceHandlers(loggingService, tracingService)
 
+	// Checks run continuously once schedules are added through the API
+	scheduler := services.NewScheduler()
+	scheduleHandlers := handlers.NewScheduleHandlers(loggingService, scheduler, basicHandlers, integrationHandlers, testingHandlers)
+
 	// Create HTTP mux
 	mux := http.NewServeMux()
 
@@ -71,6 +86,7 @@ func main() {
 	mux.HandleFunc("/generate-logs/unstructured", testingHandlers.GenerateUnstructuredLogsHandler)
 	mux.HandleFunc("/generate-logs/mixed", testingHandlers.GenerateMixedLogsHandler)
 	mux.HandleFunc("/generate-logs/multiline", testingHandlers.GenerateMultilineLogsHandler)
+	mux.HandleFunc("/generate-logs/format", testingHandlers.GenerateFormatLogsHandler)
 	mux.HandleFunc("/simulate-service/wordpress", testingHandlers.SimulateWordPressServiceHandler)
 	mux.HandleFunc("/simulate-service/nextjs", testingHandlers.SimulateNextJSServiceHandler)
 	mux.HandleFunc("/simulate-trace/cross-service", testingHandlers.SimulateCrossServiceTracingHandler)
@@ -80,14 +96,26 @@ func main() {
 	mux.HandleFunc("/test-reverse-proxy", testingHandlers.TestReverseProxyHandler)
 	mux.HandleFunc("/test-ssl-monitoring", testingHandlers.TestSSLMonitoringHandler)
 	mux.HandleFunc("/test-domain-health", testingHandlers.TestDomainHealthHandler)
+	mux.HandleFunc("/test-pii-redaction", testingHandlers.TestPIIRedactionHandler)
 
 	// LGTM Stack Configuration & Integration endpoints
 	mux.HandleFunc("/test-lgtm-integration", integrationHandlers.TestLGTMIntegration)
 	mux.HandleFunc("/test-grafana-dashboards", integrationHandlers.TestGrafanaDashboards)
 	mux.HandleFunc("/test-alert-rules", integrationHandlers.TestAlertRules)
+	mux.HandleFunc("/test-grafana-alerting", integrationHandlers.TestGrafanaAlerting)
+	mux.HandleFunc("/test-loki-rules", integrationHandlers.TestLokiRules)
+	mux.HandleFunc("/test-tempo-search", integrationHandlers.TestTempoSearch)
+	mux.HandleFunc("/test-scrape-coverage", integrationHandlers.TestScrapeCoverage)
+	mux.HandleFunc("/test-tempo-service-graph", integrationHandlers.TestTempoServiceGraph)
+	mux.HandleFunc("/test-exemplars", integrationHandlers.TestExemplars)
+	mux.HandleFunc("/test-metrics-lint", integrationHandlers.TestMetricsLint)
+	mux.HandleFunc("/test-otel-pipeline", integrationHandlers.TestOTELPipeline)
+	mux.HandleFunc("/api/dashboards", integrationHandlers.DashboardLibraryHandler)
+	mux.HandleFunc("/api/alerting/webhook/", integrationHandlers.AlertWebhookHandler)
 
 	// LGTM Stack Performance & Scale Testing endpoints
 	mux.HandleFunc("/test-metrics-scale", performanceHandlers.TestMetricsScale)
+	mux.HandleFunc("/test-cardinality", performanceHandlers.TestCardinality)
 	mux.HandleFunc("/test-logs-scale", performanceHandlers.TestLogsScale)
 	mux.HandleFunc("/test-traces-scale", performanceHandlers.TestTracesScale)
 	mux.HandleFunc("/test-dashboard-load", performanceHandlers.TestDashboardLoad)
@@ -103,11 +131,24 @@ func main() {
 	mux.HandleFunc("/active-incidents", alertingHandlers.GetActiveIncidentsHandler)
 
 	// Prometheus metrics endpoint
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", metrics.Handler(prometheus.DefaultRegisterer, prometheus.DefaultGatherer, serviceConfig.OpenMetrics))
 
 	// Settings and configuration API
 	mux.HandleFunc("/api/settings", basicHandlers.SettingsHandler)
 	mux.HandleFunc("/api/test-connection/", basicHandlers.TestConnectionHandler)
+	mux.HandleFunc("/api/log-sinks", basicHandlers.LogSinksHandler)
+
+	// Log replay API
+	mux.HandleFunc("/api/log-replays", testingHandlers.LogReplaysHandler)
+	mux.HandleFunc("/api/log-replays/", testingHandlers.LogReplayHandler)
+
+	// Metric pattern API
+	mux.HandleFunc("/api/metric-patterns", basicHandlers.MetricPatternsHandler)
+	mux.HandleFunc("/api/metric-patterns/", basicHandlers.MetricPatternHandler)
+
+	// Scheduled check API
+	mux.HandleFunc("/api/schedules", scheduleHandlers.SchedulesHandler)
+	mux.HandleFunc("/api/schedules/", scheduleHandlers.ScheduleHandler)
 
 	// Simple test endpoint for HTMX debugging
 	mux.HandleFunc("/test-simple", func(w http.ResponseWriter, r *http.Request) {
@@ -182,6 +223,12 @@ func main() {
 	} else {
 		fmt.Printf("Argus server stopped gracefully\n")
 	}
+
+	// Stop scheduled checks, then export what is still buffered before exiting
+	scheduler.Stop()
+	loggingService.Exporter().Shutdown()
+	loggingService.Sinks().Close()
+	metricExporter.Shutdown()
 }
 
 func encodeJSON(w http.ResponseWriter, data interface{}) error {
@@ -5,6 +5,8 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/nahuelsantos/argus/internal/types"
 )
 
 // Build-time variables (set via ldflags)
@@ -21,6 +23,9 @@ type ServiceConfig struct {
 	Environment string
 	StartTime   time.Time
 	Port        string
+	PublicURL   string // URL at which stack components (Grafana, Alertmanager) reach Argus
+	OpenMetrics bool   // offer the OpenMetrics format on /metrics
+	ReplayDir   string // log replays read files under this directory; empty disables them
 }
 
 // GetServiceConfig returns the current service configuration
@@ -30,12 +35,20 @@ func GetServiceConfig() *ServiceConfig {
 		environment = "development"
 	}
 
+	publicURL := os.Getenv("ARGUS_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:3001"
+	}
+
 	return &ServiceConfig{
 		Name:        "argus",
 		Version:     GetVersion(),
 		Environment: environment,
 		StartTime:   time.Now(),
 		Port:        ":3001",
+		PublicURL:   strings.TrimRight(publicURL, "/"),
+		OpenMetrics: getBoolEnv("ARGUS_OPENMETRICS", false),
+		ReplayDir:   os.Getenv("ARGUS_LOG_REPLAY_DIR"),
 	}
 }
 
@@ -51,17 +64,16 @@ func (sc *ServiceConfig) GetAPIBaseURL() string {
 type TracingConfig struct {
 	ServiceName    string
 	ServiceVersion string
-	JaegerEndpoint string
-	SamplingRate   float64
+	types.TracingSettings
 }
 
-// GetTracingConfig returns the tracing configuration
+// GetTracingConfig returns the tracing configuration, with exporter, sampling
+// and batching taken from the standard OTEL_* environment variables
 func GetTracingConfig() *TracingConfig {
 	return &TracingConfig{
-		ServiceName:    "argus",
-		ServiceVersion: GetVersion(),
-		JaegerEndpoint: "http://localhost:14268/api/traces",
-		SamplingRate:   1.0,
+		ServiceName:     "argus",
+		ServiceVersion:  GetVersion(),
+		TracingSettings: types.TracingSettingsFromEnv(),
 	}
 }
 
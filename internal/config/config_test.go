@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetServiceConfig(t *testing.T) {
@@ -90,6 +91,24 @@ func TestGetServiceConfig(t *testing.T) {
 	}
 }
 
+func TestGetServiceConfig_PublicURL(t *testing.T) {
+	os.Unsetenv("ARGUS_PUBLIC_URL")
+	assert.Equal(t, "http://localhost:3001", GetServiceConfig().PublicURL)
+
+	os.Setenv("ARGUS_PUBLIC_URL", "http://argus.monitoring:3001/")
+	defer os.Unsetenv("ARGUS_PUBLIC_URL")
+	assert.Equal(t, "http://argus.monitoring:3001", GetServiceConfig().PublicURL)
+}
+
+func TestGetServiceConfig_OpenMetrics(t *testing.T) {
+	os.Unsetenv("ARGUS_OPENMETRICS")
+	assert.False(t, GetServiceConfig().OpenMetrics)
+
+	os.Setenv("ARGUS_OPENMETRICS", "true")
+	defer os.Unsetenv("ARGUS_OPENMETRICS")
+	assert.True(t, GetServiceConfig().OpenMetrics)
+}
+
 func TestServiceConfig_GetAPIBaseURL(t *testing.T) {
 	// GetAPIBaseURL now always returns localhost since frontend auto-detects the actual URL
 	config := GetServiceConfig()
@@ -104,10 +123,29 @@ func TestGetTracingConfig(t *testing.T) {
 
 	assert.Equal(t, "argus", config.ServiceName)
 	assert.NotEmpty(t, config.ServiceVersion) // From GetVersion()
-	assert.Equal(t, "http://localhost:14268/api/traces", config.JaegerEndpoint)
+	assert.Equal(t, "http/protobuf", config.Exporter.Protocol)
+	assert.Equal(t, "http://localhost:4318", config.Exporter.Endpoint)
+	assert.Equal(t, "/v1/traces", config.Exporter.URLPath)
 	assert.Equal(t, 1.0, config.SamplingRate)
 }
 
+func TestGetTracingConfig_OTELEnvironment(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "https://tempo.example.com:4317")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "X-Scope-OrgID=tenant-a,Authorization=Bearer%20abc")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.25")
+	t.Setenv("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", "128")
+
+	config := GetTracingConfig()
+
+	assert.Equal(t, "grpc", config.Exporter.Protocol)
+	assert.Equal(t, "https://tempo.example.com:4317", config.Exporter.Endpoint)
+	assert.Equal(t, map[string]string{"X-Scope-OrgID": "tenant-a", "Authorization": "Bearer abc"}, config.Exporter.Headers)
+	assert.Equal(t, 0.25, config.SamplingRate)
+	assert.Equal(t, 128, config.Batch.MaxExportBatchSize)
+	assert.NoError(t, config.Validate())
+}
+
 func TestGetVersion(t *testing.T) {
 	// Save original state
 	originalVersion := Version
@@ -288,14 +326,16 @@ func TestTracingConfigValidation(t *testing.T) {
 	t.Run("tracing config has required fields", func(t *testing.T) {
 		assert.NotEmpty(t, config.ServiceName)
 		assert.NotEmpty(t, config.ServiceVersion)
-		assert.NotEmpty(t, config.JaegerEndpoint)
+		assert.NotEmpty(t, config.Exporter.Endpoint)
 		assert.GreaterOrEqual(t, config.SamplingRate, 0.0)
 		assert.LessOrEqual(t, config.SamplingRate, 1.0)
 	})
 
-	t.Run("jaeger endpoint is valid URL format", func(t *testing.T) {
-		assert.True(t, strings.HasPrefix(config.JaegerEndpoint, "http"))
-		assert.Contains(t, config.JaegerEndpoint, ":")
+	t.Run("exporter endpoint resolves to host:port", func(t *testing.T) {
+		hostPort, _, _, err := config.Exporter.Target()
+		require.NoError(t, err)
+		assert.Contains(t, hostPort, ":")
+		assert.NotContains(t, hostPort, "http")
 	})
 }
 
@@ -17,6 +17,7 @@ type SecurityConfig struct {
 	WriteTimeout    time.Duration `json:"write_timeout"`
 	IdleTimeout     time.Duration `json:"idle_timeout"`
 	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+	ClientTimeout   time.Duration `json:"client_timeout"` // outbound requests to the LGTM stack
 
 	// Limits
 	MaxTestDuration time.Duration `json:"max_test_duration"`
@@ -46,6 +47,7 @@ func GetSecurityConfig() SecurityConfig {
 		WriteTimeout:    getDurationEnv("ARGUS_WRITE_TIMEOUT", 30*time.Second),
 		IdleTimeout:     getDurationEnv("ARGUS_IDLE_TIMEOUT", 60*time.Second),
 		ShutdownTimeout: getDurationEnv("ARGUS_SHUTDOWN_TIMEOUT", 30*time.Second),
+		ClientTimeout:   getDurationEnv("ARGUS_CLIENT_TIMEOUT", 10*time.Second),
 
 		// Limits
 		MaxTestDuration: getDurationEnv("ARGUS_MAX_TEST_DURATION", 10*time.Minute),
@@ -18,6 +18,7 @@ func TestGetSecurityConfig(t *testing.T) {
 		"ARGUS_WRITE_TIMEOUT",
 		"ARGUS_IDLE_TIMEOUT",
 		"ARGUS_SHUTDOWN_TIMEOUT",
+		"ARGUS_CLIENT_TIMEOUT",
 		"ARGUS_MAX_TEST_DURATION",
 		"ARGUS_MAX_CONCURRENCY",
 		"ARGUS_MAX_COUNT",
@@ -54,6 +55,7 @@ func TestGetSecurityConfig(t *testing.T) {
 		assert.Equal(t, 30*time.Second, config.WriteTimeout)
 		assert.Equal(t, 60*time.Second, config.IdleTimeout)
 		assert.Equal(t, 30*time.Second, config.ShutdownTimeout)
+		assert.Equal(t, 10*time.Second, config.ClientTimeout)
 
 		// Limits
 		assert.Equal(t, 10*time.Minute, config.MaxTestDuration)
@@ -79,6 +81,7 @@ func TestGetSecurityConfig(t *testing.T) {
 		os.Setenv("ARGUS_WRITE_TIMEOUT", "40s")
 		os.Setenv("ARGUS_IDLE_TIMEOUT", "90s")
 		os.Setenv("ARGUS_SHUTDOWN_TIMEOUT", "60s")
+		os.Setenv("ARGUS_CLIENT_TIMEOUT", "5s")
 		os.Setenv("ARGUS_MAX_TEST_DURATION", "15m")
 		os.Setenv("ARGUS_MAX_CONCURRENCY", "100")
 		os.Setenv("ARGUS_MAX_COUNT", "200000")
@@ -95,6 +98,7 @@ func TestGetSecurityConfig(t *testing.T) {
 		assert.Equal(t, 40*time.Second, config.WriteTimeout)
 		assert.Equal(t, 90*time.Second, config.IdleTimeout)
 		assert.Equal(t, 60*time.Second, config.ShutdownTimeout)
+		assert.Equal(t, 5*time.Second, config.ClientTimeout)
 		assert.Equal(t, 15*time.Minute, config.MaxTestDuration)
 		assert.Equal(t, 100, config.MaxConcurrency)
 		assert.Equal(t, 200000, config.MaxCount)
@@ -0,0 +1,14 @@
+// Package configs embeds the Grafana, Loki and Prometheus configuration shipped with Argus
+package configs
+
+import "embed"
+
+// Grafana holds the bundled Grafana dashboard library (library.json plus dashboards/)
+//
+//go:embed grafana
+var Grafana embed.FS
+
+// Loki holds the bundled Loki ruler rule groups (rules/)
+//
+//go:embed loki
+var Loki embed.FS
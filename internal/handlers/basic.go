@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -14,6 +18,7 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/models"
 	"github.com/nahuelsantos/argus/internal/services"
 	"github.com/nahuelsantos/argus/internal/types"
 	"github.com/nahuelsantos/argus/internal/utils"
@@ -23,13 +28,17 @@ import (
 type BasicHandlers struct {
 	loggingService *services.LoggingService
 	tracingService *services.TracingService
+	metricExporter *services.MetricExporter
+	patternEngine  *services.MetricPatternEngine
 }
 
 // NewBasicHandlers creates a new basic handlers instance
-func NewBasicHandlers(loggingService *services.LoggingService, tracingService *services.TracingService) *BasicHandlers {
+func NewBasicHandlers(loggingService *services.LoggingService, tracingService *services.TracingService, metricExporter *services.MetricExporter, patternEngine *services.MetricPatternEngine) *BasicHandlers {
 	return &BasicHandlers{
 		loggingService: loggingService,
 		tracingService: tracingService,
+		metricExporter: metricExporter,
+		patternEngine:  patternEngine,
 	}
 }
 
@@ -60,7 +69,8 @@ func (bh *BasicHandlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	bh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Health check performed")
 }
 
-// GenerateMetricsHandler generates sample metrics
+// GenerateMetricsHandler generates sample metrics. With a "pattern" preset it
+// also starts a series following that pattern, named by "series".
 func (bh *BasicHandlers) GenerateMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	count := 10
 	if c := r.URL.Query().Get("count"); c != "" {
@@ -69,6 +79,20 @@ func (bh *BasicHandlers) GenerateMetricsHandler(w http.ResponseWriter, r *http.R
 		}
 	}
 
+	var patternSeries *models.PatternSeries
+	if preset := r.URL.Query().Get("pattern"); preset != "" {
+		name := r.URL.Query().Get("series")
+		if name == "" {
+			name = preset
+		}
+		created, err := bh.patternEngine.Set(types.MetricSeries{Name: name, Preset: preset})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid pattern: %v (presets: %s)", err, strings.Join(types.PatternPresetNames(), ", ")), http.StatusBadRequest)
+			return
+		}
+		patternSeries = &created
+	}
+
 	for i := 0; i < count; i++ {
 		// Generate random metrics
 		metrics.CustomMetric.WithLabelValues("test", "generated").Set(rand.Float64() * 100)
@@ -90,6 +114,9 @@ func (bh *BasicHandlers) GenerateMetricsHandler(w http.ResponseWriter, r *http.R
 			"http_requests_total",
 		},
 	}
+	if patternSeries != nil {
+		response["pattern_series"] = patternSeries
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, response)
@@ -100,6 +127,10 @@ func (bh *BasicHandlers) GenerateMetricsHandler(w http.ResponseWriter, r *http.R
 
 // GenerateLogsHandler generates sample logs
 func (bh *BasicHandlers) GenerateLogsHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, bh.loggingService)
+	if !ok {
+		return
+	}
 	count := 5
 	if c := r.URL.Query().Get("count"); c != "" {
 		if parsed, err := strconv.Atoi(c); err == nil && parsed > 0 {
@@ -114,17 +145,23 @@ func (bh *BasicHandlers) GenerateLogsHandler(w http.ResponseWriter, r *http.Requ
 
 		switch logType {
 		case "info":
-			bh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(),
+			bh.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(),
 				fmt.Sprintf("Generated info log #%d", i+1))
 		case "warn":
-			bh.loggingService.LogWithContext(zapcore.WarnLevel, r.Context(),
+			bh.loggingService.LogToSink(sink, zapcore.WarnLevel, r.Context(),
 				fmt.Sprintf("Generated warning log #%d", i+1))
 		case "error":
+			if sink != nil {
+				bh.loggingService.LogToSink(sink, zapcore.ErrorLevel, r.Context(),
+					fmt.Sprintf("Generated error log #%d", i+1),
+					zap.String("error_type", "test_error"), zap.String("error_code", "TEST001"), zap.Int("iteration", i+1))
+				break
+			}
 			bh.loggingService.LogError(r.Context(), "test_error", "TEST001",
 				fmt.Sprintf("Generated error log #%d", i+1), nil,
 				map[string]interface{}{"iteration": i + 1})
 		case "debug":
-			bh.loggingService.LogWithContext(zapcore.DebugLevel, r.Context(),
+			bh.loggingService.LogToSink(sink, zapcore.DebugLevel, r.Context(),
 				fmt.Sprintf("Generated debug log #%d", i+1))
 		}
 
@@ -145,13 +182,17 @@ func (bh *BasicHandlers) GenerateLogsHandler(w http.ResponseWriter, r *http.Requ
 
 // GenerateErrorHandler generates sample errors
 func (bh *BasicHandlers) GenerateErrorHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, bh.loggingService)
+	if !ok {
+		return
+	}
 	errorTypes := []string{"validation", "database", "network", "timeout", "auth"}
 	errorType := errorTypes[rand.Intn(len(errorTypes))]
 
 	errorCode := fmt.Sprintf("ERR_%s_%03d", errorType, rand.Intn(999)+1)
 	errorMessage := fmt.Sprintf("Simulated %s error for testing", errorType)
 
-	bh.loggingService.LogError(r.Context(), errorType, errorCode, errorMessage,
+	bh.loggingService.LogErrorToSink(sink, r.Context(), errorType, errorCode, errorMessage,
 		fmt.Errorf("simulated error"), map[string]interface{}{
 			"severity": "medium",
 			"category": "testing",
@@ -292,19 +333,10 @@ func (bh *BasicHandlers) LGTMStatusHandler(w http.ResponseWriter, r *http.Reques
 		settings = types.GetDefaults()
 	}
 
-	// Use actual configured URLs from settings
-	services := map[string]string{
-		"prometheus":   settings.Prometheus.URL + "/-/healthy",
-		"alertmanager": settings.AlertManager.URL + "/-/healthy",
-		"grafana":      settings.Grafana.URL + "/api/health",
-		"loki":         settings.Loki.URL + "/ready",
-		"tempo":        settings.Tempo.URL + "/ready",
-	}
-
 	status := make(map[string]interface{})
 
-	for service, url := range services {
-		serviceStatus := bh.checkServiceHealth(url)
+	for service, target := range lgtmComponents(settings) {
+		serviceStatus := bh.checkServiceHealth(r.Context(), target.config, target.path)
 		status[service] = serviceStatus
 	}
 
@@ -312,12 +344,28 @@ func (bh *BasicHandlers) LGTMStatusHandler(w http.ResponseWriter, r *http.Reques
 	utils.EncodeJSON(w, status)
 }
 
-func (bh *BasicHandlers) checkServiceHealth(url string) string {
-	client := &http.Client{
-		Timeout: 8 * time.Second,
+// lgtmComponent is a stack component and the path that reports its health
+type lgtmComponent struct {
+	config types.ServiceConfig
+	path   string
+}
+
+// lgtmComponents returns the configured stack components by name
+func lgtmComponents(settings *types.LGTMSettings) map[string]lgtmComponent {
+	return map[string]lgtmComponent{
+		"prometheus":   {settings.Prometheus, "/-/healthy"},
+		"alertmanager": {settings.AlertManager, "/-/healthy"},
+		"grafana":      {settings.Grafana, "/api/health"},
+		"loki":         {settings.Loki, "/ready"},
+		"tempo":        {settings.Tempo, "/ready"},
 	}
+}
+
+func (bh *BasicHandlers) checkServiceHealth(ctx context.Context, service types.ServiceConfig, path string) string {
+	ctx, cancel := context.WithTimeout(ctx, 8*time.Second)
+	defer cancel()
 
-	resp, err := client.Get(url)
+	resp, err := lgtmClient.get(ctx, service, path)
 	if err != nil {
 		return "offline"
 	}
@@ -350,8 +398,21 @@ func (bh *BasicHandlers) getSettings(w http.ResponseWriter, r *http.Request) {
 		globalSettings = types.GetDefaults()
 	}
 
+	// Report the exporter settings actually in effect, without their header secrets
+	settings := *globalSettings
+	tracing := bh.tracingService.Settings()
+	tracing.Exporter = tracing.Exporter.Redacted()
+	settings.Tracing = &tracing
+	logExport := bh.loggingService.Exporter().Settings()
+	logExport.Exporter = logExport.Exporter.Redacted()
+	settings.LogExport = &logExport
+	metricExport := bh.metricExporter.Settings()
+	metricExport.Exporter = metricExport.Exporter.Redacted()
+	settings.MetricExport = &metricExport
+	settings.LogSinks = bh.loggingService.Sinks().Configs()
+
 	w.Header().Set("Content-Type", "application/json")
-	utils.EncodeJSON(w, globalSettings)
+	utils.EncodeJSON(w, settings)
 }
 
 func (bh *BasicHandlers) saveSettings(w http.ResponseWriter, r *http.Request) {
@@ -361,6 +422,93 @@ func (bh *BasicHandlers) saveSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Redacted exporter headers from a previous GET keep their current values
+	if settings.Tracing != nil {
+		settings.Tracing.Exporter.RestoreRedacted(bh.tracingService.Settings().Exporter)
+	}
+	if settings.LogExport != nil {
+		settings.LogExport.Exporter.RestoreRedacted(bh.loggingService.Exporter().Settings().Exporter)
+	}
+	if settings.MetricExport != nil {
+		settings.MetricExport.Exporter.RestoreRedacted(bh.metricExporter.Settings().Exporter)
+	}
+
+	// Check the plain sections first, then build every exporter and sink, so a
+	// bad section leaves the running configuration and the settings untouched
+	for name, service := range map[string]types.ServiceConfig{
+		"grafana":        settings.Grafana,
+		"prometheus":     settings.Prometheus,
+		"alertmanager":   settings.AlertManager,
+		"loki":           settings.Loki,
+		"tempo":          settings.Tempo,
+		"otel_collector": settings.OTELCollector.ServiceConfig,
+	} {
+		if err := service.TLS.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid %s TLS settings: %v", name, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, provider := range settings.Discovery {
+		if err := provider.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid discovery provider %s: %v", providerName(provider), err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if settings.ReverseProxy != nil {
+		if err := settings.ReverseProxy.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid reverse proxy settings: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var pending []services.PendingConfig
+	discard := func() {
+		for _, config := range pending {
+			config.Discard()
+		}
+	}
+	if settings.Tracing != nil {
+		config, err := bh.tracingService.Prepare(*settings.Tracing)
+		if err != nil {
+			discard()
+			http.Error(w, fmt.Sprintf("Invalid tracing settings: %v", err), http.StatusBadRequest)
+			return
+		}
+		pending = append(pending, config)
+	}
+	if settings.LogExport != nil {
+		config, err := bh.loggingService.Exporter().Prepare(*settings.LogExport)
+		if err != nil {
+			discard()
+			http.Error(w, fmt.Sprintf("Invalid log export settings: %v", err), http.StatusBadRequest)
+			return
+		}
+		pending = append(pending, config)
+	}
+	if settings.MetricExport != nil {
+		config, err := bh.metricExporter.Prepare(*settings.MetricExport)
+		if err != nil {
+			discard()
+			http.Error(w, fmt.Sprintf("Invalid metric export settings: %v", err), http.StatusBadRequest)
+			return
+		}
+		pending = append(pending, config)
+	}
+	if settings.LogSinks != nil {
+		config, err := bh.loggingService.Sinks().Prepare(settings.LogSinks, settings.Loki)
+		if err != nil {
+			discard()
+			http.Error(w, fmt.Sprintf("Invalid log sinks: %v", err), http.StatusBadRequest)
+			return
+		}
+		pending = append(pending, config)
+	}
+	for _, config := range pending {
+		config.Apply()
+	}
+
 	globalSettings = &settings
 
 	response := map[string]interface{}{
@@ -373,6 +521,20 @@ func (bh *BasicHandlers) saveSettings(w http.ResponseWriter, r *http.Request) {
 	utils.EncodeJSON(w, response)
 }
 
+// LogSinksHandler reports stdout and the configured log sinks with their
+// delivery totals
+func (bh *BasicHandlers) LogSinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, map[string]interface{}{
+		"sinks":     bh.loggingService.Sinks().Stats(),
+		"timestamp": time.Now(),
+	})
+}
+
 // TestConnectionHandler tests connection to specific LGTM services
 func (bh *BasicHandlers) TestConnectionHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract service name from URL path
@@ -388,6 +550,9 @@ func (bh *BasicHandlers) TestConnectionHandler(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	// CA, certificate and key paths are read from disk, so only the saved
+	// settings may name them; a TLS block in the request body is ignored
+	config.TLS = savedServiceTLS(service)
 
 	result := bh.testServiceConnection(service, config)
 
@@ -395,26 +560,42 @@ func (bh *BasicHandlers) TestConnectionHandler(w http.ResponseWriter, r *http.Re
 	utils.EncodeJSON(w, result)
 }
 
+// savedServiceTLS returns the TLS settings saved for the service, if any
+func savedServiceTLS(service string) *types.TLSConfig {
+	settings := getGlobalSettings()
+	switch service {
+	case "grafana":
+		return settings.Grafana.TLS
+	case "prometheus":
+		return settings.Prometheus.TLS
+	case "alertmanager":
+		return settings.AlertManager.TLS
+	case "loki":
+		return settings.Loki.TLS
+	case "tempo":
+		return settings.Tempo.TLS
+	case "otel_collector":
+		return settings.OTELCollector.TLS
+	}
+	return nil
+}
+
 func (bh *BasicHandlers) testServiceConnection(service string, config types.ServiceConfig) map[string]interface{} {
 	var testURL string
-	var requiresAuth bool
 
 	switch service {
 	case "grafana":
 		testURL = config.URL + "/api/user" // This endpoint requires authentication
-		requiresAuth = true
 	case "prometheus":
 		testURL = config.URL + "/-/healthy"
-		requiresAuth = config.Username != ""
 	case "alertmanager":
 		testURL = config.URL + "/-/healthy"
-		requiresAuth = false
 	case "loki":
 		testURL = config.URL + "/ready"
-		requiresAuth = false
 	case "tempo":
 		testURL = config.URL + "/ready"
-		requiresAuth = false
+	case "otel_collector":
+		testURL = config.URL + "/metrics"
 	default:
 		return map[string]interface{}{
 			"status":  "error",
@@ -422,41 +603,70 @@ func (bh *BasicHandlers) testServiceConnection(service string, config types.Serv
 		}
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	req, err := http.NewRequest("GET", testURL, nil)
+	if config.TLS != nil {
+		if _, err := services.NewServiceTLSConfig(*config.TLS); err != nil {
+			return map[string]interface{}{
+				"status":  "error",
+				"stage":   "tls_config",
+				"message": fmt.Sprintf("Invalid TLS settings: %v", err),
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", testURL, nil)
 	if err != nil {
 		return map[string]interface{}{
 			"status":  "error",
+			"stage":   "request",
 			"message": fmt.Sprintf("Failed to create request: %v", err),
 		}
 	}
 
-	// For Grafana, always test with credentials if provided
-	if service == "grafana" && config.Username != "" {
-		req.SetBasicAuth(config.Username, config.Password)
-	} else if requiresAuth && config.Username != "" {
-		req.SetBasicAuth(config.Username, config.Password)
-	}
-
-	resp, err := client.Do(req)
+	// Test with the credentials, tenant, headers and TLS settings the checks will use
+	resp, err := lgtmClient.do(config, req)
 	if err != nil {
+		if isTLSHandshakeError(err) {
+			result := map[string]interface{}{
+				"status":  "error",
+				"stage":   "tls_handshake",
+				"message": fmt.Sprintf("TLS handshake failed: %v", err),
+			}
+			// Report the certificate the server presented even though it was rejected
+			var verifyErr *tls.CertificateVerificationError
+			if errors.As(err, &verifyErr) && len(verifyErr.UnverifiedCertificates) > 0 {
+				result["details"] = map[string]interface{}{
+					"url":         testURL,
+					"certificate": certificateDetails(verifyErr.UnverifiedCertificates[0]),
+				}
+			}
+			return result
+		}
 		return map[string]interface{}{
 			"status":  "error",
+			"stage":   "connection",
 			"message": fmt.Sprintf("Connection failed: %v", err),
 		}
 	}
 	defer resp.Body.Close()
 
+	details := map[string]interface{}{
+		"url":         testURL,
+		"status_code": resp.StatusCode,
+	}
+	if resp.TLS != nil {
+		details["tls"] = connectionStateDetails(resp.TLS)
+	}
+
 	// For Grafana, check for authentication errors
 	if service == "grafana" {
 		if resp.StatusCode == 401 || resp.StatusCode == 403 {
 			return map[string]interface{}{
 				"status":  "error",
+				"stage":   "http",
 				"message": "Authentication failed - check username/password",
-				"details": map[string]interface{}{
-					"url":         testURL,
-					"status_code": resp.StatusCode,
-				},
+				"details": details,
 			}
 		}
 	}
@@ -465,19 +675,61 @@ func (bh *BasicHandlers) testServiceConnection(service string, config types.Serv
 		return map[string]interface{}{
 			"status":  "success",
 			"message": fmt.Sprintf("%s is accessible", service),
-			"details": map[string]interface{}{
-				"url":         testURL,
-				"status_code": resp.StatusCode,
-			},
-		}
-	} else {
-		return map[string]interface{}{
-			"status":  "error",
-			"message": fmt.Sprintf("%s returned HTTP %d", service, resp.StatusCode),
-			"details": map[string]interface{}{
-				"url":         testURL,
-				"status_code": resp.StatusCode,
-			},
+			"details": details,
 		}
 	}
+	return map[string]interface{}{
+		"status":  "error",
+		"stage":   "http",
+		"message": fmt.Sprintf("%s returned HTTP %d", service, resp.StatusCode),
+		"details": details,
+	}
+}
+
+// isTLSHandshakeError reports whether err came from the TLS handshake rather
+// than from connecting or from the HTTP exchange
+func isTLSHandshakeError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &recordErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return true
+	}
+	// Alerts sent by the server, e.g. a missing client certificate, are not exported types
+	return strings.Contains(err.Error(), "remote error: tls:")
+}
+
+// connectionStateDetails describes a negotiated TLS connection and the server's certificate
+func connectionStateDetails(state *tls.ConnectionState) map[string]interface{} {
+	details := map[string]interface{}{
+		"version":      tls.VersionName(state.Version),
+		"cipher_suite": tls.CipherSuiteName(state.CipherSuite),
+		"server_name":  state.ServerName,
+		"verified":     len(state.VerifiedChains) > 0,
+		"chain_length": len(state.PeerCertificates),
+	}
+	if len(state.PeerCertificates) > 0 {
+		details["certificate"] = certificateDetails(state.PeerCertificates[0])
+	}
+	return details
+}
+
+// certificateDetails summarises a certificate for connection test results
+func certificateDetails(cert *x509.Certificate) map[string]interface{} {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return map[string]interface{}{
+		"subject":    cert.Subject.String(),
+		"issuer":     cert.Issuer.String(),
+		"sans":       sans,
+		"serial":     cert.SerialNumber.String(),
+		"not_before": cert.NotBefore.Format(time.RFC3339),
+		"not_after":  cert.NotAfter.Format(time.RFC3339),
+		"days_left":  int(time.Until(cert.NotAfter).Hours() / 24),
+	}
 }
@@ -1,25 +1,34 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/nahuelsantos/argus/internal/models"
 	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
 )
 
 func TestNewBasicHandlers(t *testing.T) {
 	loggingService := services.NewLoggingService()
 	tracingService := services.NewTracingService()
 
-	handlers := NewBasicHandlers(loggingService, tracingService)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 	assert.NotNil(t, handlers)
 	assert.Equal(t, loggingService, handlers.loggingService)
@@ -54,7 +63,7 @@ func TestBasicHandlers_HealthHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request
 			req := httptest.NewRequest(tt.method, "/health", nil)
@@ -149,7 +158,7 @@ func TestBasicHandlers_GenerateMetricsHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request with query parameters
 			req := httptest.NewRequest("POST", "/generate-metrics", nil)
@@ -228,7 +237,7 @@ func TestBasicHandlers_GenerateLogsHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request
 			req := httptest.NewRequest("POST", "/generate-logs", nil)
@@ -291,7 +300,7 @@ func TestBasicHandlers_GenerateErrorHandler(t *testing.T) {
 				tracingService := services.NewTracingService()
 				loggingService.InitTestLogger()
 				tracingService.InitTracer()
-				handlers := NewBasicHandlers(loggingService, tracingService)
+				handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 				// Create request
 				req := httptest.NewRequest("POST", "/generate-error", nil)
@@ -372,7 +381,7 @@ func TestBasicHandlers_CPULoadHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request
 			req := httptest.NewRequest("POST", "/cpu-load", nil)
@@ -449,7 +458,7 @@ func TestBasicHandlers_MemoryLoadHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request
 			req := httptest.NewRequest("POST", "/memory-load", nil)
@@ -512,7 +521,7 @@ func TestBasicHandlers_LGTMStatusHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request
 			req := httptest.NewRequest("GET", "/lgtm-status", nil)
@@ -565,6 +574,30 @@ func TestBasicHandlers_SettingsHandler(t *testing.T) {
 			body:           `{"loki":{"url":"http://localhost:3100","username":"test","password":"pass"}}`,
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:           "POST settings with tracing exporter",
+			method:         "POST",
+			body:           `{"tracing":{"exporter":{"protocol":"grpc","endpoint":"localhost:4317","insecure":true,"headers":{"X-Scope-OrgID":"tenant-a"}},"sampling_rate":0.5,"batch":{"max_queue_size":1024,"max_export_batch_size":256}}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST settings with invalid tracing exporter",
+			method:         "POST",
+			body:           `{"tracing":{"exporter":{"protocol":"http/json","endpoint":"localhost:4318"},"sampling_rate":1}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "POST settings with log and metric export",
+			method:         "POST",
+			body:           `{"log_export":{"enabled":true,"exporter":{"protocol":"http/protobuf","endpoint":"http://localhost:4318"},"batch":{"max_export_batch_size":100}},"metric_export":{"enabled":false}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "POST settings with invalid metric export",
+			method:         "POST",
+			body:           `{"metric_export":{"enabled":true,"exporter":{"protocol":"grpc","endpoint":"localhost:4317"},"interval_ms":-1}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "POST settings with invalid JSON",
 			method:         "POST",
@@ -586,7 +619,7 @@ func TestBasicHandlers_SettingsHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request
 			var body *strings.Reader
@@ -621,6 +654,9 @@ func TestBasicHandlers_SettingsHandler(t *testing.T) {
 					assert.Contains(t, response, "grafana")
 					assert.Contains(t, response, "tempo")
 					assert.Contains(t, response, "prometheus")
+					assert.Contains(t, response, "tracing")
+					assert.Contains(t, response, "log_export")
+					assert.Contains(t, response, "metric_export")
 				} else if tt.method == "POST" {
 					// POST should return success message
 					assert.Contains(t, response, "message")
@@ -677,7 +713,7 @@ func TestBasicHandlers_TestConnectionHandler(t *testing.T) {
 			tracingService := services.NewTracingService()
 			loggingService.InitTestLogger()
 			tracingService.InitTracer()
-			handlers := NewBasicHandlers(loggingService, tracingService)
+			handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 			// Create request with JSON body (required by handler)
 			reqBody := `{"url": "http://localhost:3100", "username": "", "password": ""}`
@@ -713,13 +749,120 @@ func TestBasicHandlers_TestConnectionHandler(t *testing.T) {
 	}
 }
 
+func TestBasicHandlers_TenantHeaders(t *testing.T) {
+	loki := newHeaderRecorder(t)
+	tempo := newHeaderRecorder(t)
+	globalSettings = &types.LGTMSettings{
+		Loki:  types.ServiceConfig{URL: loki.URL, TenantID: "team-logs"},
+		Tempo: types.ServiceConfig{URL: tempo.URL, TenantID: "team-traces", BearerToken: "tempo-token"},
+	}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
+
+	w := httptest.NewRecorder()
+	handlers.LGTMStatusHandler(w, httptest.NewRequest("GET", "/lgtm-status", nil))
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "online", response["loki"])
+	assert.Equal(t, "online", response["tempo"])
+	loki.assertHeaders(t, types.TenantHeader, "team-logs")
+	tempo.assertHeaders(t, types.TenantHeader, "team-traces")
+	tempo.assertHeaders(t, "Authorization", "Bearer tempo-token")
+
+	// The connection test sends the tenant and headers from the posted config
+	prom := newHeaderRecorder(t)
+	body := `{"url": "` + prom.URL + `", "tenant_id": "team-metrics", "headers": {"X-Extra": "1"}}`
+	w = httptest.NewRecorder()
+	handlers.TestConnectionHandler(w, httptest.NewRequest("POST", "/api/test-connection/prometheus", strings.NewReader(body)))
+
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "success", response["status"])
+	prom.assertHeaders(t, types.TenantHeader, "team-metrics")
+	prom.assertHeaders(t, "X-Extra", "1")
+}
+
+func TestBasicHandlers_TestServiceConnectionTLS(t *testing.T) {
+	loki := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	loki.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	loki.StartTLS()
+	defer loki.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: loki.Certificate().Raw}), 0o600))
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
+
+	t.Run("trusted CA reports the certificate", func(t *testing.T) {
+		result := handlers.testServiceConnection("loki", types.ServiceConfig{URL: loki.URL, TLS: &types.TLSConfig{CAFile: caFile}})
+		assert.Equal(t, "success", result["status"])
+		tlsDetails := result["details"].(map[string]interface{})["tls"].(map[string]interface{})
+		assert.Equal(t, true, tlsDetails["verified"])
+		certificate := tlsDetails["certificate"].(map[string]interface{})
+		assert.Contains(t, certificate["sans"], "example.com")
+		assert.Equal(t, loki.Certificate().NotAfter.Format(time.RFC3339), certificate["not_after"])
+	})
+
+	t.Run("untrusted certificate is a handshake error", func(t *testing.T) {
+		result := handlers.testServiceConnection("loki", types.ServiceConfig{URL: loki.URL})
+		assert.Equal(t, "error", result["status"])
+		assert.Equal(t, "tls_handshake", result["stage"])
+		certificate := result["details"].(map[string]interface{})["certificate"].(map[string]interface{})
+		assert.Contains(t, certificate["issuer"], "Acme Co")
+	})
+
+	t.Run("HTTP errors are reported after the handshake", func(t *testing.T) {
+		result := handlers.testServiceConnection("tempo", types.ServiceConfig{URL: loki.URL + "/broken", TLS: &types.TLSConfig{InsecureSkipVerify: true}})
+		assert.Equal(t, "error", result["status"])
+		assert.Equal(t, "http", result["stage"])
+		assert.Contains(t, result["details"], "tls")
+	})
+
+	t.Run("connection and configuration errors", func(t *testing.T) {
+		result := handlers.testServiceConnection("loki", types.ServiceConfig{URL: "https://127.0.0.1:1"})
+		assert.Equal(t, "connection", result["stage"])
+
+		result = handlers.testServiceConnection("loki", types.ServiceConfig{URL: loki.URL, TLS: &types.TLSConfig{KeyFile: caFile}})
+		assert.Equal(t, "tls_config", result["stage"])
+	})
+
+	t.Run("handler takes TLS files from saved settings only", func(t *testing.T) {
+		t.Cleanup(func() { globalSettings = nil })
+		body := `{"url": "` + loki.URL + `", "tls": {"ca_file": "` + caFile + `"}}`
+		post := func() map[string]interface{} {
+			w := httptest.NewRecorder()
+			handlers.TestConnectionHandler(w, httptest.NewRequest("POST", "/api/test-connection/loki", strings.NewReader(body)))
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			return response
+		}
+
+		assert.Equal(t, "tls_handshake", post()["stage"], "the posted CA file must be ignored")
+
+		globalSettings = &types.LGTMSettings{Loki: types.ServiceConfig{URL: loki.URL, TLS: &types.TLSConfig{CAFile: caFile}}}
+		assert.Equal(t, "success", post()["status"])
+	})
+}
+
 // Benchmark tests for performance validation
 func BenchmarkBasicHandlers_HealthHandler(b *testing.B) {
 	loggingService := services.NewLoggingService()
 	tracingService := services.NewTracingService()
 	loggingService.InitTestLogger()
 	tracingService.InitTracer()
-	handlers := NewBasicHandlers(loggingService, tracingService)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 	req := httptest.NewRequest("GET", "/health", nil)
 
@@ -735,7 +878,7 @@ func BenchmarkBasicHandlers_GenerateMetricsHandler(b *testing.B) {
 	tracingService := services.NewTracingService()
 	loggingService.InitTestLogger()
 	tracingService.InitTracer()
-	handlers := NewBasicHandlers(loggingService, tracingService)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 	req := httptest.NewRequest("POST", "/generate-metrics?count=10", nil)
 
@@ -751,7 +894,7 @@ func BenchmarkBasicHandlers_GenerateLogsHandler(b *testing.B) {
 	tracingService := services.NewTracingService()
 	loggingService.InitTestLogger()
 	tracingService.InitTracer()
-	handlers := NewBasicHandlers(loggingService, tracingService)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 	req := httptest.NewRequest("POST", "/generate-logs?count=5", nil)
 
@@ -768,7 +911,7 @@ func ExampleBasicHandlers_HealthHandler() {
 	loggingService := services.NewLoggingService()
 	tracingService := services.NewTracingService()
 	// Don't initialize logger to avoid unwanted output
-	handlers := NewBasicHandlers(loggingService, tracingService)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	w := httptest.NewRecorder()
@@ -789,7 +932,7 @@ func ExampleBasicHandlers_GenerateMetricsHandler() {
 	loggingService := services.NewLoggingService()
 	tracingService := services.NewTracingService()
 	// Don't initialize logger to avoid unwanted output
-	handlers := NewBasicHandlers(loggingService, tracingService)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
 
 	req := httptest.NewRequest("POST", "/generate-metrics?count=5", nil)
 	w := httptest.NewRecorder()
@@ -802,3 +945,143 @@ func ExampleBasicHandlers_GenerateMetricsHandler() {
 	// Status: 200
 	// Generated metrics for LGTM stack testing
 }
+
+func TestBasicHandlers_LogSinks(t *testing.T) {
+	t.Cleanup(func() { globalSettings = nil })
+	loggingService := services.NewLoggingService()
+	loggingService.InitTestLogger()
+	t.Cleanup(loggingService.Sinks().Close)
+	handlers := NewBasicHandlers(loggingService, services.NewTracingService(), services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
+
+	path := filepath.Join(t.TempDir(), "argus.log")
+	body := fmt.Sprintf(`{"log_sinks":[{"name":"tail","type":"file","path":%q}]}`, path)
+	w := httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("POST", "/api/settings", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("GET", "/api/settings", nil))
+	var settings types.LGTMSettings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, []types.LogSinkConfig{{Name: "tail", Type: types.LogSinkFile, Path: path}}, settings.LogSinks)
+
+	w = httptest.NewRecorder()
+	handlers.GenerateLogsHandler(w, httptest.NewRequest("GET", "/generate-logs?count=3&sink=tail", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		assert.Contains(t, entry["msg"], "Generated")
+		assert.Contains(t, entry, "request_id")
+	}
+
+	w = httptest.NewRecorder()
+	handlers.LogSinksHandler(w, httptest.NewRequest("GET", "/api/log-sinks", nil))
+	var response struct {
+		Sinks []models.LogSinkStats `json:"sinks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Sinks, 2)
+	assert.Equal(t, "stdout", response.Sinks[0].Name)
+	assert.Equal(t, int64(3), response.Sinks[1].Entries)
+
+	// Errors and simulations write to the sink as well
+	w = httptest.NewRecorder()
+	handlers.GenerateErrorHandler(w, httptest.NewRequest("GET", "/generate-error?sink=tail", nil))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[3], `"error_type":`)
+
+	simulations := NewSimulationHandlers(loggingService, services.NewTracingService())
+	testingHandlers := NewTestingHandlers(loggingService, services.NewTracingService())
+	for target, handler := range map[string]http.HandlerFunc{
+		"/generate-error":             handlers.GenerateErrorHandler,
+		"/simulate/web-service":       simulations.SimulateWebServiceHandler,
+		"/simulate/api-service":       simulations.SimulateAPIServiceHandler,
+		"/simulate/database-service":  simulations.SimulateDatabaseServiceHandler,
+		"/simulate/static-site":       simulations.SimulateStaticSiteHandler,
+		"/simulate/microservice":      simulations.SimulateMicroserviceHandler,
+		"/simulate-service/wordpress": testingHandlers.SimulateWordPressServiceHandler,
+		"/simulate-service/nextjs":    testingHandlers.SimulateNextJSServiceHandler,
+	} {
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", target+"?sink=kafka", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+	w = httptest.NewRecorder()
+	testingHandlers.SimulateWordPressServiceHandler(w, httptest.NewRequest("GET", "/simulate-service/wordpress?sink=tail", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "WordPress service simulation completed")
+
+	w = httptest.NewRecorder()
+	handlers.GenerateLogsHandler(w, httptest.NewRequest("GET", "/generate-logs?sink=kafka", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "configured sinks: stdout, tail")
+
+	w = httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("POST", "/api/settings", strings.NewReader(`{"log_sinks":[{"name":"tail","type":"file"}]}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, ok := loggingService.Sinks().Get("tail")
+	assert.True(t, ok, "invalid sinks leave the current ones in place")
+}
+
+func TestBasicHandlers_SettingsRedactExporterHeaders(t *testing.T) {
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
+	t.Cleanup(func() { globalSettings = nil })
+
+	w := httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("POST", "/api/settings",
+		strings.NewReader(`{"tracing":{"exporter":{"protocol":"grpc","endpoint":"localhost:4317","insecure":true,"headers":{"Authorization":"Bearer abc"}}}}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 1.0, tracingService.Settings().SamplingRate, "a missing sampling_rate keeps every span")
+
+	w = httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("GET", "/api/settings", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Bearer abc")
+
+	// Saving the GET response back keeps the token in effect
+	var settings types.LGTMSettings
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, types.RedactedValue, settings.Tracing.Exporter.Headers["Authorization"])
+	body, err := json.Marshal(settings)
+	require.NoError(t, err)
+	w = httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("POST", "/api/settings", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Bearer abc", tracingService.Settings().Exporter.Headers["Authorization"])
+}
+
+func TestBasicHandlers_SettingsApplyAllOrNothing(t *testing.T) {
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	t.Cleanup(loggingService.Sinks().Close)
+	handlers := NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
+	t.Cleanup(func() { globalSettings = nil })
+	before := tracingService.Settings()
+
+	// The Loki sink only fails once it is built, after tracing was prepared
+	w := httptest.NewRecorder()
+	handlers.SettingsHandler(w, httptest.NewRequest("POST", "/api/settings", strings.NewReader(
+		`{"tracing":{"sampling_rate":0.5,"exporter":{"protocol":"grpc","endpoint":"localhost:4317","insecure":true}},"log_sinks":[{"name":"push","type":"loki"}]}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "no Loki URL configured")
+
+	assert.Equal(t, before, tracingService.Settings(), "tracing is left as it was")
+	assert.False(t, loggingService.Sinks().Active())
+	assert.Nil(t, globalSettings)
+}
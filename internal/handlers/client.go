@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// serviceClient sends the handlers' outbound requests to the LGTM stack and
+// applies each service's credentials, tenant ID, custom headers and TLS settings
+type serviceClient struct {
+	client *http.Client
+}
+
+// lgtmClient is shared by every handler; its timeout comes from ARGUS_CLIENT_TIMEOUT
+var lgtmClient = newServiceClient(config.GetSecurityConfig().ClientTimeout)
+
+func newServiceClient(timeout time.Duration) *serviceClient {
+	return &serviceClient{client: &http.Client{Timeout: timeout}}
+}
+
+// get requests path on the service. Callers may bound it further with ctx.
+func (c *serviceClient) get(ctx context.Context, service types.ServiceConfig, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(service.URL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(service, req)
+}
+
+// do sends req with the service's headers and TLS settings applied
+func (c *serviceClient) do(service types.ServiceConfig, req *http.Request) (*http.Response, error) {
+	return services.DoServiceRequest(c.client, service, req)
+}
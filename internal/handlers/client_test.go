@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// headerRecorder is a stand-in LGTM service that answers every request with
+// 200 and records the headers it was sent
+type headerRecorder struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newHeaderRecorder(t *testing.T) *headerRecorder {
+	recorder := &headerRecorder{}
+	recorder.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder.mu.Lock()
+		recorder.requests = append(recorder.requests, r)
+		recorder.mu.Unlock()
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(recorder.Close)
+	return recorder
+}
+
+// assertHeaders checks that at least one request arrived and all carried the header
+func (hr *headerRecorder) assertHeaders(t *testing.T, key, value string) {
+	hr.mu.Lock()
+	defer hr.mu.Unlock()
+	require.NotEmpty(t, hr.requests)
+	for _, req := range hr.requests {
+		assert.Equal(t, value, req.Header.Get(key), "%s %s", req.Method, req.URL)
+	}
+}
+
+func TestServiceClient(t *testing.T) {
+	recorder := newHeaderRecorder(t)
+	service := types.ServiceConfig{
+		URL:         recorder.URL + "/",
+		BearerToken: "token",
+		TenantID:    "team-a",
+		Headers:     map[string]string{"X-Extra": "1"},
+	}
+
+	resp, err := newServiceClient(time.Second).get(context.Background(), service, "/ready")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Len(t, recorder.requests, 1)
+	assert.Equal(t, "/ready", recorder.requests[0].URL.Path)
+	recorder.assertHeaders(t, "Authorization", "Bearer token")
+	recorder.assertHeaders(t, types.TenantHeader, "team-a")
+	recorder.assertHeaders(t, "X-Extra", "1")
+}
+
+func TestServiceClientTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	_, err := newServiceClient(20*time.Millisecond).get(context.Background(), types.ServiceConfig{URL: slow.URL}, "/")
+	assert.Error(t, err)
+}
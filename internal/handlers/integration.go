@@ -1,15 +1,20 @@
 package handlers
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nahuelsantos/argus/internal/models"
 	"github.com/nahuelsantos/argus/internal/services"
 	"github.com/nahuelsantos/argus/internal/types"
 	"github.com/nahuelsantos/argus/internal/utils"
@@ -25,20 +30,49 @@ func getGlobalSettings() *types.LGTMSettings {
 	return types.GetDefaults()
 }
 
+// getGrafanaSettings returns the Grafana settings, falling back to the default
+// admin credentials since most Grafana APIs require authentication
+func getGrafanaSettings() types.ServiceConfig {
+	grafanaConfig := getGlobalSettings().Grafana
+	if grafanaConfig.Username == "" {
+		grafanaConfig.Username = "admin"
+		grafanaConfig.Password = "admin"
+	}
+	return grafanaConfig
+}
+
 // LGTM Integration Testing Handlers
 // Tests that all monitoring components are properly configured and working together
 
 // IntegrationHandlers contains LGTM integration testing handlers
 type IntegrationHandlers struct {
-	loggingService *services.LoggingService
-	tracingService *services.TracingService
+	loggingService         *services.LoggingService
+	tracingService         *services.TracingService
+	dashboardService       *services.DashboardService
+	grafanaAlertingService *services.GrafanaAlertingService
+	lokiRulerService       *services.LokiRulerService
+	tempoSearchService     *services.TempoSearchService
+	serviceGraphService    *services.ServiceGraphService
+	collectorService       *services.CollectorPipelineService
+	scrapeCoverageService  *services.ScrapeCoverageService
+	exemplarService        *services.ExemplarService
+	metricsLintService     *services.MetricsLintService
 }
 
 // NewIntegrationHandlers creates a new integration handlers instance
 func NewIntegrationHandlers(loggingService *services.LoggingService, tracingService *services.TracingService) *IntegrationHandlers {
 	return &IntegrationHandlers{
-		loggingService: loggingService,
-		tracingService: tracingService,
+		loggingService:         loggingService,
+		tracingService:         tracingService,
+		dashboardService:       services.NewDashboardService(),
+		grafanaAlertingService: services.NewGrafanaAlertingService(),
+		lokiRulerService:       services.NewLokiRulerService(),
+		tempoSearchService:     services.NewTempoSearchService(),
+		serviceGraphService:    services.NewServiceGraphService(),
+		collectorService:       services.NewCollectorPipelineService(),
+		scrapeCoverageService:  services.NewScrapeCoverageService(),
+		exemplarService:        services.NewExemplarService(),
+		metricsLintService:     services.NewMetricsLintService(prometheus.DefaultGatherer),
 	}
 }
 
@@ -66,23 +100,23 @@ func (ih *IntegrationHandlers) TestLGTMIntegration(w http.ResponseWriter, r *htt
 	components := []LGTMIntegrationStatus{}
 
 	// Test Grafana datasources
-	grafanaStatus := ih.testGrafanaDatasources()
+	grafanaStatus := ih.testGrafanaDatasources(r.Context())
 	components = append(components, grafanaStatus)
 
 	// Test Prometheus targets
-	prometheusStatus := ih.testPrometheusTargets()
+	prometheusStatus := ih.testPrometheusTargets(r.Context())
 	components = append(components, prometheusStatus)
 
 	// Test Loki ingestion
-	lokiStatus := ih.testLokiIngestion()
+	lokiStatus := ih.testLokiIngestion(r.Context())
 	components = append(components, lokiStatus)
 
-	// Test Tempo tracing
-	tempoStatus := ih.testTempoTracing()
+	// Test Tempo tracing, searching for a probe trace only when asked
+	tempoStatus := ih.testTempoTracing(r.Context(), r.URL.Query().Get("tempo_search") == "true")
 	components = append(components, tempoStatus)
 
 	// Test OTEL Collector
-	otelStatus := ih.testOTELCollector()
+	otelStatus := ih.testOTELCollector(r.Context())
 	components = append(components, otelStatus)
 
 	// Calculate overall status
@@ -115,7 +149,7 @@ func (ih *IntegrationHandlers) TestLGTMIntegration(w http.ResponseWriter, r *htt
 }
 
 // Test Grafana Datasources
-func (ih *IntegrationHandlers) testGrafanaDatasources() LGTMIntegrationStatus {
+func (ih *IntegrationHandlers) testGrafanaDatasources(ctx context.Context) LGTMIntegrationStatus {
 	start := time.Now()
 	status := LGTMIntegrationStatus{
 		Component: "grafana_datasources",
@@ -123,8 +157,11 @@ func (ih *IntegrationHandlers) testGrafanaDatasources() LGTMIntegrationStatus {
 		Details:   make(map[string]string),
 	}
 
+	grafanaConfig := getGrafanaSettings()
+	status.Details["url"] = grafanaConfig.URL
+
 	// Test Grafana API health
-	resp, err := http.Get("http://grafana:3000/api/health")
+	resp, err := lgtmClient.get(ctx, grafanaConfig, "/api/health")
 	if err != nil {
 		status.Status = "failed"
 		status.Message = fmt.Sprintf("Cannot connect to Grafana: %v", err)
@@ -141,7 +178,7 @@ func (ih *IntegrationHandlers) testGrafanaDatasources() LGTMIntegrationStatus {
 	}
 
 	// Test datasources endpoint
-	dsResp, err := http.Get("http://grafana:3000/api/datasources")
+	dsResp, err := lgtmClient.get(ctx, grafanaConfig, "/api/datasources")
 	if err != nil {
 		status.Status = "degraded"
 		status.Message = "Grafana is running but datasources endpoint failed"
@@ -165,7 +202,7 @@ func (ih *IntegrationHandlers) testGrafanaDatasources() LGTMIntegrationStatus {
 }
 
 // Test Prometheus Targets
-func (ih *IntegrationHandlers) testPrometheusTargets() LGTMIntegrationStatus {
+func (ih *IntegrationHandlers) testPrometheusTargets(ctx context.Context) LGTMIntegrationStatus {
 	start := time.Now()
 	status := LGTMIntegrationStatus{
 		Component: "prometheus_targets",
@@ -173,8 +210,11 @@ func (ih *IntegrationHandlers) testPrometheusTargets() LGTMIntegrationStatus {
 		Details:   make(map[string]string),
 	}
 
+	prometheusConfig := getGlobalSettings().Prometheus
+	status.Details["url"] = prometheusConfig.URL
+
 	// Test Prometheus health
-	resp, err := http.Get("http://prometheus:9090/-/healthy")
+	resp, err := lgtmClient.get(ctx, prometheusConfig, "/-/healthy")
 	if err != nil {
 		status.Status = "failed"
 		status.Message = fmt.Sprintf("Cannot connect to Prometheus: %v", err)
@@ -190,25 +230,26 @@ func (ih *IntegrationHandlers) testPrometheusTargets() LGTMIntegrationStatus {
 		return status
 	}
 
-	// Test targets endpoint
-	targetsResp, err := http.Get("http://prometheus:9090/api/v1/targets")
+	// Analyse the active targets
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	report, err := ih.scrapeCoverageService.Analyze(ctx, prometheusConfig)
 	if err != nil {
 		status.Status = "degraded"
 		status.Message = "Prometheus is running but targets endpoint failed"
 		status.Details["error"] = err.Error()
 	} else {
-		defer targetsResp.Body.Close()
-		if targetsResp.StatusCode == 200 {
-			body, _ := io.ReadAll(targetsResp.Body)
-			upCount := strings.Count(string(body), `"health":"up"`)
-			totalCount := strings.Count(string(body), `"health":`)
-			status.Status = "healthy"
-			status.Message = fmt.Sprintf("Prometheus running with %d/%d targets up", upCount, totalCount)
-			status.Details["targets_up"] = strconv.Itoa(upCount)
-			status.Details["targets_total"] = strconv.Itoa(totalCount)
-		} else {
-			status.Status = "degraded"
-			status.Message = "Prometheus running but targets not accessible"
+		status.Status = "healthy"
+		status.Message = fmt.Sprintf("Prometheus running with %d/%d targets up", report.UpTargets, report.TotalTargets)
+		status.Details["targets_up"] = strconv.Itoa(report.UpTargets)
+		status.Details["targets_total"] = strconv.Itoa(report.TotalTargets)
+		status.Details["scrape_issues"] = strconv.Itoa(len(report.Issues))
+		for _, job := range report.Jobs {
+			if job.Up == 0 {
+				status.Status = "degraded"
+				status.Message = fmt.Sprintf("Prometheus running but job %s has no healthy instance (%d/%d targets up)", job.Job, report.UpTargets, report.TotalTargets)
+				break
+			}
 		}
 	}
 
@@ -216,8 +257,36 @@ func (ih *IntegrationHandlers) testPrometheusTargets() LGTMIntegrationStatus {
 	return status
 }
 
+// TestScrapeCoverage - Analyse Prometheus' active targets and recommend fixes
+func (ih *IntegrationHandlers) TestScrapeCoverage(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Analysing Prometheus scrape targets...")
+
+	prometheusConfig := getGlobalSettings().Prometheus
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	report, err := ih.scrapeCoverageService.Analyze(ctx, prometheusConfig)
+	if err != nil {
+		result := map[string]interface{}{
+			"status":         "connection_error",
+			"message":        "Cannot list Prometheus targets",
+			"error":          err.Error(),
+			"prometheus_url": prometheusConfig.URL,
+			"timestamp":      time.Now(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, result)
+		return
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), fmt.Sprintf("Scrape coverage: %d/%d targets up, %d issues, %d recommendations",
+		report.UpTargets, report.TotalTargets, len(report.Issues), len(report.Recommendations)))
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, report)
+}
+
 // Test Loki Ingestion
-func (ih *IntegrationHandlers) testLokiIngestion() LGTMIntegrationStatus {
+func (ih *IntegrationHandlers) testLokiIngestion(ctx context.Context) LGTMIntegrationStatus {
 	start := time.Now()
 	status := LGTMIntegrationStatus{
 		Component: "loki_ingestion",
@@ -225,8 +294,11 @@ func (ih *IntegrationHandlers) testLokiIngestion() LGTMIntegrationStatus {
 		Details:   make(map[string]string),
 	}
 
+	lokiConfig := getGlobalSettings().Loki
+	status.Details["url"] = lokiConfig.URL
+
 	// Test Loki ready endpoint
-	resp, err := http.Get("http://loki:3100/ready")
+	resp, err := lgtmClient.get(ctx, lokiConfig, "/ready")
 	if err != nil {
 		status.Status = "failed"
 		status.Message = fmt.Sprintf("Cannot connect to Loki: %v", err)
@@ -243,7 +315,7 @@ func (ih *IntegrationHandlers) testLokiIngestion() LGTMIntegrationStatus {
 	}
 
 	// Test metrics endpoint for ingestion stats
-	metricsResp, err := http.Get("http://loki:3100/metrics")
+	metricsResp, err := lgtmClient.get(ctx, lokiConfig, "/metrics")
 	if err != nil {
 		status.Status = "degraded"
 		status.Message = "Loki is ready but metrics endpoint failed"
@@ -272,12 +344,28 @@ func (ih *IntegrationHandlers) testLokiIngestion() LGTMIntegrationStatus {
 		}
 	}
 
+	// Check the ruler: rule groups loaded and their LogQL parses
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if report, err := ih.lokiRulerService.Validate(ctx, lokiConfig); err != nil {
+		status.Details["ruler"] = err.Error()
+	} else {
+		status.Details["rule_groups"] = strconv.Itoa(len(report.RuleGroups))
+		status.Details["rules"] = strconv.Itoa(report.TotalRules)
+		status.Details["invalid_rules"] = strconv.Itoa(report.InvalidRules)
+		if report.InvalidRules > 0 && status.Status == "healthy" {
+			status.Status = "degraded"
+			status.Message = fmt.Sprintf("Loki ingesting logs but %d ruler rules have invalid LogQL", report.InvalidRules)
+		}
+	}
+
 	status.ResponseTime = time.Since(start)
 	return status
 }
 
-// Test Tempo Tracing
-func (ih *IntegrationHandlers) testTempoTracing() LGTMIntegrationStatus {
+// Test Tempo Tracing - with search, also find a trace Argus just emitted so
+// search backend regressions show up
+func (ih *IntegrationHandlers) testTempoTracing(ctx context.Context, search bool) LGTMIntegrationStatus {
 	start := time.Now()
 	status := LGTMIntegrationStatus{
 		Component: "tempo_tracing",
@@ -285,8 +373,11 @@ func (ih *IntegrationHandlers) testTempoTracing() LGTMIntegrationStatus {
 		Details:   make(map[string]string),
 	}
 
+	tempoConfig := getGlobalSettings().Tempo
+	status.Details["url"] = tempoConfig.URL
+
 	// Test Tempo ready endpoint
-	resp, err := http.Get("http://tempo:3200/ready")
+	resp, err := lgtmClient.get(ctx, tempoConfig, "/ready")
 	if err != nil {
 		status.Status = "failed"
 		status.Message = fmt.Sprintf("Cannot connect to Tempo: %v", err)
@@ -303,7 +394,7 @@ func (ih *IntegrationHandlers) testTempoTracing() LGTMIntegrationStatus {
 	}
 
 	// Test status endpoint
-	statusResp, err := http.Get("http://tempo:3200/status")
+	statusResp, err := lgtmClient.get(ctx, tempoConfig, "/status")
 	if err != nil {
 		status.Status = "degraded"
 		status.Message = "Tempo is ready but status endpoint failed"
@@ -320,12 +411,74 @@ func (ih *IntegrationHandlers) testTempoTracing() LGTMIntegrationStatus {
 		}
 	}
 
+	if search && status.Status == "healthy" {
+		report, err := ih.runTempoSearch(ctx, tempoConfig, 15*time.Second)
+		if err != nil {
+			status.Details["search"] = err.Error()
+		} else {
+			status.Details["search"] = report.Status
+			for _, search := range report.Searches {
+				status.Details["search_"+search.Name+"_ms"] = strconv.FormatInt(search.Duration.Milliseconds(), 10)
+			}
+			if report.Status != "healthy" {
+				status.Status = "degraded"
+				status.Message = "Tempo ready but search did not find Argus traces: " + strings.Join(report.Problems, "; ")
+			}
+		}
+	}
+
 	status.ResponseTime = time.Since(start)
 	return status
 }
 
+// runTempoSearch emits a probe trace tagged with a fresh run ID and searches Tempo for it
+func (ih *IntegrationHandlers) runTempoSearch(ctx context.Context, tempo types.ServiceConfig, timeout time.Duration) (*models.TempoSearchReport, error) {
+	runID := uuid.New().String()
+	emitted := time.Now()
+
+	traceID, err := ih.tracingService.EmitSearchProbe(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ih.tempoSearchService.Validate(ctx, tempo, ih.tracingService.ServiceName(), runID, traceID, emitted, timeout), nil
+}
+
+// Test Tempo Search - Find a freshly emitted trace through TraceQL and tag search
+func (ih *IntegrationHandlers) TestTempoSearch(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Testing Tempo search and TraceQL...")
+
+	timeout := 30 * time.Second
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= 2*time.Minute {
+			timeout = parsed
+		}
+	}
+
+	tempoConfig := getGlobalSettings().Tempo
+
+	var result interface{}
+	report, err := ih.runTempoSearch(r.Context(), tempoConfig, timeout)
+	if err != nil {
+		result = map[string]interface{}{
+			"status":    "error",
+			"message":   "Cannot emit probe trace",
+			"error":     err.Error(),
+			"tempo_url": tempoConfig.URL,
+			"timestamp": time.Now(),
+		}
+	} else {
+		result = report
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), "Tempo search test completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, result)
+}
+
 // Test OTEL Collector
-func (ih *IntegrationHandlers) testOTELCollector() LGTMIntegrationStatus {
+func (ih *IntegrationHandlers) testOTELCollector(ctx context.Context) LGTMIntegrationStatus {
 	start := time.Now()
 	status := LGTMIntegrationStatus{
 		Component: "otel_collector",
@@ -333,8 +486,11 @@ func (ih *IntegrationHandlers) testOTELCollector() LGTMIntegrationStatus {
 		Details:   make(map[string]string),
 	}
 
+	collectorConfig := getGlobalSettings().OTELCollector
+	status.Details["url"] = collectorConfig.URL
+
 	// Test OTEL Collector metrics endpoint
-	resp, err := http.Get("http://otel-collector:8888/metrics")
+	resp, err := lgtmClient.get(ctx, collectorConfig.ServiceConfig, "/metrics")
 	if err != nil {
 		status.Status = "failed"
 		status.Message = fmt.Sprintf("Cannot connect to OTEL Collector: %v", err)
@@ -382,157 +538,405 @@ func (ih *IntegrationHandlers) testOTELCollector() LGTMIntegrationStatus {
 	return status
 }
 
-// Test Grafana Dashboard Creation
+// Test Grafana Dashboard Provisioning - Upsert the bundled dashboard library
+// Pass dry_run=true to only report what would change, and uid=a,b to limit the dashboards
 func (ih *IntegrationHandlers) TestGrafanaDashboards(w http.ResponseWriter, r *http.Request) {
-	ih.loggingService.LogWithContext(0, r.Context(), "Creating Argus test dashboard in Grafana...")
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	var uids []string
+	if uidParam := r.URL.Query().Get("uid"); uidParam != "" {
+		uids = strings.Split(uidParam, ",")
+	}
 
-	// Load dashboard config
-	dashboardPath := "internal/configs/grafana/argus-test-dashboard.json"
-	dashboardData, err := os.ReadFile(dashboardPath)
-	if err != nil {
-		result := map[string]interface{}{
-			"status":    "error",
-			"message":   "Cannot load dashboard configuration",
-			"error":     err.Error(),
-			"timestamp": time.Now(),
-		}
-		w.Header().Set("Content-Type", "application/json")
-		utils.EncodeJSON(w, result)
+	if dryRun {
+		ih.loggingService.LogWithContext(0, r.Context(), "Diffing Argus dashboard library against Grafana...")
+	} else {
+		ih.loggingService.LogWithContext(0, r.Context(), "Provisioning Argus dashboard library in Grafana...")
+	}
+
+	grafanaConfig := getGrafanaSettings()
+
+	provision, err := ih.dashboardService.Provision(r.Context(), grafanaConfig, uids, dryRun)
+	var unknown *services.UnknownDashboardsError
+	if errors.As(err, &unknown) {
+		http.Error(w, fmt.Sprintf("Invalid uid: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Get settings from global settings or defaults
-	settings := getGlobalSettings()
-	grafanaConfig := settings.Grafana
+	var result map[string]interface{}
+	var apiErr *services.GrafanaAPIError
+
+	switch {
+	case err == nil:
+		status := "provisioned"
+		message := fmt.Sprintf("✅ Argus dashboard library v%s provisioned: %d created, %d updated, %d unchanged",
+			provision.LibraryVersion, provision.Created, provision.Updated, provision.Unchanged)
+		if dryRun {
+			status = "dry_run"
+			message = fmt.Sprintf("Dry run: %d dashboards would be created, %d updated, %d unchanged",
+				provision.Created, provision.Updated, provision.Unchanged)
+		}
+		if provision.Failed > 0 {
+			status = "partial"
+			message = fmt.Sprintf("⚠️ %d of %d dashboards could not be provisioned", provision.Failed, len(provision.Changes)-1)
+		}
 
-	// Create the dashboard creation URL
-	grafanaURL := grafanaConfig.URL + "/api/dashboards/db"
+		result = map[string]interface{}{
+			"status":      status,
+			"message":     message,
+			"provision":   provision,
+			"grafana_url": grafanaConfig.URL,
+			"folder_url":  grafanaConfig.URL + "/dashboards/f/" + provision.Folder.UID,
+			"timestamp":   time.Now(),
+		}
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403):
+		result = map[string]interface{}{
+			"status":      "auth_error",
+			"message":     "Authentication failed - check Grafana credentials in Settings",
+			"error":       err.Error(),
+			"grafana_url": grafanaConfig.URL,
+			"instructions": []string{
+				"1. Go to Settings and verify Grafana username/password",
+				"2. Test connection to ensure credentials work",
+				"3. Try running the dashboard test again",
+			},
+			"timestamp": time.Now(),
+		}
+	case apiErr != nil:
+		result = map[string]interface{}{
+			"status":      "error",
+			"message":     "Grafana rejected the dashboard library",
+			"error":       err.Error(),
+			"grafana_url": grafanaConfig.URL,
+			"timestamp":   time.Now(),
+		}
+	default:
+		// Fallback to pointing at the library for manual import
+		result = map[string]interface{}{
+			"status":      "manual_import_required",
+			"message":     "Could not connect to Grafana - import the dashboard library manually",
+			"error":       err.Error(),
+			"grafana_url": grafanaConfig.URL,
+			"instructions": []string{
+				"1. Download a dashboard from /api/dashboards?uid=<uid>",
+				"2. Go to " + grafanaConfig.URL + " and login to Grafana",
+				"3. Navigate to '+' → Import and upload the dashboard JSON",
+				"4. List every bundled dashboard at /api/dashboards",
+			},
+			"timestamp": time.Now(),
+		}
+	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", grafanaURL, bytes.NewBuffer(dashboardData))
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, result)
+}
+
+// DashboardLibraryHandler lists the bundled dashboard library, or returns a
+// single dashboard model for manual import when uid is given
+func (ih *IntegrationHandlers) DashboardLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	library, err := ih.dashboardService.LoadLibrary()
 	if err != nil {
-		result := map[string]interface{}{
-			"status":    "error",
-			"message":   "Cannot create dashboard request",
-			"error":     err.Error(),
-			"timestamp": time.Now(),
+		http.Error(w, fmt.Sprintf("Cannot load dashboard library: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if uid := r.URL.Query().Get("uid"); uid != "" {
+		for _, dashboard := range library.Dashboards {
+			if dashboard.UID == uid {
+				w.Header().Set("Content-Type", "application/json")
+				utils.EncodeJSON(w, dashboard.Model)
+				return
+			}
 		}
-		w.Header().Set("Content-Type", "application/json")
-		utils.EncodeJSON(w, result)
+		http.Error(w, "Unknown dashboard uid", http.StatusNotFound)
 		return
 	}
 
-	// Set proper headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, library)
+}
 
-	// Add authentication - Grafana requires credentials
-	if grafanaConfig.Username != "" {
-		req.SetBasicAuth(grafanaConfig.Username, grafanaConfig.Password)
-	} else {
-		// Use default admin credentials if none provided
-		req.SetBasicAuth("admin", "admin")
+// Test Grafana Alerting - Validate Grafana-managed (unified) alert rules, contact points and policies
+func (ih *IntegrationHandlers) TestGrafanaAlerting(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Testing Grafana-managed alerting configuration...")
+
+	receiverTimeout := 10 * time.Second
+	if t := r.URL.Query().Get("receiver_timeout"); t != "" {
+		if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= time.Minute {
+			receiverTimeout = parsed
+		}
+	}
+
+	// Test notifications page whoever is behind a contact point, so they are opt-in
+	var testPoints []string
+	switch points := r.URL.Query().Get("test_contact_points"); points {
+	case "", "false":
+	case "true":
+		testPoints = []string{services.AllContactPoints}
+	default:
+		testPoints = strings.Split(points, ",")
 	}
 
-	// Create HTTP client with timeout
-	client := &http.Client{Timeout: 15 * time.Second}
-	resp, err := client.Do(req)
+	grafanaConfig := getGrafanaSettings()
 
+	var result interface{}
+	report, err := ih.grafanaAlertingService.Validate(r.Context(), grafanaConfig, receiverTimeout, testPoints)
 	if err != nil {
-		// Fallback to providing JSON for manual import
-		result := map[string]interface{}{
-			"status":          "manual_import_required",
-			"message":         "Could not connect to Grafana - providing JSON for manual import",
-			"error":           err.Error(),
-			"dashboard_title": "Argus Testing Dashboard",
-			"dashboard_uid":   "argus-test-dashboard",
-			"grafana_url":     grafanaConfig.URL,
-			"dashboard_url":   grafanaConfig.URL + "/d/argus-test-dashboard",
-			"instructions": []string{
-				"1. Go to " + grafanaConfig.URL + " and login to Grafana",
-				"2. Navigate to '+' → Import",
-				"3. Upload the dashboard JSON provided below",
-				"4. Dashboard will be accessible at: " + grafanaConfig.URL + "/d/argus-test-dashboard",
-			},
-			"dashboard_json": string(dashboardData),
-			"timestamp":      time.Now(),
+		status := "connection_error"
+		var apiErr *services.GrafanaAPIError
+		if errors.As(err, &apiErr) {
+			status = "api_error"
+			if apiErr.StatusCode == 401 || apiErr.StatusCode == 403 {
+				status = "auth_error"
+			}
 		}
-		w.Header().Set("Content-Type", "application/json")
-		utils.EncodeJSON(w, result)
+		result = map[string]interface{}{
+			"status":      status,
+			"message":     "Cannot validate Grafana alerting",
+			"error":       err.Error(),
+			"grafana_url": grafanaConfig.URL,
+			"timestamp":   time.Now(),
+		}
+	} else {
+		result = report
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), "Grafana alerting test completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, result)
+}
+
+// AlertWebhookHandler is the Argus-hosted receiver for test notifications
+// sent by Grafana or Alertmanager to /api/alerting/webhook/{test-id}
+func (ih *IntegrationHandlers) AlertWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	testID := strings.TrimPrefix(r.URL.Path, "/api/alerting/webhook/")
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Cannot read payload", http.StatusBadRequest)
 		return
 	}
-	defer resp.Body.Close()
 
-	responseBody, _ := io.ReadAll(resp.Body)
+	expected := ih.grafanaAlertingService.Receiver().Deliver(testID, payload)
+	ih.loggingService.LogWithContext(0, r.Context(), fmt.Sprintf("Alert notification received for test %s (expected=%t)", testID, expected))
 
-	var result map[string]interface{}
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, map[string]interface{}{
+		"status":    "received",
+		"test_id":   testID,
+		"expected":  expected,
+		"timestamp": time.Now(),
+	})
+}
 
-	// Check authentication first
-	if resp.StatusCode == 401 || resp.StatusCode == 403 {
+// Test Tempo Service Graph - Emit a known topology and verify metrics-generator series in Prometheus
+func (ih *IntegrationHandlers) TestTempoServiceGraph(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Testing Tempo metrics-generator service graph...")
+
+	iterations := 5
+	if n, err := strconv.Atoi(r.URL.Query().Get("iterations")); err == nil && n > 0 && n <= 100 {
+		iterations = n
+	}
+	timeout := 2 * time.Minute
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= 10*time.Minute {
+			timeout = parsed
+		}
+	}
+
+	prometheusConfig := getGlobalSettings().Prometheus
+	scenarios := services.CrossServiceScenarios
+	runID := uuid.New().String()
+
+	emit := func(ctx context.Context) (int, error) {
+		return ih.tracingService.EmitTopology(ctx, scenarios, runID, iterations)
+	}
+
+	var result interface{}
+	report, err := ih.serviceGraphService.Verify(r.Context(), prometheusConfig, scenarios, runID, iterations, emit, timeout)
+	if err != nil {
 		result = map[string]interface{}{
-			"status":      "auth_error",
-			"message":     "Authentication failed - check Grafana credentials in Settings",
-			"error":       fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(responseBody)),
-			"grafana_url": grafanaConfig.URL,
-			"instructions": []string{
-				"1. Go to Settings and verify Grafana username/password",
-				"2. Test connection to ensure credentials work",
-				"3. Try running the dashboard test again",
-			},
-			"timestamp": time.Now(),
+			"status":         "error",
+			"message":        "Cannot verify Tempo service graph",
+			"error":          err.Error(),
+			"prometheus_url": prometheusConfig.URL,
+			"timestamp":      time.Now(),
 		}
-	} else if resp.StatusCode == 200 || resp.StatusCode == 412 { // 412 = already exists
-		status := "created"
-		message := "✅ Argus test dashboard created successfully in Grafana!"
+	} else {
+		result = report
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), "Tempo service graph test completed")
 
-		if resp.StatusCode == 412 {
-			status = "updated"
-			message = "✅ Argus test dashboard already exists - updated successfully!"
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, result)
+}
+
+// Test Exemplars - Observe real span durations into the native latency histograms
+// with trace ID exemplars, then check Prometheus stored them and Grafana links them to Tempo
+func (ih *IntegrationHandlers) TestExemplars(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Testing histogram exemplars...")
+
+	count := 10
+	if n, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil && n > 0 && n <= 100 {
+		count = n
+	}
+	// Exemplars only reach Prometheus with the next scrape of /metrics
+	timeout := time.Minute
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= 5*time.Minute {
+			timeout = parsed
 		}
+	}
+
+	settings := getGlobalSettings()
+	emitted := time.Now()
 
+	var result interface{}
+	probes, err := ih.tracingService.EmitExemplarProbes(r.Context(), uuid.New().String(), count)
+	if err != nil {
 		result = map[string]interface{}{
-			"status":          status,
-			"message":         message,
-			"dashboard_title": "Argus Testing Dashboard",
-			"dashboard_uid":   "argus-test-dashboard",
-			"grafana_url":     grafanaConfig.URL,
-			"dashboard_url":   grafanaConfig.URL + "/d/argus-test-dashboard",
-			"panels": []string{
-				"Performance Test Results",
-				"System Resource Usage",
-				"LGTM Stack Health",
-				"Generated Metrics Over Time",
-				"Log Generation Rate",
-				"Test Execution Status",
-			},
-			"actions_completed": []string{
-				"✅ Dashboard JSON loaded from config",
-				"✅ Connected to Grafana at " + grafanaConfig.URL,
-				"✅ Dashboard created/updated successfully",
-				"✅ Direct access URL provided",
-			},
-			"timestamp": time.Now(),
+			"status":         "error",
+			"message":        "Cannot emit exemplar probes",
+			"error":          err.Error(),
+			"prometheus_url": settings.Prometheus.URL,
+			"timestamp":      time.Now(),
+		}
+	} else {
+		result = ih.exemplarService.Validate(r.Context(), settings.Prometheus, getGrafanaSettings(), ih.tracingService.ServiceName(), probes, emitted, timeout)
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), "Exemplar test completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, result)
+}
+
+// Test Metrics Lint - Check an exposition's naming, HELP/TYPE lines, label names
+// and histogram buckets: Argus' own metrics, or any target's with ?target=URL
+func (ih *IntegrationHandlers) TestMetricsLint(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Linting metrics exposition...")
+
+	var report *models.MetricsLintReport
+	if target := r.URL.Query().Get("target"); target != "" {
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			http.Error(w, "target must be an http(s) URL", http.StatusBadRequest)
+			return
 		}
+		report = ih.metricsLintService.LintURL(r.Context(), target)
 	} else {
+		report = ih.metricsLintService.LintSelf()
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), "Metrics lint completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, report)
+}
+
+// Test OTEL Collector Pipelines - Send known spans, logs and metric points through the
+// collector's OTLP receiver and compare its receiver, processor and exporter counters
+func (ih *IntegrationHandlers) TestOTELPipeline(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Testing OTEL Collector pipelines...")
+
+	query := r.URL.Query()
+	count := func(name string) int {
+		if n, err := strconv.Atoi(query.Get(name)); err == nil && n >= 0 && n <= 10000 {
+			return n
+		}
+		return 100
+	}
+	load := models.CollectorLoad{Spans: count("spans"), LogRecords: count("logs"), MetricPoints: count("metrics")}
+
+	timeout := 30 * time.Second
+	if t := query.Get("timeout"); t != "" {
+		if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= 5*time.Minute {
+			timeout = parsed
+		}
+	}
+
+	collectorConfig := getGlobalSettings().OTELCollector
+	telemetry := collectorConfig.ServiceConfig
+	if u := query.Get("telemetry_url"); u != "" {
+		telemetry.URL = u
+	}
+	exporter := collectorConfig.Exporter()
+	if e := query.Get("otlp_endpoint"); e != "" {
+		exporter.Endpoint = e
+	}
+	if p := query.Get("protocol"); p != "" {
+		exporter.Protocol = p
+	}
+
+	var result interface{}
+	report, err := ih.collectorService.Validate(r.Context(), telemetry, exporter, load, uuid.New().String(), timeout)
+	if err != nil {
 		result = map[string]interface{}{
-			"status":      "error",
-			"message":     fmt.Sprintf("Failed to create dashboard: HTTP %d", resp.StatusCode),
-			"response":    string(responseBody),
-			"grafana_url": grafanaConfig.URL,
-			"fallback_instructions": []string{
-				"1. Copy the dashboard JSON below",
-				"2. Go to " + grafanaConfig.URL + " and manually import it",
-				"3. Check Grafana logs for more details",
-			},
-			"dashboard_json": string(dashboardData),
-			"timestamp":      time.Now(),
+			"status":        "error",
+			"message":       "Cannot validate OTEL Collector pipelines",
+			"error":         err.Error(),
+			"telemetry_url": telemetry.URL,
+			"timestamp":     time.Now(),
 		}
+	} else {
+		result = report
 	}
 
+	ih.loggingService.LogWithContext(0, r.Context(), "OTEL Collector pipeline test completed")
+
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, result)
 }
 
+// Test Loki Rules - Validate Loki ruler rule groups and optionally drill a log-based alert
+func (ih *IntegrationHandlers) TestLokiRules(w http.ResponseWriter, r *http.Request) {
+	ih.loggingService.LogWithContext(0, r.Context(), "Testing Loki ruler configuration...")
+
+	settings := getGlobalSettings()
+	lokiConfig := settings.Loki
+
+	report, err := ih.lokiRulerService.Validate(r.Context(), lokiConfig)
+	if err != nil {
+		result := map[string]interface{}{
+			"status":    "connection_error",
+			"message":   "Cannot list Loki rule groups",
+			"error":     err.Error(),
+			"loki_url":  lokiConfig.URL,
+			"timestamp": time.Now(),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, result)
+		return
+	}
+
+	if r.URL.Query().Get("drill") == "true" {
+		timeout := 3 * time.Minute
+		if t := r.URL.Query().Get("timeout"); t != "" {
+			if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= 10*time.Minute {
+				timeout = parsed
+			}
+		}
+
+		ih.loggingService.LogWithContext(0, r.Context(), fmt.Sprintf("Running Loki alert drill (timeout %s)...", timeout))
+		drill := ih.lokiRulerService.RunDrill(r.Context(), lokiConfig, settings.AlertManager, timeout)
+		report.Drill = &drill
+		if !drill.InAlertmanager {
+			report.Status = "failed"
+			report.Problems = append(report.Problems, "Loki alert drill failed: "+drill.Error)
+		}
+	}
+
+	ih.loggingService.LogWithContext(0, r.Context(), "Loki ruler test completed")
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, report)
+}
+
 // Test Alert Rules Configuration - Verify rules are loaded and working
 func (ih *IntegrationHandlers) TestAlertRules(w http.ResponseWriter, r *http.Request) {
 	ih.loggingService.LogWithContext(0, r.Context(), "Testing Prometheus alert rules configuration...")
@@ -541,11 +945,8 @@ func (ih *IntegrationHandlers) TestAlertRules(w http.ResponseWriter, r *http.Req
 	settings := getGlobalSettings()
 	prometheusConfig := settings.Prometheus
 
-	client := &http.Client{Timeout: 10 * time.Second}
-
 	// Test 1: Check if Prometheus rules API is accessible
-	rulesURL := prometheusConfig.URL + "/api/v1/rules"
-	rulesResp, err := client.Get(rulesURL)
+	rulesResp, err := lgtmClient.get(r.Context(), prometheusConfig, "/api/v1/rules")
 	if err != nil {
 		result := map[string]interface{}{
 			"status":         "connection_error",
@@ -589,8 +990,7 @@ func (ih *IntegrationHandlers) TestAlertRules(w http.ResponseWriter, r *http.Req
 	}
 
 	// Test 2: Check alerts endpoint
-	alertsURL := prometheusConfig.URL + "/api/v1/alerts"
-	alertsResp, err := client.Get(alertsURL)
+	alertsResp, err := lgtmClient.get(r.Context(), prometheusConfig, "/api/v1/alerts")
 	var alertsBody []byte
 	alertsAccessible := false
 
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/nahuelsantos/argus/internal/types"
+	"github.com/nahuelsantos/argus/internal/utils"
+)
+
+// MetricPatternsHandler lists pattern series and presets (GET) and starts a
+// series (POST) on /api/metric-patterns
+func (bh *BasicHandlers) MetricPatternsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, map[string]interface{}{
+			"series":    bh.patternEngine.List(),
+			"presets":   types.PatternPresets,
+			"timestamp": time.Now(),
+		})
+	case "POST":
+		var series types.MetricSeries
+		if err := json.NewDecoder(r.Body).Decode(&series); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		created, err := bh.patternEngine.Set(series)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid pattern series: %v", err), http.StatusBadRequest)
+			return
+		}
+		bh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(),
+			fmt.Sprintf("Pattern series %s started: %s %s", created.Name, created.Metric, strings.Join(created.Components, "+")))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.EncodeJSON(w, created)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// MetricPatternHandler reads (GET) and stops (DELETE) the series at
+// /api/metric-patterns/{name}
+func (bh *BasicHandlers) MetricPatternHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/metric-patterns/")
+	switch r.Method {
+	case "GET":
+		series, ok := bh.patternEngine.Get(name)
+		if !ok {
+			http.Error(w, "Pattern series not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, series)
+	case "DELETE":
+		if !bh.patternEngine.Remove(name) {
+			http.Error(w, "Pattern series not found", http.StatusNotFound)
+			return
+		}
+		bh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), fmt.Sprintf("Pattern series %s stopped", name))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
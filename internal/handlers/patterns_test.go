@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func newTestPatternHandlers() *BasicHandlers {
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	return NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine())
+}
+
+func TestBasicHandlers_MetricPatterns(t *testing.T) {
+	handlers := newTestPatternHandlers()
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if path == "/api/metric-patterns" {
+			handlers.MetricPatternsHandler(w, req)
+		} else {
+			handlers.MetricPatternHandler(w, req)
+		}
+		return w
+	}
+
+	w := request("POST", "/api/metric-patterns", `{"name": "checkout_latency", "seed": 7, "pattern": [
+		{"type": "constant", "value": 120},
+		{"type": "sine", "amplitude": 30, "period_seconds": 3600},
+		{"type": "spikes", "value": 900, "probability": 0.02}
+	]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.PatternSeries
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "gauge", created.Kind)
+	assert.Equal(t, "argus_pattern_value", created.Metric)
+	assert.Equal(t, []string{"constant", "sine", "spikes"}, created.Components)
+	assert.Equal(t, int64(7), created.Seed)
+
+	w = request("POST", "/api/metric-patterns", `{"name": "orders", "preset": "counter_resets"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	w = request("GET", "/api/metric-patterns", "")
+	var list struct {
+		Series  []models.PatternSeries        `json:"series"`
+		Presets map[string]types.MetricSeries `json:"presets"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Series, 2)
+	assert.Equal(t, "checkout_latency", list.Series[0].Name)
+	assert.Equal(t, "argus_pattern_total", list.Series[1].Metric)
+	assert.Contains(t, list.Presets, "spiky")
+
+	w = request("GET", "/api/metric-patterns/orders", "")
+	var series types.MetricSeries
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+	assert.Equal(t, "counter", series.Kind)
+	assert.Len(t, series.Pattern, 3)
+
+	assert.Equal(t, http.StatusNoContent, request("DELETE", "/api/metric-patterns/orders", "").Code)
+	assert.Equal(t, http.StatusNotFound, request("DELETE", "/api/metric-patterns/orders", "").Code)
+	assert.Equal(t, http.StatusNotFound, request("GET", "/api/metric-patterns/orders", "").Code)
+
+	for _, body := range []string{
+		`{"name": "x"}`,
+		`{"name": "x", "preset": "nope"}`,
+		`{"name": "x", "pattern": [{"type": "sine", "amplitude": 1}]}`,
+		`{"name": "x", "pattern": [{"type": "counter_reset", "every_seconds": 60}]}`,
+		`not json`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, request("POST", "/api/metric-patterns", body).Code, body)
+	}
+}
+
+func TestBasicHandlers_GenerateMetricsPattern(t *testing.T) {
+	handlers := newTestPatternHandlers()
+
+	w := httptest.NewRecorder()
+	handlers.GenerateMetricsHandler(w, httptest.NewRequest("GET", "/generate-metrics?pattern=spiky&series=api_errors", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Metrics generated successfully", response["message"])
+	series := response["pattern_series"].(map[string]interface{})
+	assert.Equal(t, "api_errors", series["name"])
+	assert.Equal(t, "spiky", series["preset"])
+	require.Len(t, handlers.patternEngine.List(), 1)
+
+	w = httptest.NewRecorder()
+	handlers.GenerateMetricsHandler(w, httptest.NewRequest("GET", "/generate-metrics?pattern=flat", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "counter_resets, daily, gappy")
+}
@@ -23,15 +23,17 @@ import (
 
 // PerformanceHandlers contains LGTM stack performance testing handlers
 type PerformanceHandlers struct {
-	loggingService *services.LoggingService
-	tracingService *services.TracingService
+	loggingService     *services.LoggingService
+	tracingService     *services.TracingService
+	cardinalityService *services.CardinalityService
 }
 
 // NewPerformanceHandlers creates a new performance handlers instance
 func NewPerformanceHandlers(loggingService *services.LoggingService, tracingService *services.TracingService) *PerformanceHandlers {
 	return &PerformanceHandlers{
-		loggingService: loggingService,
-		tracingService: tracingService,
+		loggingService:     loggingService,
+		tracingService:     tracingService,
+		cardinalityService: services.NewCardinalityService(),
 	}
 }
 
@@ -136,6 +138,81 @@ func (ph *PerformanceHandlers) TestMetricsScale(w http.ResponseWriter, r *http.R
 	utils.EncodeJSON(w, result)
 }
 
+// TestCardinality writes values^labels unique series through remote write,
+// replacing churn percent of them every interval and any older than
+// lifetime, and watches the receiver's head series, memory and refusals.
+// Series go to remote_write_url, or Prometheus' /api/v1/write, which needs
+// --web.enable-remote-write-receiver.
+func (ph *PerformanceHandlers) TestCardinality(w http.ResponseWriter, r *http.Request) {
+	test, err := cardinalityTestFromQuery(r)
+	if err == nil {
+		err = test.Validate()
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid cardinality test: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ph.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Starting cardinality test...",
+		zap.Int("series", test.Series()),
+		zap.Float64("churn_percent", test.ChurnPercent),
+		zap.Int("lifetime_seconds", test.LifetimeSeconds),
+		zap.Int("duration_seconds", test.DurationSeconds))
+
+	settings := getGlobalSettings()
+	report := ph.cardinalityService.Run(r.Context(), test, settings.Prometheus, settings.RemoteWriteURL)
+
+	ph.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Cardinality test completed",
+		zap.String("status", report.Status),
+		zap.Int("series_created", report.SeriesCreated),
+		zap.Int("samples_rejected", report.SamplesRejected),
+		zap.Float64("peak_head_series", report.PeakHeadSeries))
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, report)
+}
+
+// cardinalityTestFromQuery reads a cardinality test from the request
+// parameters; lifetime, interval and duration are Go durations
+func cardinalityTestFromQuery(r *http.Request) (types.CardinalityTest, error) {
+	query := r.URL.Query()
+	test := types.CardinalityTest{
+		Labels:          3,
+		Values:          10,
+		IntervalSeconds: 15,
+		DurationSeconds: 60,
+		HeadSeriesQuery: query.Get("head_series_query"),
+		MemoryQuery:     query.Get("memory_query"),
+	}
+
+	for name, field := range map[string]*int{"labels": &test.Labels, "values": &test.Values, "batch": &test.BatchSize} {
+		if param := query.Get(name); param != "" {
+			value, err := strconv.Atoi(param)
+			if err != nil {
+				return test, fmt.Errorf("%s must be an integer", name)
+			}
+			*field = value
+		}
+	}
+	for name, field := range map[string]*int{"lifetime": &test.LifetimeSeconds, "interval": &test.IntervalSeconds, "duration": &test.DurationSeconds} {
+		if param := query.Get(name); param != "" {
+			value, err := time.ParseDuration(param)
+			if err != nil {
+				return test, fmt.Errorf("%s must be a duration such as 30s", name)
+			}
+			*field = int(value.Seconds())
+		}
+	}
+	if param := query.Get("churn"); param != "" {
+		churn, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return test, fmt.Errorf("churn must be a percentage")
+		}
+		test.ChurnPercent = churn
+	}
+	return test, nil
+}
+
 // Test Logs Scale - Generate high-volume logs
 func (ph *PerformanceHandlers) TestLogsScale(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
@@ -374,22 +451,22 @@ func (ph *PerformanceHandlers) TestDashboardLoad(w http.ResponseWriter, r *http.
 		zap.Int("concurrency", concurrency),
 		zap.Int("requests", requests))
 
-	// Test dashboard endpoints - use LGTM settings URLs
-	lgtmSettings := types.GetDefaults()
-	grafanaURL := lgtmSettings.Grafana.URL
-	prometheusURL := lgtmSettings.Prometheus.URL
-	lokiURL := lgtmSettings.Loki.URL
-	tempoURL := lgtmSettings.Tempo.URL
-
-	dashboardEndpoints := []string{
-		grafanaURL + "/api/health",
-		grafanaURL + "/api/datasources",
-		grafanaURL + "/api/dashboards/home",
-		grafanaURL + "/api/search",
-		prometheusURL + "/api/v1/query?query=up",
-		prometheusURL + "/api/v1/targets",
-		lokiURL + "/ready",
-		tempoURL + "/ready",
+	// Test dashboard endpoints - use the active LGTM settings
+	lgtmSettings := getGlobalSettings()
+	grafanaConfig := getGrafanaSettings()
+
+	dashboardEndpoints := []struct {
+		service types.ServiceConfig
+		path    string
+	}{
+		{grafanaConfig, "/api/health"},
+		{grafanaConfig, "/api/datasources"},
+		{grafanaConfig, "/api/dashboards/home"},
+		{grafanaConfig, "/api/search"},
+		{lgtmSettings.Prometheus, "/api/v1/query?query=up"},
+		{lgtmSettings.Prometheus, "/api/v1/targets"},
+		{lgtmSettings.Loki, "/ready"},
+		{lgtmSettings.Tempo, "/ready"},
 	}
 
 	var wg sync.WaitGroup
@@ -406,7 +483,7 @@ func (ph *PerformanceHandlers) TestDashboardLoad(w http.ResponseWriter, r *http.
 			for j := 0; j < requests; j++ {
 				endpoint := dashboardEndpoints[rand.Intn(len(dashboardEndpoints))]
 
-				resp, err := http.Get(endpoint)
+				resp, err := lgtmClient.get(r.Context(), endpoint.service, endpoint.path)
 				if err == nil {
 					resp.Body.Close()
 					if resp.StatusCode < 400 {
@@ -463,15 +540,11 @@ func (ph *PerformanceHandlers) TestResourceUsage(w http.ResponseWriter, r *http.
 	// Get resource usage from various sources
 	resourceData := make(map[string]interface{})
 
-	// Get LGTM settings URLs
-	lgtmSettings := types.GetDefaults()
-	prometheusURL := lgtmSettings.Prometheus.URL
-	lokiURL := lgtmSettings.Loki.URL
-	tempoURL := lgtmSettings.Tempo.URL
-	grafanaURL := lgtmSettings.Grafana.URL
+	// Get the active LGTM settings
+	lgtmSettings := getGlobalSettings()
 
 	// Test Prometheus metrics endpoint for resource data
-	if resp, err := http.Get(prometheusURL + "/api/v1/query?query=up"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Prometheus, "/api/v1/query?query=up"); err == nil {
 		defer resp.Body.Close()
 		if body, err := io.ReadAll(resp.Body); err == nil {
 			upTargets := strings.Count(string(body), `"value":[`)
@@ -480,7 +553,7 @@ func (ph *PerformanceHandlers) TestResourceUsage(w http.ResponseWriter, r *http.
 	}
 
 	// Test Loki metrics
-	if resp, err := http.Get(lokiURL + "/metrics"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Loki, "/metrics"); err == nil {
 		defer resp.Body.Close()
 		if body, err := io.ReadAll(resp.Body); err == nil {
 			bodyStr := string(body)
@@ -494,7 +567,7 @@ func (ph *PerformanceHandlers) TestResourceUsage(w http.ResponseWriter, r *http.
 	}
 
 	// Test Tempo status
-	if resp, err := http.Get(tempoURL + "/status"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Tempo, "/status"); err == nil {
 		defer resp.Body.Close()
 		resourceData["tempo_status"] = "accessible"
 	} else {
@@ -502,7 +575,7 @@ func (ph *PerformanceHandlers) TestResourceUsage(w http.ResponseWriter, r *http.
 	}
 
 	// Test Grafana health
-	if resp, err := http.Get(grafanaURL + "/api/health"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Grafana, "/api/health"); err == nil {
 		defer resp.Body.Close()
 		if resp.StatusCode == 200 {
 			resourceData["grafana_health"] = "healthy"
@@ -554,14 +627,11 @@ func (ph *PerformanceHandlers) TestStorageLimits(w http.ResponseWriter, r *http.
 
 	storageData := make(map[string]interface{})
 
-	// Get LGTM settings URLs
-	lgtmSettings := types.GetDefaults()
-	prometheusURL := lgtmSettings.Prometheus.URL
-	lokiURL := lgtmSettings.Loki.URL
-	tempoURL := lgtmSettings.Tempo.URL
+	// Get the active LGTM settings
+	lgtmSettings := getGlobalSettings()
 
 	// Test Prometheus storage metrics
-	if resp, err := http.Get(prometheusURL + "/api/v1/query?query=prometheus_tsdb_symbol_table_size_bytes"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Prometheus, "/api/v1/query?query=prometheus_tsdb_symbol_table_size_bytes"); err == nil {
 		defer resp.Body.Close()
 		storageData["prometheus_storage_accessible"] = true
 	} else {
@@ -569,7 +639,7 @@ func (ph *PerformanceHandlers) TestStorageLimits(w http.ResponseWriter, r *http.
 	}
 
 	// Test Loki ingestion rate
-	if resp, err := http.Get(lokiURL + "/metrics"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Loki, "/metrics"); err == nil {
 		defer resp.Body.Close()
 		if body, err := io.ReadAll(resp.Body); err == nil {
 			// Look for ingestion rate metrics
@@ -579,7 +649,7 @@ func (ph *PerformanceHandlers) TestStorageLimits(w http.ResponseWriter, r *http.
 	}
 
 	// Test Tempo storage
-	if resp, err := http.Get(tempoURL + "/status"); err == nil {
+	if resp, err := lgtmClient.get(r.Context(), lgtmSettings.Tempo, "/status"); err == nil {
 		defer resp.Body.Close()
 		storageData["tempo_storage_accessible"] = true
 	} else {
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/nahuelsantos/argus/internal/middleware"
 	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -598,3 +602,91 @@ func BenchmarkPerformanceHandlers_TestResourceUsage(b *testing.B) {
 		handlers.TestResourceUsage(w, req)
 	}
 }
+
+func TestPerformanceHandlers_TenantHeaders(t *testing.T) {
+	stack := newHeaderRecorder(t)
+	service := types.ServiceConfig{URL: stack.URL, TenantID: "team-a", Headers: map[string]string{"X-Extra": "1"}}
+	globalSettings = &types.LGTMSettings{
+		Grafana:    types.ServiceConfig{URL: stack.URL, Username: "admin", Password: "admin", TenantID: "team-a", Headers: service.Headers},
+		Prometheus: service,
+		Loki:       service,
+		Tempo:      service,
+	}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewPerformanceHandlers(loggingService, tracingService)
+
+	for _, test := range []struct {
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"/test-dashboard-load?concurrency=2&requests=5", handlers.TestDashboardLoad},
+		{"/test-resource-usage", handlers.TestResourceUsage},
+		{"/test-storage-limits", handlers.TestStorageLimits},
+	} {
+		w := httptest.NewRecorder()
+		test.handler(w, httptest.NewRequest("GET", test.path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, test.path)
+	}
+
+	stack.assertHeaders(t, types.TenantHeader, "team-a")
+	stack.assertHeaders(t, "X-Extra", "1")
+}
+
+func TestPerformanceHandlers_TestCardinality(t *testing.T) {
+	var mu sync.Mutex
+	writes := 0
+	stack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/api/v1/write" {
+			assert.Equal(t, "team-a", r.Header.Get(types.TenantHeader))
+			writes++
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"%d"]}]}}`, writes*4)
+	}))
+	defer stack.Close()
+	globalSettings = &types.LGTMSettings{Prometheus: types.ServiceConfig{URL: stack.URL, TenantID: "team-a"}}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewPerformanceHandlers(loggingService, tracingService)
+
+	w := httptest.NewRecorder()
+	handlers.TestCardinality(w, httptest.NewRequest("POST", "/test-cardinality?labels=2&values=2&interval=1s&duration=1s&batch=2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "healthy", report["status"], report["problems"])
+	assert.Equal(t, float64(4), report["active_series"])
+	assert.Equal(t, float64(2), report["requests"])
+	assert.Equal(t, float64(8), report["peak_head_series"])
+
+	for _, query := range []string{"labels=0", "values=1000&labels=3", "churn=150", "interval=soon", "interval=30s&duration=10s", "duration=15m"} {
+		w := httptest.NewRecorder()
+		handlers.TestCardinality(w, httptest.NewRequest("POST", "/test-cardinality?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+
+	// Through the router's middleware the default one minute run must not
+	// hit the 30 second request timeout
+	chain := middleware.AddMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		require.True(t, ok)
+		assert.Greater(t, time.Until(deadline), time.Duration(types.MaxCardinalityDurationSeconds)*time.Second)
+		handlers.TestCardinality(w, r)
+	}), loggingService)
+	w = httptest.NewRecorder()
+	chain.ServeHTTP(w, httptest.NewRequest("POST", "/test-cardinality?labels=2&values=2&interval=1s&duration=1s", nil))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
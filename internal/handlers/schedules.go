@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+
+	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
+	"github.com/nahuelsantos/argus/internal/utils"
+)
+
+// ScheduleHandlers manages the schedules that run checks continuously, so
+// Argus can act as a canary for the stack
+type ScheduleHandlers struct {
+	loggingService *services.LoggingService
+	scheduler      *services.Scheduler
+}
+
+// NewScheduleHandlers creates a new schedule handlers instance and registers
+// the checks that schedules can run
+func NewScheduleHandlers(loggingService *services.LoggingService, scheduler *services.Scheduler, basic *BasicHandlers, integration *IntegrationHandlers, testing *TestingHandlers) *ScheduleHandlers {
+	scheduler.RegisterCheck("lgtm_status", lgtmStatusCheck(basic))
+	scheduler.RegisterCheck("domain_probe", domainProbeCheck(testing.domainProbeService))
+	// prometheus_targets and loki_ingestion check readiness and what the
+	// services report; only tempo_roundtrip writes data and reads it back
+	scheduler.RegisterCheck("prometheus_targets", componentCheck(integration.testPrometheusTargets))
+	scheduler.RegisterCheck("loki_ingestion", componentCheck(integration.testLokiIngestion))
+	scheduler.RegisterCheck("tempo_roundtrip", componentCheck(func(ctx context.Context) LGTMIntegrationStatus {
+		return integration.testTempoTracing(ctx, true)
+	}))
+
+	return &ScheduleHandlers{
+		loggingService: loggingService,
+		scheduler:      scheduler,
+	}
+}
+
+// lgtmStatusCheck fails when any stack component, or any of the
+// comma-separated "components" param, is offline
+func lgtmStatusCheck(basic *BasicHandlers) services.CheckFunc {
+	return func(ctx context.Context, params map[string]string) error {
+		components := lgtmComponents(getGlobalSettings())
+		names := make([]string, 0, len(components))
+		if param := params["components"]; param != "" {
+			for _, name := range strings.Split(param, ",") {
+				names = append(names, strings.TrimSpace(name))
+			}
+		} else {
+			for name := range components {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+
+		var offline []string
+		for _, name := range names {
+			component, ok := components[name]
+			if !ok {
+				return fmt.Errorf("unknown component %q", name)
+			}
+			if basic.checkServiceHealth(ctx, component.config, component.path) != "online" {
+				offline = append(offline, name)
+			}
+		}
+		if len(offline) > 0 {
+			return fmt.Errorf("offline: %s", strings.Join(offline, ", "))
+		}
+		return nil
+	}
+}
+
+// domainProbeCheck probes the "urls" param, or the configured domains, and
+// fails when any probe fails. The "resolver" param overrides dns_resolver.
+func domainProbeCheck(prober *services.DomainProbeService) services.CheckFunc {
+	return func(ctx context.Context, params map[string]string) error {
+		settings := getGlobalSettings()
+		resolver := params["resolver"]
+		if resolver == "" {
+			resolver = settings.DNSResolver
+		}
+
+		targets := domainHealthTargets(params["urls"], params["body_regex"], settings)
+		if len(targets) == 0 {
+			return fmt.Errorf("no domains to probe")
+		}
+		report := prober.Probe(ctx, targets, resolver)
+		if len(report.Problems) > 0 {
+			return errors.New(strings.Join(report.Problems, "; "))
+		}
+		return nil
+	}
+}
+
+// componentCheck runs an integration component test, which fails unless the
+// component is healthy. The test stops when the check's context ends.
+func componentCheck(test func(context.Context) LGTMIntegrationStatus) services.CheckFunc {
+	return func(ctx context.Context, _ map[string]string) error {
+		status := test(ctx)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if status.Status != "healthy" {
+			return fmt.Errorf("%s: %s", status.Status, status.Message)
+		}
+		return nil
+	}
+}
+
+// SchedulesHandler lists schedules (GET) and creates them (POST) on /api/schedules
+func (sh *ScheduleHandlers) SchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, map[string]interface{}{
+			"schedules": sh.scheduler.List(),
+			"checks":    sh.scheduler.Checks(),
+			"timestamp": time.Now(),
+		})
+	case "POST":
+		var schedule types.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		created, err := sh.scheduler.Add(schedule)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid schedule: %v", err), http.StatusBadRequest)
+			return
+		}
+		sh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(),
+			fmt.Sprintf("Schedule %s created: check=%s profile=%s interval=%ds", created.ID, created.Check, created.Profile, created.IntervalSeconds))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.EncodeJSON(w, created)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// ScheduleHandler reads (GET), replaces (PUT) and deletes (DELETE) the schedule
+// at /api/schedules/{id}, and runs it at once on POST /api/schedules/{id}/run
+func (sh *ScheduleHandlers) ScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/schedules/")
+	if runID, ok := strings.CutSuffix(id, "/run"); ok {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		run, err := sh.scheduler.RunNow(r.Context(), runID)
+		if err != nil {
+			sh.scheduleError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, run)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		schedule, err := sh.scheduler.Get(id)
+		if err != nil {
+			sh.scheduleError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, schedule)
+	case "PUT":
+		var schedule types.Schedule
+		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		updated, err := sh.scheduler.Update(id, schedule)
+		if err != nil {
+			sh.scheduleError(w, err)
+			return
+		}
+		sh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), fmt.Sprintf("Schedule %s updated", id))
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, updated)
+	case "DELETE":
+		if err := sh.scheduler.Remove(id); err != nil {
+			sh.scheduleError(w, err)
+			return
+		}
+		sh.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), fmt.Sprintf("Schedule %s deleted", id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// scheduleError maps scheduler errors to status codes
+func (sh *ScheduleHandlers) scheduleError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrScheduleNotFound) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Invalid schedule: %v", err), http.StatusBadRequest)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func newTestScheduleHandlers(t *testing.T) *ScheduleHandlers {
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+
+	scheduler := services.NewScheduler()
+	t.Cleanup(scheduler.Stop)
+	return NewScheduleHandlers(loggingService, scheduler,
+		NewBasicHandlers(loggingService, tracingService, services.NewMetricExporter(prometheus.NewRegistry()), services.NewMetricPatternEngine()),
+		NewIntegrationHandlers(loggingService, tracingService),
+		NewTestingHandlers(loggingService, tracingService))
+}
+
+func TestScheduleHandlers_CRUD(t *testing.T) {
+	handlers := newTestScheduleHandlers(t)
+
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if path == "/api/schedules" {
+			handlers.SchedulesHandler(w, req)
+		} else {
+			handlers.ScheduleHandler(w, req)
+		}
+		return w
+	}
+
+	w := request("GET", "/api/schedules", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var listing struct {
+		Schedules []models.ScheduledCheck `json:"schedules"`
+		Checks    []string                `json:"checks"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listing))
+	assert.Empty(t, listing.Schedules)
+	assert.Equal(t, []string{"domain_probe", "lgtm_status", "loki_ingestion", "prometheus_targets", "tempo_roundtrip"}, listing.Checks)
+
+	w = request("POST", "/api/schedules", `{"check": "lgtm_status", "interval_seconds": 60, "jitter_seconds": 90}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "jitter_seconds")
+
+	w = request("POST", "/api/schedules", `{"id": "stack", "check": "lgtm_status", "profile": "prod", "interval_seconds": 60, "paused": true}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created models.ScheduledCheck
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.Equal(t, "stack", created.ID)
+	assert.Equal(t, "prod", created.Profile)
+
+	w = request("PUT", "/api/schedules/stack", `{"check": "lgtm_status", "profile": "prod", "interval_seconds": 300, "paused": true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = request("GET", "/api/schedules/stack", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"interval_seconds":300`)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, request("GET", "/api/schedules/stack/run", "").Code)
+	assert.Equal(t, http.StatusNoContent, request("DELETE", "/api/schedules/stack", "").Code)
+	assert.Equal(t, http.StatusNotFound, request("GET", "/api/schedules/stack", "").Code)
+	assert.Equal(t, http.StatusNotFound, request("POST", "/api/schedules/stack/run", "").Code)
+}
+
+func TestScheduleHandlers_RunChecks(t *testing.T) {
+	stack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Loki is reached through localhost and is not ready
+		if strings.HasPrefix(r.Host, "localhost") && r.URL.Path == "/ready" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer stack.Close()
+	online := types.ServiceConfig{URL: stack.URL}
+	globalSettings = &types.LGTMSettings{
+		Prometheus: online, AlertManager: online, Grafana: online, Tempo: online,
+		Loki:    types.ServiceConfig{URL: strings.Replace(stack.URL, "127.0.0.1", "localhost", 1)},
+		Domains: []types.DomainTarget{{Name: "stack", URL: stack.URL + "/"}},
+	}
+	t.Cleanup(func() { globalSettings = nil })
+
+	handlers := newTestScheduleHandlers(t)
+	run := func(schedule types.Schedule) models.CheckRun {
+		schedule.IntervalSeconds, schedule.Paused = 60, true
+		created, err := handlers.scheduler.Add(schedule)
+		require.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		handlers.ScheduleHandler(w, httptest.NewRequest("POST", "/api/schedules/"+created.ID+"/run", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var result models.CheckRun
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	result := run(types.Schedule{Check: "lgtm_status", Profile: "core", Params: map[string]string{"components": "prometheus,grafana"}})
+	assert.True(t, result.Success, result.Error)
+
+	result = run(types.Schedule{Check: "lgtm_status", Profile: "all"})
+	assert.False(t, result.Success)
+	assert.Equal(t, "offline: loki", result.Error)
+
+	result = run(types.Schedule{Check: "domain_probe"})
+	assert.True(t, result.Success, result.Error)
+}
+
+func TestComponentCheck_StopsWithContext(t *testing.T) {
+	cancelled := make(chan struct{})
+	loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	defer loki.Close()
+
+	globalSettings = &types.LGTMSettings{Loki: types.ServiceConfig{URL: loki.URL}}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	check := componentCheck(NewIntegrationHandlers(loggingService, tracingService).testLokiIngestion)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, check(ctx, nil), context.DeadlineExceeded)
+
+	// The request to Loki is abandoned with the check rather than left running
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the component test kept its request open")
+	}
+}
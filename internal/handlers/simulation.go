@@ -29,13 +29,17 @@ func NewSimulationHandlers(loggingService *services.LoggingService, tracingServi
 
 // SimulateWebServiceHandler simulates a typical web service (WordPress, web apps)
 func (h *SimulationHandlers) SimulateWebServiceHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, h.loggingService)
+	if !ok {
+		return
+	}
 	// Simulate web service characteristics
 	pageViews := rand.Intn(50) + 10
 	avgResponseTime := rand.Intn(200) + 50 // 50-250ms
 	errorRate := rand.Float64() * 0.05     // 0-5% error rate
 
 	// Generate web-specific logs
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Web service simulation started",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Web service simulation started",
 		zap.String("service_type", "web-service"),
 		zap.Int("page_views", pageViews),
 		zap.Int("avg_response_time_ms", avgResponseTime),
@@ -49,7 +53,7 @@ func (h *SimulationHandlers) SimulateWebServiceHandler(w http.ResponseWriter, r
 
 		// Simulate some errors
 		if rand.Float64() < errorRate {
-			h.loggingService.LogError(r.Context(), "web_error", "WEB001", "Web request failed",
+			h.loggingService.LogErrorToSink(sink, r.Context(), "web_error", "WEB001", "Web request failed",
 				fmt.Errorf("internal server error"), map[string]interface{}{
 					"endpoint":         endpoint,
 					"response_time_ms": responseTime.Milliseconds(),
@@ -57,7 +61,7 @@ func (h *SimulationHandlers) SimulateWebServiceHandler(w http.ResponseWriter, r
 					"user_agent":       "Mozilla/5.0 (simulated)",
 				})
 		} else {
-			h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Web request processed",
+			h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Web request processed",
 				zap.String("endpoint", endpoint),
 				zap.Int64("response_time_ms", responseTime.Milliseconds()),
 				zap.Int("status_code", 200),
@@ -68,7 +72,7 @@ func (h *SimulationHandlers) SimulateWebServiceHandler(w http.ResponseWriter, r
 		time.Sleep(time.Millisecond * 10)
 	}
 
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Web service simulation completed",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Web service simulation completed",
 		zap.Int("total_requests", pageViews))
 
 	response := map[string]interface{}{
@@ -87,13 +91,17 @@ func (h *SimulationHandlers) SimulateWebServiceHandler(w http.ResponseWriter, r
 
 // SimulateAPIServiceHandler simulates REST API services
 func (h *SimulationHandlers) SimulateAPIServiceHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, h.loggingService)
+	if !ok {
+		return
+	}
 	// API service characteristics
 	apiCalls := rand.Intn(100) + 20
 	avgLatency := rand.Intn(100) + 25 // 25-125ms
 	rateLimitHits := rand.Intn(5)     // 0-5 rate limit hits
 	authFailures := rand.Intn(3)      // 0-3 auth failures
 
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "API service simulation started",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "API service simulation started",
 		zap.String("service_type", "api-service"),
 		zap.Int("api_calls", apiCalls),
 		zap.Int("avg_latency_ms", avgLatency))
@@ -143,7 +151,7 @@ func (h *SimulationHandlers) SimulateAPIServiceHandler(w http.ResponseWriter, r
 		switch {
 		case rateLimitHits > 0 && rand.Float64() < 0.05: // 5% chance of rate limit
 			rateLimitHits--
-			h.loggingService.LogWithContext(zapcore.WarnLevel, r.Context(), "API rate limit exceeded",
+			h.loggingService.LogToSink(sink, zapcore.WarnLevel, r.Context(), "API rate limit exceeded",
 				zap.String("method", selectedEndpoint.method),
 				zap.String("endpoint", selectedEndpoint.path),
 				zap.Int("status_code", 429),
@@ -151,7 +159,7 @@ func (h *SimulationHandlers) SimulateAPIServiceHandler(w http.ResponseWriter, r
 				zap.String("client_ip", "192.168.1."+fmt.Sprintf("%d", rand.Intn(255))))
 		case authFailures > 0 && rand.Float64() < 0.03: // 3% chance of auth failure
 			authFailures--
-			h.loggingService.LogError(r.Context(), "api_auth", "AUTH001", "API authentication failed",
+			h.loggingService.LogErrorToSink(sink, r.Context(), "api_auth", "AUTH001", "API authentication failed",
 				fmt.Errorf("invalid token"), map[string]interface{}{
 					"method":      selectedEndpoint.method,
 					"endpoint":    selectedEndpoint.path,
@@ -159,7 +167,7 @@ func (h *SimulationHandlers) SimulateAPIServiceHandler(w http.ResponseWriter, r
 					"latency_ms":  latency.Milliseconds(),
 				})
 		case rand.Float64() < 0.02: // 2% chance of server error
-			h.loggingService.LogError(r.Context(), "api_internal", "API001", "API internal error",
+			h.loggingService.LogErrorToSink(sink, r.Context(), "api_internal", "API001", "API internal error",
 				fmt.Errorf("database connection timeout"), map[string]interface{}{
 					"method":      selectedEndpoint.method,
 					"endpoint":    selectedEndpoint.path,
@@ -167,7 +175,7 @@ func (h *SimulationHandlers) SimulateAPIServiceHandler(w http.ResponseWriter, r
 					"latency_ms":  latency.Milliseconds(),
 				})
 		default: // Successful request
-			h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "API request processed",
+			h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "API request processed",
 				zap.String("method", selectedEndpoint.method),
 				zap.String("endpoint", selectedEndpoint.path),
 				zap.Int("status_code", 200),
@@ -195,13 +203,17 @@ func (h *SimulationHandlers) SimulateAPIServiceHandler(w http.ResponseWriter, r
 
 // SimulateDatabaseServiceHandler simulates database-heavy applications
 func (h *SimulationHandlers) SimulateDatabaseServiceHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, h.loggingService)
+	if !ok {
+		return
+	}
 	// Database service characteristics
 	queries := rand.Intn(80) + 20
 	avgQueryTime := rand.Intn(50) + 10      // 10-60ms
 	slowQueries := rand.Intn(5)             // 0-5 slow queries
 	connectionPoolSize := rand.Intn(10) + 5 // 5-15 connections
 
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Database service simulation started",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Database service simulation started",
 		zap.String("service_type", "database-service"),
 		zap.Int("query_count", queries),
 		zap.Int("connection_pool_size", connectionPoolSize))
@@ -218,14 +230,14 @@ func (h *SimulationHandlers) SimulateDatabaseServiceHandler(w http.ResponseWrite
 		if slowQueries > 0 && rand.Float64() < 0.08 { // 8% chance of slow query
 			slowQueries--
 			queryTime = time.Duration(rand.Intn(2000)+1000) * time.Millisecond // 1-3 seconds
-			h.loggingService.LogWithContext(zapcore.WarnLevel, r.Context(), "Slow database query detected",
+			h.loggingService.LogToSink(sink, zapcore.WarnLevel, r.Context(), "Slow database query detected",
 				zap.String("query_type", queryType),
 				zap.String("table", table),
 				zap.Int64("duration_ms", queryTime.Milliseconds()),
 				zap.Int("rows_affected", rand.Intn(10000)),
 				zap.String("query_id", fmt.Sprintf("query_%d", i)))
 		} else if rand.Float64() < 0.03 { // 3% chance of query error
-			h.loggingService.LogError(r.Context(), "database_error", "DB001", "Database query failed",
+			h.loggingService.LogErrorToSink(sink, r.Context(), "database_error", "DB001", "Database query failed",
 				fmt.Errorf("table lock timeout"), map[string]interface{}{
 					"query_type":  queryType,
 					"table":       table,
@@ -233,7 +245,7 @@ func (h *SimulationHandlers) SimulateDatabaseServiceHandler(w http.ResponseWrite
 					"query_id":    fmt.Sprintf("query_%d", i),
 				})
 		} else {
-			h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Database query executed",
+			h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Database query executed",
 				zap.String("query_type", queryType),
 				zap.String("table", table),
 				zap.Int64("duration_ms", queryTime.Milliseconds()),
@@ -245,7 +257,7 @@ func (h *SimulationHandlers) SimulateDatabaseServiceHandler(w http.ResponseWrite
 	}
 
 	// Simulate connection pool metrics
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Database connection pool status",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Database connection pool status",
 		zap.Int("pool_size", connectionPoolSize),
 		zap.Int("active_connections", rand.Intn(connectionPoolSize)),
 		zap.Int("idle_connections", rand.Intn(connectionPoolSize/2)),
@@ -268,11 +280,15 @@ func (h *SimulationHandlers) SimulateDatabaseServiceHandler(w http.ResponseWrite
 
 // SimulateStaticSiteHandler simulates static file serving (CDN-like)
 func (h *SimulationHandlers) SimulateStaticSiteHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, h.loggingService)
+	if !ok {
+		return
+	}
 	// Static site characteristics
 	requests := rand.Intn(200) + 50
 	cacheHitRate := rand.Float64()*0.3 + 0.7 // 70-100% cache hit rate
 
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Static site simulation started",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Static site simulation started",
 		zap.String("service_type", "static-site"),
 		zap.Int("expected_requests", requests),
 		zap.Float64("cache_hit_rate", cacheHitRate))
@@ -334,7 +350,7 @@ func (h *SimulationHandlers) SimulateStaticSiteHandler(w http.ResponseWriter, r
 
 		if isCache {
 			cacheHits++
-			h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Static file served from cache",
+			h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Static file served from cache",
 				zap.String("file", fileName),
 				zap.Int("size_bytes", fileSize*1024),
 				zap.Int64("response_time_ms", responseTime.Milliseconds()),
@@ -342,7 +358,7 @@ func (h *SimulationHandlers) SimulateStaticSiteHandler(w http.ResponseWriter, r
 				zap.String("client_ip", "192.168.1."+fmt.Sprintf("%d", rand.Intn(255))))
 		} else {
 			cacheMisses++
-			h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Static file served from origin",
+			h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Static file served from origin",
 				zap.String("file", fileName),
 				zap.Int("size_bytes", fileSize*1024),
 				zap.Int64("response_time_ms", responseTime.Milliseconds()),
@@ -355,7 +371,7 @@ func (h *SimulationHandlers) SimulateStaticSiteHandler(w http.ResponseWriter, r
 
 	actualCacheHitRate := float64(cacheHits) / float64(requests)
 
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Static site simulation completed",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Static site simulation completed",
 		zap.Int("total_requests", requests),
 		zap.Int("cache_hits", cacheHits),
 		zap.Int("cache_misses", cacheMisses),
@@ -380,12 +396,16 @@ func (h *SimulationHandlers) SimulateStaticSiteHandler(w http.ResponseWriter, r
 
 // SimulateMicroserviceHandler simulates microservice communication patterns
 func (h *SimulationHandlers) SimulateMicroserviceHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, h.loggingService)
+	if !ok {
+		return
+	}
 	// Microservice characteristics
 	serviceCalls := rand.Intn(30) + 10
 	circuitBreakerTrips := rand.Intn(2)
 	retryAttempts := rand.Intn(5)
 
-	h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Microservice simulation started",
+	h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Microservice simulation started",
 		zap.String("service_type", "microservice"),
 		zap.Int("service_calls", serviceCalls))
 
@@ -414,7 +434,7 @@ func (h *SimulationHandlers) SimulateMicroserviceHandler(w http.ResponseWriter,
 		switch {
 		case circuitBreakerTrips > 0 && rand.Float64() < 0.1: // 10% chance of circuit breaker
 			circuitBreakerTrips--
-			h.loggingService.LogError(r.Context(), "circuit_breaker", "CB001", "Circuit breaker tripped",
+			h.loggingService.LogErrorToSink(sink, r.Context(), "circuit_breaker", "CB001", "Circuit breaker tripped",
 				fmt.Errorf("service unavailable"), map[string]interface{}{
 					"caller_service":        caller,
 					"target_service":        callee,
@@ -423,14 +443,14 @@ func (h *SimulationHandlers) SimulateMicroserviceHandler(w http.ResponseWriter,
 				})
 		case retryAttempts > 0 && rand.Float64() < 0.08: // 8% chance of retry
 			retryAttempts--
-			h.loggingService.LogWithContext(zapcore.WarnLevel, r.Context(), "Service call retry",
+			h.loggingService.LogToSink(sink, zapcore.WarnLevel, r.Context(), "Service call retry",
 				zap.String("caller_service", caller),
 				zap.String("target_service", callee),
 				zap.Int64("latency_ms", latency.Milliseconds()),
 				zap.Int("retry_attempt", rand.Intn(3)+1),
 				zap.String("original_error", "Connection timeout"))
 		case rand.Float64() < 0.05: // 5% chance of service error
-			h.loggingService.LogError(r.Context(), "microservice_error", "MS001", "Microservice call failed",
+			h.loggingService.LogErrorToSink(sink, r.Context(), "microservice_error", "MS001", "Microservice call failed",
 				fmt.Errorf("service temporarily unavailable"), map[string]interface{}{
 					"caller_service": caller,
 					"target_service": callee,
@@ -438,7 +458,7 @@ func (h *SimulationHandlers) SimulateMicroserviceHandler(w http.ResponseWriter,
 					"status_code":    503,
 				})
 		default: // Successful call
-			h.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Microservice call succeeded",
+			h.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Microservice call succeeded",
 				zap.String("caller_service", caller),
 				zap.String("target_service", callee),
 				zap.Int64("latency_ms", latency.Milliseconds()),
@@ -2,34 +2,65 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/models"
 	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
 	"github.com/nahuelsantos/argus/internal/utils"
 )
 
 // TestingHandlers contains testing handlers for LGTM stack validation
 type TestingHandlers struct {
-	loggingService *services.LoggingService
-	tracingService *services.TracingService
+	loggingService       *services.LoggingService
+	tracingService       *services.TracingService
+	tlsInspectionService *services.TLSInspectionService
+	domainProbeService   *services.DomainProbeService
+	discoveryService     *services.DiscoveryService
+	reverseProxyService  *services.ReverseProxyService
+	logReplayService     *services.LogReplayService
+	piiService           *services.PIIService
+	logOutput            io.Writer // where format generator entries are written
 }
 
 // NewTestingHandlers creates a new testing handlers instance
 func NewTestingHandlers(loggingService *services.LoggingService, tracingService *services.TracingService) *TestingHandlers {
 	return &TestingHandlers{
-		loggingService: loggingService,
-		tracingService: tracingService,
+		loggingService:       loggingService,
+		tracingService:       tracingService,
+		tlsInspectionService: services.NewTLSInspectionService(),
+		domainProbeService:   services.NewDomainProbeService(),
+		discoveryService:     services.NewDiscoveryService(),
+		reverseProxyService:  services.NewReverseProxyService(),
+		logReplayService:     services.NewLogReplayService(loggingService.Sinks(), config.GetServiceConfig().ReplayDir),
+		piiService:           services.NewPIIService(),
+		logOutput:            os.Stdout,
 	}
 }
 
 // GenerateJSONLogsHandler tests Loki with structured JSON logs
 func (th *TestingHandlers) GenerateJSONLogsHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
+	}
+	pii, ok := requestPII(w, r, "json")
+	if !ok {
+		return
+	}
 	count := 10
 
 	logFormats := []map[string]interface{}{
@@ -66,10 +97,15 @@ func (th *TestingHandlers) GenerateJSONLogsHandler(w http.ResponseWriter, r *htt
 	var generatedLogs []string
 	for i := 0; i < count; i++ {
 		logEntry := logFormats[i%len(logFormats)]
+		var values []models.PIIValue
+		if pii != nil {
+			values = pii.Next()
+			logEntry = services.WithPIIFields(logEntry, values)
+		}
 		logJSON, _ := json.Marshal(logEntry)
 
 		// Log to Loki via our logging service
-		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), string(logJSON))
+		th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), string(logJSON), recordPII(pii, string(logJSON), values)...)
 		generatedLogs = append(generatedLogs, string(logJSON))
 	}
 
@@ -83,6 +119,7 @@ func (th *TestingHandlers) GenerateJSONLogsHandler(w http.ResponseWriter, r *htt
 		"service":        "argus",
 		"functionality":  "loki_json_validation",
 	}
+	th.addPIIRun(response, pii)
 
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, response)
@@ -90,8 +127,19 @@ func (th *TestingHandlers) GenerateJSONLogsHandler(w http.ResponseWriter, r *htt
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "JSON logs generated for Loki testing")
 }
 
-// GenerateUnstructuredLogsHandler tests Loki with plain text logs
+// GenerateUnstructuredLogsHandler tests Loki with plain text logs. The
+// templates are a fixed smoke set of web stack lines ("[time] LEVEL: ...")
+// that the format generator has no format for; /generate-logs/format covers
+// configurable volumes and distributions.
 func (th *TestingHandlers) GenerateUnstructuredLogsHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
+	}
+	pii, ok := requestPII(w, r, "unstructured")
+	if !ok {
+		return
+	}
 	count := 10
 
 	logTemplates := []string{
@@ -121,9 +169,14 @@ func (th *TestingHandlers) GenerateUnstructuredLogsHandler(w http.ResponseWriter
 		default:
 			logEntry = fmt.Sprintf(template, time.Now().Format("2006-01-02 15:04:05"))
 		}
+		var values []models.PIIValue
+		if pii != nil {
+			values = pii.Next()
+			logEntry += services.PIIText(values)
+		}
 
 		// Log to Loki via our logging service
-		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
+		th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), logEntry, recordPII(pii, logEntry, values)...)
 		generatedLogs = append(generatedLogs, logEntry)
 	}
 
@@ -137,6 +190,7 @@ func (th *TestingHandlers) GenerateUnstructuredLogsHandler(w http.ResponseWriter
 		"service":        "argus",
 		"functionality":  "loki_unstructured_validation",
 	}
+	th.addPIIRun(response, pii)
 
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, response)
@@ -146,11 +200,28 @@ func (th *TestingHandlers) GenerateUnstructuredLogsHandler(w http.ResponseWriter
 
 // GenerateMixedLogsHandler tests Loki with mixed format logs
 func (th *TestingHandlers) GenerateMixedLogsHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
+	}
+	pii, ok := requestPII(w, r, "mixed")
+	if !ok {
+		return
+	}
 	count := 15
 	var generatedLogs []string
+	keyValues, err := services.NewLogFormatGenerator(types.LogFormatSpec{Format: types.LogFormatLogfmt, Count: count})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	for i := 0; i < count; i++ {
 		var logEntry string
+		var values []models.PIIValue
+		if pii != nil {
+			values = pii.Next()
+		}
 
 		switch i % 3 {
 		case 0: // JSON format
@@ -164,19 +235,18 @@ func (th *TestingHandlers) GenerateMixedLogsHandler(w http.ResponseWriter, r *ht
 					"time":   fmt.Sprintf("%dms", rand.Intn(100)+10),
 				},
 			}
-			logJSON, _ := json.Marshal(logData)
+			logJSON, _ := json.Marshal(services.WithPIIFields(logData, values))
 			logEntry = string(logJSON)
 
 		case 1: // Key-value format
-			logEntry = fmt.Sprintf("time=%s level=WARN service=database query=\"SELECT COUNT(*) FROM sessions\" duration=%dms rows=%d",
-				time.Now().Format(time.RFC3339), rand.Intn(1000)+100, rand.Intn(10000))
+			logEntry = keyValues.Next(time.Now()) + services.PIIKeyValues(values)
 
 		case 2: // Plain text format
 			logEntry = fmt.Sprintf("[%s] ERROR: Redis connection failed, retrying in %d seconds",
-				time.Now().Format("2006-01-02 15:04:05"), rand.Intn(5)+1)
+				time.Now().Format("2006-01-02 15:04:05"), rand.Intn(5)+1) + services.PIIText(values)
 		}
 
-		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
+		th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), logEntry, recordPII(pii, logEntry, values)...)
 		generatedLogs = append(generatedLogs, logEntry)
 	}
 
@@ -190,6 +260,7 @@ func (th *TestingHandlers) GenerateMixedLogsHandler(w http.ResponseWriter, r *ht
 		"service":        "argus",
 		"functionality":  "loki_mixed_validation",
 	}
+	th.addPIIRun(response, pii)
 
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, response)
@@ -197,44 +268,36 @@ func (th *TestingHandlers) GenerateMixedLogsHandler(w http.ResponseWriter, r *ht
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Mixed format logs generated for Loki testing")
 }
 
-// GenerateMultilineLogsHandler tests Loki with multi-line logs (stack traces)
+// GenerateMultilineLogsHandler tests Loki with multi-line logs: one Java,
+// Python and Go stack trace from the format generator, logged line by line
+// as an application writing to stdout would
 func (th *TestingHandlers) GenerateMultilineLogsHandler(w http.ResponseWriter, r *http.Request) {
-	stackTraces := []string{
-		`[2024-01-15 10:30:45] ERROR: Application exception
-java.lang.NullPointerException: Cannot invoke method on null object
-    at com.example.UserService.getUser(UserService.java:45)
-    at com.example.UserController.handleRequest(UserController.java:23)
-    at javax.servlet.http.HttpServlet.service(HttpServlet.java:731)
-    at org.apache.catalina.core.ApplicationFilterChain.doFilter(ApplicationFilterChain.java:166)`,
-
-		`[2024-01-15 10:31:12] ERROR: Database connection failed
-org.postgresql.util.PSQLException: Connection to localhost:5432 refused
-    at org.postgresql.core.v3.ConnectionFactoryImpl.openConnectionImpl(ConnectionFactoryImpl.java:280)
-    at org.postgresql.core.ConnectionFactory.openConnection(ConnectionFactory.java:49)
-    at org.postgresql.jdbc.PgConnection.<init>(PgConnection.java:195)
-    at org.postgresql.Driver.makeConnection(Driver.java:454)`,
-
-		`[2024-01-15 10:31:45] ERROR: Payment processing error
-stripe.error.CardError: Your card was declined
-    at stripe.api_requestor.APIRequestor.request(api_requestor.py:123)
-    at stripe.api_resources.charge.Charge.create(charge.py:45)
-    at payment_service.process_payment(payment_service.py:67)
-    at app.routes.checkout(routes.py:89)`,
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
 	}
+	formats := []string{types.LogFormatJavaStack, types.LogFormatPythonStack, types.LogFormatGoStack}
 
 	var generatedLogs []string
-	for i, stackTrace := range stackTraces {
-		lines := strings.Split(stackTrace, "\n")
+	totalLines := 0
+	for _, format := range formats {
+		generator, err := services.NewLogFormatGenerator(types.LogFormatSpec{Format: format, Count: 1})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		lines := strings.Split(generator.Next(time.Now()), "\n")
 		for _, line := range lines {
-			th.loggingService.LogWithContext(zapcore.ErrorLevel, r.Context(), line)
+			th.loggingService.LogToSink(sink, zapcore.ErrorLevel, r.Context(), line)
 		}
-		generatedLogs = append(generatedLogs, fmt.Sprintf("Stack trace %d (%d lines)", i+1, len(lines)))
+		totalLines += len(lines)
+		generatedLogs = append(generatedLogs, fmt.Sprintf("%s (%d lines)", format, len(lines)))
 	}
 
 	response := map[string]interface{}{
 		"message":          "Multi-line logs (stack traces) generated for Loki testing",
-		"stack_traces":     len(stackTraces),
-		"total_log_lines":  strings.Count(strings.Join(stackTraces, "\n"), "\n") + len(stackTraces),
+		"stack_traces":     len(formats),
+		"total_log_lines":  totalLines,
 		"generated_traces": generatedLogs,
 		"test_purpose":     "Validate Loki multi-line log parsing (stack traces)",
 		"timestamp":        time.Now().Format(time.RFC3339),
@@ -248,8 +311,221 @@ stripe.error.CardError: Your card was declined
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Multi-line stack traces generated for Loki testing")
 }
 
+// GenerateFormatLogsHandler writes entries in one of the log format
+// generator's formats to stdout, where the container log collector picks
+// them up, or to the sink named by the sink parameter. GET takes format,
+// count, rate and seed parameters; POST takes a types.LogFormatSpec, which
+// can also set field distributions.
+func (th *TestingHandlers) GenerateFormatLogsHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
+	}
+	spec := types.LogFormatSpec{Format: types.LogFormatLogfmt, Count: 10}
+	switch r.Method {
+	case "GET":
+		query := r.URL.Query()
+		if format := query.Get("format"); format != "" {
+			spec.Format = format
+		}
+		var err error
+		if value := query.Get("count"); value != "" {
+			spec.Count, err = strconv.Atoi(value)
+		}
+		if value := query.Get("rate"); value != "" && err == nil {
+			spec.Rate, err = strconv.ParseFloat(value, 64)
+		}
+		if value := query.Get("seed"); value != "" && err == nil {
+			spec.Seed, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			http.Error(w, "count, rate and seed must be numbers", http.StatusBadRequest)
+			return
+		}
+	case "POST":
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	generator, err := services.NewLogFormatGenerator(spec)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid log format spec: %v", err), http.StatusBadRequest)
+		return
+	}
+	var out io.Writer = th.logOutput
+	if sink != nil {
+		out = sink
+	}
+	report := generator.Run(r.Context(), out)
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, report)
+
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(),
+		fmt.Sprintf("Generated %d %s log entries (%d lines)", report.Entries, report.Format, report.Lines))
+}
+
+// requestLogSink returns the sink named by the request's sink parameter, or
+// nil to log to the default outputs. An unknown sink is answered with a 400.
+func requestLogSink(w http.ResponseWriter, r *http.Request, loggingService *services.LoggingService) (services.LogSink, bool) {
+	name := r.URL.Query().Get("sink")
+	if name == "" {
+		return nil, true
+	}
+	sink, ok := loggingService.Sinks().Get(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown log sink %q, configured sinks: %s", name, strings.Join(loggingService.Sinks().Names(), ", ")), http.StatusBadRequest)
+		return nil, false
+	}
+	return sink, true
+}
+
+// requestPII returns a PII generator when the request's pii parameter asks
+// for synthetic PII ("true" or a comma-separated list of kinds, with
+// pii_rate), or nil. An invalid spec is answered with a 400.
+func requestPII(w http.ResponseWriter, r *http.Request, generator string) (*services.PIIGenerator, bool) {
+	query := r.URL.Query()
+	if query.Get("pii") == "" || query.Get("pii") == "false" {
+		return nil, true
+	}
+	spec, err := types.ParsePIISpec(query.Get("pii"), query.Get("pii_rate"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid PII spec: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	return services.NewPIIGenerator(spec, generator, time.Now().UnixNano()), true
+}
+
+// recordPII records a generated line in the request's PII run and returns
+// the field tagging it with the run, or nothing without a PII run
+func recordPII(pii *services.PIIGenerator, line string, values []models.PIIValue) []zap.Field {
+	if pii == nil {
+		return nil
+	}
+	pii.Record(line, values)
+	return []zap.Field{zap.String(services.PIIRunField, pii.RunID())}
+}
+
+// addPIIRun keeps the request's PII run for the redaction check and adds it
+// to the generator response
+func (th *TestingHandlers) addPIIRun(response map[string]interface{}, pii *services.PIIGenerator) {
+	if pii == nil {
+		return
+	}
+	run := pii.Run()
+	th.piiService.Record(run)
+	response["pii_run"] = run
+}
+
+// TestPIIRedactionHandler checks that the synthetic PII of a generator run
+// was redacted before it reached Loki. The run parameter picks the run (the
+// latest by default), selector narrows the LogQL stream selector and timeout
+// bounds the wait for the run's lines to be ingested.
+func (th *TestingHandlers) TestPIIRedactionHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := th.piiService.Get(r.URL.Query().Get("run"))
+	if err != nil {
+		http.Error(w, "PII run not found - generate logs with ?pii=true first", http.StatusNotFound)
+		return
+	}
+	selector := services.DefaultPIISelector
+	if s := r.URL.Query().Get("selector"); s != "" {
+		selector = s
+	}
+	timeout := time.Minute
+	if t := r.URL.Query().Get("timeout"); t != "" {
+		if parsed, err := time.ParseDuration(t); err == nil && parsed > 0 && parsed <= 5*time.Minute {
+			timeout = parsed
+		}
+	}
+
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), fmt.Sprintf("Checking PII redaction of a %s log run...", run.Generator))
+	report := th.piiService.Verify(r.Context(), getGlobalSettings().Loki, selector, run, timeout)
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(),
+		fmt.Sprintf("PII redaction check completed: %d of %d values leaked", report.Leaked, report.Values))
+
+	w.Header().Set("Content-Type", "application/json")
+	utils.EncodeJSON(w, report)
+}
+
+// LogReplaysHandler lists log replays (GET) and starts one (POST) on
+// /api/log-replays. A replay streams a captured log file, optionally
+// gzip-compressed, into a log sink with its timestamps rewritten to now.
+func (th *TestingHandlers) LogReplaysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, map[string]interface{}{
+			"replays":           th.logReplayService.List(),
+			"timestamp_layouts": types.ReplayTimestampLayouts,
+			"timestamp":         time.Now(),
+		})
+	case "POST":
+		var spec types.LogReplaySpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		replay, err := th.logReplayService.Start(spec)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid log replay: %v", err), http.StatusBadRequest)
+			return
+		}
+		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(),
+			fmt.Sprintf("Log replay %s started: path=%s sink=%s speed=%g", replay.ID, replay.Path, replay.Sink, replay.Speed))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		utils.EncodeJSON(w, replay)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// LogReplayHandler reads (GET) the replay at /api/log-replays/{id}. DELETE
+// stops it when running and forgets it otherwise.
+func (th *TestingHandlers) LogReplayHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/log-replays/")
+	switch r.Method {
+	case "GET":
+		replay, err := th.logReplayService.Get(id)
+		if err != nil {
+			replayError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, replay)
+	case "DELETE":
+		if err := th.logReplayService.Remove(id); err != nil {
+			replayError(w, err)
+			return
+		}
+		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), fmt.Sprintf("Log replay %s deleted", id))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// replayError maps log replay errors to status codes
+func replayError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrReplayNotFound) {
+		http.Error(w, "Log replay not found", http.StatusNotFound)
+		return
+	}
+	http.Error(w, fmt.Sprintf("Invalid log replay: %v", err), http.StatusBadRequest)
+}
+
 // SimulateWordPressServiceHandler tests monitoring stack with WordPress-like service patterns
 func (th *TestingHandlers) SimulateWordPressServiceHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
+	}
 	// Generate WordPress-typical logs and metrics
 	activities := []string{
 		"user_login", "post_view", "admin_access", "plugin_activation",
@@ -267,7 +543,7 @@ func (th *TestingHandlers) SimulateWordPressServiceHandler(w http.ResponseWriter
 		logEntry := fmt.Sprintf(`192.168.1.%d - - [%s] "GET /wp-%s HTTP/1.1" %d %d "https://example.com/" "Mozilla/5.0"`,
 			rand.Intn(255), time.Now().Format("02/Jan/2006:15:04:05 -0700"), activity, statusCode, rand.Intn(5000)+500)
 
-		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
+		th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), logEntry)
 		generatedEvents = append(generatedEvents, fmt.Sprintf("%s (HTTP %d)", activity, statusCode))
 	}
 
@@ -295,11 +571,15 @@ func (th *TestingHandlers) SimulateWordPressServiceHandler(w http.ResponseWriter
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, response)
 
-	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "WordPress service simulation completed")
+	th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "WordPress service simulation completed")
 }
 
 // SimulateNextJSServiceHandler tests monitoring stack with Next.js-like service patterns
 func (th *TestingHandlers) SimulateNextJSServiceHandler(w http.ResponseWriter, r *http.Request) {
+	sink, ok := requestLogSink(w, r, th.loggingService)
+	if !ok {
+		return
+	}
 	// Generate Next.js-typical logs
 	routes := []string{
 		"/", "/about", "/blog", "/api/users", "/api/posts",
@@ -334,7 +614,7 @@ func (th *TestingHandlers) SimulateNextJSServiceHandler(w http.ResponseWriter, r
 		}
 
 		logJSON, _ := json.Marshal(logData)
-		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), string(logJSON))
+		th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), string(logJSON))
 
 		generatedEvents = append(generatedEvents, fmt.Sprintf("%s %s (%d, %dms)", method, route, statusCode, duration))
 	}
@@ -363,39 +643,19 @@ func (th *TestingHandlers) SimulateNextJSServiceHandler(w http.ResponseWriter, r
 	w.Header().Set("Content-Type", "application/json")
 	utils.EncodeJSON(w, response)
 
-	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Next.js service simulation completed")
+	th.loggingService.LogToSink(sink, zapcore.InfoLevel, r.Context(), "Next.js service simulation completed")
 }
 
 // SimulateCrossServiceTracingHandler tests Tempo with cross-service tracing scenarios
 func (th *TestingHandlers) SimulateCrossServiceTracingHandler(w http.ResponseWriter, r *http.Request) {
 	// Simulate e-commerce flow: User → Frontend → API → Database → Payment
-	traceScenarios := []struct {
-		name     string
-		services []string
-		flow     string
-	}{
-		{
-			name:     "e-commerce-checkout",
-			services: []string{"frontend", "user-api", "product-api", "payment-api", "database"},
-			flow:     "User checkout process",
-		},
-		{
-			name:     "content-delivery",
-			services: []string{"cdn", "origin-server", "api-gateway", "content-service", "database"},
-			flow:     "Content delivery pipeline",
-		},
-		{
-			name:     "authentication-flow",
-			services: []string{"auth-service", "user-service", "session-service", "database"},
-			flow:     "User authentication process",
-		},
-	}
+	traceScenarios := services.CrossServiceScenarios
 
 	var generatedTraces []string
 	for _, scenario := range traceScenarios {
 		traceID := fmt.Sprintf("trace-%d", rand.Intn(100000))
 
-		for i, service := range scenario.services {
+		for i, service := range scenario.Services {
 			spanID := fmt.Sprintf("span-%d", i)
 			duration := rand.Intn(100) + 10
 
@@ -405,7 +665,7 @@ func (th *TestingHandlers) SimulateCrossServiceTracingHandler(w http.ResponseWri
 				"trace_id":  traceID,
 				"span_id":   spanID,
 				"service":   service,
-				"operation": scenario.name,
+				"operation": scenario.Name,
 				"duration":  duration,
 				"status":    "success",
 			}
@@ -415,14 +675,14 @@ func (th *TestingHandlers) SimulateCrossServiceTracingHandler(w http.ResponseWri
 		}
 
 		generatedTraces = append(generatedTraces, fmt.Sprintf("%s: %s (%d services)",
-			scenario.name, scenario.flow, len(scenario.services)))
+			scenario.Name, scenario.Flow, len(scenario.Services)))
 	}
 
 	response := map[string]interface{}{
 		"message":          "Cross-service tracing simulation for Tempo testing",
 		"trace_scenarios":  len(traceScenarios),
 		"generated_traces": generatedTraces,
-		"total_spans":      len(traceScenarios[0].services) + len(traceScenarios[1].services) + len(traceScenarios[2].services),
+		"total_spans":      len(traceScenarios[0].Services) + len(traceScenarios[1].Services) + len(traceScenarios[2].Services),
 		"test_purpose":     "Validate Tempo cross-service tracing capabilities",
 		"timestamp":        time.Now().Format(time.RFC3339),
 		"service":          "argus",
@@ -435,84 +695,76 @@ func (th *TestingHandlers) SimulateCrossServiceTracingHandler(w http.ResponseWri
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Cross-service tracing simulation completed")
 }
 
-// TestServiceDiscoveryHandler tests service discovery and registration
+// TestServiceDiscoveryHandler health-checks the services and discovery
+// providers configured in settings and compares them with Prometheus' targets
 func (th *TestingHandlers) TestServiceDiscoveryHandler(w http.ResponseWriter, r *http.Request) {
-	// Simulate service discovery scenarios
-	services := []struct {
-		name      string
-		status    string
-		healthURL string
-		lastSeen  time.Time
-		version   string
-	}{
-		{
-			name:      "user-api",
-			status:    "healthy",
-			healthURL: "http://user-api:3000/health",
-			lastSeen:  time.Now(),
-			version:   "v1.2.3",
-		},
-		{
-			name:      "payment-service",
-			status:    "degraded",
-			healthURL: "http://payment-service:3001/health",
-			lastSeen:  time.Now().Add(-30 * time.Second),
-			version:   "v2.1.0",
-		},
-		{
-			name:      "notification-worker",
-			status:    "unhealthy",
-			healthURL: "http://notification-worker:3002/health",
-			lastSeen:  time.Now().Add(-5 * time.Minute),
-			version:   "v1.0.1",
-		},
+	settings := getGlobalSettings()
+	if len(settings.Services) == 0 && len(settings.Discovery) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		utils.EncodeJSON(w, map[string]interface{}{
+			"message":         "No services configured: add services or discovery providers in settings",
+			"status":          "not_configured",
+			"services_tested": 0,
+			"health_results":  []models.DiscoveredTarget{},
+			"test_purpose":    "Validate service discovery and health monitoring",
+			"timestamp":       time.Now().Format(time.RFC3339),
+			"service":         "argus",
+			"functionality":   "service_discovery_validation",
+		})
+		return
 	}
+	th.discoverServices(w, r, settings)
+}
 
-	// Test health check endpoints
-	var healthResults []map[string]interface{}
-	for _, service := range services {
-		// Simulate health check response
-		var responseTime int
-		var available bool
+// discoverServices runs the configured services and discovery providers,
+// health-checks every target they find and reports the running ones
+// Prometheus does not scrape
+func (th *TestingHandlers) discoverServices(w http.ResponseWriter, r *http.Request, settings *types.LGTMSettings) {
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Starting service discovery...")
 
-		switch service.status {
-		case "healthy":
-			responseTime = rand.Intn(50) + 10 // 10-60ms
-			available = true
-		case "degraded":
-			responseTime = rand.Intn(500) + 200 // 200-700ms
-			available = true
-		case "unhealthy":
-			responseTime = 0
-			available = false
+	var providers []services.DiscoveryProvider
+	if len(settings.Services) > 0 {
+		providers = append(providers, services.NewServiceProvider(settings.Services))
+	}
+	var configErrors []string
+	for _, config := range settings.Discovery {
+		provider, err := services.NewDiscoveryProvider(config, settings.DNSResolver)
+		if err != nil {
+			configErrors = append(configErrors, fmt.Sprintf("provider %s: %v", providerName(config), err))
+			continue
 		}
+		providers = append(providers, provider)
+	}
 
-		healthResult := map[string]interface{}{
-			"service":       service.name,
-			"status":        service.status,
-			"available":     available,
-			"response_time": responseTime,
-			"last_seen":     service.lastSeen.Format(time.RFC3339),
-			"version":       service.version,
-		}
+	report := th.discoveryService.Discover(r.Context(), providers, settings.Prometheus)
+	report.Problems = append(configErrors, report.Problems...)
+	if len(configErrors) > 0 && report.Status == "healthy" {
+		report.Status = "degraded"
+	}
 
-		healthResults = append(healthResults, healthResult)
+	counts := map[string]int{}
+	for _, target := range report.Targets {
+		counts[target.Status]++
 
 		// Log service discovery event
-		logEntry := fmt.Sprintf("Service discovery: %s status=%s response_time=%dms version=%s",
-			service.name, service.status, responseTime, service.version)
+		logEntry := fmt.Sprintf("Service discovery: %s provider=%s status=%s scraped=%t",
+			target.Address, target.Provider, target.Status, target.Scraped)
 		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
 	}
 
 	response := map[string]interface{}{
 		"message":           "Service discovery testing completed",
-		"services_tested":   len(services),
-		"health_results":    healthResults,
-		"healthy_services":  2,
-		"degraded_services": 1,
-		"failed_services":   1,
+		"status":            report.Status,
+		"providers":         report.Providers,
+		"services_tested":   len(report.Targets),
+		"health_results":    report.Targets,
+		"healthy_services":  counts["healthy"],
+		"degraded_services": counts["degraded"],
+		"failed_services":   counts["unhealthy"],
+		"unscraped_targets": report.Unscraped,
+		"problems":          report.Problems,
 		"test_purpose":      "Validate service discovery and health monitoring",
-		"timestamp":         time.Now().Format(time.RFC3339),
+		"timestamp":         report.Timestamp.Format(time.RFC3339),
 		"service":           "argus",
 		"functionality":     "service_discovery_validation",
 	}
@@ -523,88 +775,76 @@ func (th *TestingHandlers) TestServiceDiscoveryHandler(w http.ResponseWriter, r
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Service discovery testing completed")
 }
 
-// TestReverseProxyHandler tests Traefik reverse proxy integration
-func (th *TestingHandlers) TestReverseProxyHandler(w http.ResponseWriter, r *http.Request) {
-	// Simulate reverse proxy routing scenarios
-	routes := []struct {
-		domain  string
-		path    string
-		backend string
-		status  string
-		latency int
-		ssl     bool
-	}{
-		{
-			domain:  "api.example.com",
-			path:    "/users",
-			backend: "user-api:3000",
-			status:  "active",
-			latency: rand.Intn(50) + 10,
-			ssl:     true,
-		},
-		{
-			domain:  "blog.example.com",
-			path:    "/",
-			backend: "wordpress:80",
-			status:  "active",
-			latency: rand.Intn(100) + 20,
-			ssl:     true,
-		},
-		{
-			domain:  "admin.example.com",
-			path:    "/dashboard",
-			backend: "admin-panel:8080",
-			status:  "maintenance",
-			latency: 0,
-			ssl:     true,
-		},
-		{
-			domain:  "legacy.example.com",
-			path:    "/old-api",
-			backend: "legacy-service:3003",
-			status:  "deprecated",
-			latency: rand.Intn(1000) + 500,
-			ssl:     false,
-		},
+// providerName names a discovery provider in errors, by its type when it has no name
+func providerName(config types.DiscoveryProvider) string {
+	if config.Name == "" {
+		return config.Type
 	}
+	return config.Name
+}
 
-	var routeResults []map[string]interface{}
-	for _, route := range routes {
-		// Simulate load balancing
-		backendInstances := []string{
-			fmt.Sprintf("%s-1", route.backend),
-			fmt.Sprintf("%s-2", route.backend),
-			fmt.Sprintf("%s-3", route.backend),
-		}
-		selectedBackend := backendInstances[rand.Intn(len(backendInstances))]
+// TestReverseProxyHandler sends requests through the routes configured in the
+// reverse_proxy settings and checks which backend served them, that TLS is
+// verified where expected, that X-Forwarded-For and X-Request-ID reach the
+// backends and that load is spread over every expected backend. The
+// "requests" parameter overrides how many requests each route gets.
+func (th *TestingHandlers) TestReverseProxyHandler(w http.ResponseWriter, r *http.Request) {
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Starting reverse proxy route checks...")
 
-		routeResult := map[string]interface{}{
-			"domain":           route.domain,
-			"path":             route.path,
-			"selected_backend": selectedBackend,
-			"status":           route.status,
-			"latency_ms":       route.latency,
-			"ssl_enabled":      route.ssl,
-			"load_balanced":    true,
+	var config types.ProxyConfig
+	if settings := getGlobalSettings(); settings.ReverseProxy != nil {
+		config = *settings.ReverseProxy
+	}
+	if param := r.URL.Query().Get("requests"); param != "" {
+		requests, err := strconv.Atoi(param)
+		if err != nil || requests < 1 || requests > 1000 {
+			http.Error(w, "requests must be between 1 and 1000", http.StatusBadRequest)
+			return
 		}
+		routes := make([]types.ProxyRoute, len(config.Routes))
+		for i, route := range config.Routes {
+			route.Requests = requests
+			routes[i] = route
+		}
+		config.Routes = routes
+	}
+
+	report := th.reverseProxyService.CheckRoutes(r.Context(), config)
 
-		routeResults = append(routeResults, routeResult)
+	active, ssl, balanced := 0, 0, 0
+	for _, route := range report.Routes {
+		if route.Passed {
+			active++
+		}
+		if route.TLSVerified != nil && *route.TLSVerified {
+			ssl++
+		}
+		if route.Balanced != nil && *route.Balanced {
+			balanced++
+		}
 
 		// Log reverse proxy event
-		logEntry := fmt.Sprintf("Reverse proxy: %s%s -> %s (latency=%dms, ssl=%t)",
-			route.domain, route.path, selectedBackend, route.latency, route.ssl)
+		logEntry := fmt.Sprintf("Reverse proxy: %s passed=%t succeeded=%d/%d backends=%v avg_latency=%s",
+			route.URL, route.Passed, route.Succeeded, route.Requests, route.Backends, route.AvgLatency)
 		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
 	}
 
+	message := "Reverse proxy testing completed"
+	if len(config.Routes) == 0 {
+		message = "No proxy routes: configure reverse_proxy routes in settings"
+	}
+
 	response := map[string]interface{}{
-		"message":       "Reverse proxy testing completed",
-		"routes_tested": len(routes),
-		"route_results": routeResults,
-		"active_routes": 2,
-		"ssl_routes":    3,
-		"load_balanced": len(routes),
+		"message":       message,
+		"status":        report.Status,
+		"routes_tested": len(report.Routes),
+		"route_results": report.Routes,
+		"active_routes": active,
+		"ssl_routes":    ssl,
+		"load_balanced": balanced,
+		"problems":      report.Problems,
 		"test_purpose":  "Validate Traefik reverse proxy configuration",
-		"timestamp":     time.Now().Format(time.RFC3339),
+		"timestamp":     report.Timestamp.Format(time.RFC3339),
 		"service":       "argus",
 		"functionality": "reverse_proxy_validation",
 	}
@@ -615,92 +855,51 @@ func (th *TestingHandlers) TestReverseProxyHandler(w http.ResponseWriter, r *htt
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Reverse proxy testing completed")
 }
 
-// TestSSLMonitoringHandler tests SSL certificate monitoring
+// TestSSLMonitoringHandler inspects the certificates of TLS endpoints. Targets
+// come from the "targets" parameter (comma-separated host:port), then from
+// the tls_targets settings, then from the stack services configured with https.
 func (th *TestingHandlers) TestSSLMonitoringHandler(w http.ResponseWriter, r *http.Request) {
-	// Simulate SSL certificate monitoring
-	certificates := []struct {
-		domain    string
-		issuer    string
-		expiresAt time.Time
-		daysLeft  int
-		status    string
-		autoRenew bool
-	}{
-		{
-			domain:    "api.example.com",
-			issuer:    "Let's Encrypt",
-			expiresAt: time.Now().AddDate(0, 0, 45),
-			daysLeft:  45,
-			status:    "valid",
-			autoRenew: true,
-		},
-		{
-			domain:    "blog.example.com",
-			issuer:    "Let's Encrypt",
-			expiresAt: time.Now().AddDate(0, 0, 15),
-			daysLeft:  15,
-			status:    "expiring_soon",
-			autoRenew: true,
-		},
-		{
-			domain:    "legacy.example.com",
-			issuer:    "Legacy CA",
-			expiresAt: time.Now().AddDate(0, 0, -5),
-			daysLeft:  -5,
-			status:    "expired",
-			autoRenew: false,
-		},
-		{
-			domain:    "admin.example.com",
-			issuer:    "Let's Encrypt",
-			expiresAt: time.Now().AddDate(0, 0, 75),
-			daysLeft:  75,
-			status:    "valid",
-			autoRenew: true,
-		},
-	}
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Starting SSL certificate inspection...")
 
-	var certResults []map[string]interface{}
-	var alertCount int
-
-	for _, cert := range certificates {
-		alertLevel := "none"
-		if cert.daysLeft < 0 {
-			alertLevel = "critical"
-			alertCount++
-		} else if cert.daysLeft <= 30 {
-			alertLevel = "warning"
-			alertCount++
+	warningDays := 30
+	if param := r.URL.Query().Get("warning_days"); param != "" {
+		if parsed, err := strconv.Atoi(param); err == nil && parsed >= 0 && parsed <= 365 {
+			warningDays = parsed
 		}
+	}
 
-		certResult := map[string]interface{}{
-			"domain":      cert.domain,
-			"issuer":      cert.issuer,
-			"expires_at":  cert.expiresAt.Format(time.RFC3339),
-			"days_left":   cert.daysLeft,
-			"status":      cert.status,
-			"auto_renew":  cert.autoRenew,
-			"alert_level": alertLevel,
-		}
+	targets := sslMonitoringTargets(r)
+	report := th.tlsInspectionService.Inspect(r.Context(), targets, warningDays)
 
-		certResults = append(certResults, certResult)
+	counts := map[string]int{}
+	for _, check := range report.Targets {
+		counts[check.Status]++
 
 		// Log SSL monitoring event
-		logEntry := fmt.Sprintf("SSL monitoring: %s expires in %d days (issuer=%s, status=%s)",
-			cert.domain, cert.daysLeft, cert.issuer, cert.status)
+		logEntry := fmt.Sprintf("SSL monitoring: %s status=%s days_left=%d chain_valid=%t",
+			check.Target, check.Status, check.DaysLeft, check.ChainValid)
 		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
 	}
 
+	message := "SSL certificate monitoring completed"
+	if len(targets) == 0 {
+		message = "No TLS targets: pass targets=host:port or configure tls_targets in settings"
+	}
+
 	response := map[string]interface{}{
-		"message":              "SSL certificate monitoring completed",
-		"certificates_checked": len(certificates),
-		"certificate_results":  certResults,
-		"valid_certificates":   2,
-		"expiring_soon":        1,
-		"expired_certificates": 1,
-		"alerts_generated":     alertCount,
+		"message":              message,
+		"status":               report.Status,
+		"certificates_checked": len(report.Targets),
+		"certificate_results":  report.Targets,
+		"valid_certificates":   counts["valid"],
+		"expiring_soon":        counts["expiring_soon"],
+		"expired_certificates": counts["expired"],
+		"invalid_chains":       counts["invalid"],
+		"failed_handshakes":    counts["failed"],
+		"warning_days":         warningDays,
+		"problems":             report.Problems,
 		"test_purpose":         "Validate SSL certificate monitoring and alerting",
-		"timestamp":            time.Now().Format(time.RFC3339),
+		"timestamp":            report.Timestamp.Format(time.RFC3339),
 		"service":              "argus",
 		"functionality":        "ssl_monitoring_validation",
 	}
@@ -711,94 +910,101 @@ func (th *TestingHandlers) TestSSLMonitoringHandler(w http.ResponseWriter, r *ht
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "SSL certificate monitoring completed")
 }
 
-// TestDomainHealthHandler tests domain-specific health monitoring
-func (th *TestingHandlers) TestDomainHealthHandler(w http.ResponseWriter, r *http.Request) {
-	// Simulate domain health monitoring
-	domains := []struct {
-		name         string
-		status       string
-		responseTime int
-		statusCode   int
-		dnsTime      int
-		location     string
-	}{
-		{
-			name:         "api.example.com",
-			status:       "healthy",
-			responseTime: rand.Intn(100) + 50,
-			statusCode:   200,
-			dnsTime:      rand.Intn(20) + 5,
-			location:     "US-East",
-		},
-		{
-			name:         "blog.example.com",
-			status:       "healthy",
-			responseTime: rand.Intn(150) + 100,
-			statusCode:   200,
-			dnsTime:      rand.Intn(30) + 10,
-			location:     "EU-West",
-		},
-		{
-			name:         "admin.example.com",
-			status:       "degraded",
-			responseTime: rand.Intn(2000) + 1000,
-			statusCode:   200,
-			dnsTime:      rand.Intn(50) + 20,
-			location:     "Asia-Pacific",
-		},
-		{
-			name:         "legacy.example.com",
-			status:       "down",
-			responseTime: 0,
-			statusCode:   503,
-			dnsTime:      rand.Intn(100) + 50,
-			location:     "US-West",
-		},
+// sslMonitoringTargets picks the endpoints to inspect for a request. Targets
+// from the request use the TLS settings of the configured target with the
+// same address, so CA files are only ever read from settings.
+func sslMonitoringTargets(r *http.Request) []types.TLSTarget {
+	var targets []types.TLSTarget
+	settings := getGlobalSettings()
+	if param := r.URL.Query().Get("targets"); param != "" {
+		configured := make(map[string]*types.TLSConfig, len(settings.TLSTargets))
+		for _, target := range settings.TLSTargets {
+			configured[target.Address] = target.TLS
+		}
+		serverName := r.URL.Query().Get("server_name")
+		for _, address := range strings.Split(param, ",") {
+			if address = strings.TrimSpace(address); address == "" {
+				continue
+			}
+			tlsConfig := configured[address]
+			if serverName != "" {
+				override := types.TLSConfig{}
+				if tlsConfig != nil {
+					override = *tlsConfig
+				}
+				override.ServerName = serverName
+				tlsConfig = &override
+			}
+			targets = append(targets, types.TLSTarget{Address: address, TLS: tlsConfig})
+		}
+		return targets
 	}
 
-	var healthResults []map[string]interface{}
-	uptime := 0
-
-	for _, domain := range domains {
-		var availability float64
-		if domain.status == "healthy" {
-			availability = 99.9
-			uptime++
-		} else if domain.status == "degraded" {
-			availability = 95.5
-		} else {
-			availability = 0.0
+	if len(settings.TLSTargets) > 0 {
+		return settings.TLSTargets
+	}
+	for _, service := range []types.ServiceConfig{
+		settings.Grafana, settings.Prometheus, settings.AlertManager,
+		settings.Loki, settings.Tempo, settings.OTELCollector.ServiceConfig,
+	} {
+		if parsed, err := url.Parse(service.URL); err == nil && parsed.Scheme == "https" {
+			targets = append(targets, types.TLSTarget{Address: parsed.Host, TLS: service.TLS})
 		}
+	}
+	return targets
+}
 
-		healthResult := map[string]interface{}{
-			"domain":        domain.name,
-			"status":        domain.status,
-			"response_time": domain.responseTime,
-			"status_code":   domain.statusCode,
-			"dns_time":      domain.dnsTime,
-			"location":      domain.location,
-			"availability":  availability,
-		}
+// TestDomainHealthHandler probes domains blackbox-style, timing the DNS, TCP,
+// TLS and HTTP phases of each request. Targets come from the "urls" parameter
+// (comma-separated, optionally checked against "body_regex"), then from the
+// domains settings, then from the configured stack services.
+func (th *TestingHandlers) TestDomainHealthHandler(w http.ResponseWriter, r *http.Request) {
+	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Starting domain health probes...")
+
+	settings := getGlobalSettings()
+	resolver := r.URL.Query().Get("resolver")
+	if resolver == "" {
+		resolver = settings.DNSResolver
+	}
 
-		healthResults = append(healthResults, healthResult)
+	targets := domainHealthTargets(r.URL.Query().Get("urls"), r.URL.Query().Get("body_regex"), settings)
+	report := th.domainProbeService.Probe(r.Context(), targets, resolver)
+
+	healthy := 0
+	var totalDuration time.Duration
+	for _, probe := range report.Probes {
+		if probe.Success {
+			healthy++
+		}
+		totalDuration += probe.Duration
 
 		// Log domain health event
-		logEntry := fmt.Sprintf("Domain health: %s status=%s response_time=%dms location=%s availability=%.1f%%",
-			domain.name, domain.status, domain.responseTime, domain.location, availability)
+		logEntry := fmt.Sprintf("Domain health: %s success=%t status_code=%d dns=%s connect=%s tls=%s duration=%s",
+			probe.Name, probe.Success, probe.StatusCode, probe.Phases.DNS, probe.Phases.Connect, probe.Phases.TLS, probe.Duration)
 		th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), logEntry)
 	}
 
-	overallUptime := float64(uptime) / float64(len(domains)) * 100
+	message := "Domain health monitoring completed"
+	overallUptime, avgResponseTime := 0.0, 0.0
+	if len(report.Probes) > 0 {
+		overallUptime = float64(healthy) / float64(len(report.Probes)) * 100
+		avgResponseTime = float64(totalDuration.Milliseconds()) / float64(len(report.Probes))
+	} else {
+		message = "No domains to probe: pass urls= or configure domains in settings"
+	}
 
 	response := map[string]interface{}{
-		"message":           "Domain health monitoring completed",
-		"domains_checked":   len(domains),
-		"health_results":    healthResults,
-		"healthy_domains":   uptime,
+		"message":           message,
+		"status":            report.Status,
+		"resolver":          report.Resolver,
+		"domains_checked":   len(report.Probes),
+		"health_results":    report.Probes,
+		"healthy_domains":   healthy,
 		"overall_uptime":    overallUptime,
-		"avg_response_time": (domains[0].responseTime + domains[1].responseTime + domains[2].responseTime) / 3,
-		"test_purpose":      "Validate domain health monitoring from multiple locations",
-		"timestamp":         time.Now().Format(time.RFC3339),
+		"avg_response_time": avgResponseTime,
+		"problems":          report.Problems,
+		"test_purpose":      "Validate domain health monitoring with per-phase probe timings",
+		"timestamp":         report.Timestamp.Format(time.RFC3339),
 		"service":           "argus",
 		"functionality":     "domain_health_validation",
 	}
@@ -808,3 +1014,34 @@ func (th *TestingHandlers) TestDomainHealthHandler(w http.ResponseWriter, r *htt
 
 	th.loggingService.LogWithContext(zapcore.InfoLevel, r.Context(), "Domain health monitoring completed")
 }
+
+// domainHealthTargets picks the domains to probe: the comma-separated urls
+// when given, otherwise the settings
+func domainHealthTargets(urls, bodyRegex string, settings *types.LGTMSettings) []types.DomainTarget {
+	var targets []types.DomainTarget
+	if urls != "" {
+		for _, rawURL := range strings.Split(urls, ",") {
+			if rawURL = strings.TrimSpace(rawURL); rawURL != "" {
+				targets = append(targets, types.DomainTarget{URL: rawURL, BodyRegex: bodyRegex})
+			}
+		}
+		return targets
+	}
+
+	if len(settings.Domains) > 0 {
+		return settings.Domains
+	}
+	stack := []struct {
+		name    string
+		service types.ServiceConfig
+	}{
+		{"grafana", settings.Grafana}, {"prometheus", settings.Prometheus}, {"alertmanager", settings.AlertManager},
+		{"loki", settings.Loki}, {"tempo", settings.Tempo},
+	}
+	for _, s := range stack {
+		if s.service.URL != "" {
+			targets = append(targets, types.DomainTarget{Name: s.name, URL: s.service.URL, ExpectedStatus: []int{200, 401, 404}, TLS: s.service.TLS})
+		}
+	}
+	return targets
+}
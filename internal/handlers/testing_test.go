@@ -2,12 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/nahuelsantos/argus/internal/models"
 	"github.com/nahuelsantos/argus/internal/services"
+	"github.com/nahuelsantos/argus/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -300,6 +309,14 @@ func TestTestingHandlers_GenerateMultilineLogsHandler(t *testing.T) {
 			assert.Contains(t, response, "message")
 			assert.Contains(t, response, "stack_traces")
 			assert.Contains(t, response, "functionality")
+
+			// One generated trace per language, each spanning several lines
+			assert.Equal(t, 3.0, response["stack_traces"])
+			assert.Greater(t, response["total_log_lines"], 9.0)
+			traces, ok := response["generated_traces"].([]interface{})
+			require.True(t, ok)
+			require.Len(t, traces, 3)
+			assert.True(t, strings.HasPrefix(traces[0].(string), types.LogFormatJavaStack))
 		})
 	}
 }
@@ -484,10 +501,106 @@ func TestTestingHandlers_TestServiceDiscoveryHandler(t *testing.T) {
 			assert.Contains(t, response, "message")
 			assert.Contains(t, response, "functionality")
 			assert.Contains(t, response, "services_tested")
+
+			// Nothing is simulated without configured services
+			assert.Equal(t, "not_configured", response["status"])
+			assert.Equal(t, float64(0), response["services_tested"])
 		})
 	}
 }
 
+func TestTestingHandlers_TestServiceDiscoveryConfiguredServices(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "/health", r.URL.Path)
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	healthyAddress := strings.TrimPrefix(healthy.URL, "http://")
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"status": "success", "data": {"activeTargets": [{"labels": {"instance": %q, "job": "orders"}, "health": "up"}]}}`, healthyAddress)
+	}))
+	defer prometheus.Close()
+
+	globalSettings = &types.LGTMSettings{
+		Prometheus: types.ServiceConfig{URL: prometheus.URL},
+		Services: []types.DiscoveryTarget{
+			{Name: "orders", ServiceConfig: types.ServiceConfig{URL: healthy.URL + "/health", Headers: map[string]string{"Authorization": "secret-token"}}},
+			{Name: "billing", ServiceConfig: types.ServiceConfig{URL: failing.URL + "/health"}},
+		},
+	}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewTestingHandlers(loggingService, tracingService)
+
+	w := httptest.NewRecorder()
+	handlers.TestServiceDiscoveryHandler(w, httptest.NewRequest("POST", "/test-service-discovery", nil))
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "degraded", response["status"])
+	assert.Equal(t, float64(2), response["services_tested"])
+	assert.Equal(t, float64(1), response["healthy_services"])
+	assert.Equal(t, float64(1), response["failed_services"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "services", "type": "static", "targets": float64(2)}}, response["providers"])
+
+	results := response["health_results"].([]interface{})
+	orders := results[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"service": "orders"}, orders["labels"])
+	assert.Equal(t, "healthy", orders["status"])
+	assert.Equal(t, true, orders["scraped"])
+	assert.Equal(t, "orders", orders["scrape_job"])
+	billing := results[1].(map[string]interface{})
+	assert.Equal(t, "unhealthy", billing["status"])
+	assert.Equal(t, float64(http.StatusServiceUnavailable), billing["status_code"])
+}
+
+func TestTestingHandlers_TestServiceDiscoveryProviders(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer app.Close()
+	address := strings.TrimPrefix(app.URL, "http://")
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status": "success", "data": {"activeTargets": []}}`))
+	}))
+	defer prometheus.Close()
+
+	globalSettings = &types.LGTMSettings{
+		Prometheus: types.ServiceConfig{URL: prometheus.URL},
+		Discovery: []types.DiscoveryProvider{
+			{Type: types.DiscoveryStatic, Targets: []string{address}},
+			{Type: types.DiscoveryDNS},
+		},
+	}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewTestingHandlers(loggingService, tracingService)
+
+	w := httptest.NewRecorder()
+	handlers.TestServiceDiscoveryHandler(w, httptest.NewRequest("GET", "/test-service-discovery", nil))
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "degraded", response["status"])
+	assert.Equal(t, float64(1), response["healthy_services"])
+	assert.Equal(t, []interface{}{address}, response["unscraped_targets"])
+	assert.Equal(t, []interface{}{
+		"provider dns: dns discovery needs names",
+		address + " is running but not scraped by Prometheus",
+	}, response["problems"])
+}
+
 func TestTestingHandlers_TestReverseProxyHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -578,6 +691,56 @@ func TestTestingHandlers_TestSSLMonitoringHandler(t *testing.T) {
 	}
 }
 
+func TestTestingHandlers_TestSSLMonitoringTargets(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewTestingHandlers(loggingService, tracingService)
+
+	inspect := func(query string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		handlers.TestSSLMonitoringHandler(w, httptest.NewRequest("GET", "/test-ssl-monitoring?"+query, nil))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// Nothing to inspect
+	response := inspect("")
+	assert.Equal(t, "not_configured", response["status"])
+
+	// The CA file cannot be chosen by the request
+	response = inspect("targets=" + address + "&ca_file=" + caFile + "&server_name=example.com")
+	assert.Equal(t, "failed", response["status"])
+	assert.Equal(t, float64(1), response["invalid_chains"])
+
+	// The httptest certificate is valid for example.com and expires decades from now
+	globalSettings = &types.LGTMSettings{TLSTargets: []types.TLSTarget{{Address: address, TLS: &types.TLSConfig{CAFile: caFile}}}}
+	t.Cleanup(func() { globalSettings = nil })
+	response = inspect("targets=" + address + "&server_name=example.com")
+	assert.Equal(t, "healthy", response["status"])
+	assert.Equal(t, float64(1), response["valid_certificates"])
+	result := response["certificate_results"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, address, result["target"])
+	assert.Equal(t, true, result["chain_valid"])
+	leaf := result["chain"].([]interface{})[0].(map[string]interface{})
+	assert.Contains(t, leaf["sans"], "example.com")
+	assert.Equal(t, "RSA", leaf["key_type"])
+
+	// Settings are used when the request names no targets
+	globalSettings = &types.LGTMSettings{TLSTargets: []types.TLSTarget{{Address: address}}}
+	response = inspect("")
+	assert.Equal(t, "failed", response["status"])
+	assert.Equal(t, float64(1), response["invalid_chains"])
+}
+
 func TestTestingHandlers_TestDomainHealthHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -623,6 +786,109 @@ func TestTestingHandlers_TestDomainHealthHandler(t *testing.T) {
 	}
 }
 
+func TestTestingHandlers_TestDomainHealthProbes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("welcome home"))
+	}))
+	defer server.Close()
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewTestingHandlers(loggingService, tracingService)
+
+	probe := func(query string) map[string]interface{} {
+		w := httptest.NewRecorder()
+		handlers.TestDomainHealthHandler(w, httptest.NewRequest("GET", "/test-domain-health?"+query, nil))
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	response := probe("urls=" + server.URL + "/&body_regex=welcome")
+	assert.Equal(t, "healthy", response["status"])
+	assert.Equal(t, "system", response["resolver"])
+	assert.Equal(t, float64(1), response["healthy_domains"])
+	result := response["health_results"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(200), result["status_code"])
+	assert.Equal(t, true, result["body_matched"])
+	assert.Len(t, result["redirect_chain"], 1)
+	assert.Contains(t, result["phases"], "connect_ns")
+
+	// Settings are used when the request names no URLs
+	globalSettings = &types.LGTMSettings{Domains: []types.DomainTarget{
+		{Name: "home", URL: server.URL + "/home"},
+		{Name: "strict", URL: server.URL + "/", NoFollowRedirects: true},
+	}}
+	t.Cleanup(func() { globalSettings = nil })
+	response = probe("")
+	assert.Equal(t, "degraded", response["status"])
+	assert.Equal(t, float64(50), response["overall_uptime"])
+	assert.Equal(t, []interface{}{"strict: unexpected status 302"}, response["problems"])
+}
+
+func TestTestingHandlers_TestReverseProxyRoutes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", "whoami-1")
+		_ = r.Header.Write(w)
+	}))
+	defer backend.Close()
+	backendURL, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+
+	// The proxy stand-in serves one host and 404s the rest
+	forward := httputil.NewSingleHostReverseProxy(backendURL)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Host, "whoami.example.com") {
+			http.NotFound(w, r)
+			return
+		}
+		forward.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	globalSettings = &types.LGTMSettings{ReverseProxy: &types.ProxyConfig{
+		Address: proxy.Listener.Addr().String(),
+		Routes: []types.ProxyRoute{
+			{Name: "whoami", Host: "whoami.example.com", BackendHeader: "X-Backend", ExpectedBackends: []string{"whoami-1"}, EchoesHeaders: true},
+			{Name: "missing", Host: "missing.example.com"},
+		},
+	}}
+	t.Cleanup(func() { globalSettings = nil })
+
+	loggingService := services.NewLoggingService()
+	tracingService := services.NewTracingService()
+	loggingService.InitTestLogger()
+	tracingService.InitTracer()
+	handlers := NewTestingHandlers(loggingService, tracingService)
+
+	w := httptest.NewRecorder()
+	handlers.TestReverseProxyHandler(w, httptest.NewRequest("GET", "/test-reverse-proxy?requests=3", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "degraded", response["status"])
+	assert.Equal(t, float64(2), response["routes_tested"])
+	assert.Equal(t, float64(1), response["active_routes"])
+	assert.Equal(t, []interface{}{"missing: HTTP 404"}, response["problems"])
+
+	whoami := response["route_results"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(3), whoami["requests"])
+	assert.Equal(t, map[string]interface{}{"whoami-1": float64(3)}, whoami["backends"])
+	assert.Equal(t, true, whoami["forwarded_for"])
+	assert.Equal(t, true, whoami["request_id_propagated"])
+
+	w = httptest.NewRecorder()
+	handlers.TestReverseProxyHandler(w, httptest.NewRequest("GET", "/test-reverse-proxy?requests=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 // Benchmark tests for performance validation
 func BenchmarkTestingHandlers_GenerateJSONLogsHandler(b *testing.B) {
 	loggingService := services.NewLoggingService()
@@ -671,3 +937,158 @@ func BenchmarkTestingHandlers_GenerateMixedLogsHandler(b *testing.B) {
 		handlers.GenerateMixedLogsHandler(w, req)
 	}
 }
+
+func TestTestingHandlers_GenerateFormatLogsHandler(t *testing.T) {
+	loggingService := services.NewLoggingService()
+	loggingService.InitTestLogger()
+	handlers := NewTestingHandlers(loggingService, services.NewTracingService())
+	var out strings.Builder
+	handlers.logOutput = &out
+
+	w := httptest.NewRecorder()
+	handlers.GenerateFormatLogsHandler(w, httptest.NewRequest("GET", "/generate-logs/format?format=combined&count=4&seed=3", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report models.LogFormatReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "combined", report.Format)
+	assert.Equal(t, 4, report.Entries)
+	assert.Equal(t, int64(3), report.Seed)
+	assert.Equal(t, 4, strings.Count(out.String(), "\n"))
+	assert.Contains(t, out.String(), " HTTP/1.1\" ")
+
+	out.Reset()
+	body := `{"format":"logfmt","count":3,"fields":{"service":{"values":["checkout"]}}}`
+	w = httptest.NewRecorder()
+	handlers.GenerateFormatLogsHandler(w, httptest.NewRequest("POST", "/generate-logs/format", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 3, strings.Count(out.String(), "service=checkout "))
+
+	for _, target := range []string{"/generate-logs/format?format=xml", "/generate-logs/format?count=abc", "/generate-logs/format?count=0", "/generate-logs/format?sink=missing"} {
+		w = httptest.NewRecorder()
+		handlers.GenerateFormatLogsHandler(w, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestTestingHandlers_LogReplayHandlers(t *testing.T) {
+	loggingService := services.NewLoggingService()
+	loggingService.InitTestLogger()
+	dir := t.TempDir()
+	capture := filepath.Join(dir, "capture.log")
+	require.NoError(t, loggingService.Sinks().Configure([]types.LogSinkConfig{{Name: "capture", Type: types.LogSinkFile, Path: capture}}, types.ServiceConfig{}))
+	t.Cleanup(loggingService.Sinks().Close)
+	t.Setenv("ARGUS_LOG_REPLAY_DIR", dir)
+	handlers := NewTestingHandlers(loggingService, services.NewTracingService())
+
+	source := filepath.Join(dir, "incident.log")
+	require.NoError(t, os.WriteFile(source, []byte("first line\nsecond line\n"), 0o644))
+
+	w := httptest.NewRecorder()
+	handlers.LogReplaysHandler(w, httptest.NewRequest("POST", "/api/log-replays", strings.NewReader(`{"path":"`+source+`","sink":"capture"}`)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var replay models.LogReplay
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &replay))
+	assert.Equal(t, "running", replay.Status)
+
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		handlers.LogReplayHandler(w, httptest.NewRequest("GET", "/api/log-replays/"+replay.ID, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &replay))
+		return replay.Status == "completed"
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(2), replay.Entries)
+	data, err := os.ReadFile(capture)
+	require.NoError(t, err)
+	assert.Equal(t, "first line\nsecond line\n", string(data))
+
+	w = httptest.NewRecorder()
+	handlers.LogReplaysHandler(w, httptest.NewRequest("GET", "/api/log-replays", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), replay.ID)
+	assert.Contains(t, w.Body.String(), `"common":"02/Jan/2006:15:04:05 -0700"`)
+
+	for _, body := range []string{`{}`, `{"path":"` + source + `","sink":"missing"}`, `{"path":"` + filepath.Join(dir, "missing.log") + `"}`, `{"path":"/etc/passwd"}`, `not json`} {
+		w = httptest.NewRecorder()
+		handlers.LogReplaysHandler(w, httptest.NewRequest("POST", "/api/log-replays", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w = httptest.NewRecorder()
+	handlers.LogReplayHandler(w, httptest.NewRequest("DELETE", "/api/log-replays/"+replay.ID, nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = httptest.NewRecorder()
+	handlers.LogReplayHandler(w, httptest.NewRequest("GET", "/api/log-replays/"+replay.ID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = httptest.NewRecorder()
+	handlers.LogReplayHandler(w, httptest.NewRequest("PUT", "/api/log-replays/"+replay.ID, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestTestingHandlers_PIIRedaction(t *testing.T) {
+	loggingService := services.NewLoggingService()
+	loggingService.InitTestLogger()
+	handlers := NewTestingHandlers(loggingService, services.NewTracingService())
+
+	w := httptest.NewRecorder()
+	handlers.TestPIIRedactionHandler(w, httptest.NewRequest("GET", "/test-pii-redaction", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	handlers.GenerateJSONLogsHandler(w, httptest.NewRequest("GET", "/generate-logs/json?pii=ssn", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	for _, generate := range []struct {
+		handler http.HandlerFunc
+		target  string
+	}{
+		{handlers.GenerateUnstructuredLogsHandler, "/generate-logs/unstructured?pii=email,ip&pii_rate=1"},
+		{handlers.GenerateMixedLogsHandler, "/generate-logs/mixed?pii=email,ip&pii_rate=1"},
+		{handlers.GenerateJSONLogsHandler, "/generate-logs/json?pii=email,ip&pii_rate=1"},
+	} {
+		w = httptest.NewRecorder()
+		generate.handler(w, httptest.NewRequest("GET", generate.target, nil))
+		require.Equal(t, http.StatusOK, w.Code, generate.target)
+	}
+	var response struct {
+		SampleLogs []string      `json:"sample_logs"`
+		PIIRun     models.PIIRun `json:"pii_run"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	run := response.PIIRun
+	assert.Equal(t, "json", run.Generator)
+	require.Len(t, run.Lines, 10)
+	assert.Equal(t, map[string]int{"email": 10, "ip": 10}, run.Values)
+	for i, sample := range response.SampleLogs {
+		assert.Equal(t, run.Lines[i].Line, sample)
+		assert.Contains(t, sample, `"email":"`+run.Lines[i].PII[0].Value+`"`)
+	}
+
+	// Loki returns the lines as generated: nothing was redacted
+	loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.URL.Query().Get("query"), run.ID)
+		values := [][2]string{}
+		for _, line := range run.Lines {
+			values = append(values, [2]string{"1700000000000000000", line.Line})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   map[string]interface{}{"resultType": "streams", "result": []interface{}{map[string]interface{}{"stream": map[string]string{}, "values": values}}},
+		})
+	}))
+	t.Cleanup(loki.Close)
+	globalSettings = &types.LGTMSettings{Loki: types.ServiceConfig{URL: loki.URL}}
+	t.Cleanup(func() { globalSettings = nil })
+
+	w = httptest.NewRecorder()
+	handlers.TestPIIRedactionHandler(w, httptest.NewRequest("GET", "/test-pii-redaction?timeout=5s", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var report models.PIIRedactionReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, run.ID, report.RunID, "the latest run is checked by default")
+	assert.Equal(t, "failed", report.Status)
+	assert.Equal(t, 20, report.Values)
+	assert.Equal(t, 20, report.Leaked)
+}
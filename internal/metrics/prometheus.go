@@ -1,7 +1,20 @@
 package metrics
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Native (sparse) histogram resolution. The latency histograms keep their
+// classic buckets too, so scrapers without native histogram support still
+// get them; scrapers that negotiate protobuf receive both.
+const (
+	NativeHistogramBucketFactor = 1.1
+	NativeHistogramMaxBuckets   = 160
 )
 
 var (
@@ -19,6 +32,10 @@ var (
 			Name:    "http_request_duration_seconds",
 			Help:    "HTTP request duration in seconds",
 			Buckets: prometheus.DefBuckets,
+
+			NativeHistogramBucketFactor:     NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  NativeHistogramMaxBuckets,
+			NativeHistogramMinResetDuration: time.Hour,
 		},
 		[]string{"method", "endpoint"},
 	)
@@ -71,6 +88,10 @@ var (
 			Name:    "apm_span_duration_seconds",
 			Help:    "APM span duration in seconds",
 			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0},
+
+			NativeHistogramBucketFactor:     NativeHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  NativeHistogramMaxBuckets,
+			NativeHistogramMinResetDuration: time.Hour,
 		},
 		[]string{"service", "operation"},
 	)
@@ -159,6 +180,59 @@ var (
 		},
 		[]string{"service", "severity"},
 	)
+
+	// TLS certificate metrics
+	TLSCertificateExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tls_certificate_expiry_timestamp_seconds",
+			Help: "Expiry of each certificate presented by an inspected TLS endpoint, as a Unix timestamp",
+		},
+		[]string{"target", "subject", "issuer", "position"},
+	)
+
+	// Domain probe metrics
+	ProbeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "probe_phase_duration_seconds",
+			Help:    "Duration of each phase of a domain probe",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0},
+		},
+		[]string{"target", "phase"},
+	)
+
+	ProbeSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Whether the last domain probe succeeded (1) or failed (0)",
+		},
+		[]string{"target"},
+	)
+
+	ProbeHTTPStatusCode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_http_status_code",
+			Help: "Status code of the final response of the last domain probe",
+		},
+		[]string{"target"},
+	)
+
+	// Scheduled check metrics
+	CheckSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "argus_check_success",
+			Help: "Whether the last run of a scheduled check succeeded (1) or failed (0)",
+		},
+		[]string{"check", "profile"},
+	)
+
+	CheckDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "argus_check_duration_seconds",
+			Help:    "Duration of scheduled check runs",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0, 60.0},
+		},
+		[]string{"check", "profile"},
+	)
 )
 
 // RegisterMetrics registers all Prometheus metrics
@@ -182,5 +256,58 @@ func RegisterMetrics() {
 		NotificationLatency,
 		AlertManagerHealth,
 		MTTRGauge,
+		TLSCertificateExpiry,
+		ProbeDuration,
+		ProbeSuccess,
+		ProbeHTTPStatusCode,
+		CheckSuccess,
+		CheckDuration,
 	)
 }
+
+// ObserveWithTrace records a duration and, when a trace ID is given, attaches
+// it as an exemplar so Grafana can jump from the histogram to the trace
+func ObserveWithTrace(observer prometheus.Observer, seconds float64, traceID, spanID string) {
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || traceID == "" {
+		observer.Observe(seconds)
+		return
+	}
+	labels := prometheus.Labels{"trace_id": traceID}
+	if spanID != "" {
+		labels["span_id"] = spanID
+	}
+	exemplarObserver.ObserveWithExemplar(seconds, labels)
+}
+
+// Handler serves the gathered metrics like promhttp.Handler. With openMetrics
+// the OpenMetrics format is offered to scrapers that accept it. The format
+// query parameter ("openmetrics", "text" or "protobuf") overrides content
+// negotiation for manual inspection.
+func Handler(registerer prometheus.Registerer, gatherer prometheus.Gatherer, openMetrics bool) http.Handler {
+	negotiated := promhttp.InstrumentMetricHandler(registerer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: openMetrics,
+	}))
+	forced := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true})
+
+	formats := map[string]expfmt.Format{
+		"openmetrics": expfmt.FmtOpenMetrics_1_0_0,
+		"text":        expfmt.FmtText,
+		"protobuf":    expfmt.FmtProtoDelim,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("format")
+		if name == "" {
+			negotiated.ServeHTTP(w, r)
+			return
+		}
+		format, ok := formats[name]
+		if !ok {
+			http.Error(w, "format must be openmetrics, text or protobuf", http.StatusBadRequest)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", string(format))
+		forced.ServeHTTP(w, r)
+	})
+}
@@ -1,6 +1,9 @@
 package metrics
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -42,10 +45,16 @@ func TestMetricsRegistration(t *testing.T) {
 		"notification_latency_seconds",
 		"alert_manager_health",
 		"mttr_seconds",
+		"tls_certificate_expiry_timestamp_seconds",
+		"probe_phase_duration_seconds",
+		"probe_success",
+		"probe_http_status_code",
+		"argus_check_success",
+		"argus_check_duration_seconds",
 	}
 
 	// Test that all expected metrics exist by verifying we can create them
-	assert.Equal(t, 18, len(expectedMetrics), "Should have 18 different metric types")
+	assert.Equal(t, 24, len(expectedMetrics), "Should have 24 different metric types")
 }
 
 func TestHTTPMetrics(t *testing.T) {
@@ -266,6 +275,68 @@ func TestAPMMetrics(t *testing.T) {
 	}
 }
 
+func TestObserveWithTrace(t *testing.T) {
+	observer := APMSpanDuration.WithLabelValues("exemplar-test", "checkout")
+	ObserveWithTrace(observer, 0.042, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	ObserveWithTrace(observer, 0.3, "", "")
+
+	m := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(m))
+	histogram := m.GetHistogram()
+	assert.Equal(t, uint64(2), histogram.GetSampleCount())
+	assert.NotEmpty(t, histogram.GetBucket(), "classic buckets are kept")
+	assert.NotZero(t, histogram.GetSchema(), "native buckets are exposed")
+	assert.NotEmpty(t, histogram.GetPositiveSpan())
+
+	var exemplars []*dto.Exemplar
+	for _, bucket := range histogram.GetBucket() {
+		if bucket.GetExemplar() != nil {
+			exemplars = append(exemplars, bucket.GetExemplar())
+		}
+	}
+	require.Len(t, exemplars, 1)
+	labels := map[string]string{}
+	for _, pair := range exemplars[0].GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	assert.Equal(t, map[string]string{"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "span_id": "00f067aa0ba902b7"}, labels)
+	assert.Equal(t, 0.042, exemplars[0].GetValue())
+}
+
+func TestHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "probe_duration_seconds", Help: "Probe duration."})
+	registry.MustRegister(histogram)
+	ObserveWithTrace(histogram, 0.2, "4bf92f3577b34da6a3ce929d0e0e4736", "")
+
+	openMetricsAccept := "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5"
+	get := func(handler http.Handler, target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get(Handler(registry, registry, true), "/metrics", openMetricsAccept)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/openmetrics-text")
+	assert.Contains(t, w.Body.String(), `# {trace_id="4bf92f3577b34da6a3ce929d0e0e4736"} 0.2`)
+	assert.True(t, strings.HasSuffix(w.Body.String(), "# EOF\n"))
+
+	w = get(Handler(prometheus.NewRegistry(), registry, false), "/metrics", openMetricsAccept)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+	assert.NotContains(t, w.Body.String(), "# EOF")
+
+	w = get(Handler(prometheus.NewRegistry(), registry, false), "/metrics?format=openmetrics", "")
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/openmetrics-text")
+
+	w = get(Handler(prometheus.NewRegistry(), registry, true), "/metrics?format=text", openMetricsAccept)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+
+	w = get(Handler(prometheus.NewRegistry(), registry, true), "/metrics?format=json", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestAlertingMetrics(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -204,6 +204,18 @@ func isLongRunningEndpoint(path string) bool {
 		"/simulate/database-service",
 		"/simulate/static-site",
 		"/simulate/microservice",
+		"/test-pii-redaction",
+		"/test-loki-rules",
+		"/test-tempo-search",
+		"/test-tempo-service-graph",
+		"/test-otel-pipeline",
+		"/test-cardinality",
+		"/test-exemplars",
+		"/generate-logs/format",
+		"/test-grafana-alerting",
+		"/test-ssl-monitoring",
+		"/test-domain-health",
+		"/test-reverse-proxy",
 	}
 
 	for _, longPath := range longRunningPaths {
@@ -219,7 +231,7 @@ func CORSMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Allow internal network access (safe for internal networks)
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Request-ID, X-User-ID, X-Session-ID")
 		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-Trace-ID")
 		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
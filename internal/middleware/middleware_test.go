@@ -222,6 +222,17 @@ func TestIsLongRunningEndpoint(t *testing.T) {
 		{"/simulate/database-service", true},
 		{"/simulate/static-site", true},
 		{"/simulate/microservice", true},
+		{"/test-loki-rules", true},
+		{"/test-tempo-search", true},
+		{"/test-tempo-service-graph", true},
+		{"/test-otel-pipeline", true},
+		{"/test-cardinality", true},
+		{"/test-exemplars", true},
+		{"/generate-logs/format", true},
+		{"/test-grafana-alerting", true},
+		{"/test-ssl-monitoring", true},
+		{"/test-domain-health", true},
+		{"/test-reverse-proxy", true},
 		{"/api/health", false},
 		{"/api/metrics", false},
 		{"/random/path", false},
@@ -279,7 +290,7 @@ func TestCORSMiddleware(t *testing.T) {
 
 			if tt.checkHeaders {
 				assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
-				assert.Equal(t, "GET, POST, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+				assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
 				assert.Equal(t, "Content-Type, X-Request-ID, X-User-ID, X-Session-ID", w.Header().Get("Access-Control-Allow-Headers"))
 				assert.Equal(t, "X-Request-ID, X-Trace-ID", w.Header().Get("Access-Control-Expose-Headers"))
 				assert.Equal(t, "86400", w.Header().Get("Access-Control-Max-Age"))
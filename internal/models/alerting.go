@@ -131,3 +131,96 @@ type AlertManager struct {
 	SilencedRules        map[string]time.Time  `json:"silenced_rules"`
 	Mutex                sync.RWMutex          `json:"-"`
 }
+
+// GrafanaAlertingReport represents the validation of Grafana-managed alerting
+type GrafanaAlertingReport struct {
+	Status        string                    `json:"status"` // "healthy", "degraded", "failed"
+	GrafanaURL    string                    `json:"grafana_url"`
+	Rules         []GrafanaRuleCheck        `json:"rules"`
+	ContactPoints []GrafanaContactPointTest `json:"contact_points"`
+	Policies      GrafanaPolicyCheck        `json:"policies"`
+	Receiver      ArgusReceiverCheck        `json:"argus_receiver"`
+	Problems      []string                  `json:"problems"`
+	Timestamp     time.Time                 `json:"timestamp"`
+}
+
+// GrafanaRuleCheck represents the datasource validation of one Grafana alert rule
+type GrafanaRuleCheck struct {
+	UID                string   `json:"uid"`
+	Title              string   `json:"title"`
+	RuleGroup          string   `json:"rule_group"`
+	FolderUID          string   `json:"folder_uid"`
+	Datasources        []string `json:"datasources"`
+	MissingDatasources []string `json:"missing_datasources,omitempty"`
+	Valid              bool     `json:"valid"`
+}
+
+// GrafanaContactPointTest represents the result of a test notification through a contact point
+type GrafanaContactPointTest struct {
+	UID    string `json:"uid"`
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Status string `json:"status"` // "ok", "failed", "skipped", "not_tested"
+	Error  string `json:"error,omitempty"`
+}
+
+// GrafanaPolicyCheck represents the validation of the notification policy tree
+type GrafanaPolicyCheck struct {
+	RootReceiver     string   `json:"root_receiver"`
+	Routes           int      `json:"routes"`
+	Receivers        []string `json:"receivers"`
+	MissingReceivers []string `json:"missing_receivers,omitempty"`
+	Valid            bool     `json:"valid"`
+}
+
+// ArgusReceiverCheck represents a round-trip test notification delivered to Argus
+type ArgusReceiverCheck struct {
+	URL       string        `json:"url"`
+	Delivered bool          `json:"delivered"`
+	Latency   time.Duration `json:"latency_ns,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// LokiRulesReport represents the validation of the rule groups loaded by the Loki ruler
+type LokiRulesReport struct {
+	Status       string          `json:"status"` // "healthy", "degraded", "failed"
+	LokiURL      string          `json:"loki_url"`
+	RuleGroups   []LokiRuleGroup `json:"rule_groups"`
+	TotalRules   int             `json:"total_rules"`
+	InvalidRules int             `json:"invalid_rules"`
+	Drill        *LokiAlertDrill `json:"drill,omitempty"`
+	Problems     []string        `json:"problems"`
+	Timestamp    time.Time       `json:"timestamp"`
+}
+
+// LokiRuleGroup represents one rule group as returned by the Loki ruler
+type LokiRuleGroup struct {
+	Namespace string          `json:"namespace"`
+	Name      string          `json:"name"`
+	Interval  string          `json:"interval,omitempty"`
+	Rules     []LokiRuleCheck `json:"rules"`
+}
+
+// LokiRuleCheck represents the LogQL validation of a single alerting or recording rule
+type LokiRuleCheck struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"` // "alert", "record"
+	Expr  string `json:"expr"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// LokiAlertDrill represents a log-based alert drill from Loki ingestion to Alertmanager
+type LokiAlertDrill struct {
+	DrillID        string            `json:"drill_id"`
+	AlertName      string            `json:"alert_name"`
+	Stream         map[string]string `json:"stream"`
+	RuleLoaded     bool              `json:"rule_loaded"`
+	RuleCreated    bool              `json:"rule_created,omitempty"`
+	LinesSent      int               `json:"lines_sent"`
+	FiredInLoki    bool              `json:"fired_in_loki"`
+	FiredAfter     time.Duration     `json:"fired_after_ns,omitempty"`
+	InAlertmanager bool              `json:"in_alertmanager"`
+	ConfirmedAfter time.Duration     `json:"confirmed_after_ns,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
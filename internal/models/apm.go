@@ -63,3 +63,172 @@ type PerformanceProfile struct {
 	Bottlenecks     []string        `json:"bottlenecks"`
 	Recommendations []string        `json:"recommendations"`
 }
+
+// TempoSearchReport represents the validation of Tempo's search backend with traces Argus emitted
+type TempoSearchReport struct {
+	Status    string             `json:"status"` // "healthy", "degraded", "failed"
+	TempoURL  string             `json:"tempo_url"`
+	Service   string             `json:"service"`
+	RunID     string             `json:"run_id"`
+	TraceID   string             `json:"trace_id"`
+	Searches  []TempoSearchCheck `json:"searches"`
+	Tags      TempoTagCheck      `json:"tags"`
+	Problems  []string           `json:"problems"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// TempoSearchCheck represents one timed search for the probe trace
+type TempoSearchCheck struct {
+	Name     string        `json:"name"`
+	Query    string        `json:"query"`
+	Found    bool          `json:"found"`
+	Traces   int           `json:"traces"`
+	Attempts int           `json:"attempts"`
+	Duration time.Duration `json:"duration_ns"` // last search request
+	Elapsed  time.Duration `json:"elapsed_ns"`  // time until found or given up
+	Error    string        `json:"error,omitempty"`
+}
+
+// TempoTagCheck represents the tag discovery check through /api/search/tags
+type TempoTagCheck struct {
+	Total    int           `json:"total"`
+	Expected []string      `json:"expected"`
+	Missing  []string      `json:"missing,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ExemplarProbe represents a span whose duration was observed into the latency
+// histograms with its trace ID as exemplar
+type ExemplarProbe struct {
+	TraceID  string        `json:"trace_id"`
+	SpanID   string        `json:"span_id"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// ExemplarReport represents the check that Prometheus stores the probes'
+// exemplars and that Grafana links them to their traces
+type ExemplarReport struct {
+	Status        string                `json:"status"` // "healthy", "degraded", "failed"
+	PrometheusURL string                `json:"prometheus_url"`
+	GrafanaURL    string                `json:"grafana_url"`
+	Probes        []ExemplarProbe       `json:"probes"`
+	Metrics       []ExemplarMetricCheck `json:"metrics"`
+	TraceLink     ExemplarLinkCheck     `json:"trace_link"`
+	Problems      []string              `json:"problems"`
+	Timestamp     time.Time             `json:"timestamp"`
+}
+
+// ExemplarMetricCheck represents the exemplars and native histogram stored for one histogram
+type ExemplarMetricCheck struct {
+	Metric          string        `json:"metric"`
+	Query           string        `json:"query"`
+	NativeHistogram bool          `json:"native_histogram"`
+	Exemplars       int           `json:"exemplars"` // stored exemplars of any trace
+	Matched         []string      `json:"matched"`   // probe trace IDs among them
+	Attempts        int           `json:"attempts"`
+	Elapsed         time.Duration `json:"elapsed_ns"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// ExemplarLinkCheck represents following a stored exemplar to its trace through Grafana
+type ExemplarLinkCheck struct {
+	PrometheusDatasource string        `json:"prometheus_datasource,omitempty"`
+	TraceIDLabel         string        `json:"trace_id_label,omitempty"`
+	TempoDatasource      string        `json:"tempo_datasource,omitempty"`
+	TraceID              string        `json:"trace_id,omitempty"`
+	TraceFound           bool          `json:"trace_found"`
+	Attempts             int           `json:"attempts"`
+	Duration             time.Duration `json:"duration_ns"` // last trace request
+	Error                string        `json:"error,omitempty"`
+}
+
+// TraceScenario represents a chain of services a simulated request flows through
+type TraceScenario struct {
+	Name     string   `json:"name"`
+	Services []string `json:"services"`
+	Flow     string   `json:"flow"`
+}
+
+// ServiceGraphReport represents the verification of Tempo metrics-generator output in Prometheus
+type ServiceGraphReport struct {
+	Status        string             `json:"status"` // "healthy", "degraded", "failed"
+	PrometheusURL string             `json:"prometheus_url"`
+	RunID         string             `json:"run_id"`
+	Scenarios     []string           `json:"scenarios"`
+	Iterations    int                `json:"iterations"`
+	SpansEmitted  int                `json:"spans_emitted"`
+	Edges         []ServiceGraphEdge `json:"edges"`
+	SpanMetrics   []SpanMetricSeries `json:"span_metrics"`
+	MissingEdges  int                `json:"missing_edges"`
+	MissingSeries int                `json:"missing_series"`
+	Elapsed       time.Duration      `json:"elapsed_ns"`
+	Problems      []string           `json:"problems"`
+	Timestamp     time.Time          `json:"timestamp"`
+}
+
+// ServiceGraphEdge represents one expected client -> server edge of the service graph
+type ServiceGraphEdge struct {
+	Client     string   `json:"client"`
+	Server     string   `json:"server"`
+	Found      bool     `json:"found"`
+	Before     float64  `json:"before"`
+	After      float64  `json:"after"`
+	Mismatches []string `json:"mismatches,omitempty"` // near-miss series with different labels
+}
+
+// SpanMetricSeries represents one expected span-metrics series
+type SpanMetricSeries struct {
+	Service    string   `json:"service"`
+	SpanName   string   `json:"span_name"`
+	SpanKind   string   `json:"span_kind"`
+	Found      bool     `json:"found"`
+	Before     float64  `json:"before"`
+	After      float64  `json:"after"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// CollectorLoad represents how many items Argus sends through each collector pipeline
+type CollectorLoad struct {
+	Spans        int `json:"spans"`
+	LogRecords   int `json:"log_records"`
+	MetricPoints int `json:"metric_points"`
+}
+
+// CollectorPipelineReport represents the validation of an OpenTelemetry Collector with known traffic
+type CollectorPipelineReport struct {
+	Status       string                   `json:"status"` // "healthy", "degraded", "failed"
+	TelemetryURL string                   `json:"telemetry_url"`
+	OTLPEndpoint string                   `json:"otlp_endpoint"`
+	RunID        string                   `json:"run_id"`
+	Load         CollectorLoad            `json:"load"`
+	Pipelines    []CollectorPipelineCheck `json:"pipelines"`
+	Elapsed      time.Duration            `json:"elapsed_ns"`
+	Problems     []string                 `json:"problems"`
+	Timestamp    time.Time                `json:"timestamp"`
+}
+
+// CollectorPipelineCheck represents the counter deltas of one signal's pipeline.
+// Deltas include any other traffic the collector handled during the check.
+type CollectorPipelineCheck struct {
+	Signal           string                   `json:"signal"` // "traces", "logs", "metrics"
+	Item             string                   `json:"item"`   // "spans", "log_records", "metric_points"
+	Sent             int                      `json:"sent"`
+	SendError        string                   `json:"send_error,omitempty"`
+	Accepted         float64                  `json:"accepted"`
+	Refused          float64                  `json:"refused"`
+	ProcessorDropped float64                  `json:"processor_dropped"`
+	ProcessorRefused float64                  `json:"processor_refused"`
+	Exporters        []CollectorExporterCheck `json:"exporters"`
+	Status           string                   `json:"status"`
+}
+
+// CollectorExporterCheck represents one exporter of a pipeline
+type CollectorExporterCheck struct {
+	Name          string  `json:"name"`
+	Sent          float64 `json:"sent"`
+	SendFailed    float64 `json:"send_failed"`
+	EnqueueFailed float64 `json:"enqueue_failed"`
+	QueueSize     float64 `json:"queue_size"` // items still queued after the check
+	QueueCapacity float64 `json:"queue_capacity,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+// DashboardLibrary represents the manifest of the bundled Grafana dashboards
+type DashboardLibrary struct {
+	Version    string             `json:"version"`
+	Folder     DashboardFolder    `json:"folder"`
+	Dashboards []LibraryDashboard `json:"dashboards"`
+}
+
+// DashboardFolder represents the Grafana folder the library is provisioned into
+type DashboardFolder struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+// LibraryDashboard represents a single dashboard shipped in the library
+type LibraryDashboard struct {
+	UID    string                 `json:"uid"`
+	File   string                 `json:"file"`
+	Title  string                 `json:"title,omitempty"`
+	Panels int                    `json:"panels,omitempty"`
+	Model  map[string]interface{} `json:"-"`
+}
+
+// DashboardChange represents the planned or applied change for one dashboard or folder
+type DashboardChange struct {
+	UID     string   `json:"uid"`
+	Title   string   `json:"title"`
+	Kind    string   `json:"kind"`   // "folder", "dashboard"
+	Action  string   `json:"action"` // "create", "update", "unchanged"
+	Diff    []string `json:"diff,omitempty"`
+	Applied bool     `json:"applied"`
+	Error   string   `json:"error,omitempty"`
+	URL     string   `json:"url,omitempty"`
+}
+
+// DashboardProvisionResult represents the outcome of a library provisioning run
+type DashboardProvisionResult struct {
+	LibraryVersion string            `json:"library_version"`
+	GrafanaURL     string            `json:"grafana_url"`
+	Folder         DashboardFolder   `json:"folder"`
+	DryRun         bool              `json:"dry_run"`
+	Changes        []DashboardChange `json:"changes"`
+	Created        int               `json:"created"`
+	Updated        int               `json:"updated"`
+	Unchanged      int               `json:"unchanged"`
+	Failed         int               `json:"failed"`
+	Timestamp      time.Time         `json:"timestamp"`
+}
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"time"
+)
+
+// CertificateReport represents the inspection of the certificates presented by TLS endpoints
+type CertificateReport struct {
+	Status      string             `json:"status"` // "healthy", "degraded", "failed"
+	WarningDays int                `json:"warning_days"`
+	Targets     []CertificateCheck `json:"targets"`
+	Problems    []string           `json:"problems"`
+	Timestamp   time.Time          `json:"timestamp"`
+}
+
+// CertificateCheck represents one TLS handshake and the chain the server presented
+type CertificateCheck struct {
+	Target      string            `json:"target"` // host:port
+	ServerName  string            `json:"server_name"`
+	Status      string            `json:"status"` // "valid", "expiring_soon", "expired", "invalid", "failed"
+	Error       string            `json:"error,omitempty"`
+	TLSVersion  string            `json:"tls_version,omitempty"`
+	CipherSuite string            `json:"cipher_suite,omitempty"`
+	ChainValid  bool              `json:"chain_valid"`
+	ChainError  string            `json:"chain_error,omitempty"`
+	OCSP        OCSPStaple        `json:"ocsp"`
+	ExpiresAt   time.Time         `json:"expires_at,omitempty"` // earliest expiry in the chain
+	DaysLeft    int               `json:"days_left"`
+	Chain       []CertificateInfo `json:"chain"` // leaf first, as presented
+	Elapsed     time.Duration     `json:"elapsed_ns"`
+}
+
+// CertificateInfo represents one certificate of a presented chain
+type CertificateInfo struct {
+	Subject            string    `json:"subject"`
+	Issuer             string    `json:"issuer"`
+	SANs               []string  `json:"sans,omitempty"`
+	SerialNumber       string    `json:"serial_number"`
+	NotBefore          time.Time `json:"not_before"`
+	NotAfter           time.Time `json:"not_after"`
+	DaysLeft           int       `json:"days_left"`
+	KeyType            string    `json:"key_type"` // "RSA", "ECDSA", "Ed25519"
+	KeySize            int       `json:"key_size"` // bits
+	SignatureAlgorithm string    `json:"signature_algorithm"`
+	IsCA               bool      `json:"is_ca"`
+}
+
+// OCSPStaple represents the OCSP response stapled to a handshake. The
+// response's signature is not verified.
+type OCSPStaple struct {
+	Stapled    bool      `json:"stapled"`
+	Status     string    `json:"status,omitempty"` // "good", "revoked", "unknown"
+	ThisUpdate time.Time `json:"this_update,omitempty"`
+	NextUpdate time.Time `json:"next_update,omitempty"`
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DomainHealthReport represents blackbox-style probes of configured domains
+type DomainHealthReport struct {
+	Status    string        `json:"status"` // "healthy", "degraded", "failed"
+	Resolver  string        `json:"resolver"`
+	Probes    []DomainProbe `json:"probes"`
+	Problems  []string      `json:"problems"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// DomainProbe represents one probe, following redirects from the configured URL
+type DomainProbe struct {
+	Name          string        `json:"name"`
+	URL           string        `json:"url"`
+	Success       bool          `json:"success"`
+	Error         string        `json:"error,omitempty"`
+	StatusCode    int           `json:"status_code,omitempty"` // of the final response
+	BodyMatched   *bool         `json:"body_matched,omitempty"`
+	ResolvedIP    string        `json:"resolved_ip,omitempty"`
+	TLSVersion    string        `json:"tls_version,omitempty"`
+	Phases        ProbePhases   `json:"phases"` // summed over all redirect hops
+	RedirectChain []RedirectHop `json:"redirect_chain,omitempty"`
+	Duration      time.Duration `json:"duration_ns"`
+}
+
+// ProbePhases represents the time spent in each phase of a probe
+type ProbePhases struct {
+	DNS        time.Duration `json:"dns_ns"`
+	Connect    time.Duration `json:"connect_ns"`
+	TLS        time.Duration `json:"tls_ns"`
+	Processing time.Duration `json:"processing_ns"` // request sent until first response byte
+	Transfer   time.Duration `json:"transfer_ns"`   // first byte until body read
+}
+
+// RedirectHop represents one redirect response followed by a probe
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// ScheduledCheck represents a schedule and the outcome of its runs
+type ScheduledCheck struct {
+	ID              string            `json:"id"`
+	Check           string            `json:"check"`
+	Profile         string            `json:"profile"`
+	IntervalSeconds int               `json:"interval_seconds"`
+	JitterSeconds   int               `json:"jitter_seconds"`
+	TimeoutSeconds  int               `json:"timeout_seconds"`
+	Params          map[string]string `json:"params,omitempty"`
+	Paused          bool              `json:"paused"`
+	Runs            int               `json:"runs"`
+	Failures        int               `json:"failures"`
+	LastRun         *CheckRun         `json:"last_run,omitempty"`
+	NextRun         *time.Time        `json:"next_run,omitempty"` // unset while paused
+}
+
+// CheckRun represents one run of a scheduled check
+type CheckRun struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// DiscoveryReport represents discovered scrape targets, their health and
+// whether Prometheus scrapes them
+type DiscoveryReport struct {
+	Status          string              `json:"status"` // "healthy", "degraded", "failed"
+	Providers       []DiscoveryProvider `json:"providers"`
+	Targets         []DiscoveredTarget  `json:"targets"`
+	Unscraped       []string            `json:"unscraped"` // running targets Prometheus does not scrape
+	PrometheusError string              `json:"prometheus_error,omitempty"`
+	Problems        []string            `json:"problems"`
+	Timestamp       time.Time           `json:"timestamp"`
+}
+
+// DiscoveryProvider represents the outcome of one discovery provider
+type DiscoveryProvider struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Targets int    `json:"targets"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DiscoveredTarget represents one discovered target and its health check
+type DiscoveredTarget struct {
+	Address      string            `json:"address"`
+	Provider     string            `json:"provider"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	HealthURL    string            `json:"health_url"`
+	Status       string            `json:"status"` // "healthy", "degraded", "unhealthy"
+	StatusCode   int               `json:"status_code,omitempty"`
+	ResponseTime time.Duration     `json:"response_time_ns"`
+	Error        string            `json:"error,omitempty"`
+	Scraped      bool              `json:"scraped"`
+	ScrapeJob    string            `json:"scrape_job,omitempty"`
+	ScrapeHealth string            `json:"scrape_health,omitempty"` // "up", "down" or "unknown"
+}
+
+// ScrapeCoverageReport represents the health of Prometheus' active scrape
+// targets and what to do about the issues found
+type ScrapeCoverageReport struct {
+	Status          string           `json:"status"` // "healthy", "degraded", "failed"
+	TotalTargets    int              `json:"total_targets"`
+	UpTargets       int              `json:"up_targets"`
+	DownTargets     int              `json:"down_targets"`
+	Jobs            []ScrapeJob      `json:"jobs"`
+	Targets         []ScrapeTarget   `json:"targets"`
+	Issues          []ScrapeIssue    `json:"issues"`
+	Recommendations []Recommendation `json:"recommendations"`
+	Problems        []string         `json:"problems"` // data that could not be fetched
+	Timestamp       time.Time        `json:"timestamp"`
+}
+
+// ScrapeJob summarises the targets of one scrape job
+type ScrapeJob struct {
+	Job     string `json:"job"`
+	Targets int    `json:"targets"`
+	Up      int    `json:"up"`
+}
+
+// ScrapeTarget represents one active target
+type ScrapeTarget struct {
+	Job            string        `json:"job"`
+	Instance       string        `json:"instance"`
+	ScrapeURL      string        `json:"scrape_url"`
+	Health         string        `json:"health"` // "up", "down", "unknown"
+	LastError      string        `json:"last_error,omitempty"`
+	LastScrape     time.Time     `json:"last_scrape"`
+	ScrapeDuration time.Duration `json:"scrape_duration_ns"`
+	ScrapeInterval time.Duration `json:"scrape_interval_ns"`
+	ScrapeTimeout  time.Duration `json:"scrape_timeout_ns"`
+	Samples        int           `json:"samples,omitempty"`      // after metric relabelling
+	SampleLimit    int           `json:"sample_limit,omitempty"` // 0 when unlimited
+	MetricFamilies int           `json:"metric_families"`        // from target metadata
+}
+
+// ScrapeIssue represents one finding about a target or job
+type ScrapeIssue struct {
+	Type     string `json:"type"` // "target_down", "slow_scrape", "sample_limit", "stale_target", "job_down", "duplicate_instance"
+	Job      string `json:"job"`
+	Instance string `json:"instance,omitempty"`
+	Message  string `json:"message"`
+}
+
+// ProxyReport represents requests sent through a reverse proxy's routes
+type ProxyReport struct {
+	Status    string             `json:"status"` // "healthy", "degraded", "failed"
+	Routes    []ProxyRouteResult `json:"routes"`
+	Problems  []string           `json:"problems"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// ProxyRouteResult represents the checks of one route
+type ProxyRouteResult struct {
+	Name        string         `json:"name"`
+	URL         string         `json:"url"`
+	TLS         bool           `json:"tls"`
+	Passed      bool           `json:"passed"`
+	Requests    int            `json:"requests"`
+	Succeeded   int            `json:"succeeded"` // answered 2xx by an expected backend
+	StatusCodes map[int]int    `json:"status_codes"`
+	Backends    map[string]int `json:"backends"` // requests served per backend identity
+	// Checks that do not apply to the route are left unset
+	Routed              bool          `json:"routed"`
+	TLSVerified         *bool         `json:"tls_verified,omitempty"`
+	Balanced            *bool         `json:"balanced,omitempty"`
+	ForwardedFor        *bool         `json:"forwarded_for,omitempty"`
+	RequestIDPropagated *bool         `json:"request_id_propagated,omitempty"`
+	AvgLatency          time.Duration `json:"avg_latency_ns"`
+	Problems            []string      `json:"problems"`
+}
+
+// CardinalityReport represents a cardinality stress run and how Prometheus or
+// Mimir coped with it
+type CardinalityReport struct {
+	Status             string                `json:"status"` // "healthy", "degraded", "failed"
+	RunID              string                `json:"run_id"`
+	RemoteWriteURL     string                `json:"remote_write_url"`
+	ActiveSeries       int                   `json:"active_series"`
+	SeriesCreated      int                   `json:"series_created"` // unique series written, churned ones included
+	SamplesSent        int                   `json:"samples_sent"`
+	SamplesRejected    int                   `json:"samples_rejected"`
+	Requests           int                   `json:"requests"`
+	RejectionsByStatus map[int]int           `json:"rejections_by_status"`
+	FirstRejection     *CardinalityRejection `json:"first_rejection,omitempty"`
+	HeadSeriesBefore   float64               `json:"head_series_before"`
+	PeakHeadSeries     float64               `json:"peak_head_series"`
+	PeakMemoryBytes    float64               `json:"peak_memory_bytes"`
+	Intervals          []CardinalityInterval `json:"intervals"`
+	Problems           []string              `json:"problems"`
+	Duration           time.Duration         `json:"duration_ns"`
+	Timestamp          time.Time             `json:"timestamp"`
+}
+
+// CardinalityInterval represents one interval of a cardinality run
+type CardinalityInterval struct {
+	Elapsed         time.Duration `json:"elapsed_ns"`
+	SeriesCreated   int           `json:"series_created"` // running total
+	SeriesChurned   int           `json:"series_churned"`
+	SamplesRejected int           `json:"samples_rejected"`
+	WriteDuration   time.Duration `json:"write_duration_ns"`
+	HeadSeries      float64       `json:"head_series"`
+	MemoryBytes     float64       `json:"memory_bytes"`
+	QueryError      string        `json:"query_error,omitempty"`
+}
+
+// CardinalityRejection represents the first remote write the receiver refused
+type CardinalityRejection struct {
+	StatusCode    int           `json:"status_code"`
+	Message       string        `json:"message"`
+	SeriesCreated int           `json:"series_created"`
+	HeadSeries    float64       `json:"head_series"` // at the previous interval
+	Elapsed       time.Duration `json:"elapsed_ns"`
+}
+
+// PatternSeries represents a generated series and its latest value
+type PatternSeries struct {
+	Name       string    `json:"name"`
+	Kind       string    `json:"kind"`
+	Metric     string    `json:"metric"`
+	Preset     string    `json:"preset,omitempty"`
+	Components []string  `json:"components"`
+	Seed       int64     `json:"seed"`
+	Started    time.Time `json:"started"`
+	Value      float64   `json:"value"`
+	Present    bool      `json:"present"` // false while a gap hides the series
+}
+
+// MetricsLintReport represents the lint of one metrics exposition
+type MetricsLintReport struct {
+	Status      string              `json:"status"` // "healthy", "degraded" (warnings only), "failed"
+	Target      string              `json:"target"`
+	ContentType string              `json:"content_type,omitempty"`
+	Families    int                 `json:"families"`
+	Series      int                 `json:"series"`
+	Findings    []MetricLintFinding `json:"findings"`
+	Rules       map[string]int      `json:"rules"`    // findings per rule
+	Problems    []string            `json:"problems"` // exposition that could not be fetched or parsed
+	Timestamp   time.Time           `json:"timestamp"`
+}
+
+// MetricLintFinding represents one convention a metric family breaks
+type MetricLintFinding struct {
+	Metric   string `json:"metric"`
+	Label    string `json:"label,omitempty"`
+	Rule     string `json:"rule"`     // "help_missing", "type_missing", "metric_name", "counter_suffix", "reserved_suffix", "unit_suffix", "label_name", "histogram_buckets", "bucket_layout"
+	Severity string `json:"severity"` // "error" breaks the format or queries, "warning" breaks a naming convention
+	Message  string `json:"message"`
+}
@@ -64,3 +64,124 @@ const (
 	SessionIDKey ContextKey = "session_id"
 	StartTimeKey ContextKey = "start_time"
 )
+
+// OTLPExportStats represents the running totals of one OTLP signal exporter
+type OTLPExportStats struct {
+	Signal     string    `json:"signal"`
+	Enabled    bool      `json:"enabled"`
+	Endpoint   string    `json:"endpoint,omitempty"`
+	Exported   int64     `json:"exported"` // items accepted by the collector
+	Rejected   int64     `json:"rejected"` // items refused through partial_success
+	Failed     int64     `json:"failed"`   // items in requests that errored
+	Dropped    int64     `json:"dropped"`  // items discarded because the queue was full
+	LastError  string    `json:"last_error,omitempty"`
+	LastExport time.Time `json:"last_export,omitempty"`
+}
+
+// LogSinkStats represents the running totals of one log sink
+type LogSinkStats struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Target    string    `json:"target"`            // path, address or push URL
+	Entries   int64     `json:"entries"`           // entries written or accepted by Loki
+	Bytes     int64     `json:"bytes"`             // bytes of the entries
+	Failed    int64     `json:"failed"`            // entries that could not be delivered
+	Dropped   int64     `json:"dropped"`           // entries discarded because the queue was full
+	Batches   int64     `json:"batches,omitempty"` // Loki pushes
+	Retries   int64     `json:"retries,omitempty"` // Loki pushes repeated after a failure
+	Rotations int64     `json:"rotations,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	LastWrite time.Time `json:"last_write,omitempty"`
+}
+
+// LogFormatReport represents one run of the log format generator
+type LogFormatReport struct {
+	Format    string        `json:"format"`
+	Requested int           `json:"requested"`
+	Entries   int           `json:"entries"`
+	Lines     int           `json:"lines"` // stack traces span several lines per entry
+	Bytes     int64         `json:"bytes"`
+	Rate      float64       `json:"rate"` // entries per second requested, 0 for unpaced
+	Seed      int64         `json:"seed"`
+	Elapsed   time.Duration `json:"elapsed_ns"`
+	Samples   []string      `json:"samples"`
+	Error     string        `json:"error,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// LogReplay represents a replay of a captured log file and its progress
+type LogReplay struct {
+	ID              string            `json:"id"`
+	Path            string            `json:"path"`
+	Sink            string            `json:"sink"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	TimestampLayout string            `json:"timestamp_layout,omitempty"`
+	Speed           float64           `json:"speed,omitempty"`
+	Status          string            `json:"status"` // running, completed, stopped or failed
+	Compressed      bool              `json:"compressed"`
+	Entries         int64             `json:"entries"`
+	Lines           int64             `json:"lines"`
+	Bytes           int64             `json:"bytes"`
+	Rewritten       int64             `json:"rewritten"`                 // entries whose timestamp was rewritten
+	FirstTimestamp  *time.Time        `json:"first_timestamp,omitempty"` // original time of the first entry
+	LastTimestamp   *time.Time        `json:"last_timestamp,omitempty"`  // original time of the latest entry
+	OriginalSpan    time.Duration     `json:"original_span_ns"`          // between the first and latest original timestamps
+	Elapsed         time.Duration     `json:"elapsed_ns"`
+	Error           string            `json:"error,omitempty"`
+	StartedAt       time.Time         `json:"started_at"`
+	FinishedAt      *time.Time        `json:"finished_at,omitempty"`
+}
+
+// PIIValue is a fake PII value embedded in a generated log line
+type PIIValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// PIILine is a generated log line and the PII it contains
+type PIILine struct {
+	Index int        `json:"index"`
+	Line  string     `json:"line"`
+	PII   []PIIValue `json:"pii"`
+}
+
+// PIIRun records which lines of a generator request contained which PII
+type PIIRun struct {
+	ID        string         `json:"id"`
+	Generator string         `json:"generator"` // json, unstructured or mixed
+	Lines     []PIILine      `json:"lines"`
+	Values    map[string]int `json:"values"` // PII values per type
+	StartedAt time.Time      `json:"started_at"`
+}
+
+// PIILeak is a PII value that reached Loki unredacted
+type PIILeak struct {
+	Line     int    `json:"line"` // index of the generated line
+	Type     string `json:"type"`
+	Value    string `json:"value"`
+	LokiLine string `json:"loki_line"`
+}
+
+// PIITypeResult counts the values of one PII type and how many leaked
+type PIITypeResult struct {
+	Values int `json:"values"`
+	Leaked int `json:"leaked"`
+}
+
+// PIIRedactionReport represents a check of a PII run's lines in Loki
+type PIIRedactionReport struct {
+	RunID     string                   `json:"run_id"`
+	LokiURL   string                   `json:"loki_url"`
+	Query     string                   `json:"query"`
+	Status    string                   `json:"status"`
+	Generated int                      `json:"generated"` // lines generated
+	Found     int                      `json:"found"`     // lines returned by Loki
+	Values    int                      `json:"values"`    // PII values generated
+	Leaked    int                      `json:"leaked"`    // PII values found unredacted
+	ByType    map[string]PIITypeResult `json:"by_type"`
+	Leaks     []PIILeak                `json:"leaks"`
+	Attempts  int                      `json:"attempts"`
+	Elapsed   time.Duration            `json:"elapsed_ns"`
+	Problems  []string                 `json:"problems"`
+	Timestamp time.Time                `json:"timestamp"`
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+const (
+	cardinalityMetric       = "argus_cardinality_test"
+	defaultHeadSeriesQuery  = "sum(prometheus_tsdb_head_series)"
+	defaultMemoryQuery      = `sum(process_resident_memory_bytes{job="prometheus"})`
+	defaultCardinalityBatch = 5000
+)
+
+// CardinalityService writes a configurable number of unique series through
+// Prometheus remote write, churning them as it goes, and watches the
+// receiver's head series, memory and refusals to find where its limits are
+type CardinalityService struct {
+	client *http.Client
+}
+
+// NewCardinalityService creates a new cardinality service
+func NewCardinalityService() *CardinalityService {
+	return &CardinalityService{client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Run writes test's series to remoteWriteURL every interval, with the
+// credentials of prometheus, and queries prometheus after each interval.
+// Series are named argus_cardinality_test and carry a run label, so a run's
+// series can be told apart from earlier ones. test must be valid.
+func (cs *CardinalityService) Run(ctx context.Context, test types.CardinalityTest, prometheus types.ServiceConfig, remoteWriteURL string) *models.CardinalityReport {
+	start := time.Now()
+	report := &models.CardinalityReport{
+		RunID:              uuid.New().String()[:8],
+		RemoteWriteURL:     remoteWriteURL,
+		ActiveSeries:       test.Series(),
+		RejectionsByStatus: map[int]int{},
+		Intervals:          []models.CardinalityInterval{},
+		Problems:           []string{},
+		Timestamp:          start,
+	}
+	if report.RemoteWriteURL == "" {
+		report.RemoteWriteURL = strings.TrimRight(prometheus.URL, "/") + "/api/v1/write"
+	}
+	if test.HeadSeriesQuery == "" {
+		test.HeadSeriesQuery = defaultHeadSeriesQuery
+	}
+	if test.MemoryQuery == "" {
+		test.MemoryQuery = defaultMemoryQuery
+	}
+	if test.BatchSize == 0 {
+		test.BatchSize = defaultCardinalityBatch
+	}
+
+	headSeries, err := cs.queryValue(ctx, prometheus, test.HeadSeriesQuery)
+	if err != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("cannot query head series: %v", err))
+	}
+	report.HeadSeriesBefore = headSeries
+
+	run := newCardinalityRun(test, report.RunID)
+	interval := time.Duration(test.IntervalSeconds) * time.Second
+	writeErrors := map[string]bool{}
+
+	for i := 0; i < test.DurationSeconds/test.IntervalSeconds; i++ {
+		if i > 0 {
+			timer := time.NewTimer(time.Until(start.Add(time.Duration(i) * interval)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		result := models.CardinalityInterval{
+			Elapsed:       time.Since(start),
+			SeriesChurned: run.advance(i),
+		}
+		report.SeriesCreated = run.created
+		result.SeriesCreated = run.created
+
+		writeStart := time.Now()
+		for _, batch := range run.batches(test.BatchSize, writeStart) {
+			report.Requests++
+			report.SamplesSent += len(batch)
+			err := remoteWrite(ctx, cs.client, prometheus, report.RemoteWriteURL, batch)
+			if err == nil {
+				continue
+			}
+			result.SamplesRejected += len(batch)
+
+			var refused *remoteWriteError
+			if errors.As(err, &refused) {
+				report.RejectionsByStatus[refused.StatusCode]++
+				if report.FirstRejection == nil {
+					report.FirstRejection = &models.CardinalityRejection{
+						StatusCode:    refused.StatusCode,
+						Message:       refused.Message,
+						SeriesCreated: run.created,
+						HeadSeries:    headSeries,
+						Elapsed:       time.Since(start),
+					}
+				}
+			} else if !writeErrors[err.Error()] && len(writeErrors) < 5 {
+				writeErrors[err.Error()] = true
+				report.Problems = append(report.Problems, fmt.Sprintf("remote write: %v", err))
+			}
+		}
+		result.WriteDuration = time.Since(writeStart)
+		report.SamplesRejected += result.SamplesRejected
+
+		var queryErrors []string
+		if value, err := cs.queryValue(ctx, prometheus, test.HeadSeriesQuery); err != nil {
+			queryErrors = append(queryErrors, err.Error())
+		} else {
+			headSeries = value
+			result.HeadSeries = value
+			report.PeakHeadSeries = math.Max(report.PeakHeadSeries, value)
+		}
+		if value, err := cs.queryValue(ctx, prometheus, test.MemoryQuery); err != nil {
+			queryErrors = append(queryErrors, err.Error())
+		} else {
+			result.MemoryBytes = value
+			report.PeakMemoryBytes = math.Max(report.PeakMemoryBytes, value)
+		}
+		result.QueryError = strings.Join(queryErrors, "; ")
+		report.Intervals = append(report.Intervals, result)
+
+		if result.WriteDuration > interval {
+			report.Problems = append(report.Problems, fmt.Sprintf("interval %d: writing took %s, longer than the interval", i, result.WriteDuration.Round(time.Millisecond)))
+		}
+	}
+
+	if rejection := report.FirstRejection; rejection != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("receiver refused writes once %d series were created: HTTP %d: %s",
+			rejection.SeriesCreated, rejection.StatusCode, rejection.Message))
+	}
+	// Without refusals every series written should have reached the head
+	if report.SamplesRejected == 0 && report.PeakHeadSeries > 0 {
+		if growth := report.PeakHeadSeries - report.HeadSeriesBefore; growth < 0.9*float64(report.ActiveSeries) {
+			report.Problems = append(report.Problems, fmt.Sprintf("head series grew by %.0f, fewer than the %d series written", growth, report.ActiveSeries))
+		}
+	}
+
+	report.Duration = time.Since(start)
+	switch {
+	case report.SamplesSent == 0 || report.SamplesRejected == report.SamplesSent:
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report
+}
+
+// queryValue runs a query expected to return a single value
+func (cs *CardinalityService) queryValue(ctx context.Context, prometheus types.ServiceConfig, query string) (float64, error) {
+	samples, err := prometheusQuery(ctx, cs.client, prometheus, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("query %q returned no samples", query)
+	}
+	return samples[0].Value, nil
+}
+
+// cardinalityRun tracks the series of a run. Every slot is one active
+// series; replacing a slot bumps its generation, which yields a new series.
+type cardinalityRun struct {
+	test       types.CardinalityTest
+	runID      string
+	labelNames []string
+	generation []uint32
+	born       []int // interval a slot's current series was created in
+	cursor     int   // next slot churn replaces, so the oldest go first
+	created    int
+}
+
+func newCardinalityRun(test types.CardinalityTest, runID string) *cardinalityRun {
+	run := &cardinalityRun{
+		test:       test,
+		runID:      runID,
+		labelNames: make([]string, test.Labels),
+		generation: make([]uint32, test.Series()),
+		born:       make([]int, test.Series()),
+	}
+	for i := range run.labelNames {
+		run.labelNames[i] = "label_" + strconv.Itoa(i)
+	}
+	return run
+}
+
+// advance replaces the churned and expired series before interval is
+// written and returns how many were replaced. The first interval creates
+// every series.
+func (r *cardinalityRun) advance(interval int) int {
+	series := len(r.generation)
+	if interval == 0 {
+		r.created = series
+		return 0
+	}
+
+	replaced := 0
+	replace := func(slot int) {
+		r.generation[slot]++
+		r.born[slot] = interval
+		r.created++
+		replaced++
+	}
+
+	churn := int(math.Round(r.test.ChurnPercent / 100 * float64(series)))
+	for i := 0; i < churn; i++ {
+		replace((r.cursor + i) % series)
+	}
+	r.cursor = (r.cursor + churn) % series
+
+	if r.test.LifetimeSeconds > 0 {
+		lifetime := int(math.Ceil(float64(r.test.LifetimeSeconds) / float64(r.test.IntervalSeconds)))
+		for slot := range r.born {
+			if interval-r.born[slot] >= lifetime {
+				replace(slot)
+			}
+		}
+	}
+	return replaced
+}
+
+// batches returns one sample at now for every active series, split into
+// requests of batchSize series
+func (r *cardinalityRun) batches(batchSize int, now time.Time) [][]remoteWriteSeries {
+	var batches [][]remoteWriteSeries
+	batch := make([]remoteWriteSeries, 0, batchSize)
+	for slot := range r.generation {
+		labels := map[string]string{
+			"__name__":   cardinalityMetric,
+			"run":        r.runID,
+			"generation": strconv.FormatUint(uint64(r.generation[slot]), 10),
+		}
+		// The slot's digits in base Values pick each label's value
+		rest := slot
+		for _, name := range r.labelNames {
+			labels[name] = "v" + strconv.Itoa(rest%r.test.Values)
+			rest /= r.test.Values
+		}
+
+		batch = append(batch, remoteWriteSeries{Labels: labels, Value: rand.Float64() * 100, Timestamp: now.UnixMilli()})
+		if len(batch) == batchSize {
+			batches = append(batches, batch)
+			batch = make([]remoteWriteSeries, 0, batchSize)
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// snappyDecodeLiterals decodes the literal-only blocks snappyEncode writes
+func snappyDecodeLiterals(t *testing.T, src []byte) []byte {
+	size, n := binary.Uvarint(src)
+	require.Greater(t, n, 0)
+	src = src[n:]
+	dst := make([]byte, 0, size)
+	for len(src) > 0 {
+		tag := src[0]
+		require.Equal(t, byte(0), tag&3, "only literals are expected")
+		length := int(tag>>2) + 1
+		src = src[1:]
+		switch tag >> 2 {
+		case 60:
+			length = int(src[0]) + 1
+			src = src[1:]
+		case 61:
+			length = int(src[0]) | int(src[1])<<8 + 1
+			src = src[2:]
+		}
+		dst = append(dst, src[:length]...)
+		src = src[length:]
+	}
+	require.Len(t, dst, int(size))
+	return dst
+}
+
+// decodeWriteRequest returns the label sets of a WriteRequest, rendered as strings
+func decodeWriteRequest(t *testing.T, data []byte) []string {
+	fields := func(b []byte, each func(num protowire.Number, value []byte)) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			require.Greater(t, n, 0)
+			b = b[n:]
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			require.Greater(t, n, 0)
+			if typ == protowire.BytesType {
+				value, _ := protowire.ConsumeBytes(b)
+				each(num, value)
+			}
+			b = b[n:]
+		}
+	}
+
+	var series []string
+	fields(data, func(_ protowire.Number, ts []byte) {
+		var labels []string
+		samples := 0
+		fields(ts, func(num protowire.Number, value []byte) {
+			if num == 2 {
+				samples++
+				return
+			}
+			var name, labelValue string
+			fields(value, func(num protowire.Number, v []byte) {
+				if num == 1 {
+					name = string(v)
+				} else {
+					labelValue = string(v)
+				}
+			})
+			labels = append(labels, name+"="+labelValue)
+		})
+		require.Equal(t, 1, samples)
+		require.True(t, sort.StringsAreSorted(labels), "labels must be sorted")
+		series = append(series, strings.Join(labels, ","))
+	})
+	return series
+}
+
+// fakeRemoteWriteReceiver accepts remote writes up to a series limit, as
+// Mimir does per tenant, and reports its series count as head series
+type fakeRemoteWriteReceiver struct {
+	t      *testing.T
+	limit  int
+	mu     sync.Mutex
+	series map[string]bool
+}
+
+func (f *fakeRemoteWriteReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/v1/write":
+		assert.Equal(f.t, "snappy", r.Header.Get("Content-Encoding"))
+		assert.Equal(f.t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(f.t, err)
+		series := decodeWriteRequest(f.t, snappyDecodeLiterals(f.t, body))
+
+		unknown := 0
+		for _, s := range series {
+			if !f.series[s] {
+				unknown++
+			}
+		}
+		if f.limit > 0 && len(f.series)+unknown > f.limit {
+			http.Error(w, fmt.Sprintf("per-user series limit of %d exceeded", f.limit), http.StatusBadRequest)
+			return
+		}
+		for _, s := range series {
+			f.series[s] = true
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "/api/v1/query":
+		value := len(f.series)
+		if strings.Contains(r.URL.Query().Get("query"), "memory") {
+			value *= 1000
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"%d"]}]}}`, value)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestCardinalityService_Run(t *testing.T) {
+	t.Run("churn creates new series every interval", func(t *testing.T) {
+		receiver := &fakeRemoteWriteReceiver{t: t, series: map[string]bool{}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		test := types.CardinalityTest{Labels: 2, Values: 3, ChurnPercent: 34, IntervalSeconds: 1, DurationSeconds: 3, BatchSize: 4}
+		require.NoError(t, test.Validate())
+		report := NewCardinalityService().Run(context.Background(), test, types.ServiceConfig{URL: server.URL}, "")
+
+		assert.Equal(t, "healthy", report.Status, report.Problems)
+		assert.Equal(t, server.URL+"/api/v1/write", report.RemoteWriteURL)
+		assert.Equal(t, 9, report.ActiveSeries)
+		assert.Equal(t, 15, report.SeriesCreated)
+		assert.Equal(t, 27, report.SamplesSent)
+		assert.Equal(t, 9, report.Requests) // 3 batches of at most 4 series per interval
+		assert.Equal(t, float64(0), report.HeadSeriesBefore)
+		assert.Equal(t, float64(15), report.PeakHeadSeries)
+		assert.Equal(t, float64(15000), report.PeakMemoryBytes)
+		require.Len(t, report.Intervals, 3)
+		assert.Equal(t, []int{0, 3, 3}, []int{report.Intervals[0].SeriesChurned, report.Intervals[1].SeriesChurned, report.Intervals[2].SeriesChurned})
+		assert.Equal(t, float64(12), report.Intervals[1].HeadSeries)
+		assert.Len(t, receiver.series, 15)
+
+		for s := range receiver.series {
+			assert.Contains(t, s, "__name__=argus_cardinality_test,generation=")
+			assert.Contains(t, s, "run="+report.RunID)
+		}
+	})
+
+	t.Run("series limit is found", func(t *testing.T) {
+		receiver := &fakeRemoteWriteReceiver{t: t, limit: 10, series: map[string]bool{}}
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		test := types.CardinalityTest{Labels: 2, Values: 3, LifetimeSeconds: 1, IntervalSeconds: 1, DurationSeconds: 2, BatchSize: 5}
+		report := NewCardinalityService().Run(context.Background(), test, types.ServiceConfig{URL: server.URL}, server.URL+"/api/v1/write")
+
+		assert.Equal(t, "degraded", report.Status)
+		assert.Equal(t, 18, report.SeriesCreated)
+		assert.Equal(t, 9, report.SamplesRejected)
+		assert.Equal(t, map[int]int{400: 2}, report.RejectionsByStatus)
+		require.NotNil(t, report.FirstRejection)
+		assert.Equal(t, 18, report.FirstRejection.SeriesCreated)
+		assert.Equal(t, float64(9), report.FirstRejection.HeadSeries)
+		assert.Equal(t, "per-user series limit of 10 exceeded", report.FirstRejection.Message)
+		assert.Contains(t, report.Problems, "receiver refused writes once 18 series were created: HTTP 400: per-user series limit of 10 exceeded")
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		test := types.CardinalityTest{Labels: 1, Values: 2, IntervalSeconds: 1, DurationSeconds: 1}
+		report := NewCardinalityService().Run(context.Background(), test, types.ServiceConfig{URL: "http://127.0.0.1:1"}, "")
+		assert.Equal(t, "failed", report.Status)
+		assert.Equal(t, 2, report.SamplesRejected)
+		assert.NotEmpty(t, report.Intervals[0].QueryError)
+	})
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// collectorSignal describes how one signal is sent and how the collector counts it
+type collectorSignal struct {
+	name   string // pipeline type
+	item   string // suffix of the otelcol_* counters
+	path   string
+	method string
+}
+
+var collectorSignals = []collectorSignal{
+	{"traces", "spans", "/v1/traces", "/opentelemetry.proto.collector.trace.v1.TraceService/Export"},
+	{"logs", "log_records", "/v1/logs", logsExportMethod},
+	{"metrics", "metric_points", "/v1/metrics", metricsExportMethod},
+}
+
+// collectorTelemetry is one scrape of the collector's internal metrics, keyed
+// by metric name without the _total suffix newer collectors add
+type collectorTelemetry map[string][]promSample
+
+// CollectorPipelineService validates OpenTelemetry Collector pipelines by
+// sending known traffic and comparing the collector's own counters
+type CollectorPipelineService struct {
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewCollectorPipelineService creates a new collector pipeline validation service
+func NewCollectorPipelineService() *CollectorPipelineService {
+	return &CollectorPipelineService{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: 2 * time.Second,
+	}
+}
+
+// Validate scrapes the collector telemetry at telemetry.URL, sends load
+// through its OTLP receiver and polls until every item has been accepted and
+// exported or timeout elapses. The report covers each pipeline's receiver,
+// processor and exporter counters.
+func (cp *CollectorPipelineService) Validate(ctx context.Context, telemetry types.ServiceConfig, exporter types.OTLPExporterConfig, load models.CollectorLoad, runID string, timeout time.Duration) (*models.CollectorPipelineReport, error) {
+	report := &models.CollectorPipelineReport{
+		TelemetryURL: telemetry.URL,
+		OTLPEndpoint: exporter.Endpoint,
+		RunID:        runID,
+		Load:         load,
+		Pipelines:    []models.CollectorPipelineCheck{},
+		Problems:     []string{},
+		Timestamp:    time.Now(),
+	}
+
+	before, err := cp.scrape(ctx, telemetry)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{"traces": load.Spans, "logs": load.LogRecords, "metrics": load.MetricPoints}
+	start := time.Now()
+	for _, signal := range collectorSignals {
+		if counts[signal.name] <= 0 {
+			continue
+		}
+		check := models.CollectorPipelineCheck{Signal: signal.name, Item: signal.item, Sent: counts[signal.name]}
+		if err := cp.send(ctx, exporter, signal, check.Sent, runID); err != nil {
+			check.SendError = err.Error()
+		}
+		report.Pipelines = append(report.Pipelines, check)
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(cp.pollInterval)
+	defer ticker.Stop()
+
+	after := before
+	for {
+		if current, err := cp.scrape(pollCtx, telemetry); err == nil {
+			after = current
+		}
+		for i := range report.Pipelines {
+			compareTelemetry(&report.Pipelines[i], before, after)
+		}
+		if pipelinesSettled(report.Pipelines) || pollCtx.Err() != nil {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-pollCtx.Done():
+		}
+	}
+	report.Elapsed = time.Since(start)
+
+	failed := 0
+	for i := range report.Pipelines {
+		problems := pipelineProblems(report.Pipelines[i])
+		switch {
+		case report.Pipelines[i].SendError != "" || report.Pipelines[i].Accepted == 0:
+			report.Pipelines[i].Status = "failed"
+			failed++
+		case len(problems) > 0:
+			report.Pipelines[i].Status = "degraded"
+		default:
+			report.Pipelines[i].Status = "healthy"
+		}
+		report.Problems = append(report.Problems, problems...)
+	}
+
+	switch {
+	case len(report.Pipelines) == 0:
+		report.Status = "failed"
+		report.Problems = append(report.Problems, "No load requested")
+	case failed == len(report.Pipelines):
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report, nil
+}
+
+// send exports count items of one signal, tagged with the run ID, in a single request
+func (cp *CollectorPipelineService) send(ctx context.Context, exporter types.OTLPExporterConfig, signal collectorSignal, count int, runID string) error {
+	client, err := newOTLPClient(exporter, signal.path)
+	if err != nil {
+		return err
+	}
+	defer client.close()
+
+	serviceConfig := config.GetServiceConfig()
+	resource := newOTLPResource(serviceConfig)
+	resource.Attributes = append(resource.Attributes, &commonpb.KeyValue{Key: "argus.run_id", Value: otlpAnyValue(runID)})
+	now := uint64(time.Now().UnixNano())
+
+	var request, response proto.Message
+	switch signal.name {
+	case "traces":
+		spans := make([]*tracepb.Span, count)
+		for i := range spans {
+			traceID, spanID := uuid.New(), uuid.New()
+			spans[i] = &tracepb.Span{
+				TraceId:           traceID[:],
+				SpanId:            spanID[:8],
+				Name:              "argus.pipeline_probe",
+				Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+				StartTimeUnixNano: now - uint64(time.Millisecond),
+				EndTimeUnixNano:   now,
+				Attributes:        otlpAttributes(map[string]interface{}{"argus.sequence": i}),
+			}
+		}
+		request = &coltracepb.ExportTraceServiceRequest{ResourceSpans: []*tracepb.ResourceSpans{{
+			Resource:   resource,
+			ScopeSpans: []*tracepb.ScopeSpans{{Scope: otlpScope, Spans: spans}},
+		}}}
+		response = &coltracepb.ExportTraceServiceResponse{}
+
+	case "logs":
+		records := make([]*logspb.LogRecord, count)
+		for i := range records {
+			records[i] = &logspb.LogRecord{
+				TimeUnixNano:   now,
+				SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+				SeverityText:   "INFO",
+				Body:           otlpAnyValue(fmt.Sprintf("argus pipeline probe %d", i)),
+				Attributes:     otlpAttributes(map[string]interface{}{"argus.sequence": i}),
+			}
+		}
+		request = &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  resource,
+			ScopeLogs: []*logspb.ScopeLogs{{Scope: otlpScope, LogRecords: records}},
+		}}}
+		response = &collogspb.ExportLogsServiceResponse{}
+
+	case "metrics":
+		points := make([]*metricspb.NumberDataPoint, count)
+		for i := range points {
+			points[i] = &metricspb.NumberDataPoint{
+				Attributes:   otlpAttributes(map[string]interface{}{"argus.sequence": i}),
+				TimeUnixNano: now,
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: float64(i)},
+			}
+		}
+		request = &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: otlpScope, Metrics: []*metricspb.Metric{{
+				Name:        "argus_pipeline_probe",
+				Description: "Argus collector pipeline probe",
+				Data:        &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{DataPoints: points}},
+			}}}},
+		}}}
+		response = &colmetricspb.ExportMetricsServiceResponse{}
+	}
+
+	if err := client.export(ctx, signal.method, request, response); err != nil {
+		return err
+	}
+	if rejected, message := partialSuccess(response); rejected > 0 {
+		return fmt.Errorf("collector rejected %d of %d %s: %s", rejected, count, signal.item, message)
+	}
+	return nil
+}
+
+func partialSuccess(response proto.Message) (int64, string) {
+	switch r := response.(type) {
+	case *coltracepb.ExportTraceServiceResponse:
+		return r.GetPartialSuccess().GetRejectedSpans(), r.GetPartialSuccess().GetErrorMessage()
+	case *collogspb.ExportLogsServiceResponse:
+		return r.GetPartialSuccess().GetRejectedLogRecords(), r.GetPartialSuccess().GetErrorMessage()
+	case *colmetricspb.ExportMetricsServiceResponse:
+		return r.GetPartialSuccess().GetRejectedDataPoints(), r.GetPartialSuccess().GetErrorMessage()
+	}
+	return 0, ""
+}
+
+// scrape reads the collector's Prometheus telemetry endpoint
+func (cp *CollectorPipelineService) scrape(ctx context.Context, telemetry types.ServiceConfig) (collectorTelemetry, error) {
+	url := strings.TrimRight(telemetry.URL, "/")
+	if !strings.HasSuffix(url, "/metrics") {
+		url += "/metrics"
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	resp, err := DoServiceRequest(cp.client, telemetry, req)
+	if err != nil {
+		return nil, fmt.Errorf("scrape collector telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape collector telemetry: HTTP %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("parse collector telemetry: %w", err)
+	}
+
+	result := make(collectorTelemetry)
+	for name, family := range families {
+		if !strings.HasPrefix(name, "otelcol_") {
+			continue
+		}
+		name = strings.TrimSuffix(name, "_total")
+		for _, m := range family.GetMetric() {
+			sample := promSample{Metric: make(map[string]string), Value: metricValue(family.GetType(), m)}
+			for _, label := range m.GetLabel() {
+				sample.Metric[label.GetName()] = label.GetValue()
+			}
+			result[name] = append(result[name], sample)
+		}
+	}
+	return result, nil
+}
+
+func metricValue(metricType dto.MetricType, m *dto.Metric) float64 {
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue()
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
+
+// sum adds up the samples of a metric that match the filter
+func (t collectorTelemetry) sum(name string, match func(map[string]string) bool) float64 {
+	total := 0.0
+	for _, sample := range t[name] {
+		if match(sample.Metric) {
+			total += sample.Value
+		}
+	}
+	return total
+}
+
+// byLabel sums a metric per value of one label
+func (t collectorTelemetry) byLabel(name, label string, match func(map[string]string) bool) map[string]float64 {
+	totals := make(map[string]float64)
+	for _, sample := range t[name] {
+		if match(sample.Metric) {
+			totals[sample.Metric[label]] += sample.Value
+		}
+	}
+	return totals
+}
+
+// compareTelemetry fills a pipeline check with the counter deltas between
+// two scrapes. Only OTLP receivers count as accepting Argus' traffic.
+func compareTelemetry(check *models.CollectorPipelineCheck, before, after collectorTelemetry) {
+	otlpReceiver := func(labels map[string]string) bool {
+		receiver := labels["receiver"]
+		return receiver == "otlp" || strings.HasPrefix(receiver, "otlp/")
+	}
+	all := func(map[string]string) bool { return true }
+	delta := func(name string, match func(map[string]string) bool) float64 {
+		return after.sum(name, match) - before.sum(name, match)
+	}
+
+	check.Accepted = delta("otelcol_receiver_accepted_"+check.Item, otlpReceiver)
+	check.Refused = delta("otelcol_receiver_refused_"+check.Item, otlpReceiver)
+	check.ProcessorDropped = delta("otelcol_processor_dropped_"+check.Item, all)
+	check.ProcessorRefused = delta("otelcol_processor_refused_"+check.Item, all)
+
+	sentBefore := before.byLabel("otelcol_exporter_sent_"+check.Item, "exporter", all)
+	sentAfter := after.byLabel("otelcol_exporter_sent_"+check.Item, "exporter", all)
+	failedBefore := before.byLabel("otelcol_exporter_send_failed_"+check.Item, "exporter", all)
+	failedAfter := after.byLabel("otelcol_exporter_send_failed_"+check.Item, "exporter", all)
+	enqueueBefore := before.byLabel("otelcol_exporter_enqueue_failed_"+check.Item, "exporter", all)
+	enqueueAfter := after.byLabel("otelcol_exporter_enqueue_failed_"+check.Item, "exporter", all)
+
+	// Queue gauges carry data_type on newer collectors; older ones have one queue per exporter
+	signalQueue := func(labels map[string]string) bool {
+		return labels["data_type"] == "" || labels["data_type"] == check.Signal
+	}
+	queueSize := after.byLabel("otelcol_exporter_queue_size", "exporter", signalQueue)
+	queueCapacity := after.byLabel("otelcol_exporter_queue_capacity", "exporter", signalQueue)
+
+	names := make([]string, 0, len(sentAfter))
+	for name := range sentAfter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	check.Exporters = []models.CollectorExporterCheck{}
+	for _, name := range names {
+		check.Exporters = append(check.Exporters, models.CollectorExporterCheck{
+			Name:          name,
+			Sent:          sentAfter[name] - sentBefore[name],
+			SendFailed:    failedAfter[name] - failedBefore[name],
+			EnqueueFailed: enqueueAfter[name] - enqueueBefore[name],
+			QueueSize:     queueSize[name],
+			QueueCapacity: queueCapacity[name],
+		})
+	}
+}
+
+// pipelinesSettled reports whether every sent item has been accepted or
+// refused and every exporter has accounted for what its pipeline passed on
+func pipelinesSettled(checks []models.CollectorPipelineCheck) bool {
+	for _, check := range checks {
+		if check.SendError != "" {
+			continue
+		}
+		if check.Accepted+check.Refused < float64(check.Sent) {
+			return false
+		}
+		for _, exporter := range check.Exporters {
+			if exporter.Sent+exporter.SendFailed+exporter.EnqueueFailed < expectedExports(check) || exporter.QueueSize > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// expectedExports is how many items each exporter should receive: what the
+// receiver accepted minus what processors dropped or refused
+func expectedExports(check models.CollectorPipelineCheck) float64 {
+	return check.Accepted - check.ProcessorDropped - check.ProcessorRefused
+}
+
+func pipelineProblems(check models.CollectorPipelineCheck) []string {
+	prefix := check.Signal + " pipeline: "
+	if check.SendError != "" {
+		return []string{prefix + "OTLP export failed: " + check.SendError}
+	}
+
+	var problems []string
+	if check.Accepted < float64(check.Sent) {
+		problems = append(problems, fmt.Sprintf("%sreceiver accepted %.0f of %d %s", prefix, check.Accepted, check.Sent, check.Item))
+	}
+	if check.Refused > 0 {
+		problems = append(problems, fmt.Sprintf("%sreceiver refused %.0f %s", prefix, check.Refused, check.Item))
+	}
+	if check.ProcessorDropped > 0 {
+		problems = append(problems, fmt.Sprintf("%sprocessors dropped %.0f %s", prefix, check.ProcessorDropped, check.Item))
+	}
+	if check.ProcessorRefused > 0 {
+		problems = append(problems, fmt.Sprintf("%sprocessors refused %.0f %s", prefix, check.ProcessorRefused, check.Item))
+	}
+	if len(check.Exporters) == 0 {
+		problems = append(problems, fmt.Sprintf("%sno exporter reported sent %s", prefix, check.Item))
+	}
+	for _, exporter := range check.Exporters {
+		name := prefix + "exporter " + exporter.Name
+		if exporter.SendFailed > 0 {
+			problems = append(problems, fmt.Sprintf("%s failed to send %.0f %s", name, exporter.SendFailed, check.Item))
+		}
+		if exporter.EnqueueFailed > 0 {
+			problems = append(problems, fmt.Sprintf("%s could not enqueue %.0f %s (queue full)", name, exporter.EnqueueFailed, check.Item))
+		}
+		if exporter.QueueSize > 0 {
+			problems = append(problems, fmt.Sprintf("%s still has %.0f items queued", name, exporter.QueueSize))
+		}
+		if missing := expectedExports(check) - exporter.Sent - exporter.SendFailed - exporter.EnqueueFailed; missing > 0 {
+			problems = append(problems, fmt.Sprintf("%s has not accounted for %.0f %s", name, missing, check.Item))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeCollector accepts OTLP/HTTP and serves otelcol_* telemetry. Traces are
+// exported in full, one log record per request fails to send, and two metric
+// points per request stay in the exporter queue.
+type fakeCollector struct {
+	mu       sync.Mutex
+	counters map[string]float64 // metric{labels} -> value
+	queue    float64
+}
+
+func newFakeCollector(t *testing.T) *httptest.Server {
+	c := &fakeCollector{counters: map[string]float64{
+		// Pre-existing traffic must not count towards the check
+		`otelcol_receiver_accepted_spans_total{receiver="otlp",transport="http"}`: 1000,
+		`otelcol_exporter_sent_spans_total{exporter="otlp/tempo"}`:                1000,
+		// Spans received by other receivers are ignored
+		`otelcol_receiver_accepted_spans_total{receiver="zipkin",transport="http"}`: 50,
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			keys := make([]string, 0, len(c.counters))
+			for key := range c.counters {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			previous := ""
+			for _, key := range keys {
+				if name := key[:strings.Index(key, "{")]; name != previous {
+					fmt.Fprintf(w, "# TYPE %s counter\n", name)
+					previous = name
+				}
+				fmt.Fprintf(w, "%s %g\n", key, c.counters[key])
+			}
+			fmt.Fprintf(w, "# TYPE otelcol_exporter_queue_size gauge\n")
+			fmt.Fprintf(w, "otelcol_exporter_queue_size{exporter=\"prometheusremotewrite\"} %g\n", c.queue)
+			fmt.Fprintf(w, "otelcol_exporter_queue_size{exporter=\"otlp/tempo\"} 0\n")
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		var response proto.Message
+		switch r.URL.Path {
+		case "/v1/traces":
+			request := &coltracepb.ExportTraceServiceRequest{}
+			require.NoError(t, proto.Unmarshal(body, request))
+			n := float64(len(request.ResourceSpans[0].ScopeSpans[0].Spans))
+			c.counters[`otelcol_receiver_accepted_spans_total{receiver="otlp",transport="http"}`] += n
+			c.counters[`otelcol_exporter_sent_spans_total{exporter="otlp/tempo"}`] += n
+			response = &coltracepb.ExportTraceServiceResponse{}
+		case "/v1/logs":
+			request := &collogspb.ExportLogsServiceRequest{}
+			require.NoError(t, proto.Unmarshal(body, request))
+			n := float64(len(request.ResourceLogs[0].ScopeLogs[0].LogRecords))
+			c.counters[`otelcol_receiver_accepted_log_records_total{receiver="otlp",transport="http"}`] += n
+			c.counters[`otelcol_exporter_sent_log_records_total{exporter="loki"}`] += n - 1
+			c.counters[`otelcol_exporter_send_failed_log_records_total{exporter="loki"}`]++
+			response = &collogspb.ExportLogsServiceResponse{}
+		case "/v1/metrics":
+			request := &colmetricspb.ExportMetricsServiceRequest{}
+			require.NoError(t, proto.Unmarshal(body, request))
+			n := float64(len(request.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetGauge().DataPoints))
+			c.counters[`otelcol_receiver_accepted_metric_points_total{receiver="otlp",transport="http"}`] += n
+			c.counters[`otelcol_exporter_sent_metric_points_total{exporter="prometheusremotewrite"}`] += n - 2
+			c.queue += 2
+			response = &colmetricspb.ExportMetricsServiceResponse{}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		payload, _ := proto.Marshal(response)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(payload)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCollectorPipelineService_Validate(t *testing.T) {
+	collector := newFakeCollector(t)
+	cp := NewCollectorPipelineService()
+	cp.pollInterval = 20 * time.Millisecond
+
+	telemetry := types.ServiceConfig{URL: collector.URL}
+	exporter := types.OTLPExporterConfig{Protocol: types.OTLPProtocolHTTP, Endpoint: collector.URL}
+
+	t.Run("reports failed, queued and healthy pipelines", func(t *testing.T) {
+		load := models.CollectorLoad{Spans: 20, LogRecords: 10, MetricPoints: 5}
+		report, err := cp.Validate(context.Background(), telemetry, exporter, load, "run-1", 300*time.Millisecond)
+		require.NoError(t, err)
+
+		assert.Equal(t, "degraded", report.Status)
+		require.Len(t, report.Pipelines, 3)
+
+		traces := report.Pipelines[0]
+		assert.Equal(t, "healthy", traces.Status)
+		assert.Equal(t, 20.0, traces.Accepted)
+		require.Len(t, traces.Exporters, 1)
+		assert.Equal(t, models.CollectorExporterCheck{Name: "otlp/tempo", Sent: 20}, traces.Exporters[0])
+
+		logs := report.Pipelines[1]
+		assert.Equal(t, "degraded", logs.Status)
+		assert.Equal(t, 10.0, logs.Accepted)
+		assert.Equal(t, 9.0, logs.Exporters[0].Sent)
+		assert.Equal(t, 1.0, logs.Exporters[0].SendFailed)
+
+		metrics := report.Pipelines[2]
+		assert.Equal(t, "degraded", metrics.Status)
+		assert.Equal(t, 2.0, metrics.Exporters[0].QueueSize)
+
+		assert.Contains(t, report.Problems, "logs pipeline: exporter loki failed to send 1 log_records")
+		assert.Contains(t, report.Problems, "metrics pipeline: exporter prometheusremotewrite still has 2 items queued")
+		assert.Contains(t, report.Problems, "metrics pipeline: exporter prometheusremotewrite has not accounted for 2 metric_points")
+	})
+
+	t.Run("unreachable receiver fails the pipeline", func(t *testing.T) {
+		unreachable := types.OTLPExporterConfig{Protocol: types.OTLPProtocolHTTP, Endpoint: "http://127.0.0.1:1"}
+		report, err := cp.Validate(context.Background(), telemetry, unreachable, models.CollectorLoad{Spans: 5}, "run-2", 100*time.Millisecond)
+		require.NoError(t, err)
+
+		assert.Equal(t, "failed", report.Status)
+		assert.Equal(t, "failed", report.Pipelines[0].Status)
+		assert.NotEmpty(t, report.Pipelines[0].SendError)
+	})
+
+	t.Run("unreachable telemetry is an error", func(t *testing.T) {
+		_, err := cp.Validate(context.Background(), types.ServiceConfig{URL: "http://127.0.0.1:1"}, exporter, models.CollectorLoad{Spans: 1}, "run-3", time.Second)
+		assert.ErrorContains(t, err, "scrape collector telemetry")
+	})
+}
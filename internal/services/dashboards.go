@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/configs"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// maxDashboardDiffs caps the number of differing paths reported per dashboard
+const maxDashboardDiffs = 25
+
+// GrafanaAPIError reports an unexpected HTTP status returned by the Grafana API
+type GrafanaAPIError struct {
+	Operation  string
+	StatusCode int
+}
+
+func (e *GrafanaAPIError) Error() string {
+	return fmt.Sprintf("%s: HTTP %d", e.Operation, e.StatusCode)
+}
+
+// UnknownDashboardsError reports requested dashboard UIDs that are not in the library
+type UnknownDashboardsError struct {
+	UIDs []string
+}
+
+func (e *UnknownDashboardsError) Error() string {
+	return fmt.Sprintf("unknown dashboard UIDs: %s", strings.Join(e.UIDs, ", "))
+}
+
+// DashboardService provisions the bundled Argus dashboard library into Grafana
+type DashboardService struct {
+	library fs.FS
+	client  *http.Client
+}
+
+// NewDashboardService creates a new dashboard service backed by the embedded library
+func NewDashboardService() *DashboardService {
+	library, err := fs.Sub(configs.Grafana, "grafana")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to open embedded dashboard library: %v", err))
+	}
+
+	return &DashboardService{
+		library: library,
+		client:  &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// LoadLibrary reads the library manifest and every dashboard it references
+func (ds *DashboardService) LoadLibrary() (*models.DashboardLibrary, error) {
+	manifest, err := fs.ReadFile(ds.library, "library.json")
+	if err != nil {
+		return nil, fmt.Errorf("read library manifest: %w", err)
+	}
+
+	var library models.DashboardLibrary
+	if err := json.Unmarshal(manifest, &library); err != nil {
+		return nil, fmt.Errorf("parse library manifest: %w", err)
+	}
+
+	for i := range library.Dashboards {
+		dashboard := &library.Dashboards[i]
+
+		data, err := fs.ReadFile(ds.library, path.Clean(dashboard.File))
+		if err != nil {
+			return nil, fmt.Errorf("read dashboard %s: %w", dashboard.UID, err)
+		}
+		if err := json.Unmarshal(data, &dashboard.Model); err != nil {
+			return nil, fmt.Errorf("parse dashboard %s: %w", dashboard.UID, err)
+		}
+		if uid, _ := dashboard.Model["uid"].(string); uid != dashboard.UID {
+			return nil, fmt.Errorf("dashboard %s: file declares uid %q", dashboard.UID, uid)
+		}
+
+		dashboard.Title, _ = dashboard.Model["title"].(string)
+		if panels, ok := dashboard.Model["panels"].([]interface{}); ok {
+			dashboard.Panels = len(panels)
+		}
+	}
+
+	return &library, nil
+}
+
+// Provision compares the library against Grafana and, unless dryRun is set,
+// creates the folder and upserts every dashboard that differs. When uids is
+// non-empty only those dashboards are considered, and UIDs the library does
+// not have are an UnknownDashboardsError.
+func (ds *DashboardService) Provision(ctx context.Context, grafana types.ServiceConfig, uids []string, dryRun bool) (*models.DashboardProvisionResult, error) {
+	library, err := ds.LoadLibrary()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DashboardProvisionResult{
+		LibraryVersion: library.Version,
+		GrafanaURL:     grafana.URL,
+		Folder:         library.Folder,
+		DryRun:         dryRun,
+		Changes:        []models.DashboardChange{},
+		Timestamp:      time.Now(),
+	}
+
+	known := make(map[string]bool, len(library.Dashboards))
+	for _, dashboard := range library.Dashboards {
+		known[dashboard.UID] = true
+	}
+	selected := make(map[string]bool, len(uids))
+	unknown := &UnknownDashboardsError{}
+	for _, uid := range uids {
+		if !known[uid] {
+			unknown.UIDs = append(unknown.UIDs, uid)
+		}
+		selected[uid] = true
+	}
+	if len(unknown.UIDs) > 0 {
+		return nil, unknown
+	}
+
+	folderChange, err := ds.syncFolder(ctx, grafana, library.Folder, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	result.Changes = append(result.Changes, folderChange)
+
+	for _, dashboard := range library.Dashboards {
+		if len(selected) > 0 && !selected[dashboard.UID] {
+			continue
+		}
+
+		change := ds.syncDashboard(ctx, grafana, library, dashboard, dryRun)
+		switch {
+		case change.Error != "":
+			result.Failed++
+		case change.Action == "create":
+			result.Created++
+		case change.Action == "update":
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	return result, nil
+}
+
+// syncFolder makes sure the library folder exists with the expected title
+func (ds *DashboardService) syncFolder(ctx context.Context, grafana types.ServiceConfig, folder models.DashboardFolder, dryRun bool) (models.DashboardChange, error) {
+	change := models.DashboardChange{
+		UID:   folder.UID,
+		Title: folder.Title,
+		Kind:  "folder",
+	}
+
+	var existing struct {
+		Title string `json:"title"`
+	}
+	status, err := grafanaJSON(ctx, ds.client, grafana, "GET", "/api/folders/"+folder.UID, nil, &existing)
+	if err != nil {
+		return change, fmt.Errorf("look up folder %s: %w", folder.UID, err)
+	}
+
+	var method, endpoint string
+	var body interface{}
+	switch {
+	case status == http.StatusNotFound:
+		change.Action = "create"
+		method, endpoint = "POST", "/api/folders"
+		body = map[string]interface{}{"uid": folder.UID, "title": folder.Title}
+	case status == http.StatusOK && existing.Title != folder.Title:
+		change.Action = "update"
+		change.Diff = []string{fmt.Sprintf("title: %q -> %q", existing.Title, folder.Title)}
+		method, endpoint = "PUT", "/api/folders/"+folder.UID
+		body = map[string]interface{}{"title": folder.Title, "overwrite": true}
+	case status == http.StatusOK:
+		change.Action = "unchanged"
+		return change, nil
+	default:
+		return change, &GrafanaAPIError{Operation: "look up folder " + folder.UID, StatusCode: status}
+	}
+
+	if dryRun {
+		return change, nil
+	}
+
+	status, err = grafanaJSON(ctx, ds.client, grafana, method, endpoint, body, nil)
+	if err != nil {
+		return change, fmt.Errorf("%s folder %s: %w", change.Action, folder.UID, err)
+	}
+	if status != http.StatusOK {
+		return change, &GrafanaAPIError{Operation: change.Action + " folder " + folder.UID, StatusCode: status}
+	}
+	change.Applied = true
+	return change, nil
+}
+
+// syncDashboard diffs one dashboard against Grafana and upserts it when needed
+func (ds *DashboardService) syncDashboard(ctx context.Context, grafana types.ServiceConfig, library *models.DashboardLibrary, dashboard models.LibraryDashboard, dryRun bool) models.DashboardChange {
+	change := models.DashboardChange{
+		UID:   dashboard.UID,
+		Title: dashboard.Title,
+		Kind:  "dashboard",
+	}
+
+	desired := desiredDashboard(dashboard.Model, library.Version)
+
+	var existing struct {
+		Dashboard map[string]interface{} `json:"dashboard"`
+		Meta      struct {
+			FolderUID string `json:"folderUid"`
+		} `json:"meta"`
+	}
+	status, err := grafanaJSON(ctx, ds.client, grafana, "GET", "/api/dashboards/uid/"+dashboard.UID, nil, &existing)
+	if err != nil {
+		change.Error = err.Error()
+		return change
+	}
+
+	switch status {
+	case http.StatusNotFound:
+		change.Action = "create"
+	case http.StatusOK:
+		change.Diff = diffJSON("", desired, existing.Dashboard, nil)
+		if existing.Meta.FolderUID != library.Folder.UID {
+			change.Diff = append(change.Diff, fmt.Sprintf("folder: %q -> %q", existing.Meta.FolderUID, library.Folder.UID))
+		}
+		if len(change.Diff) == 0 {
+			change.Action = "unchanged"
+			change.URL = grafana.URL + "/d/" + dashboard.UID
+			return change
+		}
+		change.Action = "update"
+		if len(change.Diff) > maxDashboardDiffs {
+			more := len(change.Diff) - maxDashboardDiffs
+			change.Diff = append(change.Diff[:maxDashboardDiffs], fmt.Sprintf("... and %d more", more))
+		}
+	default:
+		change.Error = fmt.Sprintf("look up dashboard: HTTP %d", status)
+		return change
+	}
+
+	if dryRun {
+		return change
+	}
+
+	payload := map[string]interface{}{
+		"dashboard": desired,
+		"folderUid": library.Folder.UID,
+		"overwrite": true,
+		"message":   fmt.Sprintf("Provisioned by Argus dashboard library v%s", library.Version),
+	}
+
+	var saved struct {
+		URL string `json:"url"`
+	}
+	status, err = grafanaJSON(ctx, ds.client, grafana, "POST", "/api/dashboards/db", payload, &saved)
+	if err != nil {
+		change.Error = err.Error()
+		return change
+	}
+	if status != http.StatusOK {
+		change.Error = fmt.Sprintf("save dashboard: HTTP %d", status)
+		return change
+	}
+
+	change.Applied = true
+	change.URL = grafana.URL + saved.URL
+	if saved.URL == "" {
+		change.URL = grafana.URL + "/d/" + dashboard.UID
+	}
+	return change
+}
+
+// grafanaJSON sends a JSON request to Grafana and decodes a 2xx response into out
+func grafanaJSON(ctx context.Context, client *http.Client, grafana types.ServiceConfig, method, endpoint string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(grafana.URL, "/")+endpoint, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := DoServiceRequest(client, grafana, req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 && out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decode %s response: %w", endpoint, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// desiredDashboard returns a copy of the library model ready to be sent to
+// Grafana, tagged with the library version so upgrades show up as drift
+func desiredDashboard(model map[string]interface{}, version string) map[string]interface{} {
+	data, _ := json.Marshal(model)
+	var desired map[string]interface{}
+	_ = json.Unmarshal(data, &desired)
+
+	delete(desired, "id")
+	delete(desired, "version")
+
+	versionTag := "argus-library-v" + version
+	tags, _ := desired["tags"].([]interface{})
+	kept := []interface{}{}
+	for _, tag := range tags {
+		if s, _ := tag.(string); !strings.HasPrefix(s, "argus-library-v") {
+			kept = append(kept, tag)
+		}
+	}
+	desired["tags"] = append(kept, versionTag)
+
+	return desired
+}
+
+// diffJSON lists the paths where have differs from want. Only keys present in
+// want are compared, so fields Grafana adds when saving do not count as drift.
+func diffJSON(prefix string, want, have interface{}, diffs []string) []string {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		h, ok := have.(map[string]interface{})
+		if !ok {
+			return append(diffs, displayPath(prefix))
+		}
+		keys := make([]string, 0, len(w))
+		for key := range w {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if prefix != "" {
+				child = prefix + "." + key
+			}
+			value, exists := h[key]
+			if !exists {
+				diffs = append(diffs, child+" (missing)")
+				continue
+			}
+			diffs = diffJSON(child, w[key], value, diffs)
+		}
+	case []interface{}:
+		h, ok := have.([]interface{})
+		if !ok || len(h) != len(w) {
+			return append(diffs, fmt.Sprintf("%s (length %d -> %d)", displayPath(prefix), len(h), len(w)))
+		}
+		for i := range w {
+			diffs = diffJSON(fmt.Sprintf("%s[%d]", prefix, i), w[i], h[i], diffs)
+		}
+	default:
+		if !reflect.DeepEqual(want, have) {
+			diffs = append(diffs, fmt.Sprintf("%s: %v -> %v", displayPath(prefix), have, want))
+		}
+	}
+	return diffs
+}
+
+func displayPath(prefix string) string {
+	if prefix == "" {
+		return "(root)"
+	}
+	return prefix
+}
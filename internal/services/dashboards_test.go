@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeGrafana is a minimal stand-in for the Grafana folder and dashboard APIs
+type fakeGrafana struct {
+	mu         sync.Mutex
+	folders    map[string]string
+	dashboards map[string]map[string]interface{}
+	saves      int
+}
+
+func newFakeGrafana() *fakeGrafana {
+	return &fakeGrafana{
+		folders:    make(map[string]string),
+		dashboards: make(map[string]map[string]interface{}),
+	}
+}
+
+func (fg *fakeGrafana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fg.mu.Lock()
+	defer fg.mu.Unlock()
+
+	switch {
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/api/folders/"):
+		title, ok := fg.folders[strings.TrimPrefix(r.URL.Path, "/api/folders/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"title": title})
+	case r.Method == "POST" && r.URL.Path == "/api/folders":
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fg.folders[body["uid"]] = body["title"]
+		_ = json.NewEncoder(w).Encode(body)
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/api/dashboards/uid/"):
+		stored, ok := fg.dashboards[strings.TrimPrefix(r.URL.Path, "/api/dashboards/uid/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(stored)
+	case r.Method == "POST" && r.URL.Path == "/api/dashboards/db":
+		var body struct {
+			Dashboard map[string]interface{} `json:"dashboard"`
+			FolderUID string                 `json:"folderUid"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		uid := body.Dashboard["uid"].(string)
+		// Grafana adds its own bookkeeping fields when saving
+		body.Dashboard["id"] = 42
+		body.Dashboard["version"] = 3
+		fg.dashboards[uid] = map[string]interface{}{
+			"dashboard": body.Dashboard,
+			"meta":      map[string]string{"folderUid": body.FolderUID},
+		}
+		fg.saves++
+		_ = json.NewEncoder(w).Encode(map[string]string{"url": "/d/" + uid})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestDashboardService_LoadLibrary(t *testing.T) {
+	ds := NewDashboardService()
+
+	library, err := ds.LoadLibrary()
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, library.Version)
+	assert.Equal(t, "argus", library.Folder.UID)
+	require.NotEmpty(t, library.Dashboards)
+
+	seen := make(map[string]bool)
+	for _, dashboard := range library.Dashboards {
+		assert.False(t, seen[dashboard.UID], "duplicate uid %s", dashboard.UID)
+		seen[dashboard.UID] = true
+		assert.NotEmpty(t, dashboard.Title)
+		assert.Greater(t, dashboard.Panels, 0)
+	}
+
+	t.Run("library covers exported metrics", func(t *testing.T) {
+		var all strings.Builder
+		for _, dashboard := range library.Dashboards {
+			data, err := json.Marshal(dashboard.Model)
+			require.NoError(t, err)
+			all.Write(data)
+		}
+		for _, metric := range []string{"http_requests_total", "apm_span_duration_seconds", "alerts_total", "mttr_seconds", "log_entries_total"} {
+			assert.Contains(t, all.String(), metric)
+		}
+	})
+
+	t.Run("latency panels exclude the exemplar probes", func(t *testing.T) {
+		for _, dashboard := range library.Dashboards {
+			for _, expr := range dashboardExprs(dashboard.Model) {
+				if strings.Contains(expr, "http_request_duration_seconds") {
+					assert.Contains(t, expr, fmt.Sprintf("endpoint!=%q", ExemplarProbeEndpoint))
+				}
+				if strings.Contains(expr, "apm_span_duration_seconds") {
+					assert.Contains(t, expr, fmt.Sprintf("operation!=%q", ExemplarProbeOperation))
+				}
+			}
+		}
+	})
+}
+
+// dashboardExprs collects every query expression in a dashboard model
+func dashboardExprs(value interface{}) []string {
+	var exprs []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if expr, ok := item.(string); ok && key == "expr" {
+				exprs = append(exprs, expr)
+			} else {
+				exprs = append(exprs, dashboardExprs(item)...)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			exprs = append(exprs, dashboardExprs(item)...)
+		}
+	}
+	return exprs
+}
+
+func TestDashboardService_Provision(t *testing.T) {
+	grafana := newFakeGrafana()
+	server := httptest.NewServer(grafana)
+	defer server.Close()
+
+	ds := NewDashboardService()
+	config := types.ServiceConfig{URL: server.URL}
+
+	library, err := ds.LoadLibrary()
+	require.NoError(t, err)
+
+	t.Run("dry run reports creates without writing", func(t *testing.T) {
+		result, err := ds.Provision(context.Background(), config, nil, true)
+		require.NoError(t, err)
+
+		assert.True(t, result.DryRun)
+		assert.Equal(t, len(library.Dashboards), result.Created)
+		assert.Equal(t, "create", result.Changes[0].Action)
+		assert.Equal(t, "folder", result.Changes[0].Kind)
+		assert.Empty(t, grafana.folders)
+		assert.Zero(t, grafana.saves)
+	})
+
+	t.Run("apply creates folder and dashboards", func(t *testing.T) {
+		result, err := ds.Provision(context.Background(), config, nil, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, len(library.Dashboards), result.Created)
+		assert.Zero(t, result.Failed)
+		assert.Equal(t, "Argus", grafana.folders["argus"])
+		assert.Equal(t, len(library.Dashboards), grafana.saves)
+		for _, change := range result.Changes {
+			assert.True(t, change.Applied, change.UID)
+		}
+	})
+
+	t.Run("second apply is idempotent", func(t *testing.T) {
+		result, err := ds.Provision(context.Background(), config, nil, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, len(library.Dashboards), result.Unchanged)
+		assert.Zero(t, result.Created+result.Updated)
+		assert.Equal(t, len(library.Dashboards), grafana.saves)
+	})
+
+	t.Run("drift is reported as an update diff", func(t *testing.T) {
+		uid := library.Dashboards[0].UID
+		grafana.mu.Lock()
+		grafana.dashboards[uid]["dashboard"].(map[string]interface{})["title"] = "Edited by hand"
+		grafana.mu.Unlock()
+
+		result, err := ds.Provision(context.Background(), config, []string{uid}, true)
+		require.NoError(t, err)
+
+		require.Len(t, result.Changes, 2)
+		change := result.Changes[1]
+		assert.Equal(t, "update", change.Action)
+		assert.False(t, change.Applied)
+		require.Len(t, change.Diff, 1)
+		assert.Contains(t, change.Diff[0], "title: Edited by hand")
+	})
+}
+
+func TestDashboardService_ProvisionUnknownUIDs(t *testing.T) {
+	ds := NewDashboardService()
+	_, err := ds.Provision(context.Background(), types.ServiceConfig{URL: "http://127.0.0.1:1"}, []string{"argus-http", "argus-htp", "nope"}, true)
+
+	var unknown *UnknownDashboardsError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []string{"argus-htp", "nope"}, unknown.UIDs)
+	assert.EqualError(t, err, "unknown dashboard UIDs: argus-htp, nope")
+}
+
+func TestDashboardService_ProvisionAuthError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	ds := NewDashboardService()
+	_, err := ds.Provision(context.Background(), types.ServiceConfig{URL: server.URL}, nil, true)
+
+	var apiErr *GrafanaAPIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+func TestDiffJSON(t *testing.T) {
+	want := map[string]interface{}{
+		"title":  "A",
+		"panels": []interface{}{map[string]interface{}{"expr": "up"}},
+	}
+
+	assert.Empty(t, diffJSON("", want, map[string]interface{}{
+		"title":  "A",
+		"panels": []interface{}{map[string]interface{}{"expr": "up", "pluginVersion": "10.0"}},
+		"id":     float64(7),
+	}, nil))
+
+	diffs := diffJSON("", want, map[string]interface{}{
+		"panels": []interface{}{map[string]interface{}{"expr": "down"}},
+	}, nil)
+	assert.Equal(t, []string{"panels[0].expr: down -> up", "title (missing)"}, diffs)
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// DiscoveryProvider finds scrape targets
+type DiscoveryProvider interface {
+	Name() string
+	Type() string
+	Discover(ctx context.Context) ([]models.DiscoveredTarget, error)
+}
+
+// NewDiscoveryProvider builds the provider for a configuration. DNS providers
+// resolve through resolver (host:port), or the system resolver when it is empty.
+func NewDiscoveryProvider(config types.DiscoveryProvider, resolver string) (DiscoveryProvider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	base := providerBase{config: config}
+	if base.config.Name == "" {
+		base.config.Name = config.Type
+	}
+
+	switch config.Type {
+	case types.DiscoveryStatic:
+		return &staticProvider{base}, nil
+	case types.DiscoveryFileSD:
+		return &fileSDProvider{base}, nil
+	default:
+		return &dnsProvider{providerBase: base, resolver: newProbeResolver(resolver)}, nil
+	}
+}
+
+// providerBase holds what every provider shares
+type providerBase struct {
+	config types.DiscoveryProvider
+}
+
+func (p providerBase) Name() string { return p.config.Name }
+func (p providerBase) Type() string { return p.config.Type }
+
+// service returns the settings for health checks of the provider's targets
+func (p providerBase) service(models.DiscoveredTarget) types.ServiceConfig {
+	return types.ServiceConfig{TLS: p.config.TLS}
+}
+
+// target builds a discovered target for address with the provider's health URL
+func (p providerBase) target(address string, labels map[string]string) models.DiscoveredTarget {
+	scheme, path := p.config.Scheme, p.config.HealthPath
+	if scheme == "" {
+		scheme = "http"
+	}
+	if path == "" {
+		path = "/metrics"
+	}
+	return models.DiscoveredTarget{
+		Address:   address,
+		Provider:  p.config.Name,
+		Labels:    labels,
+		HealthURL: scheme + "://" + address + path,
+	}
+}
+
+// staticProvider returns a fixed list of addresses
+type staticProvider struct {
+	providerBase
+}
+
+func (p *staticProvider) Discover(context.Context) ([]models.DiscoveredTarget, error) {
+	targets := make([]models.DiscoveredTarget, 0, len(p.config.Targets))
+	for _, address := range p.config.Targets {
+		targets = append(targets, p.target(address, p.config.Labels))
+	}
+	return targets, nil
+}
+
+// serviceProvider is the static provider for the services list in settings.
+// Each service is health-checked at its own URL with its own credentials.
+type serviceProvider struct {
+	services []types.DiscoveryTarget
+}
+
+// NewServiceProvider returns a static provider for application health endpoints
+func NewServiceProvider(services []types.DiscoveryTarget) DiscoveryProvider {
+	return &serviceProvider{services: services}
+}
+
+func (p *serviceProvider) Name() string { return "services" }
+func (p *serviceProvider) Type() string { return types.DiscoveryStatic }
+
+func (p *serviceProvider) Discover(context.Context) ([]models.DiscoveredTarget, error) {
+	targets := make([]models.DiscoveredTarget, 0, len(p.services))
+	var invalid []string
+	for _, service := range p.services {
+		u, err := url.Parse(service.URL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			invalid = append(invalid, service.Name)
+			continue
+		}
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		targets = append(targets, models.DiscoveredTarget{
+			Address:   withDefaultPort(u.Host, port),
+			Provider:  p.Name(),
+			Labels:    map[string]string{"service": service.Name},
+			HealthURL: service.URL,
+		})
+	}
+	if len(invalid) > 0 {
+		return targets, fmt.Errorf("invalid url for %s", strings.Join(invalid, ", "))
+	}
+	return targets, nil
+}
+
+func (p *serviceProvider) service(target models.DiscoveredTarget) types.ServiceConfig {
+	for _, service := range p.services {
+		if service.Name == target.Labels["service"] {
+			return service.ServiceConfig
+		}
+	}
+	return types.ServiceConfig{}
+}
+
+// fileSDProvider reads Prometheus file_sd files
+type fileSDProvider struct {
+	providerBase
+}
+
+// fileSDGroup is one target group of a file_sd file. YAML is a superset of
+// JSON, so both formats decode into it.
+type fileSDGroup struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels"`
+}
+
+func (p *fileSDProvider) Discover(context.Context) ([]models.DiscoveredTarget, error) {
+	var files []string
+	for _, pattern := range p.config.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("file pattern %q: %w", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", strings.Join(p.config.Files, ", "))
+	}
+
+	var targets []models.DiscoveredTarget
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var groups []fileSDGroup
+		if err := yaml.Unmarshal(data, &groups); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", file, err)
+		}
+		for _, group := range groups {
+			for _, address := range group.Targets {
+				targets = append(targets, p.target(address, group.Labels))
+			}
+		}
+	}
+	return targets, nil
+}
+
+// dnsProvider looks up SRV or A records, as Prometheus dns_sd does
+type dnsProvider struct {
+	providerBase
+	resolver *net.Resolver
+}
+
+func (p *dnsProvider) Discover(ctx context.Context) ([]models.DiscoveredTarget, error) {
+	var targets []models.DiscoveredTarget
+	for _, name := range p.config.Names {
+		labels := map[string]string{"__meta_dns_name": name}
+
+		if p.config.RecordType == "A" {
+			addrs, err := p.resolver.LookupIPAddr(ctx, name)
+			if err != nil {
+				return nil, fmt.Errorf("lookup %s: %w", name, err)
+			}
+			for _, addr := range addrs {
+				if addr.IP.To4() != nil {
+					targets = append(targets, p.target(net.JoinHostPort(addr.IP.String(), strconv.Itoa(p.config.Port)), labels))
+				}
+			}
+			continue
+		}
+
+		_, records, err := p.resolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("lookup SRV %s: %w", name, err)
+		}
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			targets = append(targets, p.target(net.JoinHostPort(host, strconv.Itoa(int(record.Port))), labels))
+		}
+	}
+	return targets, nil
+}
+
+// DiscoveryService health-checks discovered targets and compares them with
+// the targets Prometheus scrapes
+type DiscoveryService struct {
+	client      *http.Client
+	concurrency int
+}
+
+// NewDiscoveryService creates a new discovery service
+func NewDiscoveryService() *DiscoveryService {
+	return &DiscoveryService{
+		client:      &http.Client{Timeout: 5 * time.Second},
+		concurrency: 10,
+	}
+}
+
+// Discover runs every provider, health-checks the targets they find and
+// reports the running ones that Prometheus does not scrape
+func (ds *DiscoveryService) Discover(ctx context.Context, providers []DiscoveryProvider, prometheus types.ServiceConfig) *models.DiscoveryReport {
+	report := &models.DiscoveryReport{
+		Providers: []models.DiscoveryProvider{},
+		Targets:   []models.DiscoveredTarget{},
+		Unscraped: []string{},
+		Problems:  []string{},
+		Timestamp: time.Now(),
+	}
+
+	seen := map[string]bool{}
+	for _, provider := range providers {
+		result := models.DiscoveryProvider{Name: provider.Name(), Type: provider.Type()}
+		targets, err := provider.Discover(ctx)
+		if err != nil {
+			result.Error = err.Error()
+			report.Problems = append(report.Problems, fmt.Sprintf("provider %s: %v", provider.Name(), err))
+		}
+		result.Targets = len(targets)
+		report.Providers = append(report.Providers, result)
+
+		for _, target := range targets {
+			if !seen[target.Address] {
+				seen[target.Address] = true
+				report.Targets = append(report.Targets, target)
+			}
+		}
+	}
+
+	ds.checkHealth(ctx, report.Targets, providers)
+
+	scraped, err := prometheusTargets(ctx, ds.client, prometheus)
+	if err != nil {
+		report.PrometheusError = err.Error()
+		report.Problems = append(report.Problems, fmt.Sprintf("cannot list Prometheus targets: %v", err))
+	}
+	byAddress := map[string]promTarget{}
+	for _, target := range scraped {
+		for _, address := range scrapeAddresses(target) {
+			byAddress[address] = target
+		}
+	}
+
+	for i := range report.Targets {
+		target := &report.Targets[i]
+		if scrape, ok := byAddress[target.Address]; ok {
+			target.Scraped = true
+			target.ScrapeJob = scrape.Labels["job"]
+			target.ScrapeHealth = scrape.Health
+		}
+		if target.Status != "unhealthy" && !target.Scraped && err == nil {
+			report.Unscraped = append(report.Unscraped, target.Address)
+			report.Problems = append(report.Problems, fmt.Sprintf("%s is running but not scraped by Prometheus", target.Address))
+		}
+		if target.Status == "unhealthy" {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s is unhealthy: %s", target.Address, targetFailure(*target)))
+		}
+	}
+
+	switch {
+	case err != nil || (len(report.Targets) == 0 && len(report.Problems) > 0):
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report
+}
+
+// checkHealth requests every target's health URL, a few at a time. A 2xx
+// answer is healthy, or degraded when slower than a second.
+func (ds *DiscoveryService) checkHealth(ctx context.Context, targets []models.DiscoveredTarget, providers []DiscoveryProvider) {
+	// Providers may supply the TLS settings and credentials for their
+	// targets' health checks
+	type serviceSource interface {
+		service(models.DiscoveredTarget) types.ServiceConfig
+	}
+	sources := map[string]serviceSource{}
+	for _, provider := range providers {
+		if source, ok := provider.(serviceSource); ok {
+			sources[provider.Name()] = source
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ds.concurrency)
+	for i := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(target *models.DiscoveredTarget) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			var service types.ServiceConfig
+			if source, ok := sources[target.Provider]; ok {
+				service = source.service(*target)
+			}
+			ds.checkTarget(ctx, target, service)
+		}(&targets[i])
+	}
+	wg.Wait()
+}
+
+// checkTarget health-checks one target
+func (ds *DiscoveryService) checkTarget(ctx context.Context, target *models.DiscoveredTarget, service types.ServiceConfig) {
+	target.Status = "unhealthy"
+	req, err := http.NewRequestWithContext(ctx, "GET", target.HealthURL, nil)
+	if err != nil {
+		target.Error = err.Error()
+		return
+	}
+
+	start := time.Now()
+	resp, err := DoServiceRequest(ds.client, service, req)
+	target.ResponseTime = time.Since(start)
+	if err != nil {
+		target.Error = err.Error()
+		return
+	}
+	resp.Body.Close()
+
+	target.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		target.Status = "healthy"
+		if target.ResponseTime > time.Second {
+			target.Status = "degraded"
+		}
+	}
+}
+
+// scrapeAddresses returns the addresses a Prometheus target is known by
+func scrapeAddresses(target promTarget) []string {
+	addresses := []string{}
+	for _, address := range []string{target.DiscoveredLabels["__address__"], target.Labels["instance"]} {
+		if address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	if parsed, err := url.Parse(target.ScrapeURL); err == nil && parsed.Host != "" {
+		addresses = append(addresses, parsed.Host)
+	}
+	return addresses
+}
+
+// targetFailure describes why a target's health check failed
+func targetFailure(target models.DiscoveredTarget) string {
+	if target.Error != "" {
+		return target.Error
+	}
+	return fmt.Sprintf("HTTP %d", target.StatusCode)
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestDiscoveryService_Discover(t *testing.T) {
+	metricsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/metrics", r.URL.Path)
+		_, _ = w.Write([]byte("up 1\n"))
+	})
+	scrapedApp := httptest.NewServer(metricsHandler)
+	defer scrapedApp.Close()
+	unscrapedApp := httptest.NewServer(metricsHandler)
+	defer unscrapedApp.Close()
+	scrapedAddress := strings.TrimPrefix(scrapedApp.URL, "http://")
+	unscrapedAddress := strings.TrimPrefix(unscrapedApp.URL, "http://")
+	_, unscrapedPort, _ := net.SplitHostPort(unscrapedAddress)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	deadAddress := listener.Addr().String()
+	listener.Close()
+
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/targets", r.URL.Path)
+		fmt.Fprintf(w, `{"status": "success", "data": {"activeTargets": [
+			{"discoveredLabels": {"__address__": %q}, "labels": {"instance": %q, "job": "app"}, "scrapeUrl": "http://%s/metrics", "health": "up"},
+			{"discoveredLabels": {}, "labels": {"instance": "localhost:%s", "job": "dns-app"}, "health": "down"}
+		]}}`, scrapedAddress, scrapedAddress, scrapedAddress, unscrapedPort)
+	}))
+	defer prometheus.Close()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "apps.json"),
+		[]byte(fmt.Sprintf(`[{"targets": [%q], "labels": {"env": "test"}}]`, unscrapedAddress)), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "more.yaml"),
+		[]byte(fmt.Sprintf("- targets:\n    - %s\n  labels:\n    env: yaml\n", scrapedAddress)), 0o600))
+
+	port, _ := strconv.Atoi(unscrapedPort)
+	resolver := startFakeDNS(t,
+		map[string]net.IP{"apps.test": net.ParseIP("127.0.0.1")},
+		map[string][]net.SRV{"_metrics._tcp.apps.test": {{Target: "localhost.", Port: uint16(port), Priority: 10, Weight: 5}}})
+
+	var providers []DiscoveryProvider
+	for _, config := range []types.DiscoveryProvider{
+		{Type: types.DiscoveryStatic, Targets: []string{scrapedAddress, deadAddress}},
+		{Type: types.DiscoveryFileSD, Name: "apps", Files: []string{filepath.Join(dir, "*.json"), filepath.Join(dir, "*.yaml")}},
+		{Type: types.DiscoveryDNS, Name: "srv", Names: []string{"_metrics._tcp.apps.test"}},
+		{Type: types.DiscoveryDNS, Name: "a", Names: []string{"apps.test"}, RecordType: "A", Port: port},
+		{Type: types.DiscoveryFileSD, Name: "missing", Files: []string{filepath.Join(dir, "*.yml")}},
+	} {
+		provider, err := NewDiscoveryProvider(config, resolver)
+		require.NoError(t, err)
+		providers = append(providers, provider)
+	}
+
+	report := NewDiscoveryService().Discover(context.Background(), providers, types.ServiceConfig{URL: prometheus.URL})
+	assert.Equal(t, "degraded", report.Status)
+	assert.Empty(t, report.PrometheusError)
+
+	counts := map[string]int{}
+	for _, provider := range report.Providers {
+		counts[provider.Name] = provider.Targets
+	}
+	assert.Equal(t, map[string]int{"static": 2, "apps": 2, "srv": 1, "a": 1, "missing": 0}, counts)
+	assert.Contains(t, report.Providers[4].Error, "no files match")
+
+	targets := map[string]models.DiscoveredTarget{}
+	for _, target := range report.Targets {
+		targets[target.Address] = target
+	}
+	require.Len(t, targets, 4)
+
+	scraped := targets[scrapedAddress]
+	assert.Equal(t, "healthy", scraped.Status)
+	assert.True(t, scraped.Scraped)
+	assert.Equal(t, "app", scraped.ScrapeJob)
+	assert.Equal(t, "static", scraped.Provider)
+
+	unscraped := targets[unscrapedAddress]
+	assert.Equal(t, "healthy", unscraped.Status)
+	assert.False(t, unscraped.Scraped)
+	assert.Equal(t, map[string]string{"env": "test"}, unscraped.Labels)
+
+	srv := targets["localhost:"+unscrapedPort]
+	assert.Equal(t, "healthy", srv.Status)
+	assert.Equal(t, "down", srv.ScrapeHealth)
+	assert.Equal(t, "_metrics._tcp.apps.test", srv.Labels["__meta_dns_name"])
+
+	assert.Equal(t, "unhealthy", targets[deadAddress].Status)
+	assert.Equal(t, []string{unscrapedAddress}, report.Unscraped)
+	assert.Len(t, report.Problems, 3) // missing files, unscraped target, dead target
+}
+
+func TestDiscoveryService_PrometheusUnavailable(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer app.Close()
+
+	provider, err := NewDiscoveryProvider(types.DiscoveryProvider{Type: types.DiscoveryStatic, Targets: []string{strings.TrimPrefix(app.URL, "http://")}}, "")
+	require.NoError(t, err)
+
+	report := NewDiscoveryService().Discover(context.Background(), []DiscoveryProvider{provider}, types.ServiceConfig{URL: "http://127.0.0.1:1"})
+	assert.Equal(t, "failed", report.Status)
+	assert.NotEmpty(t, report.PrometheusError)
+	assert.Empty(t, report.Unscraped, "scrape coverage is unknown without Prometheus")
+	assert.Equal(t, "healthy", report.Targets[0].Status)
+}
+
+func TestNewDiscoveryProvider_Validation(t *testing.T) {
+	for _, config := range []types.DiscoveryProvider{
+		{Type: "consul"},
+		{Type: types.DiscoveryStatic},
+		{Type: types.DiscoveryFileSD},
+		{Type: types.DiscoveryDNS, Names: []string{"apps.test"}, RecordType: "A"},
+		{Type: types.DiscoveryDNS, Names: []string{"apps.test"}, RecordType: "MX"},
+		{Type: types.DiscoveryStatic, Targets: []string{"app:9100"}, Scheme: "ftp"},
+	} {
+		_, err := NewDiscoveryProvider(config, "")
+		assert.Error(t, err, config)
+	}
+}
+
+func TestServiceProvider(t *testing.T) {
+	provider := NewServiceProvider([]types.DiscoveryTarget{
+		{Name: "orders", ServiceConfig: types.ServiceConfig{URL: "https://orders.internal/health", BearerToken: "token"}},
+		{Name: "billing", ServiceConfig: types.ServiceConfig{URL: "http://billing.internal:8080/ready"}},
+		{Name: "broken", ServiceConfig: types.ServiceConfig{URL: "billing.internal"}},
+	})
+	assert.Equal(t, types.DiscoveryStatic, provider.Type())
+
+	targets, err := provider.Discover(context.Background())
+	assert.EqualError(t, err, "invalid url for broken")
+	require.Len(t, targets, 2)
+	assert.Equal(t, "orders.internal:443", targets[0].Address)
+	assert.Equal(t, "https://orders.internal/health", targets[0].HealthURL)
+	assert.Equal(t, "billing.internal:8080", targets[1].Address)
+
+	// Health checks use each service's own credentials
+	assert.Equal(t, "token", provider.(*serviceProvider).service(targets[0]).BearerToken)
+	assert.Empty(t, provider.(*serviceProvider).service(targets[1]).BearerToken)
+}
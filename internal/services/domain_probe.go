@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// maxProbeBody limits how much of a response body is read and matched
+const maxProbeBody = 10 << 20
+
+// DomainProbeService probes domains blackbox-style, timing DNS resolution,
+// TCP connect, TLS handshake and the HTTP exchange separately
+type DomainProbeService struct {
+	timeout      time.Duration
+	maxRedirects int
+	concurrency  int
+}
+
+// NewDomainProbeService creates a new domain probe service
+func NewDomainProbeService() *DomainProbeService {
+	return &DomainProbeService{timeout: 10 * time.Second, maxRedirects: 10, concurrency: 10}
+}
+
+// Probe checks every target, resolving names through resolver (host:port)
+// or the system resolver when it is empty, and records the results as
+// probe_* metrics
+func (ds *DomainProbeService) Probe(ctx context.Context, targets []types.DomainTarget, resolver string) *models.DomainHealthReport {
+	report := &models.DomainHealthReport{
+		Resolver:  resolver,
+		Problems:  []string{},
+		Timestamp: time.Now(),
+	}
+	if report.Resolver == "" {
+		report.Resolver = "system"
+	}
+	if len(targets) == 0 {
+		report.Status = "not_configured"
+		return report
+	}
+
+	netResolver := newProbeResolver(resolver)
+	// Targets are probed concurrently so slow ones don't add up
+	report.Probes = make([]models.DomainProbe, len(targets))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ds.concurrency)
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, target types.DomainTarget) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			report.Probes[i] = ds.probe(ctx, target, netResolver)
+		}(i, target)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, probe := range report.Probes {
+		recordProbe(probe)
+
+		if probe.Success {
+			succeeded++
+		} else {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %s", probe.Name, probe.Error))
+		}
+	}
+
+	switch {
+	case succeeded == len(targets):
+		report.Status = "healthy"
+	case succeeded > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "failed"
+	}
+	return report
+}
+
+// newProbeResolver returns a resolver that queries address, or the system resolver
+func newProbeResolver(address string) *net.Resolver {
+	if address == "" {
+		return net.DefaultResolver
+	}
+	address = withDefaultPort(address, "53")
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// recordProbe exports one probe's outcome and phase durations
+func recordProbe(probe models.DomainProbe) {
+	success := 0.0
+	if probe.Success {
+		success = 1
+	}
+	metrics.ProbeSuccess.WithLabelValues(probe.Name).Set(success)
+	metrics.ProbeHTTPStatusCode.WithLabelValues(probe.Name).Set(float64(probe.StatusCode))
+
+	phases := map[string]time.Duration{
+		"dns":        probe.Phases.DNS,
+		"connect":    probe.Phases.Connect,
+		"processing": probe.Phases.Processing,
+		"transfer":   probe.Phases.Transfer,
+	}
+	if probe.TLSVersion != "" {
+		phases["tls"] = probe.Phases.TLS
+	}
+	for phase, duration := range phases {
+		metrics.ProbeDuration.WithLabelValues(probe.Name, phase).Observe(duration.Seconds())
+	}
+}
+
+// probe follows a target's redirects and checks the final response
+func (ds *DomainProbeService) probe(ctx context.Context, target types.DomainTarget, resolver *net.Resolver) (probe models.DomainProbe) {
+	start := time.Now()
+	probe = models.DomainProbe{Name: target.Name, URL: target.URL}
+	defer func() { probe.Duration = time.Since(start) }()
+
+	current, err := url.Parse(target.URL)
+	if err != nil || (current.Scheme != "http" && current.Scheme != "https") || current.Host == "" {
+		probe.Error = fmt.Sprintf("invalid URL %q", target.URL)
+		return probe
+	}
+	if probe.Name == "" {
+		probe.Name = current.Host
+	}
+
+	var bodyRegex *regexp.Regexp
+	if target.BodyRegex != "" {
+		if bodyRegex, err = regexp.Compile(target.BodyRegex); err != nil {
+			probe.Error = fmt.Sprintf("invalid body_regex: %v", err)
+			return probe
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ds.timeout)
+	defer cancel()
+
+	for {
+		resp, body, err := ds.probeHop(ctx, target, current, resolver, &probe)
+		if err != nil {
+			probe.Error = err.Error()
+			return probe
+		}
+		probe.StatusCode = resp.StatusCode
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && location != "" && !target.NoFollowRedirects {
+			next, err := current.Parse(location)
+			if err != nil {
+				probe.Error = fmt.Sprintf("invalid redirect location %q", location)
+				return probe
+			}
+			probe.RedirectChain = append(probe.RedirectChain, models.RedirectHop{
+				URL:        current.String(),
+				StatusCode: resp.StatusCode,
+				Location:   next.String(),
+			})
+			if len(probe.RedirectChain) > ds.maxRedirects {
+				probe.Error = fmt.Sprintf("stopped after %d redirects", ds.maxRedirects)
+				return probe
+			}
+			current = next
+			continue
+		}
+
+		if !expectedStatus(resp.StatusCode, target.ExpectedStatus) {
+			probe.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+			return probe
+		}
+		if bodyRegex != nil {
+			matched := bodyRegex.Match(body)
+			probe.BodyMatched = &matched
+			if !matched {
+				probe.Error = fmt.Sprintf("body does not match %q", target.BodyRegex)
+				return probe
+			}
+		}
+		probe.Success = true
+		return probe
+	}
+}
+
+// probeHop makes one request over a connection it dials itself, so each phase
+// can be timed, and adds the timings to probe
+func (ds *DomainProbeService) probeHop(ctx context.Context, target types.DomainTarget, u *url.URL, resolver *net.Resolver, probe *models.DomainProbe) (*http.Response, []byte, error) {
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	// DNS
+	ip := host
+	if net.ParseIP(host) == nil {
+		start := time.Now()
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		probe.Phases.DNS += time.Since(start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("dns: %w", err)
+		}
+		ip = addrs[0].IP.String()
+		for _, addr := range addrs {
+			if addr.IP.To4() != nil {
+				ip = addr.IP.String()
+				break
+			}
+		}
+	}
+	probe.ResolvedIP = ip
+
+	// TCP
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+	probe.Phases.Connect += time.Since(start)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect: %w", err)
+	}
+
+	// TLS
+	if u.Scheme == "https" {
+		tlsConfig := &tls.Config{}
+		if target.TLS != nil {
+			if tlsConfig, err = NewServiceTLSConfig(*target.TLS); err != nil {
+				conn.Close()
+				return nil, nil, err
+			}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = host
+		}
+		tlsConn := tls.Client(conn, tlsConfig)
+		start = time.Now()
+		err = tlsConn.HandshakeContext(ctx)
+		probe.Phases.TLS += time.Since(start)
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("tls: %w", err)
+		}
+		probe.TLSVersion = tls.VersionName(tlsConn.ConnectionState().Version)
+		conn = tlsConn
+	}
+
+	// HTTP over the connection dialled above
+	dial := func(context.Context, string, string) (net.Conn, error) { return conn, nil }
+	transport := &http.Transport{DialContext: dial, DialTLSContext: dial, DisableKeepAlives: true}
+	defer transport.CloseIdleConnections()
+
+	var wroteRequest, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		WroteRequest:         func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), "GET", u.String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "argus-domain-probe")
+	for key, value := range target.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("http: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	if !wroteRequest.IsZero() && !firstByte.IsZero() {
+		probe.Phases.Processing += firstByte.Sub(wroteRequest)
+		probe.Phases.Transfer += time.Since(firstByte)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("read body: %w", err)
+	}
+	return resp, body, nil
+}
+
+// expectedStatus reports whether code is one of expected, or any 2xx when none are given
+func expectedStatus(code int, expected []int) bool {
+	if len(expected) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, want := range expected {
+		if code == want {
+			return true
+		}
+	}
+	return false
+}
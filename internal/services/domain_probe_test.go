@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// startFakeDNS answers A queries for records and SRV queries for srv over
+// UDP, and NXDOMAIN for unknown names
+func startFakeDNS(t *testing.T, records map[string]net.IP, srv map[string][]net.SRV) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+
+			// The question follows the 12 byte header: labels, then type and class
+			var labels []string
+			i := 12
+			for i < n && query[i] != 0 {
+				length := int(query[i])
+				labels = append(labels, string(query[i+1:i+1+length]))
+				i += 1 + length
+			}
+			qtype := binary.BigEndian.Uint16(query[i+1:])
+
+			response := append([]byte{}, query[:i+5]...)
+			response[2], response[3] = 0x81, 0x80 // response, recursion desired and available
+			copy(response[6:12], make([]byte, 6))
+			name := strings.Join(labels, ".")
+			ip, isHost := records[name]
+			services, isService := srv[name]
+			switch {
+			case !isHost && !isService:
+				response[3] |= 3 // NXDOMAIN
+			case qtype == 1 && isHost:
+				response[7] = 1
+				response = append(response, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+				response = append(response, ip.To4()...)
+			case qtype == 33 && isService:
+				response[7] = byte(len(services))
+				for _, service := range services {
+					var target []byte
+					for _, label := range strings.Split(strings.TrimSuffix(service.Target, "."), ".") {
+						target = append(append(target, byte(len(label))), label...)
+					}
+					target = append(target, 0)
+					response = append(response, 0xc0, 0x0c, 0, 33, 0, 1, 0, 0, 0, 60)
+					response = binary.BigEndian.AppendUint16(response, uint16(6+len(target)))
+					response = binary.BigEndian.AppendUint16(response, service.Priority)
+					response = binary.BigEndian.AppendUint16(response, service.Weight)
+					response = binary.BigEndian.AppendUint16(response, service.Port)
+					response = append(response, target...)
+				}
+			}
+			_, _ = conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDomainProbeService_Probe(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			_, _ = w.Write([]byte(`{"status": "ok"}`))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer app.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(app.URL, "http://"))
+
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secure"))
+	}))
+	defer secure.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: secure.Certificate().Raw}), 0o600))
+
+	resolver := startFakeDNS(t, map[string]net.IP{"app.test": net.ParseIP("127.0.0.1")}, nil)
+	ds := NewDomainProbeService()
+	ds.maxRedirects = 3
+
+	t.Run("resolves through the configured resolver and follows redirects", func(t *testing.T) {
+		report := ds.Probe(context.Background(), []types.DomainTarget{
+			{Name: "app", URL: fmt.Sprintf("http://app.test:%s/old", port), BodyRegex: `"status":\s*"ok"`},
+		}, resolver)
+		assert.Equal(t, "healthy", report.Status)
+		assert.Equal(t, resolver, report.Resolver)
+
+		probe := report.Probes[0]
+		assert.True(t, probe.Success, probe.Error)
+		assert.Equal(t, "127.0.0.1", probe.ResolvedIP)
+		assert.Equal(t, http.StatusOK, probe.StatusCode)
+		require.NotNil(t, probe.BodyMatched)
+		assert.True(t, *probe.BodyMatched)
+		require.Len(t, probe.RedirectChain, 1)
+		assert.Equal(t, http.StatusMovedPermanently, probe.RedirectChain[0].StatusCode)
+		assert.Equal(t, fmt.Sprintf("http://app.test:%s/new", port), probe.RedirectChain[0].Location)
+		assert.Positive(t, probe.Phases.DNS)
+		assert.Positive(t, probe.Phases.Connect)
+		assert.Positive(t, probe.Phases.Processing)
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ProbeSuccess.WithLabelValues("app")))
+		assert.Equal(t, 200.0, testutil.ToFloat64(metrics.ProbeHTTPStatusCode.WithLabelValues("app")))
+	})
+
+	t.Run("times the TLS handshake", func(t *testing.T) {
+		report := ds.Probe(context.Background(), []types.DomainTarget{
+			{URL: secure.URL, TLS: &types.TLSConfig{CAFile: caFile}},
+		}, "")
+		probe := report.Probes[0]
+		assert.True(t, probe.Success, probe.Error)
+		assert.Equal(t, strings.TrimPrefix(secure.URL, "https://"), probe.Name)
+		assert.Equal(t, "TLS 1.3", probe.TLSVersion)
+		assert.Positive(t, probe.Phases.TLS)
+		assert.Zero(t, probe.Phases.DNS)
+	})
+
+	t.Run("reports failing checks", func(t *testing.T) {
+		report := ds.Probe(context.Background(), []types.DomainTarget{
+			{Name: "missing", URL: fmt.Sprintf("http://app.test:%s/missing", port)},
+			{Name: "regex", URL: fmt.Sprintf("http://app.test:%s/new", port), BodyRegex: "maintenance"},
+			{Name: "status", URL: fmt.Sprintf("http://app.test:%s/old", port), NoFollowRedirects: true, ExpectedStatus: []int{302}},
+			{Name: "loop", URL: fmt.Sprintf("http://app.test:%s/loop", port)},
+			{Name: "nxdomain", URL: "http://unknown.test/"},
+			{Name: "untrusted", URL: secure.URL},
+		}, resolver)
+		assert.Equal(t, "failed", report.Status)
+
+		errors := map[string]string{}
+		for _, probe := range report.Probes {
+			assert.False(t, probe.Success, probe.Name)
+			errors[probe.Name] = probe.Error
+		}
+		assert.Equal(t, "unexpected status 404", errors["missing"])
+		assert.Equal(t, `body does not match "maintenance"`, errors["regex"])
+		assert.Equal(t, "unexpected status 301", errors["status"])
+		assert.Equal(t, "stopped after 3 redirects", errors["loop"])
+		assert.Contains(t, errors["nxdomain"], "dns:")
+		assert.Contains(t, errors["untrusted"], "tls:")
+		assert.Len(t, report.Problems, 6)
+
+		assert.Equal(t, 0.0, testutil.ToFloat64(metrics.ProbeSuccess.WithLabelValues("regex")))
+	})
+
+	t.Run("stalled targets are probed concurrently", func(t *testing.T) {
+		stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer stalled.Close()
+		slow := &DomainProbeService{timeout: 300 * time.Millisecond, maxRedirects: 3, concurrency: 10}
+
+		targets := []types.DomainTarget{}
+		for i := 0; i < 4; i++ {
+			targets = append(targets, types.DomainTarget{Name: fmt.Sprintf("stalled-%d", i), URL: stalled.URL})
+		}
+		targets = append(targets, types.DomainTarget{Name: "app", URL: app.URL + "/new"})
+
+		start := time.Now()
+		report := slow.Probe(context.Background(), targets, "")
+		assert.Less(t, time.Since(start), 4*slow.timeout)
+		assert.Equal(t, "degraded", report.Status)
+		require.Len(t, report.Probes, 5)
+		assert.False(t, report.Probes[0].Success)
+		assert.Equal(t, "app", report.Probes[4].Name, "results keep the target order")
+		assert.True(t, report.Probes[4].Success, report.Probes[4].Error)
+	})
+
+	t.Run("no targets", func(t *testing.T) {
+		report := ds.Probe(context.Background(), nil, resolver)
+		assert.Equal(t, "not_configured", report.Status)
+		assert.Empty(t, report.Probes)
+	})
+}
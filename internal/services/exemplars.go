@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// ExemplarService checks that exemplars Argus attached to its latency
+// histograms reach Prometheus and lead to their traces in Grafana
+type ExemplarService struct {
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewExemplarService creates a new exemplar validation service
+func NewExemplarService() *ExemplarService {
+	return &ExemplarService{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: 5 * time.Second,
+	}
+}
+
+type promExemplarSeries struct {
+	SeriesLabels map[string]string `json:"seriesLabels"`
+	Exemplars    []struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"exemplars"`
+}
+
+type grafanaDatasource struct {
+	UID      string `json:"uid"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	JSONData struct {
+		ExemplarTraceIDDestinations []struct {
+			Name          string `json:"name"`
+			DatasourceUID string `json:"datasourceUid"`
+		} `json:"exemplarTraceIdDestinations"`
+	} `json:"jsonData"`
+}
+
+// Validate waits until Prometheus has scraped the probes' observations, then
+// checks for each histogram that its probe exemplars were stored and that it
+// was ingested as a native histogram. It finally follows a stored exemplar
+// the way Grafana does: through the Prometheus datasource's trace_id
+// destination to the Tempo datasource, which must return the trace.
+func (es *ExemplarService) Validate(ctx context.Context, prometheus, grafana types.ServiceConfig, service string, probes []models.ExemplarProbe, emitted time.Time, timeout time.Duration) *models.ExemplarReport {
+	report := &models.ExemplarReport{
+		PrometheusURL: prometheus.URL,
+		GrafanaURL:    grafana.URL,
+		Probes:        probes,
+		Metrics:       []models.ExemplarMetricCheck{},
+		Problems:      []string{},
+		Timestamp:     time.Now(),
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(emitted.Add(-time.Minute).Unix(), 10))
+	params.Set("end", strconv.FormatInt(emitted.Add(5*time.Minute).Unix(), 10))
+
+	var stored string
+	for _, histogram := range []struct {
+		metric string
+		labels string
+	}{
+		{metric: "apm_span_duration_seconds", labels: fmt.Sprintf(`service=%q,operation=%q`, service, ExemplarProbeOperation)},
+		{metric: "http_request_duration_seconds", labels: fmt.Sprintf(`method="GET",endpoint=%q`, ExemplarProbeEndpoint)},
+	} {
+		// Without native histograms the exemplars sit on the classic bucket series,
+		// but only if Prometheus scrapes protobuf or OpenMetrics (ARGUS_OPENMETRICS);
+		// the default text format drops them
+		query := fmt.Sprintf(`{__name__=~"%s(_bucket)?",%s}`, histogram.metric, histogram.labels)
+		check := es.exemplarsUntilFound(checkCtx, prometheus, params, query, probes)
+		check.Metric = histogram.metric
+		check.Query = query
+
+		switch {
+		case check.Error != "":
+			report.Problems = append(report.Problems, fmt.Sprintf("%s exemplar query failed: %s", histogram.metric, check.Error))
+		case len(check.Matched) == 0:
+			report.Problems = append(report.Problems, fmt.Sprintf("%s has no exemplar of the %d probe spans after %d attempts - is exemplar storage enabled?", histogram.metric, len(probes), check.Attempts))
+		default:
+			stored = check.Matched[0]
+		}
+
+		samples, err := prometheusQuery(ctx, es.client, prometheus, fmt.Sprintf("histogram_count(%s{%s})", histogram.metric, histogram.labels))
+		switch {
+		case err != nil:
+			report.Problems = append(report.Problems, fmt.Sprintf("%s native histogram query failed: %v", histogram.metric, err))
+		case len(samples) == 0:
+			report.Problems = append(report.Problems, fmt.Sprintf("%s is stored as a classic histogram - is the native-histograms feature enabled?", histogram.metric))
+		default:
+			check.NativeHistogram = true
+		}
+		report.Metrics = append(report.Metrics, check)
+	}
+
+	report.TraceLink = es.followExemplar(ctx, checkCtx, grafana, stored)
+	if report.TraceLink.Error != "" {
+		report.Problems = append(report.Problems, "Exemplar to trace link: "+report.TraceLink.Error)
+	}
+
+	switch {
+	case stored == "":
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report
+}
+
+// exemplarsUntilFound repeats an exemplar query until it returns one of the
+// probes' trace IDs or ctx ends. Only the newest exemplar per bucket survives
+// until a scrape, so finding every probe is not expected.
+func (es *ExemplarService) exemplarsUntilFound(ctx context.Context, prometheus types.ServiceConfig, params url.Values, query string, probes []models.ExemplarProbe) models.ExemplarMetricCheck {
+	check := models.ExemplarMetricCheck{Matched: []string{}}
+	start := time.Now()
+
+	endpoint := "/api/v1/query_exemplars?" + cloneValues(params).Encode() + "&query=" + url.QueryEscape(query)
+	for {
+		check.Attempts++
+		var series []promExemplarSeries
+		err := prometheusAPI(ctx, es.client, prometheus, endpoint, &series)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			check.Error = err.Error()
+		case err == nil:
+			check.Error = ""
+			check.Exemplars = 0
+			matched := make(map[string]bool)
+			for _, s := range series {
+				for _, exemplar := range s.Exemplars {
+					check.Exemplars++
+					for _, probe := range probes {
+						if sameTraceID(exemplar.Labels["trace_id"], probe.TraceID) && !matched[probe.TraceID] {
+							matched[probe.TraceID] = true
+							check.Matched = append(check.Matched, probe.TraceID)
+						}
+					}
+				}
+			}
+			if len(check.Matched) > 0 {
+				check.Elapsed = time.Since(start)
+				return check
+			}
+		}
+
+		select {
+		case <-time.After(es.pollInterval):
+		case <-ctx.Done():
+			check.Elapsed = time.Since(start)
+			return check
+		}
+	}
+}
+
+// followExemplar finds the Prometheus datasource whose exemplars link trace_id
+// to a Tempo datasource and fetches traceID through it, retrying until Tempo
+// has ingested the trace or pollCtx ends
+func (es *ExemplarService) followExemplar(ctx, pollCtx context.Context, grafana types.ServiceConfig, traceID string) models.ExemplarLinkCheck {
+	check := models.ExemplarLinkCheck{TraceID: traceID}
+
+	var datasources []grafanaDatasource
+	status, err := grafanaJSON(ctx, es.client, grafana, "GET", "/api/datasources", nil, &datasources)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if status != http.StatusOK {
+		check.Error = (&GrafanaAPIError{Operation: "list datasources", StatusCode: status}).Error()
+		return check
+	}
+
+	tempo := make(map[string]string)
+	for _, ds := range datasources {
+		if ds.Type == "tempo" {
+			tempo[ds.UID] = ds.Name
+		}
+	}
+
+	linked := 0
+	for _, ds := range datasources {
+		if ds.Type != "prometheus" {
+			continue
+		}
+		for _, destination := range ds.JSONData.ExemplarTraceIDDestinations {
+			if destination.Name != "trace_id" {
+				continue
+			}
+			linked++
+			if _, ok := tempo[destination.DatasourceUID]; ok {
+				check.PrometheusDatasource = ds.Name
+				check.TraceIDLabel = destination.Name
+				check.TempoDatasource = tempo[destination.DatasourceUID]
+				return es.fetchTrace(pollCtx, grafana, destination.DatasourceUID, check)
+			}
+		}
+	}
+
+	if linked > 0 {
+		check.Error = "trace_id exemplars link to an external URL or a datasource that is not Tempo"
+	} else {
+		check.Error = "no Prometheus datasource links trace_id exemplars to a Tempo datasource"
+	}
+	return check
+}
+
+// fetchTrace requests the trace through Grafana's proxy to the Tempo datasource
+func (es *ExemplarService) fetchTrace(ctx context.Context, grafana types.ServiceConfig, tempoUID string, check models.ExemplarLinkCheck) models.ExemplarLinkCheck {
+	if check.TraceID == "" {
+		check.Error = "no stored exemplar to follow"
+		return check
+	}
+
+	endpoint := "/api/datasources/proxy/uid/" + url.PathEscape(tempoUID) + "/api/traces/" + check.TraceID
+	for {
+		check.Attempts++
+		start := time.Now()
+		status, err := grafanaJSON(ctx, es.client, grafana, "GET", endpoint, nil, nil)
+		check.Duration = time.Since(start)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			check.Error = err.Error()
+		case err == nil && status == http.StatusOK:
+			check.Error = ""
+			check.TraceFound = true
+			return check
+		case err == nil:
+			check.Error = fmt.Sprintf("trace %s not returned through datasource %s: HTTP %d", check.TraceID, check.TempoDatasource, status)
+		}
+
+		// Tempo answers 404 until the trace is ingested; other 4xx will not change
+		if status >= 400 && status < 500 && status != http.StatusNotFound {
+			return check
+		}
+
+		select {
+		case <-time.After(es.pollInterval):
+		case <-ctx.Done():
+			if check.Error == "" {
+				check.Error = fmt.Sprintf("trace %s not returned after %d attempts", check.TraceID, check.Attempts)
+			}
+			return check
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeExemplarStack serves Prometheus' exemplar and query APIs and Grafana's
+// datasource API with its proxy to Tempo from one server
+type fakeExemplarStack struct {
+	mu           sync.Mutex
+	traceID      string // exemplar Prometheus stores once scraped
+	scrapedAfter int    // exemplar queries before the scrape
+	native       bool
+	destination  map[string]string
+	queries      int
+	traceFetches []string
+}
+
+func (f *fakeExemplarStack) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/v1/query_exemplars":
+		f.queries++
+		series := []map[string]interface{}{}
+		if f.queries > f.scrapedAfter && r.URL.Query().Get("start") != "" {
+			series = append(series, map[string]interface{}{
+				"seriesLabels": map[string]string{"__name__": "apm_span_duration_seconds"},
+				"exemplars": []map[string]interface{}{
+					{"labels": map[string]string{"trace_id": "0123456789abcdef0123456789abcdef"}, "value": "0.2", "timestamp": 1},
+					{"labels": map[string]string{"trace_id": f.traceID, "span_id": "00f067aa0ba902b7"}, "value": "0.04", "timestamp": 2},
+				},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": series})
+	case r.URL.Path == "/api/v1/query":
+		result := []map[string]interface{}{}
+		if f.native && strings.HasPrefix(r.URL.Query().Get("query"), "histogram_count(") {
+			result = append(result, map[string]interface{}{"metric": map[string]string{}, "value": []interface{}{1, "10"}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": map[string]interface{}{"resultType": "vector", "result": result}})
+	case r.URL.Path == "/api/datasources":
+		jsonData := map[string]interface{}{}
+		if f.destination != nil {
+			jsonData["exemplarTraceIdDestinations"] = []map[string]string{f.destination}
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"uid": "prom", "name": "Prometheus", "type": "prometheus", "jsonData": jsonData},
+			{"uid": "tempo", "name": "Tempo", "type": "tempo", "jsonData": map[string]interface{}{}},
+			{"uid": "loki", "name": "Loki", "type": "loki", "jsonData": map[string]interface{}{}},
+		})
+	case strings.HasPrefix(r.URL.Path, "/api/datasources/proxy/uid/tempo/api/traces/"):
+		traceID := strings.TrimPrefix(r.URL.Path, "/api/datasources/proxy/uid/tempo/api/traces/")
+		f.traceFetches = append(f.traceFetches, traceID)
+		if traceID != f.traceID || len(f.traceFetches) < 2 {
+			http.Error(w, "trace not found", http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"batches": []interface{}{}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestExemplarService_Validate(t *testing.T) {
+	probes := []models.ExemplarProbe{
+		{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Duration: 40 * time.Millisecond},
+		{TraceID: "5cf92f3577b34da6a3ce929d0e0e4737", SpanID: "10f067aa0ba902b7", Duration: 900 * time.Millisecond},
+	}
+	tempoLink := map[string]string{"name": "trace_id", "datasourceUid": "tempo"}
+
+	validate := func(t *testing.T, stack *fakeExemplarStack, timeout time.Duration) *models.ExemplarReport {
+		server := httptest.NewServer(stack)
+		t.Cleanup(server.Close)
+		es := NewExemplarService()
+		es.pollInterval = 5 * time.Millisecond
+		config := types.ServiceConfig{URL: server.URL}
+		return es.Validate(context.Background(), config, config, "argus", probes, time.Now(), timeout)
+	}
+
+	t.Run("exemplars stored and linked to traces", func(t *testing.T) {
+		stack := &fakeExemplarStack{traceID: probes[0].TraceID, scrapedAfter: 2, native: true, destination: tempoLink}
+		report := validate(t, stack, time.Second)
+
+		assert.Equal(t, "healthy", report.Status, report.Problems)
+		require.Len(t, report.Metrics, 2)
+		spans := report.Metrics[0]
+		assert.Equal(t, "apm_span_duration_seconds", spans.Metric)
+		assert.Equal(t, `{__name__=~"apm_span_duration_seconds(_bucket)?",service="argus",operation="exemplar_probe"}`, spans.Query)
+		assert.Equal(t, 3, spans.Attempts, "exemplars are polled until scraped")
+		assert.Equal(t, 2, spans.Exemplars)
+		assert.Equal(t, []string{probes[0].TraceID}, spans.Matched)
+		assert.True(t, spans.NativeHistogram)
+		assert.Equal(t, `{__name__=~"http_request_duration_seconds(_bucket)?",method="GET",endpoint="/exemplar-probe"}`, report.Metrics[1].Query)
+
+		link := report.TraceLink
+		assert.Equal(t, "Prometheus", link.PrometheusDatasource)
+		assert.Equal(t, "trace_id", link.TraceIDLabel)
+		assert.Equal(t, "Tempo", link.TempoDatasource)
+		assert.Equal(t, probes[0].TraceID, link.TraceID)
+		assert.True(t, link.TraceFound)
+		assert.Equal(t, 2, link.Attempts, "trace fetch is retried while Tempo answers 404")
+	})
+
+	t.Run("classic histograms and no trace link", func(t *testing.T) {
+		stack := &fakeExemplarStack{traceID: probes[1].TraceID}
+		report := validate(t, stack, time.Second)
+
+		assert.Equal(t, "degraded", report.Status)
+		assert.False(t, report.Metrics[0].NativeHistogram)
+		assert.Contains(t, report.Problems, "apm_span_duration_seconds is stored as a classic histogram - is the native-histograms feature enabled?")
+		assert.Contains(t, report.Problems, "Exemplar to trace link: no Prometheus datasource links trace_id exemplars to a Tempo datasource")
+		assert.Empty(t, stack.traceFetches)
+	})
+
+	t.Run("destination is not Tempo", func(t *testing.T) {
+		stack := &fakeExemplarStack{traceID: probes[0].TraceID, native: true, destination: map[string]string{"name": "trace_id", "url": "https://jaeger.example.com/trace/${__value.raw}"}}
+		report := validate(t, stack, time.Second)
+
+		assert.Equal(t, "degraded", report.Status)
+		assert.Equal(t, "trace_id exemplars link to an external URL or a datasource that is not Tempo", report.TraceLink.Error)
+	})
+
+	t.Run("exemplars never stored", func(t *testing.T) {
+		stack := &fakeExemplarStack{traceID: "ffffffffffffffffffffffffffffffff", native: true, destination: tempoLink}
+		report := validate(t, stack, 50*time.Millisecond)
+
+		assert.Equal(t, "failed", report.Status)
+		assert.Empty(t, report.Metrics[0].Matched)
+		assert.Contains(t, report.Problems[0], "apm_span_duration_seconds has no exemplar of the 2 probe spans")
+		assert.Equal(t, "no stored exemplar to follow", report.TraceLink.Error)
+	})
+}
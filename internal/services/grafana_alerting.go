@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// receiversTestPath is Grafana's endpoint for sending test notifications through receivers
+const receiversTestPath = "/api/alertmanager/grafana/config/api/v1/receivers/test"
+
+// WebhookReceiver collects alert notifications that stack components deliver to Argus
+type WebhookReceiver struct {
+	mu      sync.Mutex
+	waiters map[string]chan []byte
+}
+
+// NewWebhookReceiver creates a new webhook receiver
+func NewWebhookReceiver() *WebhookReceiver {
+	return &WebhookReceiver{
+		waiters: make(map[string]chan []byte),
+	}
+}
+
+// Expect registers interest in a notification for the given test ID
+func (wr *WebhookReceiver) Expect(id string) <-chan []byte {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	ch := make(chan []byte, 1)
+	wr.waiters[id] = ch
+	return ch
+}
+
+// Forget drops the registration for the given test ID
+func (wr *WebhookReceiver) Forget(id string) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	delete(wr.waiters, id)
+}
+
+// Deliver hands a received payload to whoever expects it and reports whether anyone did
+func (wr *WebhookReceiver) Deliver(id string, payload []byte) bool {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	ch, ok := wr.waiters[id]
+	if !ok {
+		return false
+	}
+	select {
+	case ch <- payload:
+	default:
+		// Already delivered once, Grafana may retry
+	}
+	return true
+}
+
+// GrafanaAlertingService validates Grafana-managed (unified) alerting
+type GrafanaAlertingService struct {
+	client    *http.Client
+	publicURL string
+	receiver  *WebhookReceiver
+}
+
+// NewGrafanaAlertingService creates a new Grafana alerting validation service
+func NewGrafanaAlertingService() *GrafanaAlertingService {
+	return &GrafanaAlertingService{
+		client:    &http.Client{Timeout: 15 * time.Second},
+		publicURL: config.GetServiceConfig().PublicURL,
+		receiver:  NewWebhookReceiver(),
+	}
+}
+
+// Receiver returns the Argus-hosted receiver test notifications are delivered to
+func (gs *GrafanaAlertingService) Receiver() *WebhookReceiver {
+	return gs.receiver
+}
+
+type grafanaAlertRule struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	RuleGroup string `json:"ruleGroup"`
+	FolderUID string `json:"folderUID"`
+	Data      []struct {
+		RefID         string `json:"refId"`
+		DatasourceUID string `json:"datasourceUid"`
+	} `json:"data"`
+}
+
+// grafanaRedacted replaces secure settings in provisioning API responses
+const grafanaRedacted = "[REDACTED]"
+
+type grafanaContactPoint struct {
+	UID                   string                 `json:"uid"`
+	Name                  string                 `json:"name"`
+	Type                  string                 `json:"type"`
+	Settings              map[string]interface{} `json:"settings"`
+	DisableResolveMessage bool                   `json:"disableResolveMessage"`
+}
+
+type grafanaRoute struct {
+	Receiver string         `json:"receiver"`
+	Routes   []grafanaRoute `json:"routes"`
+}
+
+type receiverTestResult struct {
+	Receivers []struct {
+		Name    string `json:"name"`
+		Configs []struct {
+			UID    string `json:"uid"`
+			Name   string `json:"name"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"grafana_managed_receiver_configs"`
+	} `json:"receivers"`
+}
+
+// AllContactPoints selects every contact point for a test notification
+const AllContactPoints = "*"
+
+// Validate checks that every Grafana alert rule references an existing
+// datasource, that the notification policy tree only routes to existing
+// contact points and that a test notification reaches the Argus-hosted
+// receiver. Test notifications reach real people, so only the contact points
+// named in testPoints (UIDs or names, or AllContactPoints) are sent one.
+func (gs *GrafanaAlertingService) Validate(ctx context.Context, grafana types.ServiceConfig, receiverTimeout time.Duration, testPoints []string) (*models.GrafanaAlertingReport, error) {
+	report := &models.GrafanaAlertingReport{
+		GrafanaURL:    grafana.URL,
+		Rules:         []models.GrafanaRuleCheck{},
+		ContactPoints: []models.GrafanaContactPointTest{},
+		Problems:      []string{},
+		Timestamp:     time.Now(),
+	}
+
+	var datasources []struct {
+		UID  string `json:"uid"`
+		Name string `json:"name"`
+	}
+	status, err := grafanaJSON(ctx, gs.client, grafana, "GET", "/api/datasources", nil, &datasources)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, &GrafanaAPIError{Operation: "list datasources", StatusCode: status}
+	}
+
+	var rules []grafanaAlertRule
+	status, err = grafanaJSON(ctx, gs.client, grafana, "GET", "/api/v1/provisioning/alert-rules", nil, &rules)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		report.Status = "failed"
+		report.Problems = append(report.Problems, fmt.Sprintf("Alert rule provisioning API unavailable (HTTP %d) - is unified alerting enabled?", status))
+		return report, nil
+	}
+
+	known := make(map[string]bool, len(datasources))
+	for _, ds := range datasources {
+		known[ds.UID] = true
+	}
+	for _, rule := range rules {
+		check := checkRuleDatasources(rule, known)
+		if !check.Valid {
+			report.Problems = append(report.Problems, fmt.Sprintf("Rule %q references missing datasources %v", rule.Title, check.MissingDatasources))
+		}
+		report.Rules = append(report.Rules, check)
+	}
+
+	var contactPoints []grafanaContactPoint
+	status, err = grafanaJSON(ctx, gs.client, grafana, "GET", "/api/v1/provisioning/contact-points", nil, &contactPoints)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		report.Problems = append(report.Problems, fmt.Sprintf("Cannot list contact points: HTTP %d", status))
+	}
+
+	var policies grafanaRoute
+	status, err = grafanaJSON(ctx, gs.client, grafana, "GET", "/api/v1/provisioning/policies", nil, &policies)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusOK {
+		report.Policies = checkPolicyTree(policies, contactPoints)
+		for _, missing := range report.Policies.MissingReceivers {
+			report.Problems = append(report.Problems, fmt.Sprintf("Notification policy routes to unknown contact point %q", missing))
+		}
+	} else {
+		report.Problems = append(report.Problems, fmt.Sprintf("Cannot read notification policies: HTTP %d", status))
+	}
+
+	report.ContactPoints = gs.testContactPoints(ctx, grafana, contactPoints, testPoints)
+	for _, test := range report.ContactPoints {
+		if test.Status == "failed" {
+			report.Problems = append(report.Problems, fmt.Sprintf("Contact point %q (%s) test notification failed: %s", test.Name, test.Type, test.Error))
+		}
+	}
+
+	report.Receiver = gs.testArgusReceiver(ctx, grafana, receiverTimeout)
+	if !report.Receiver.Delivered {
+		report.Problems = append(report.Problems, fmt.Sprintf("Test notification did not reach Argus at %s: %s", report.Receiver.URL, report.Receiver.Error))
+	}
+
+	report.Status = "healthy"
+	if len(report.Problems) > 0 {
+		report.Status = "degraded"
+	}
+	return report, nil
+}
+
+// checkRuleDatasources verifies every query in a rule points at a known datasource
+func checkRuleDatasources(rule grafanaAlertRule, known map[string]bool) models.GrafanaRuleCheck {
+	check := models.GrafanaRuleCheck{
+		UID:         rule.UID,
+		Title:       rule.Title,
+		RuleGroup:   rule.RuleGroup,
+		FolderUID:   rule.FolderUID,
+		Datasources: []string{},
+		Valid:       true,
+	}
+
+	for _, query := range rule.Data {
+		// Server-side expressions (math, reduce, threshold) are not real datasources
+		if query.DatasourceUID == "__expr__" || query.DatasourceUID == "-100" {
+			continue
+		}
+		check.Datasources = append(check.Datasources, query.DatasourceUID)
+		if !known[query.DatasourceUID] {
+			check.MissingDatasources = append(check.MissingDatasources, query.DatasourceUID)
+			check.Valid = false
+		}
+	}
+	return check
+}
+
+// checkPolicyTree verifies that every receiver in the policy tree is a defined contact point
+func checkPolicyTree(root grafanaRoute, contactPoints []grafanaContactPoint) models.GrafanaPolicyCheck {
+	defined := make(map[string]bool, len(contactPoints))
+	for _, cp := range contactPoints {
+		defined[cp.Name] = true
+	}
+
+	check := models.GrafanaPolicyCheck{
+		RootReceiver: root.Receiver,
+		Valid:        true,
+	}
+
+	referenced := make(map[string]bool)
+	var walk func(route grafanaRoute, depth int)
+	walk = func(route grafanaRoute, depth int) {
+		if depth > 0 {
+			check.Routes++
+		}
+		if route.Receiver != "" {
+			referenced[route.Receiver] = true
+		}
+		for _, child := range route.Routes {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+
+	for receiver := range referenced {
+		check.Receivers = append(check.Receivers, receiver)
+		if !defined[receiver] {
+			check.MissingReceivers = append(check.MissingReceivers, receiver)
+			check.Valid = false
+		}
+	}
+	sort.Strings(check.Receivers)
+	sort.Strings(check.MissingReceivers)
+	return check
+}
+
+// testContactPoints sends a test notification through each selected contact
+// point; the others are listed as not tested
+func (gs *GrafanaAlertingService) testContactPoints(ctx context.Context, grafana types.ServiceConfig, contactPoints []grafanaContactPoint, testPoints []string) []models.GrafanaContactPointTest {
+	results := []models.GrafanaContactPointTest{}
+	selected := make(map[string]bool, len(testPoints))
+	for _, point := range testPoints {
+		selected[point] = true
+	}
+
+	for _, cp := range contactPoints {
+		test := models.GrafanaContactPointTest{
+			UID:  cp.UID,
+			Name: cp.Name,
+			Type: cp.Type,
+		}
+		if !selected[AllContactPoints] && !selected[cp.Name] && (cp.UID == "" || !selected[cp.UID]) {
+			test.Status = "not_tested"
+			results = append(results, test)
+			continue
+		}
+
+		// The provisioning API redacts secure settings such as webhook URLs and
+		// API keys. Sending them back as secureFields of the integration's UID
+		// makes Grafana test with the stored values instead.
+		settings := make(map[string]interface{}, len(cp.Settings))
+		secureFields := make(map[string]bool)
+		for key, value := range cp.Settings {
+			if value == grafanaRedacted {
+				secureFields[key] = true
+				continue
+			}
+			settings[key] = value
+		}
+		if len(secureFields) > 0 && cp.UID == "" {
+			test.Status = "skipped"
+			test.Error = "secure settings are redacted and the contact point has no UID to test it by"
+			results = append(results, test)
+			continue
+		}
+
+		integration := map[string]interface{}{
+			"uid":                   cp.UID,
+			"name":                  cp.Name,
+			"type":                  cp.Type,
+			"settings":              settings,
+			"secureFields":          secureFields,
+			"disableResolveMessage": cp.DisableResolveMessage,
+		}
+
+		test.Status, test.Error = gs.sendTestNotification(ctx, grafana, cp.Name, integration)
+		results = append(results, test)
+	}
+	return results
+}
+
+// testArgusReceiver sends a test notification through a temporary webhook
+// integration pointing at Argus and waits for it to arrive
+func (gs *GrafanaAlertingService) testArgusReceiver(ctx context.Context, grafana types.ServiceConfig, timeout time.Duration) models.ArgusReceiverCheck {
+	testID := uuid.New().String()
+	check := models.ArgusReceiverCheck{
+		URL: gs.publicURL + "/api/alerting/webhook/" + testID,
+	}
+
+	delivered := gs.receiver.Expect(testID)
+	defer gs.receiver.Forget(testID)
+
+	start := time.Now()
+	integration := map[string]interface{}{
+		"name":     "argus-receiver-check",
+		"type":     "webhook",
+		"settings": map[string]interface{}{"url": check.URL, "httpMethod": "POST"},
+	}
+	status, errMsg := gs.sendTestNotification(ctx, grafana, "argus-receiver-check", integration)
+	if status != "ok" {
+		check.Error = errMsg
+		return check
+	}
+
+	select {
+	case <-delivered:
+		check.Delivered = true
+		check.Latency = time.Since(start)
+	case <-time.After(timeout):
+		check.Error = fmt.Sprintf("no notification received within %s", timeout)
+	case <-ctx.Done():
+		check.Error = ctx.Err().Error()
+	}
+	return check
+}
+
+// sendTestNotification asks Grafana to fire a test alert through one integration
+func (gs *GrafanaAlertingService) sendTestNotification(ctx context.Context, grafana types.ServiceConfig, name string, integration map[string]interface{}) (string, string) {
+	body := map[string]interface{}{
+		"receivers": []map[string]interface{}{
+			{
+				"name":                             name,
+				"grafana_managed_receiver_configs": []map[string]interface{}{integration},
+			},
+		},
+		"alert": map[string]interface{}{
+			"labels": map[string]string{
+				"alertname": "ArgusContactPointTest",
+				"source":    "argus",
+			},
+			"annotations": map[string]string{
+				"summary": "Argus test notification - safe to ignore",
+			},
+		},
+	}
+
+	var result receiverTestResult
+	status, err := grafanaJSON(ctx, gs.client, grafana, "POST", receiversTestPath, body, &result)
+	if err != nil {
+		return "failed", err.Error()
+	}
+	if status != http.StatusOK && status != http.StatusMultiStatus {
+		return "failed", fmt.Sprintf("HTTP %d", status)
+	}
+
+	for _, receiver := range result.Receivers {
+		for _, cfg := range receiver.Configs {
+			if cfg.Status != "ok" {
+				return "failed", cfg.Error
+			}
+		}
+	}
+	return "ok", ""
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestWebhookReceiver(t *testing.T) {
+	wr := NewWebhookReceiver()
+
+	assert.False(t, wr.Deliver("unknown", []byte("{}")))
+
+	ch := wr.Expect("test-1")
+	assert.True(t, wr.Deliver("test-1", []byte(`{"ok":true}`)))
+	assert.True(t, wr.Deliver("test-1", []byte(`{"retry":true}`)), "retries must not block")
+	assert.Equal(t, `{"ok":true}`, string(<-ch))
+
+	wr.Forget("test-1")
+	assert.False(t, wr.Deliver("test-1", nil))
+}
+
+func TestGrafanaAlertingService_Validate(t *testing.T) {
+	gs := NewGrafanaAlertingService()
+
+	// Argus side: forward webhook deliveries into the receiver
+	argus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		gs.Receiver().Deliver(strings.TrimPrefix(r.URL.Path, "/api/alerting/webhook/"), payload)
+	}))
+	defer argus.Close()
+	gs.publicURL = argus.URL
+
+	var tested []string
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/datasources":
+			_ = json.NewEncoder(w).Encode([]map[string]string{{"uid": "prom", "name": "Prometheus"}})
+		case "/api/v1/provisioning/alert-rules":
+			_, _ = w.Write([]byte(`[
+				{"uid": "r1", "title": "Good", "ruleGroup": "g", "data": [{"refId": "A", "datasourceUid": "prom"}, {"refId": "B", "datasourceUid": "__expr__"}]},
+				{"uid": "r2", "title": "Broken", "ruleGroup": "g", "data": [{"refId": "A", "datasourceUid": "deleted-loki"}]}
+			]`))
+		case "/api/v1/provisioning/contact-points":
+			_, _ = w.Write([]byte(`[
+				{"uid": "cp1", "name": "oncall", "type": "webhook", "settings": {"url": "http://hooks.invalid"}},
+				{"uid": "cp2", "name": "slack", "type": "slack", "settings": {"recipient": "#alerts", "url": "[REDACTED]"}},
+				{"name": "pager", "type": "pagerduty", "settings": {"integrationKey": "[REDACTED]"}}
+			]`))
+		case "/api/v1/provisioning/policies":
+			_, _ = w.Write([]byte(`{"receiver": "oncall", "routes": [{"receiver": "slack"}, {"receiver": "ghost", "routes": [{"receiver": "oncall"}]}]}`))
+		case receiversTestPath:
+			var body struct {
+				Receivers []struct {
+					Name    string `json:"name"`
+					Configs []struct {
+						UID          string                 `json:"uid"`
+						Type         string                 `json:"type"`
+						Settings     map[string]interface{} `json:"settings"`
+						SecureFields map[string]bool        `json:"secureFields"`
+					} `json:"grafana_managed_receiver_configs"`
+				} `json:"receivers"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			receiver := body.Receivers[0]
+			tested = append(tested, receiver.Name)
+
+			status := "ok"
+			switch receiver.Name {
+			case "slack":
+				// Redacted secrets are left to Grafana to fill in from the stored integration
+				assert.Equal(t, "cp2", receiver.Configs[0].UID)
+				assert.Equal(t, map[string]interface{}{"recipient": "#alerts"}, receiver.Configs[0].Settings)
+				assert.Equal(t, map[string]bool{"url": true}, receiver.Configs[0].SecureFields)
+				status = "failed"
+			case "argus-receiver-check":
+				resp, err := http.Post(receiver.Configs[0].Settings["url"].(string), "application/json", bytes.NewReader([]byte(`{}`)))
+				require.NoError(t, err)
+				resp.Body.Close()
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"receivers": []map[string]interface{}{{
+					"name": receiver.Name,
+					"grafana_managed_receiver_configs": []map[string]string{{
+						"name": receiver.Name, "status": status, "error": map[bool]string{true: "", false: "invalid token"}[status == "ok"],
+					}},
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer grafana.Close()
+
+	report, err := gs.Validate(context.Background(), types.ServiceConfig{URL: grafana.URL}, 2*time.Second, []string{AllContactPoints})
+	require.NoError(t, err)
+
+	assert.Equal(t, "degraded", report.Status)
+
+	require.Len(t, report.Rules, 2)
+	assert.True(t, report.Rules[0].Valid)
+	assert.Equal(t, []string{"prom"}, report.Rules[0].Datasources)
+	assert.False(t, report.Rules[1].Valid)
+	assert.Equal(t, []string{"deleted-loki"}, report.Rules[1].MissingDatasources)
+
+	assert.Equal(t, "oncall", report.Policies.RootReceiver)
+	assert.Equal(t, 3, report.Policies.Routes)
+	assert.Equal(t, []string{"ghost"}, report.Policies.MissingReceivers)
+
+	require.Len(t, report.ContactPoints, 3)
+	assert.Equal(t, "ok", report.ContactPoints[0].Status)
+	assert.Equal(t, "failed", report.ContactPoints[1].Status)
+	assert.Equal(t, "invalid token", report.ContactPoints[1].Error)
+	assert.Equal(t, "skipped", report.ContactPoints[2].Status)
+
+	assert.True(t, report.Receiver.Delivered, report.Receiver.Error)
+	assert.Equal(t, []string{"oncall", "slack", "argus-receiver-check"}, tested)
+	assert.Len(t, report.Problems, 3)
+
+	// By default no real contact point is notified
+	tested = nil
+	report, err = gs.Validate(context.Background(), types.ServiceConfig{URL: grafana.URL}, 2*time.Second, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"argus-receiver-check"}, tested)
+	for _, test := range report.ContactPoints {
+		assert.Equal(t, "not_tested", test.Status, test.Name)
+	}
+
+	// Contact points are picked by UID or name
+	tested = nil
+	report, err = gs.Validate(context.Background(), types.ServiceConfig{URL: grafana.URL}, 2*time.Second, []string{"cp1", "pager"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"oncall", "argus-receiver-check"}, tested)
+	assert.Equal(t, []string{"ok", "not_tested", "skipped"}, []string{report.ContactPoints[0].Status, report.ContactPoints[1].Status, report.ContactPoints[2].Status})
+}
+
+func TestGrafanaAlertingService_ValidateAuthError(t *testing.T) {
+	grafana := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer grafana.Close()
+
+	gs := NewGrafanaAlertingService()
+	_, err := gs.Validate(context.Background(), types.ServiceConfig{URL: grafana.URL}, time.Second, nil)
+
+	var apiErr *GrafanaAPIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+}
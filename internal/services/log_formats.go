@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// defaultLogFields are the distributions fields are drawn from unless a spec
+// overrides them
+var defaultLogFields = map[string]types.FieldDistribution{
+	"level":       {Values: []string{"info", "warn", "error", "debug"}, Weights: []float64{70, 15, 10, 5}},
+	"service":     {Values: []string{"checkout", "payments", "users", "inventory"}},
+	"host":        {Values: []string{"web-1", "web-2", "web-3"}},
+	"pid":         {Min: 1000, Max: 32000},
+	"message":     {Values: []string{"request completed", "cache miss for session", "retrying upstream call", "user profile updated", "order submitted", "slow query detected"}},
+	"method":      {Values: []string{"GET", "POST", "PUT", "DELETE"}, Weights: []float64{70, 20, 5, 5}},
+	"path":        {Values: []string{"/api/users", "/api/orders", "/api/cart", "/health", "/static/app.js"}},
+	"status":      {Values: []string{"200", "201", "304", "400", "404", "500", "503"}, Weights: []float64{75, 5, 7, 4, 5, 3, 1}},
+	"bytes":       {Min: 200, Max: 50000},
+	"duration_ms": {Mean: 120, StdDev: 80, Min: 1},
+	"user":        {Values: []string{"-", "alice", "bob"}, Weights: []float64{90, 5, 5}},
+	"referer":     {Values: []string{"-", "https://www.example.com/", "https://www.example.com/cart"}, Weights: []float64{60, 25, 15}},
+	"user_agent": {Values: []string{
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+		"curl/8.4.0",
+	}, Weights: []float64{60, 30, 10}},
+	"partial": {Values: []string{"false", "true"}, Weights: []float64{90, 10}},
+	"cause":   {Values: []string{"false", "true"}, Weights: []float64{70, 30}},
+	"depth":   {Min: 3, Max: 8},
+}
+
+// formatLogFields override defaultLogFields for one format
+var formatLogFields = map[string]map[string]types.FieldDistribution{
+	types.LogFormatJavaStack: {"exception": {Values: []string{
+		"java.lang.NullPointerException: Cannot invoke \"Order.getId()\" because \"order\" is null",
+		"java.lang.IllegalStateException: Order is already paid",
+		"java.util.concurrent.TimeoutException: Upstream did not answer within 5000 ms",
+	}}},
+	types.LogFormatPythonStack: {"exception": {Values: []string{
+		"KeyError: 'order_id'",
+		"ValueError: invalid literal for int() with base 10: 'abc'",
+		"ConnectionRefusedError: [Errno 111] Connection refused",
+	}}},
+	types.LogFormatGoStack: {"exception": {Values: []string{
+		"runtime error: invalid memory address or nil pointer dereference",
+		"runtime error: index out of range [5] with length 3",
+		"assignment to entry in nil map",
+	}}},
+}
+
+// Frames stack traces are assembled from, per language
+var (
+	javaFrames = []string{
+		"com.example.checkout.OrderService.pay(OrderService.java:%d)",
+		"com.example.checkout.OrderController.submit(OrderController.java:%d)",
+		"com.example.payments.PaymentClient.charge(PaymentClient.java:%d)",
+		"org.springframework.web.servlet.FrameworkServlet.service(FrameworkServlet.java:%d)",
+		"jakarta.servlet.http.HttpServlet.service(HttpServlet.java:%d)",
+		"org.apache.catalina.core.ApplicationFilterChain.doFilter(ApplicationFilterChain.java:%d)",
+		"org.apache.tomcat.util.threads.ThreadPoolExecutor.runWorker(ThreadPoolExecutor.java:%d)",
+		"java.base/java.lang.Thread.run(Thread.java:%d)",
+	}
+	pythonFrames = [][2]string{
+		{"/app/checkout/views.py\", line %d, in submit", "order = Order.objects.get(id=request.POST['order_id'])"},
+		{"/app/checkout/services.py\", line %d, in pay", "return client.charge(order.total)"},
+		{"/app/payments/client.py\", line %d, in charge", "response = self.session.post(url, json=payload)"},
+		{"/usr/local/lib/python3.12/site-packages/django/core/handlers/base.py\", line %d, in _get_response", "response = wrapped_callback(request, *callback_args, **callback_kwargs)"},
+		{"/usr/local/lib/python3.12/site-packages/requests/sessions.py\", line %d, in post", "return self.request(\"POST\", url, data=data, json=json, **kwargs)"},
+	}
+	goFrames = []string{
+		"github.com/example/shop/internal/orders.(*Service).Pay(0x0, {0xc0001a2000, 0x24})\n\t/app/internal/orders/service.go:%d +0x1d",
+		"github.com/example/shop/internal/api.(*Handler).Submit(0xc000118000, {0x9a1b20, 0xc0002c4000}, 0xc0002b6100)\n\t/app/internal/api/orders.go:%d +0x8f",
+		"net/http.HandlerFunc.ServeHTTP(0xc0002b6000, {0x9a1b20, 0xc0002c4000}, 0xc0002b6100)\n\t/usr/local/go/src/net/http/server.go:%d +0x29",
+		"net/http.(*ServeMux).ServeHTTP(0xc0000a8080, {0x9a1b20, 0xc0002c4000}, 0xc0002b6100)\n\t/usr/local/go/src/net/http/server.go:%d +0x1c4",
+		"net/http.serverHandler.ServeHTTP({0xc0000b2000}, {0x9a1b20, 0xc0002c4000}, 0xc0002b6100)\n\t/usr/local/go/src/net/http/server.go:%d +0x8e",
+		"created by net/http.(*Server).Serve in goroutine 1\n\t/usr/local/go/src/net/http/server.go:%d +0x5c4",
+	}
+)
+
+// LogFormatGenerator writes log entries in one of the supported formats,
+// drawing every field from its distribution
+type LogFormatGenerator struct {
+	spec   types.LogFormatSpec
+	rng    *rand.Rand
+	fields map[string]types.FieldDistribution
+	format func(g *LogFormatGenerator, now time.Time) string
+	sleep  func(ctx context.Context, d time.Duration) error
+}
+
+// NewLogFormatGenerator validates a spec and prepares its generator. A random
+// seed is picked when the spec has none.
+func NewLogFormatGenerator(spec types.LogFormatSpec) (*LogFormatGenerator, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if spec.Seed == 0 {
+		spec.Seed = rand.Int63()
+	}
+
+	fields := make(map[string]types.FieldDistribution, len(defaultLogFields))
+	for name, field := range defaultLogFields {
+		fields[name] = field
+	}
+	for name, field := range formatLogFields[spec.Format] {
+		fields[name] = field
+	}
+	for name, field := range spec.Fields {
+		fields[name] = field
+	}
+
+	return &LogFormatGenerator{
+		spec:   spec,
+		rng:    rand.New(rand.NewSource(spec.Seed)),
+		fields: fields,
+		format: logFormatters[spec.Format],
+		sleep:  sleepContext,
+	}, nil
+}
+
+// Next returns the next entry, without a trailing newline
+func (g *LogFormatGenerator) Next(now time.Time) string {
+	return g.format(g, now)
+}
+
+// Run writes the spec's entries to out, one Write per entry so that
+// multi-line entries stay together, pacing them at the spec's rate
+func (g *LogFormatGenerator) Run(ctx context.Context, out io.Writer) *models.LogFormatReport {
+	report := &models.LogFormatReport{
+		Format:    g.spec.Format,
+		Requested: g.spec.Count,
+		Rate:      g.spec.Rate,
+		Seed:      g.spec.Seed,
+		Samples:   []string{},
+		Timestamp: time.Now(),
+	}
+
+	start := time.Now()
+	for i := 0; i < g.spec.Count; i++ {
+		if g.spec.Rate > 0 {
+			due := start.Add(time.Duration(float64(i) / g.spec.Rate * float64(time.Second)))
+			if err := g.sleep(ctx, time.Until(due)); err != nil {
+				report.Error = err.Error()
+				break
+			}
+		}
+
+		entry := g.Next(time.Now())
+		n, err := io.WriteString(out, entry+"\n")
+		report.Bytes += int64(n)
+		if err != nil {
+			report.Error = err.Error()
+			break
+		}
+		report.Entries++
+		report.Lines += strings.Count(entry, "\n") + 1
+		if len(report.Samples) < 3 {
+			report.Samples = append(report.Samples, entry)
+		}
+	}
+	report.Elapsed = time.Since(start)
+	return report
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// field draws a value for the named field
+func (g *LogFormatGenerator) field(name string) string {
+	d, ok := g.fields[name]
+	if !ok {
+		return "-"
+	}
+	if len(d.Values) > 0 {
+		return d.Values[g.pick(d.Weights, len(d.Values))]
+	}
+
+	var value float64
+	if d.StdDev > 0 {
+		value = d.Mean + g.rng.NormFloat64()*d.StdDev
+	} else {
+		value = d.Min + g.rng.Float64()*(d.Max-d.Min)
+	}
+	value = math.Max(value, d.Min)
+	return strconv.FormatFloat(value, 'f', d.Decimals, 64)
+}
+
+// intField draws a numeric field as an integer
+func (g *LogFormatGenerator) intField(name string) int {
+	value, _ := strconv.ParseFloat(g.field(name), 64)
+	return int(value)
+}
+
+// pick returns an index into n values, by weight when weights are given
+func (g *LogFormatGenerator) pick(weights []float64, n int) int {
+	if len(weights) == 0 {
+		return g.rng.Intn(n)
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	r := g.rng.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			return i
+		}
+		r -= w
+	}
+	return n - 1
+}
+
+func (g *LogFormatGenerator) clientIP() string {
+	return fmt.Sprintf("10.%d.%d.%d", g.rng.Intn(256), g.rng.Intn(256), g.rng.Intn(254)+1)
+}
+
+// logFormatters render one entry per format
+var logFormatters = map[string]func(g *LogFormatGenerator, now time.Time) string{
+	types.LogFormatLogfmt: func(g *LogFormatGenerator, now time.Time) string {
+		return g.logfmtLine(now)
+	},
+	types.LogFormatSyslog5424: func(g *LogFormatGenerator, now time.Time) string {
+		level := g.field("level")
+		return fmt.Sprintf(`<%d>1 %s %s %s %d - [argus@32473 method="%s" path="%s" status="%s" duration_ms="%s"] %s`,
+			syslogPriority(level), now.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), g.field("host"), g.field("service"), g.intField("pid"),
+			syslogParam(g.field("method")), syslogParam(g.field("path")), syslogParam(g.field("status")), syslogParam(g.field("duration_ms")), g.field("message"))
+	},
+	types.LogFormatSyslog3164: func(g *LogFormatGenerator, now time.Time) string {
+		level := g.field("level")
+		return fmt.Sprintf("<%d>%s %s %s[%d]: %s", syslogPriority(level), now.Format(time.Stamp), g.field("host"), g.field("service"), g.intField("pid"), g.field("message"))
+	},
+	types.LogFormatCommon: func(g *LogFormatGenerator, now time.Time) string {
+		return g.commonLine(now)
+	},
+	types.LogFormatCombined: func(g *LogFormatGenerator, now time.Time) string {
+		return fmt.Sprintf(`%s "%s" "%s"`, g.commonLine(now), g.field("referer"), g.field("user_agent"))
+	},
+	types.LogFormatKlog: func(g *LogFormatGenerator, now time.Time) string {
+		severity := map[string]string{"warn": "W", "error": "E"}[g.field("level")]
+		if severity == "" {
+			severity = "I"
+		}
+		return fmt.Sprintf(`%s%s %7d %s:%d] %q service=%q duration_ms=%s`,
+			severity, now.Format("0102 15:04:05.000000"), g.intField("pid"), "handler.go", 20+g.rng.Intn(300), g.field("message"), g.field("service"), g.field("duration_ms"))
+	},
+	types.LogFormatCRI: func(g *LogFormatGenerator, now time.Time) string {
+		line := g.logfmtLine(now)
+		stream := "stdout"
+		if strings.Contains(line, "level=error") {
+			stream = "stderr"
+		}
+		timestamp := now.UTC().Format(time.RFC3339Nano)
+		if g.field("partial") == "true" {
+			// The runtime splits long lines; P marks every chunk but the last
+			half := len(line) / 2
+			return fmt.Sprintf("%s %s P %s\n%s %s F %s", timestamp, stream, line[:half], timestamp, stream, line[half:])
+		}
+		return fmt.Sprintf("%s %s F %s", timestamp, stream, line)
+	},
+	types.LogFormatJavaStack: func(g *LogFormatGenerator, now time.Time) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s ERROR [http-nio-8080-exec-%d] com.example.%s.RequestHandler - %s\n%s",
+			now.Format("2006-01-02 15:04:05.000"), g.rng.Intn(20)+1, g.field("service"), g.field("message"), g.field("exception"))
+		depth := g.intField("depth")
+		for _, frame := range g.frames(len(javaFrames), depth) {
+			fmt.Fprintf(&b, "\n\tat "+javaFrames[frame], 20+g.rng.Intn(400))
+		}
+		if g.field("cause") == "true" {
+			b.WriteString("\nCaused by: java.sql.SQLTransientConnectionException: HikariPool-1 - Connection is not available, request timed out after 30000ms.")
+			fmt.Fprintf(&b, "\n\tat com.zaxxer.hikari.pool.HikariPool.getConnection(HikariPool.java:%d)", 150+g.rng.Intn(50))
+			fmt.Fprintf(&b, "\n\t... %d more", depth)
+		}
+		return b.String()
+	},
+	types.LogFormatPythonStack: func(g *LogFormatGenerator, now time.Time) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s,%03d ERROR [%s] %s\nTraceback (most recent call last):",
+			now.Format("2006-01-02 15:04:05"), now.Nanosecond()/int(time.Millisecond), g.field("service"), g.field("message"))
+		for _, frame := range g.frames(len(pythonFrames), g.intField("depth")) {
+			fmt.Fprintf(&b, "\n  File \""+pythonFrames[frame][0]+"\n    %s", 10+g.rng.Intn(400), pythonFrames[frame][1])
+		}
+		b.WriteString("\n" + g.field("exception"))
+		return b.String()
+	},
+	types.LogFormatGoStack: func(g *LogFormatGenerator, now time.Time) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "panic: %s\n\ngoroutine %d [running]:", g.field("exception"), g.rng.Intn(500)+1)
+		for _, frame := range g.frames(len(goFrames), g.intField("depth")) {
+			fmt.Fprintf(&b, "\n"+goFrames[frame], 20+g.rng.Intn(3000))
+		}
+		b.WriteString("\nexit status 2")
+		return b.String()
+	},
+}
+
+// frames returns depth frame indexes from a pool of n, innermost first and in order
+func (g *LogFormatGenerator) frames(n, depth int) []int {
+	if depth > n {
+		depth = n
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	start := g.rng.Intn(n - depth + 1)
+	frames := make([]int, depth)
+	for i := range frames {
+		frames[i] = start + i
+	}
+	return frames
+}
+
+func (g *LogFormatGenerator) logfmtLine(now time.Time) string {
+	pairs := []string{"time=" + now.UTC().Format(time.RFC3339Nano)}
+	for _, name := range []string{"level", "service", "host", "method", "path", "status", "duration_ms"} {
+		pairs = append(pairs, name+"="+logfmtValue(g.field(name)))
+	}
+	pairs = append(pairs, "msg="+strconv.Quote(g.field("message")))
+	return strings.Join(pairs, " ")
+}
+
+// logfmtValue quotes values that would otherwise break the key=value pairs
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " =\"\t") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func (g *LogFormatGenerator) commonLine(now time.Time) string {
+	return fmt.Sprintf(`%s - %s [%s] "%s %s HTTP/1.1" %s %d`,
+		g.clientIP(), g.field("user"), now.Format("02/Jan/2006:15:04:05 -0700"), g.field("method"), g.field("path"), g.field("status"), g.intField("bytes"))
+}
+
+// syslogPriority encodes the local0 facility with the level's severity
+func syslogPriority(level string) int {
+	return 16*8 + syslogSeverity(level)
+}
+
+// syslogSeverity maps a level to its syslog severity, informational by default
+func syslogSeverity(level string) int {
+	severity := map[string]int{"debug": 7, "info": 6, "warn": 4, "error": 3}[level]
+	if severity == 0 {
+		severity = 6
+	}
+	return severity
+}
+
+// syslogParam escapes an RFC 5424 structured data parameter value
+func syslogParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
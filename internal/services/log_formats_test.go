@@ -0,0 +1,99 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestLogFormatGenerator_Formats(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 123456789, time.UTC)
+	// Each pattern is what a Promtail/Alloy stage for the format would expect
+	patterns := map[string]*regexp.Regexp{
+		types.LogFormatLogfmt:      regexp.MustCompile(`^time=2024-01-02T15:04:05.123456789Z level=(info|warn|error|debug) service=\w+ host=web-\d method=[A-Z]+ path=/\S* status=\d{3} duration_ms=\d+ msg="[^"]+"$`),
+		types.LogFormatSyslog5424:  regexp.MustCompile(`^<1(31|32|34|35)>1 2024-01-02T15:04:05.123456Z web-\d \w+ \d+ - \[argus@32473 method="[A-Z]+" path="/\S*" status="\d{3}" duration_ms="\d+"\] .+$`),
+		types.LogFormatSyslog3164:  regexp.MustCompile(`^<1(31|32|34|35)>Jan  2 15:04:05 web-\d \w+\[\d+\]: .+$`),
+		types.LogFormatCommon:      regexp.MustCompile(`^10(\.\d{1,3}){3} - \S+ \[02/Jan/2024:15:04:05 \+0000\] "[A-Z]+ /\S* HTTP/1.1" \d{3} \d+$`),
+		types.LogFormatCombined:    regexp.MustCompile(`^10(\.\d{1,3}){3} - \S+ \[02/Jan/2024:15:04:05 \+0000\] "[A-Z]+ /\S* HTTP/1.1" \d{3} \d+ "[^"]*" "[^"]+"$`),
+		types.LogFormatKlog:        regexp.MustCompile(`^[IWE]0102 15:04:05.123456 +\d+ handler.go:\d+\] "[^"]+" service="\w+" duration_ms=\d+$`),
+		types.LogFormatCRI:         regexp.MustCompile(`^(2024-01-02T15:04:05.123456789Z std(out|err) P .+\n)?2024-01-02T15:04:05.123456789Z std(out|err) F .+$`),
+		types.LogFormatJavaStack:   regexp.MustCompile(`^2024-01-02 15:04:05.123 ERROR \[http-nio-8080-exec-\d+\] com\.example\.\w+\.RequestHandler - .+\njava\.[\w.]+: .+(\n\tat [\w./$]+\([\w.]+:\d+\))+(\nCaused by: .+\n\tat .+\n\t\.\.\. \d+ more)?$`),
+		types.LogFormatPythonStack: regexp.MustCompile(`^2024-01-02 15:04:05,123 ERROR \[\w+\] .+\nTraceback \(most recent call last\):(\n  File "[^"]+", line \d+, in \w+\n    .+)+\n\w+Error: .+$`),
+		types.LogFormatGoStack:     regexp.MustCompile(`^panic: .+\n\ngoroutine \d+ \[running\]:(\n.+\n\t/\S+\.go:\d+ \+0x[0-9a-f]+)+\nexit status 2$`),
+	}
+	require.Len(t, patterns, len(types.LogFormats))
+
+	for _, format := range types.LogFormats {
+		t.Run(format, func(t *testing.T) {
+			g, err := NewLogFormatGenerator(types.LogFormatSpec{Format: format, Count: 1, Seed: 11})
+			require.NoError(t, err)
+			for i := 0; i < 50; i++ {
+				entry := g.Next(now)
+				assert.Regexp(t, patterns[format], entry)
+			}
+		})
+	}
+}
+
+func TestLogFormatGenerator_FieldDistributions(t *testing.T) {
+	spec := types.LogFormatSpec{Format: types.LogFormatLogfmt, Count: 1, Seed: 5, Fields: map[string]types.FieldDistribution{
+		"level":       {Values: []string{"error", "info"}, Weights: []float64{1, 0}},
+		"path":        {Values: []string{"/api/search items"}},
+		"duration_ms": {Min: 500, Max: 1000, Decimals: 2},
+	}}
+	g, err := NewLogFormatGenerator(spec)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		entry := g.Next(time.Now())
+		assert.Contains(t, entry, "level=error ")
+		assert.Contains(t, entry, `path="/api/search items" `, "values with spaces are quoted")
+		assert.Regexp(t, `duration_ms=(5\d\d|6\d\d|7\d\d|8\d\d|9\d\d)\.\d\d `, entry)
+	}
+
+	// The same seed repeats the same entries
+	now := time.Now()
+	a, _ := NewLogFormatGenerator(spec)
+	b, _ := NewLogFormatGenerator(spec)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, a.Next(now), b.Next(now))
+	}
+}
+
+func TestLogFormatGenerator_Run(t *testing.T) {
+	g, err := NewLogFormatGenerator(types.LogFormatSpec{Format: types.LogFormatGoStack, Count: 5, Rate: 100})
+	require.NoError(t, err)
+	var waits []time.Duration
+	g.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	var out bytes.Buffer
+	report := g.Run(context.Background(), &out)
+
+	assert.Empty(t, report.Error)
+	assert.Equal(t, 5, report.Entries)
+	assert.Equal(t, strings.Count(out.String(), "\n"), report.Lines)
+	assert.Equal(t, int64(out.Len()), report.Bytes)
+	assert.Len(t, report.Samples, 3)
+	assert.NotZero(t, report.Seed)
+	assert.Equal(t, 5, strings.Count(out.String(), "panic: "))
+	require.Len(t, waits, 5)
+	assert.InDelta(t, 40*time.Millisecond, waits[4], float64(10*time.Millisecond), "entries are spaced at the rate")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, _ = NewLogFormatGenerator(types.LogFormatSpec{Format: types.LogFormatKlog, Count: 5, Rate: 1})
+	report = g.Run(ctx, &out)
+	assert.Equal(t, 0, report.Entries)
+	assert.Equal(t, "context canceled", report.Error)
+}
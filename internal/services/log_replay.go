@@ -0,0 +1,418 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// ErrReplayNotFound is returned for operations on an unknown replay ID
+var ErrReplayNotFound = errors.New("replay not found")
+
+// layoutElements maps Go layout elements to the text they match, longest first
+var layoutElements = []struct {
+	element string
+	pattern string
+}{
+	{"January", `[A-Z][a-z]+`}, {"Monday", `[A-Z][a-z]+`},
+	{"Z07:00", `(?:Z|[+-]\d{2}:\d{2})`}, {"Z0700", `(?:Z|[+-]\d{4})`},
+	{"-07:00", `[+-]\d{2}:\d{2}`}, {"-0700", `[+-]\d{4}`},
+	{"2006", `\d{4}`}, {"Jan", `[A-Z][a-z]{2}`}, {"Mon", `[A-Z][a-z]{2}`}, {"MST", `[A-Z]{2,5}`},
+	{"-07", `[+-]\d{2}`}, {"_2", `[ \d]\d`},
+	{"01", `\d{2}`}, {"02", `\d{2}`}, {"03", `\d{2}`}, {"04", `\d{2}`}, {"05", `\d{2}`}, {"06", `\d{2}`}, {"15", `\d{2}`},
+	{"PM", `[AP]M`}, {"pm", `[ap]m`},
+	{"1", `\d{1,2}`}, {"2", `\d{1,2}`}, {"3", `\d{1,2}`}, {"4", `\d{1,2}`}, {"5", `\d{1,2}`},
+}
+
+// layoutPattern builds a regular expression that finds timestamps written
+// with a Go time layout
+func layoutPattern(layout string) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+	elements := 0
+	for i := 0; i < len(layout); {
+		// Fractional seconds: ,000 or .000 are fixed width, .999 is optional
+		if (layout[i] == '.' || layout[i] == ',') && i+1 < len(layout) && (layout[i+1] == '0' || layout[i+1] == '9') {
+			j := i + 1
+			for j < len(layout) && layout[j] == layout[i+1] {
+				j++
+			}
+			if j == len(layout) || layout[j] < '0' || layout[j] > '9' {
+				if layout[i+1] == '0' {
+					fmt.Fprintf(&pattern, `[.,]\d{%d}`, j-i-1)
+				} else {
+					pattern.WriteString(`(?:[.,]\d+)?`)
+				}
+				i = j
+				continue
+			}
+		}
+
+		matched := false
+		for _, e := range layoutElements {
+			if strings.HasPrefix(layout[i:], e.element) {
+				pattern.WriteString(e.pattern)
+				i += len(e.element)
+				elements++
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			pattern.WriteString(regexp.QuoteMeta(layout[i : i+1]))
+			i++
+		}
+	}
+	if elements == 0 {
+		return nil, fmt.Errorf("timestamp_layout %q has no date or time elements", layout)
+	}
+	return regexp.Compile(pattern.String())
+}
+
+// LogReplayService replays captured log files into log sinks in the
+// background, one goroutine per replay
+type LogReplayService struct {
+	sinks *LogSinkRegistry
+	dir   string
+
+	mu      sync.Mutex
+	replays map[string]*logReplay
+
+	// sleep and now are replaced in tests to replay without waiting
+	sleep func(ctx context.Context, d time.Duration) error
+	now   func() time.Time
+}
+
+// logReplay is a replay's state and the cancel func of its goroutine
+type logReplay struct {
+	state  models.LogReplay
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLogReplayService creates a replay service writing to sinks. Replayed
+// files must be inside dir and relative paths are resolved against it;
+// without a dir every replay is refused.
+func NewLogReplayService(sinks *LogSinkRegistry, dir string) *LogReplayService {
+	return &LogReplayService{
+		sinks:   sinks,
+		dir:     dir,
+		replays: make(map[string]*logReplay),
+		sleep:   sleepContext,
+		now:     time.Now,
+	}
+}
+
+// Start validates the spec, opens the file and replays it in the background
+func (rs *LogReplayService) Start(spec types.LogReplaySpec) (models.LogReplay, error) {
+	if err := spec.Validate(); err != nil {
+		return models.LogReplay{}, err
+	}
+	if spec.Sink == "" {
+		spec.Sink = types.LogSinkStdout
+	}
+	if spec.TimestampLayout != "" && spec.Speed == 0 {
+		spec.Speed = 1
+	}
+	sink, ok := rs.sinks.Get(spec.Sink)
+	if !ok {
+		return models.LogReplay{}, fmt.Errorf("unknown log sink %q", spec.Sink)
+	}
+
+	var pattern *regexp.Regexp
+	if layout := spec.Layout(); layout != "" {
+		var err error
+		if pattern, err = layoutPattern(layout); err != nil {
+			return models.LogReplay{}, err
+		}
+	}
+
+	path, err := rs.resolve(spec.Path)
+	if err != nil {
+		return models.LogReplay{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return models.LogReplay{}, err
+	}
+	reader, compressed, err := logReader(file)
+	if err != nil {
+		file.Close()
+		return models.LogReplay{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	replay := &logReplay{
+		state: models.LogReplay{
+			ID:              uuid.New().String(),
+			Path:            path,
+			Sink:            spec.Sink,
+			Labels:          spec.Labels,
+			TimestampLayout: spec.TimestampLayout,
+			Speed:           spec.Speed,
+			Status:          "running",
+			Compressed:      compressed,
+			StartedAt:       rs.now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	rs.mu.Lock()
+	rs.replays[replay.state.ID] = replay
+	state := replay.state
+	rs.mu.Unlock()
+
+	replayer := &logReplayer{spec: spec, layout: spec.Layout(), pattern: pattern, sink: sink, sleep: rs.sleep, now: rs.now}
+	go func() {
+		defer close(replay.done)
+		defer file.Close()
+		err := replayer.run(ctx, reader, func(update func(*models.LogReplay)) {
+			rs.mu.Lock()
+			defer rs.mu.Unlock()
+			update(&replay.state)
+		})
+
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		finished := rs.now()
+		replay.state.FinishedAt = &finished
+		replay.state.Elapsed = finished.Sub(replay.state.StartedAt)
+		switch {
+		case ctx.Err() != nil:
+			replay.state.Status = "stopped"
+		case err != nil:
+			replay.state.Status = "failed"
+			replay.state.Error = err.Error()
+		default:
+			replay.state.Status = "completed"
+		}
+	}()
+	return state, nil
+}
+
+// Get returns one replay
+func (rs *LogReplayService) Get(id string) (models.LogReplay, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	replay, ok := rs.replays[id]
+	if !ok {
+		return models.LogReplay{}, ErrReplayNotFound
+	}
+	return rs.snapshot(replay), nil
+}
+
+// List returns every replay, newest first
+func (rs *LogReplayService) List() []models.LogReplay {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	list := make([]models.LogReplay, 0, len(rs.replays))
+	for _, replay := range rs.replays {
+		list = append(list, rs.snapshot(replay))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	return list
+}
+
+// Remove stops a running replay, which is then kept with status "stopped",
+// or forgets a finished one
+func (rs *LogReplayService) Remove(id string) error {
+	rs.mu.Lock()
+	replay, ok := rs.replays[id]
+	if ok && replay.state.Status != "running" {
+		delete(rs.replays, id)
+	}
+	rs.mu.Unlock()
+	if !ok {
+		return ErrReplayNotFound
+	}
+	replay.cancel()
+	<-replay.done
+	return nil
+}
+
+func (rs *LogReplayService) snapshot(replay *logReplay) models.LogReplay {
+	state := replay.state
+	if state.Status == "running" {
+		state.Elapsed = rs.now().Sub(state.StartedAt)
+	}
+	return state
+}
+
+// resolve applies the replay directory to path
+func (rs *LogReplayService) resolve(path string) (string, error) {
+	if rs.dir == "" {
+		return "", fmt.Errorf("log replays are disabled: set ARGUS_LOG_REPLAY_DIR to the directory holding the files")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(rs.dir, path)
+	}
+	relative, err := filepath.Rel(rs.dir, filepath.Clean(path))
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path must be inside %s", rs.dir)
+	}
+	return filepath.Clean(path), nil
+}
+
+// logReader returns a reader of the file's lines, decompressing it when it
+// starts with the gzip magic bytes
+func logReader(file io.Reader) (io.Reader, bool, error) {
+	buffered := bufio.NewReaderSize(file, 64*1024)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, true, err
+		}
+		return decompressed, true, nil
+	}
+	return buffered, false, nil
+}
+
+// logReplayer streams entries from a log file into a sink
+type logReplayer struct {
+	spec    types.LogReplaySpec
+	layout  string
+	pattern *regexp.Regexp
+	sink    LogSink
+	sleep   func(ctx context.Context, d time.Duration) error
+	now     func() time.Time
+}
+
+// replayEntry is an entry being collected from the file: its lines and,
+// when its first line has a timestamp, where it is and what it says
+type replayEntry struct {
+	lines    []string
+	stamp    []int
+	original time.Time
+}
+
+// run replays r until it ends or ctx is cancelled. Each entry is due at
+// the replay start plus its original offset from the first timestamp,
+// divided by the speed and with long pauses cut to max_gap_seconds; its
+// timestamp is rewritten to that time.
+func (lr *logReplayer) run(ctx context.Context, r io.Reader, update func(func(*models.LogReplay))) error {
+	start := lr.now()
+	maxGap := time.Duration(lr.spec.MaxGapSeconds * float64(time.Second))
+	var offset time.Duration
+	var previous time.Time
+
+	send := func(entry *replayEntry) error {
+		for len(entry.lines) > 1 && entry.lines[len(entry.lines)-1] == "" {
+			entry.lines = entry.lines[:len(entry.lines)-1]
+		}
+		if len(entry.lines) == 0 {
+			return nil
+		}
+		at := lr.now()
+		line := strings.Join(entry.lines, "\n")
+		rewritten := entry.stamp != nil
+		if rewritten {
+			if !previous.IsZero() {
+				gap := time.Duration(float64(entry.original.Sub(previous)) / lr.spec.Speed)
+				if gap < 0 {
+					gap = 0
+				}
+				if maxGap > 0 && gap > maxGap {
+					gap = maxGap
+				}
+				offset += gap
+			}
+			previous = entry.original
+
+			at = start.Add(offset)
+			if wait := at.Sub(lr.now()); wait > 0 {
+				if err := lr.sleep(ctx, wait); err != nil {
+					return err
+				}
+			}
+			first := entry.lines[0]
+			line = first[:entry.stamp[0]] + at.In(entry.original.Location()).Format(lr.layout) + line[entry.stamp[1]:]
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := writeEntryWait(ctx, lr.sink, SinkEntry{Time: at, Line: line, Labels: lr.spec.Labels})
+		update(func(state *models.LogReplay) {
+			state.Entries++
+			state.Lines += int64(len(entry.lines))
+			state.Bytes += int64(len(line))
+			if rewritten {
+				state.Rewritten++
+				original := entry.original
+				if state.FirstTimestamp == nil {
+					state.FirstTimestamp = &original
+				}
+				state.LastTimestamp = &original
+				state.OriginalSpan = original.Sub(*state.FirstTimestamp)
+			}
+		})
+		return err
+	}
+
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var pending replayEntry
+	for {
+		text, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		if text != "" || readErr == nil {
+			line := strings.TrimRight(text, "\r\n")
+			stamp, original, ok := lr.timestamp(line)
+			switch {
+			case line == "" && pending.stamp == nil:
+				// Blank lines are kept only inside an entry
+			case lr.pattern == nil || ok:
+				// A line without a layout, or with a timestamp, starts an entry
+				if err := send(&pending); err != nil {
+					return err
+				}
+				pending = replayEntry{lines: []string{line}}
+				if ok {
+					pending.stamp, pending.original = stamp, original
+				}
+			case pending.stamp != nil:
+				// Continuation of a timestamped entry, such as a stack frame
+				pending.lines = append(pending.lines, line)
+			default:
+				if err := send(&pending); err != nil {
+					return err
+				}
+				pending = replayEntry{lines: []string{line}}
+			}
+		}
+		if readErr == io.EOF {
+			return send(&pending)
+		}
+	}
+}
+
+// timestamp finds and parses the first timestamp in line
+func (lr *logReplayer) timestamp(line string) ([]int, time.Time, bool) {
+	if lr.pattern == nil {
+		return nil, time.Time{}, false
+	}
+	for _, loc := range lr.pattern.FindAllStringIndex(line, 3) {
+		parsed, err := time.ParseInLocation(lr.layout, line[loc[0]:loc[1]], time.Local)
+		if err == nil {
+			return loc, parsed, true
+		}
+	}
+	return nil, time.Time{}, false
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeReplayClock makes a replay service sleep by moving its clock forward
+func fakeReplayClock(rs *LogReplayService, start time.Time) {
+	var mu sync.Mutex
+	now := start
+	rs.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	rs.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+		return ctx.Err()
+	}
+}
+
+func waitReplay(t *testing.T, rs *LogReplayService, id string) models.LogReplay {
+	t.Helper()
+	var replay models.LogReplay
+	require.Eventually(t, func() bool {
+		var err error
+		replay, err = rs.Get(id)
+		require.NoError(t, err)
+		return replay.Status != "running"
+	}, 5*time.Second, 5*time.Millisecond)
+	return replay
+}
+
+func TestLogReplayService_RewritesTimestamps(t *testing.T) {
+	dir := t.TempDir()
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte(strings.Join([]string{
+		"2024-03-01T10:00:00Z INFO starting",
+		"2024-03-01T10:00:04Z ERROR panic: boom",
+		"goroutine 1 [running]:",
+		"\tmain.main()",
+		"",
+		"2024-03-01T10:01:00Z INFO recovered",
+	}, "\n")))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "incident.log.gz"), compressed.Bytes(), 0o644))
+
+	var stdout bytes.Buffer
+	rs := NewLogReplayService(NewLogSinkRegistry(&stdout), dir)
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fakeReplayClock(rs, start)
+
+	replay, err := rs.Start(types.LogReplaySpec{Path: "incident.log.gz", TimestampLayout: "rfc3339", Speed: 2, MaxGapSeconds: 3})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "incident.log.gz"), replay.Path)
+	assert.Equal(t, "stdout", replay.Sink)
+	assert.True(t, replay.Compressed)
+
+	replay = waitReplay(t, rs, replay.ID)
+	assert.Equal(t, "completed", replay.Status)
+	// 4s at speed 2 is 2s, and 56s at speed 2 is cut to the 3s max gap
+	assert.Equal(t, strings.Join([]string{
+		"2026-01-01T12:00:00Z INFO starting",
+		"2026-01-01T12:00:02Z ERROR panic: boom",
+		"goroutine 1 [running]:",
+		"\tmain.main()",
+		"2026-01-01T12:00:05Z INFO recovered",
+	}, "\n")+"\n", stdout.String())
+	assert.Equal(t, int64(3), replay.Entries)
+	assert.Equal(t, int64(5), replay.Lines)
+	assert.Equal(t, int64(3), replay.Rewritten)
+	assert.Equal(t, time.Minute, replay.OriginalSpan)
+	assert.Equal(t, 5*time.Second, replay.Elapsed)
+	require.NotNil(t, replay.FirstTimestamp)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), replay.FirstTimestamp.UTC())
+}
+
+func TestLogReplayService_Layouts(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	require.NoError(t, os.WriteFile(path, []byte(
+		`10.0.0.1 - - [01/Mar/2024:10:00:00 +0100] "GET / HTTP/1.1" 200 12`+"\n"+
+			`10.0.0.2 - - [01/Mar/2024:10:00:01 +0100] "GET /health HTTP/1.1" 200 2`+"\n"), 0o644))
+
+	var stdout bytes.Buffer
+	_, err := NewLogReplayService(NewLogSinkRegistry(&stdout), "").Start(types.LogReplaySpec{Path: path})
+	assert.ErrorContains(t, err, "ARGUS_LOG_REPLAY_DIR", "replays are refused without a replay directory")
+
+	rs := NewLogReplayService(NewLogSinkRegistry(&stdout), dir)
+	fakeReplayClock(rs, time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	replay, err := rs.Start(types.LogReplaySpec{Path: path, TimestampLayout: "common"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, replay.Speed)
+	assert.Equal(t, "completed", waitReplay(t, rs, replay.ID).Status)
+	// Timestamps keep the file's zone
+	assert.Equal(t,
+		`10.0.0.1 - - [01/Jan/2026:13:00:00 +0100] "GET / HTTP/1.1" 200 12`+"\n"+
+			`10.0.0.2 - - [01/Jan/2026:13:00:01 +0100] "GET /health HTTP/1.1" 200 2`+"\n", stdout.String())
+
+	// Without a layout every line is an entry, replayed unchanged
+	stdout.Reset()
+	replay, err = rs.Start(types.LogReplaySpec{Path: path})
+	require.NoError(t, err)
+	replay = waitReplay(t, rs, replay.ID)
+	assert.Equal(t, int64(2), replay.Entries)
+	assert.Zero(t, replay.Rewritten)
+	assert.Contains(t, stdout.String(), "[01/Mar/2024:10:00:01 +0100]")
+
+	for name, sample := range map[string]string{
+		"rfc3339":  "2024-03-01T10:00:00.123456Z",
+		"datetime": "2024-03-01 10:00:00",
+		"common":   "01/Mar/2024:10:00:00 -0700",
+		"syslog":   "Mar  1 10:00:00",
+		"klog":     "0301 10:00:00.123456",
+	} {
+		layout := types.ReplayTimestampLayouts[name]
+		pattern, err := layoutPattern(layout)
+		require.NoError(t, err, name)
+		assert.Equal(t, sample, pattern.FindString("prefix "+sample+" suffix"), name)
+	}
+	_, err = layoutPattern("no elements")
+	assert.Error(t, err)
+}
+
+func TestLogReplayService_StopAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "slow.log"), []byte(
+		"2024-03-01 10:00:00 first\n2024-03-01 11:00:00 an hour later\n"), 0o644))
+
+	var stdout bytes.Buffer
+	rs := NewLogReplayService(NewLogSinkRegistry(&stdout), dir)
+	rs.sleep = sleepContext
+
+	_, err := rs.Start(types.LogReplaySpec{Path: "../slow.log", TimestampLayout: "datetime"})
+	assert.Error(t, err, "paths outside the replay directory are rejected")
+	_, err = rs.Start(types.LogReplaySpec{Path: "slow.log", Sink: "missing"})
+	assert.Error(t, err)
+
+	replay, err := rs.Start(types.LogReplaySpec{Path: "slow.log", TimestampLayout: "datetime"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		replay, err = rs.Get(replay.ID)
+		return err == nil && replay.Entries == 1
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Len(t, rs.List(), 1)
+
+	require.NoError(t, rs.Remove(replay.ID))
+	replay, err = rs.Get(replay.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", replay.Status)
+	assert.Equal(t, int64(1), replay.Entries)
+	assert.NotNil(t, replay.FinishedAt)
+
+	require.NoError(t, rs.Remove(replay.ID))
+	_, err = rs.Get(replay.ID)
+	assert.ErrorIs(t, err, ErrReplayNotFound)
+	assert.ErrorIs(t, rs.Remove(replay.ID), ErrReplayNotFound)
+}
+
+func TestLogReplayService_LokiLabels(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		pushes = append(pushes, payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	registry := NewLogSinkRegistry(&bytes.Buffer{})
+	t.Cleanup(registry.Close)
+	require.NoError(t, registry.Configure([]types.LogSinkConfig{
+		{Name: "loki", Type: types.LogSinkLoki, BatchWaitMs: 60000, Labels: map[string]string{"job": "argus"}},
+	}, types.ServiceConfig{URL: server.URL}))
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("2024-03-01T10:00:00Z replayed\n"), 0o644))
+	rs := NewLogReplayService(registry, dir)
+	fakeReplayClock(rs, time.Unix(1700000000, 0).UTC())
+
+	replay, err := rs.Start(types.LogReplaySpec{Path: path, Sink: "loki", TimestampLayout: "rfc3339", Labels: map[string]string{"incident": "inc-42"}})
+	require.NoError(t, err)
+	assert.Equal(t, "completed", waitReplay(t, rs, replay.ID).Status)
+	require.NoError(t, registry.Flush(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, pushes, 1)
+	stream := pushes[0]["streams"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"job": "argus", "incident": "inc-42"}, stream["stream"])
+	assert.Equal(t, []interface{}{[]interface{}{"1700000000000000000", "2023-11-14T22:13:20Z replayed"}}, stream["values"])
+}
+
+func TestLogReplayService_WaitsForFullQueue(t *testing.T) {
+	var mu sync.Mutex
+	pushed := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Streams []struct {
+				Values [][2]string `json:"values"`
+			} `json:"streams"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, stream := range payload.Streams {
+			pushed += len(stream.Values)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	registry := NewLogSinkRegistry(&bytes.Buffer{})
+	t.Cleanup(registry.Close)
+	require.NoError(t, registry.Configure([]types.LogSinkConfig{
+		{Name: "loki", Type: types.LogSinkLoki, BatchSize: 10, BatchWaitMs: 60000},
+	}, types.ServiceConfig{URL: server.URL}))
+
+	// Without a layout nothing paces the replay, so it outruns the 100 entry queue
+	dir := t.TempDir()
+	lines := strings.Repeat("unpaced line\n", 1000)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "burst.log"), []byte(lines), 0o644))
+	rs := NewLogReplayService(registry, dir)
+
+	replay, err := rs.Start(types.LogReplaySpec{Path: "burst.log", Sink: "loki"})
+	require.NoError(t, err)
+	replay = waitReplay(t, rs, replay.ID)
+	assert.Equal(t, "completed", replay.Status, replay.Error)
+	assert.Equal(t, int64(1000), replay.Entries)
+	require.NoError(t, registry.Flush(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 1000, pushed)
+	sink, _ := registry.Get("loki")
+	assert.Zero(t, sink.Stats().Dropped)
+}
@@ -0,0 +1,838 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// Log sink defaults
+const (
+	defaultSinkMaxSizeMB   = 100
+	defaultSinkMaxBackups  = 5
+	defaultSinkFacility    = 16 // local0
+	defaultLokiBatchSize   = 500
+	defaultLokiBatchWait   = time.Second
+	defaultLokiMaxRetries  = 3
+	lokiSinkQueueBatches   = 10 // queued entries beyond this many batches are dropped
+	sinkDialTimeout        = 5 * time.Second
+	netSinkQueueSize       = 10000 // queued entries beyond this are dropped
+	netSinkFlushInterval   = time.Second
+	lokiSinkMinBackoff     = 100 * time.Millisecond
+	lokiSinkMaxBackoff     = 5 * time.Second
+	lokiSinkRequestTimeout = 10 * time.Second
+)
+
+// ErrSinkQueueFull is returned by WriteEntry when a sink dropped the entry
+// because its queue is full; the queue drains as the sink delivers
+var ErrSinkQueueFull = errors.New("queue is full")
+
+// SinkEntry is one log entry handed to a sink. Line may span several lines,
+// such as a stack trace.
+type SinkEntry struct {
+	Time   time.Time
+	Line   string
+	Labels map[string]string // added to a Loki sink's stream labels
+}
+
+// LogSink is a destination for log entries. Each Write is one entry, with
+// its trailing newline removed, so a sink can back a zap core or anything
+// else that writes a line at a time.
+type LogSink interface {
+	io.Writer
+	WriteEntry(entry SinkEntry) error
+	Flush(ctx context.Context) error
+	Close() error
+	Stats() models.LogSinkStats
+}
+
+// LogSinkRegistry holds stdout and the configured sinks. Configured sinks
+// receive Argus' own logs through Write; Get returns a single sink for
+// generators to write to.
+type LogSinkRegistry struct {
+	stdout LogSink
+
+	mu      sync.RWMutex
+	configs []types.LogSinkConfig
+	sinks   []LogSink
+	byName  map[string]LogSink
+}
+
+// NewLogSinkRegistry creates a registry with only the stdout sink
+func NewLogSinkRegistry(stdout io.Writer) *LogSinkRegistry {
+	return &LogSinkRegistry{
+		stdout: &writerSink{out: stdout, stats: models.LogSinkStats{Name: types.LogSinkStdout, Type: types.LogSinkStdout, Target: "stdout"}},
+		byName: map[string]LogSink{},
+	}
+}
+
+// Configure replaces the configured sinks. Loki sinks without their own Loki
+// settings push to loki. The previous sinks are flushed and closed; when a
+// sink cannot be opened they are kept.
+func (sr *LogSinkRegistry) Configure(configs []types.LogSinkConfig, loki types.ServiceConfig) error {
+	pending, err := sr.Prepare(configs, loki)
+	if err != nil {
+		return err
+	}
+	pending.Apply()
+	return nil
+}
+
+// Prepare validates the configurations and opens their sinks without
+// sending them entries yet
+func (sr *LogSinkRegistry) Prepare(configs []types.LogSinkConfig, loki types.ServiceConfig) (PendingConfig, error) {
+	if err := types.ValidateLogSinks(configs); err != nil {
+		return nil, err
+	}
+
+	pending := &pendingSinks{sr: sr, configs: append([]types.LogSinkConfig(nil), configs...), byName: make(map[string]LogSink, len(configs))}
+	for _, config := range configs {
+		sink, err := newLogSink(config, loki)
+		if err != nil {
+			pending.Discard()
+			return nil, fmt.Errorf("log sink %s: %w", config.Name, err)
+		}
+		pending.sinks = append(pending.sinks, sink)
+		pending.byName[config.Name] = sink
+	}
+	return pending, nil
+}
+
+// pendingSinks are opened sinks waiting to replace the configured ones
+type pendingSinks struct {
+	sr      *LogSinkRegistry
+	configs []types.LogSinkConfig
+	sinks   []LogSink
+	byName  map[string]LogSink
+}
+
+func (p *pendingSinks) Apply() {
+	sr := p.sr
+	sr.mu.Lock()
+	previous := sr.sinks
+	sr.configs = p.configs
+	sr.sinks = p.sinks
+	sr.byName = p.byName
+	sr.mu.Unlock()
+
+	for _, sink := range previous {
+		_ = sink.Close()
+	}
+}
+
+func (p *pendingSinks) Discard() {
+	for _, sink := range p.sinks {
+		_ = sink.Close()
+	}
+}
+
+// Configs returns the sink configurations in effect
+func (sr *LogSinkRegistry) Configs() []types.LogSinkConfig {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return append([]types.LogSinkConfig{}, sr.configs...)
+}
+
+// Get returns the sink with the given name, where "stdout" is always present
+func (sr *LogSinkRegistry) Get(name string) (LogSink, bool) {
+	if name == types.LogSinkStdout {
+		return sr.stdout, true
+	}
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	sink, ok := sr.byName[name]
+	return sink, ok
+}
+
+// Names lists stdout and the configured sinks
+func (sr *LogSinkRegistry) Names() []string {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	names := []string{types.LogSinkStdout}
+	for _, config := range sr.configs {
+		names = append(names, config.Name)
+	}
+	return names
+}
+
+// Stats returns the totals of stdout and every configured sink
+func (sr *LogSinkRegistry) Stats() []models.LogSinkStats {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	stats := []models.LogSinkStats{sr.stdout.Stats()}
+	for _, sink := range sr.sinks {
+		stats = append(stats, sink.Stats())
+	}
+	return stats
+}
+
+// Active reports whether any sink is configured besides stdout
+func (sr *LogSinkRegistry) Active() bool {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	return len(sr.sinks) > 0
+}
+
+// Write hands the entry to every configured sink. Failures are counted in
+// each sink's stats rather than returned, so one broken sink does not
+// affect logging to the others.
+func (sr *LogSinkRegistry) Write(p []byte) (int, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	for _, sink := range sr.sinks {
+		_, _ = sink.Write(p)
+	}
+	return len(p), nil
+}
+
+// Flush sends whatever the configured sinks have queued
+func (sr *LogSinkRegistry) Flush(ctx context.Context) error {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	var lastErr error
+	for _, sink := range sr.sinks {
+		if err := sink.Flush(ctx); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Close flushes and closes the configured sinks, leaving only stdout
+func (sr *LogSinkRegistry) Close() {
+	sr.mu.Lock()
+	previous := sr.sinks
+	sr.configs, sr.sinks, sr.byName = nil, nil, map[string]LogSink{}
+	sr.mu.Unlock()
+
+	for _, sink := range previous {
+		_ = sink.Flush(context.Background())
+		_ = sink.Close()
+	}
+}
+
+func newLogSink(config types.LogSinkConfig, loki types.ServiceConfig) (LogSink, error) {
+	switch config.Type {
+	case types.LogSinkFile:
+		return newFileSink(config)
+	case types.LogSinkSyslog:
+		network := config.Network
+		if network == "" {
+			network = "udp"
+		}
+		return newNetSink(config, network, syslogFramer(config, network)), nil
+	case types.LogSinkTCP:
+		return newNetSink(config, "tcp", func(entry SinkEntry) []byte { return []byte(entry.Line + "\n") }), nil
+	case types.LogSinkUDP:
+		return newNetSink(config, "udp", func(entry SinkEntry) []byte { return []byte(entry.Line) }), nil
+	case types.LogSinkLoki:
+		if config.Loki != nil {
+			loki = *config.Loki
+		}
+		if loki.URL == "" {
+			return nil, fmt.Errorf("no Loki URL configured")
+		}
+		return newLokiSink(config, loki), nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", config.Type)
+}
+
+// queuedSink is a sink that queues entries; tryWriteEntry refuses an entry
+// with ErrSinkQueueFull without counting it as dropped
+type queuedSink interface {
+	tryWriteEntry(entry SinkEntry) error
+}
+
+// writeEntryWait writes the entry, but when the sink's queue is full it
+// waits for the sink to deliver what it queued and tries again instead of
+// dropping the entry, until ctx ends
+func writeEntryWait(ctx context.Context, sink LogSink, entry SinkEntry) error {
+	queued, ok := sink.(queuedSink)
+	if !ok {
+		return sink.WriteEntry(entry)
+	}
+	for {
+		err := queued.tryWriteEntry(entry)
+		if !errors.Is(err, ErrSinkQueueFull) {
+			return err
+		}
+		// Delivery errors are counted in the sink's stats; the queue has room again either way
+		_ = sink.Flush(ctx)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// sinkWrite adapts Write to WriteEntry, stamping the entry with the current time
+func sinkWrite(sink LogSink, p []byte) (int, error) {
+	if err := sink.WriteEntry(SinkEntry{Time: time.Now(), Line: strings.TrimRight(string(p), "\n")}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// recordWrite updates the totals after delivering entries of size bytes
+func recordWrite(stats *models.LogSinkStats, entries, size int64, err error) {
+	if err != nil {
+		stats.Failed += entries
+		stats.LastError = err.Error()
+		return
+	}
+	stats.Entries += entries
+	stats.Bytes += size
+	stats.LastWrite = time.Now()
+}
+
+// writerSink writes entries to an io.Writer, one line each
+type writerSink struct {
+	mu    sync.Mutex
+	out   io.Writer
+	stats models.LogSinkStats
+}
+
+func (s *writerSink) Write(p []byte) (int, error) { return sinkWrite(s, p) }
+
+func (s *writerSink) WriteEntry(entry SinkEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := io.WriteString(s.out, entry.Line+"\n")
+	recordWrite(&s.stats, 1, int64(len(entry.Line)+1), err)
+	return err
+}
+
+func (s *writerSink) Flush(context.Context) error { return nil }
+
+func (s *writerSink) Close() error { return nil }
+
+func (s *writerSink) Stats() models.LogSinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// fileSink appends entries to a file for file-tailing agents, rotating it
+// the way logrotate does: path.1 is the newest backup
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu    sync.Mutex
+	file  *os.File
+	size  int64
+	stats models.LogSinkStats
+}
+
+func newFileSink(config types.LogSinkConfig) (*fileSink, error) {
+	s := &fileSink{
+		path:       config.Path,
+		maxSize:    int64(config.MaxSizeMB) << 20,
+		maxBackups: config.MaxBackups,
+		stats:      models.LogSinkStats{Name: config.Name, Type: config.Type, Target: config.Path},
+	}
+	if s.maxSize == 0 {
+		s.maxSize = defaultSinkMaxSizeMB << 20
+	}
+	if s.maxBackups == 0 {
+		s.maxBackups = defaultSinkMaxBackups
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate shifts path.N-1 to path.N down to path to path.1, dropping the
+// oldest backup, and starts a new file
+func (s *fileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+	for i := s.maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+	s.stats.Rotations++
+	return s.open()
+}
+
+func (s *fileSink) Write(p []byte) (int, error) { return sinkWrite(s, p) }
+
+func (s *fileSink) WriteEntry(entry SinkEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line := entry.Line + "\n"
+	var err error
+	if s.file == nil {
+		err = fmt.Errorf("file %s is closed", s.path)
+	} else if s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		err = s.rotate()
+	}
+	if err == nil {
+		var n int
+		n, err = s.file.WriteString(line)
+		s.size += int64(n)
+	}
+	recordWrite(&s.stats, 1, int64(len(line)), err)
+	return err
+}
+
+func (s *fileSink) Flush(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Sync()
+}
+
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *fileSink) Stats() models.LogSinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// netSink sends each entry, framed by frame, over a TCP connection or as a
+// UDP datagram. Entries are queued and sent by a worker, so a slow or
+// unreachable peer never blocks the logger; a broken connection is
+// redialled once per entry.
+type netSink struct {
+	network string
+	address string
+	frame   func(SinkEntry) []byte
+	dial    func(network, address string) (net.Conn, error)
+	wake    chan struct{}
+	worker  *exportWorker
+
+	mu    sync.Mutex
+	queue []SinkEntry
+	stats models.LogSinkStats
+
+	// sendMu guards conn and keeps entries in order when a flush races the worker
+	sendMu sync.Mutex
+	conn   net.Conn
+}
+
+func newNetSink(config types.LogSinkConfig, network string, frame func(SinkEntry) []byte) *netSink {
+	s := &netSink{
+		network: network,
+		address: config.Address,
+		frame:   frame,
+		dial: func(network, address string) (net.Conn, error) {
+			return net.DialTimeout(network, address, sinkDialTimeout)
+		},
+		wake:  make(chan struct{}, 1),
+		stats: models.LogSinkStats{Name: config.Name, Type: config.Type, Target: network + "://" + config.Address},
+	}
+	s.worker = startExportWorker(netSinkFlushInterval, s.wake, func() { _ = s.Flush(context.Background()) })
+	return s
+}
+
+func (s *netSink) Write(p []byte) (int, error) { return sinkWrite(s, p) }
+
+// WriteEntry queues the entry, dropping it when the queue is full
+func (s *netSink) WriteEntry(entry SinkEntry) error {
+	err := s.tryWriteEntry(entry)
+	if errors.Is(err, ErrSinkQueueFull) {
+		s.mu.Lock()
+		s.stats.Dropped++
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *netSink) tryWriteEntry(entry SinkEntry) error {
+	s.mu.Lock()
+	if len(s.queue) >= netSinkQueueSize {
+		s.mu.Unlock()
+		return fmt.Errorf("%s sink %s: %w", s.stats.Type, s.stats.Name, ErrSinkQueueFull)
+	}
+	s.queue = append(s.queue, entry)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Flush sends every queued entry and returns the last error
+func (s *netSink) Flush(ctx context.Context) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	s.mu.Lock()
+	queue := s.queue
+	s.queue = nil
+	s.mu.Unlock()
+
+	var lastErr error
+	for i, entry := range queue {
+		if err := ctx.Err(); err != nil {
+			s.mu.Lock()
+			recordWrite(&s.stats, int64(len(queue)-i), 0, err)
+			s.mu.Unlock()
+			return err
+		}
+		data := s.frame(entry)
+		err := s.send(data)
+		if err != nil && s.conn != nil {
+			// The peer may have closed an idle connection; try a fresh one
+			s.conn.Close()
+			s.conn = nil
+			err = s.send(data)
+		}
+		s.mu.Lock()
+		recordWrite(&s.stats, 1, int64(len(data)), err)
+		s.mu.Unlock()
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (s *netSink) send(data []byte) error {
+	if s.conn == nil {
+		conn, err := s.dial(s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(sinkDialTimeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(data)
+	return err
+}
+
+// Close sends what is queued, stops the worker and closes the connection
+func (s *netSink) Close() error {
+	s.worker.shutdown()
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *netSink) Stats() models.LogSinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// syslogFramer wraps entries in RFC 5424 messages whose severity follows the
+// entry's level; over TCP they are octet-counted as in RFC 6587
+func syslogFramer(config types.LogSinkConfig, network string) func(SinkEntry) []byte {
+	facility := config.Facility
+	if facility == 0 {
+		facility = defaultSinkFacility
+	}
+	appName := config.AppName
+	if appName == "" {
+		appName = "argus"
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	pid := os.Getpid()
+
+	return func(entry SinkEntry) []byte {
+		message := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", facility*8+syslogSeverity(entryLevel(entry.Line)),
+			entry.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, appName, pid, entry.Line)
+		if network == "tcp" {
+			return []byte(strconv.Itoa(len(message)) + " " + message)
+		}
+		return []byte(message)
+	}
+}
+
+// entryLevel finds the level of a JSON or logfmt entry, or returns ""
+func entryLevel(line string) string {
+	for _, key := range []string{`"level":"`, "level="} {
+		i := strings.Index(line, key)
+		if i < 0 {
+			continue
+		}
+		rest := strings.TrimPrefix(line[i+len(key):], `"`)
+		end := strings.IndexAny(rest, "\" \t\n")
+		if end < 0 {
+			end = len(rest)
+		}
+		level := strings.ToLower(rest[:end])
+		if level == "warning" {
+			level = "warn"
+		}
+		return level
+	}
+	return ""
+}
+
+// lokiSink batches entries and pushes them to Loki as JSON streams, one
+// stream per label set, retrying a failed push with exponential backoff
+type lokiSink struct {
+	loki       types.ServiceConfig
+	labels     map[string]string
+	batchSize  int
+	maxRetries int
+	minBackoff time.Duration
+	client     *http.Client
+	wake       chan struct{}
+	worker     *exportWorker
+
+	mu    sync.Mutex
+	queue []SinkEntry
+	stats models.LogSinkStats
+
+	// pushMu keeps batches in order when a flush races the worker
+	pushMu sync.Mutex
+}
+
+func newLokiSink(config types.LogSinkConfig, loki types.ServiceConfig) *lokiSink {
+	s := &lokiSink{
+		loki:       loki,
+		labels:     config.Labels,
+		batchSize:  config.BatchSize,
+		maxRetries: config.MaxRetries,
+		minBackoff: lokiSinkMinBackoff,
+		client:     &http.Client{Timeout: lokiSinkRequestTimeout},
+		wake:       make(chan struct{}, 1),
+		stats:      models.LogSinkStats{Name: config.Name, Type: config.Type, Target: strings.TrimRight(loki.URL, "/") + "/loki/api/v1/push"},
+	}
+	if len(s.labels) == 0 {
+		s.labels = map[string]string{"job": "argus", "sink": config.Name}
+	}
+	if s.batchSize == 0 {
+		s.batchSize = defaultLokiBatchSize
+	}
+	if s.maxRetries == 0 {
+		s.maxRetries = defaultLokiMaxRetries
+	}
+	wait := time.Duration(config.BatchWaitMs) * time.Millisecond
+	if wait == 0 {
+		wait = defaultLokiBatchWait
+	}
+	s.worker = startExportWorker(wait, s.wake, func() { _ = s.Flush(context.Background()) })
+	return s
+}
+
+func (s *lokiSink) Write(p []byte) (int, error) { return sinkWrite(s, p) }
+
+// WriteEntry queues the entry, dropping it when the queue is full
+func (s *lokiSink) WriteEntry(entry SinkEntry) error {
+	err := s.tryWriteEntry(entry)
+	if errors.Is(err, ErrSinkQueueFull) {
+		s.mu.Lock()
+		s.stats.Dropped++
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *lokiSink) tryWriteEntry(entry SinkEntry) error {
+	s.mu.Lock()
+	if len(s.queue) >= s.batchSize*lokiSinkQueueBatches {
+		s.mu.Unlock()
+		return fmt.Errorf("loki sink %s: %w", s.stats.Name, ErrSinkQueueFull)
+	}
+	s.queue = append(s.queue, entry)
+	full := len(s.queue) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush pushes every queued entry in batches and returns the last error
+func (s *lokiSink) Flush(ctx context.Context) error {
+	s.pushMu.Lock()
+	defer s.pushMu.Unlock()
+
+	var lastErr error
+	for {
+		s.mu.Lock()
+		size := s.batchSize
+		if size > len(s.queue) {
+			size = len(s.queue)
+		}
+		batch := s.queue[:size:size]
+		s.queue = s.queue[size:]
+		s.mu.Unlock()
+
+		if len(batch) == 0 {
+			return lastErr
+		}
+
+		payload, pushed := s.payload(batch)
+		retries, err := s.push(ctx, payload)
+
+		s.mu.Lock()
+		s.stats.Batches++
+		s.stats.Retries += int64(retries)
+		recordWrite(&s.stats, int64(len(batch)), pushed, err)
+		s.mu.Unlock()
+		if err != nil {
+			lastErr = fmt.Errorf("push to Loki: %w", err)
+		}
+	}
+}
+
+// payload groups the batch into streams by label set
+func (s *lokiSink) payload(batch []SinkEntry) ([]byte, int64) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	var streams []*stream
+	byKey := make(map[string]*stream)
+	var size int64
+
+	for _, entry := range batch {
+		labels := make(map[string]string, len(s.labels)+len(entry.Labels))
+		for name, value := range s.labels {
+			labels[name] = value
+		}
+		for name, value := range entry.Labels {
+			labels[name] = value
+		}
+		key := labelKey(labels)
+		st, ok := byKey[key]
+		if !ok {
+			st = &stream{Stream: labels}
+			byKey[key] = st
+			streams = append(streams, st)
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(entry.Time.UnixNano(), 10), entry.Line})
+		size += int64(len(entry.Line))
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"streams": streams})
+	return payload, size
+}
+
+// push sends one payload, retrying network errors, 429 and 5xx responses
+func (s *lokiSink) push(ctx context.Context, payload []byte) (int, error) {
+	backoff := s.minBackoff
+	for attempt := 0; ; attempt++ {
+		status, body, err := s.post(ctx, payload)
+		if err == nil {
+			switch {
+			case status == http.StatusNoContent || status == http.StatusOK:
+				return attempt, nil
+			case status == http.StatusTooManyRequests || status >= 500:
+				err = fmt.Errorf("HTTP %d: %s", status, body)
+			default:
+				return attempt, fmt.Errorf("HTTP %d: %s", status, body)
+			}
+		}
+		if attempt >= s.maxRetries || ctx.Err() != nil {
+			return attempt, err
+		}
+
+		if sleepErr := sleepContext(ctx, backoff); sleepErr != nil {
+			return attempt, err
+		}
+		if backoff *= 2; backoff > lokiSinkMaxBackoff {
+			backoff = lokiSinkMaxBackoff
+		}
+	}
+}
+
+func (s *lokiSink) post(ctx context.Context, payload []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(s.loki.URL, "/")+"/loki/api/v1/push", bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := DoServiceRequest(s.client, s.loki, req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return resp.StatusCode, strings.TrimSpace(string(body)), nil
+}
+
+// Close pushes what is queued and stops the batching worker
+func (s *lokiSink) Close() error {
+	s.worker.shutdown()
+	return nil
+}
+
+func (s *lokiSink) Stats() models.LogSinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// labelKey is a stable key for a label set
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%q,", name, labels[name])
+	}
+	return b.String()
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestLogSinkRegistry_Configure(t *testing.T) {
+	var stdout bytes.Buffer
+	registry := NewLogSinkRegistry(&stdout)
+	t.Cleanup(registry.Close)
+	assert.False(t, registry.Active())
+
+	sink, ok := registry.Get("stdout")
+	require.True(t, ok)
+	_, err := sink.Write([]byte("direct to stdout\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "direct to stdout\n", stdout.String())
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+	require.NoError(t, registry.Configure([]types.LogSinkConfig{
+		{Name: "first", Type: types.LogSinkFile, Path: first},
+		{Name: "second", Type: types.LogSinkFile, Path: second},
+	}, types.ServiceConfig{}))
+	assert.True(t, registry.Active())
+	assert.Equal(t, []string{"stdout", "first", "second"}, registry.Names())
+
+	// Argus' own logs reach every configured sink, but not stdout through the registry
+	_, err = registry.Write([]byte(`{"level":"info","msg":"to every sink"}` + "\n"))
+	require.NoError(t, err)
+	for _, path := range []string{first, second} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `{"level":"info","msg":"to every sink"}`+"\n", string(data))
+	}
+	assert.Equal(t, "direct to stdout\n", stdout.String())
+
+	stats := registry.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, int64(1), stats[1].Entries)
+	assert.Equal(t, first, stats[1].Target)
+
+	// A sink that cannot be opened keeps the previous ones
+	err = registry.Configure([]types.LogSinkConfig{{Name: "bad", Type: types.LogSinkFile, Path: filepath.Join(dir, "missing", "x.log")}}, types.ServiceConfig{})
+	assert.Error(t, err)
+	_, ok = registry.Get("first")
+	assert.True(t, ok)
+
+	assert.Error(t, registry.Configure([]types.LogSinkConfig{{Name: "push", Type: types.LogSinkLoki}}, types.ServiceConfig{}), "a Loki sink needs a URL")
+
+	require.NoError(t, registry.Configure(nil, types.ServiceConfig{}))
+	_, ok = registry.Get("first")
+	assert.False(t, ok)
+	assert.False(t, registry.Active())
+}
+
+func TestFileSink_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "argus.log")
+	sink, err := newFileSink(types.LogSinkConfig{Name: "file", Type: types.LogSinkFile, Path: path, MaxBackups: 2})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close() })
+	sink.maxSize = 100
+
+	line := strings.Repeat("x", 39) // 40 bytes with the newline, so two fit in a file
+	for i := 0; i < 7; i++ {
+		require.NoError(t, sink.WriteEntry(SinkEntry{Time: time.Now(), Line: line}))
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err, name)
+		assert.LessOrEqual(t, info.Size(), int64(100), name)
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only max_backups rotated files are kept")
+
+	stats := sink.Stats()
+	assert.Equal(t, int64(3), stats.Rotations)
+	assert.Equal(t, int64(7), stats.Entries)
+	assert.Equal(t, int64(280), stats.Bytes)
+}
+
+func TestNetSink_TCPAndSyslog(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					received <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+
+	sink, err := newLogSink(types.LogSinkConfig{Name: "raw", Type: types.LogSinkTCP, Address: listener.Addr().String()}, types.ServiceConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close() })
+	_, err = sink.Write([]byte("level=warn msg=\"over tcp\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, `level=warn msg="over tcp"`, receive(t, received))
+
+	// Syslog over TCP is octet-counted RFC 5424
+	syslog, err := newLogSink(types.LogSinkConfig{Name: "syslog", Type: types.LogSinkSyslog, Network: "tcp", Address: listener.Addr().String(), Facility: 1, AppName: "shop"}, types.ServiceConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = syslog.Close() })
+	entry := SinkEntry{Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Line: `{"level":"error","msg":"payment failed"}`}
+	require.NoError(t, syslog.WriteEntry(entry))
+	require.NoError(t, syslog.Close(), "the frame has no newline, so the reader sees it at EOF")
+	message := receive(t, received)
+	parts := strings.SplitN(message, " ", 2)
+	require.Len(t, parts, 2)
+	assert.Equal(t, len(parts[1]), atoi(t, parts[0]))
+	assert.Regexp(t, `^<11>1 2024-01-02T15:04:05.000000Z \S+ shop \d+ - - \{"level":"error","msg":"payment failed"\}$`, parts[1])
+}
+
+func TestNetSink_UDPSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	sink, err := newLogSink(types.LogSinkConfig{Name: "syslog", Type: types.LogSinkSyslog, Address: conn.LocalAddr().String()}, types.ServiceConfig{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close() })
+	_, err = sink.Write([]byte("time=2024-01-02T15:04:05Z level=debug msg=cache\n"))
+	require.NoError(t, err)
+
+	buf := make([]byte, 2048)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	assert.Regexp(t, `^<135>1 \S+ \S+ argus \d+ - - time=2024-01-02T15:04:05Z level=debug msg=cache$`, string(buf[:n]))
+	assert.Equal(t, "udp://"+conn.LocalAddr().String(), sink.Stats().Target)
+}
+
+func TestNetSink_QueuesWhilePeerIsSlow(t *testing.T) {
+	client, server := net.Pipe()
+	t.Cleanup(func() { server.Close() })
+	release := make(chan struct{})
+
+	sink := newNetSink(types.LogSinkConfig{Name: "raw", Type: types.LogSinkTCP, Address: "collector:514"}, "tcp",
+		func(entry SinkEntry) []byte { return []byte(entry.Line + "\n") })
+	sink.dial = func(network, address string) (net.Conn, error) {
+		<-release // a peer that takes long to answer
+		return client, nil
+	}
+
+	// The worker holds what it took while dialling; the rest queues up to the limit
+	start := time.Now()
+	var err error
+	for i := 0; i <= 2*netSinkQueueSize && err == nil; i++ {
+		err = sink.WriteEntry(SinkEntry{Line: "queued"})
+	}
+	assert.ErrorIs(t, err, ErrSinkQueueFull)
+	assert.Less(t, time.Since(start), sinkDialTimeout, "writes must not wait for the dial")
+	assert.Equal(t, int64(1), sink.Stats().Dropped)
+
+	received := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(server).ReadString('\n')
+		received <- line
+		_, _ = io.Copy(io.Discard, server)
+	}()
+	close(release)
+	assert.Equal(t, "queued\n", receive(t, received))
+	require.NoError(t, sink.Close())
+}
+
+func TestLokiSink_BatchesAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	var pushes []map[string]interface{}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		assert.Equal(t, "tenant-a", r.Header.Get("X-Scope-OrgID"))
+		attempts++
+		if attempts == 1 {
+			http.Error(w, "ingester unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		pushes = append(pushes, payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	sink := newLokiSink(types.LogSinkConfig{Name: "loki", Type: types.LogSinkLoki, BatchSize: 3, BatchWaitMs: 60000, Labels: map[string]string{"job": "argus"}},
+		types.ServiceConfig{URL: server.URL, TenantID: "tenant-a"})
+	sink.minBackoff = time.Millisecond
+	t.Cleanup(func() { _ = sink.Close() })
+
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < 4; i++ {
+		labels := map[string]string{"level": "info"}
+		if i == 3 {
+			labels["level"] = "error"
+		}
+		require.NoError(t, sink.WriteEntry(SinkEntry{Time: now, Line: "entry", Labels: labels}))
+	}
+	require.NoError(t, sink.Flush(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, pushes, 2, "four entries go out in batches of three")
+	streams := pushes[1]["streams"].([]interface{})
+	require.Len(t, streams, 1)
+	stream := streams[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"job": "argus", "level": "error"}, stream["stream"])
+	assert.Equal(t, []interface{}{[]interface{}{"1700000000000000000", "entry"}}, stream["values"])
+
+	stats := sink.Stats()
+	assert.Equal(t, int64(4), stats.Entries)
+	assert.Equal(t, int64(2), stats.Batches)
+	assert.Equal(t, int64(1), stats.Retries)
+	assert.Zero(t, stats.Failed)
+	assert.Equal(t, server.URL+"/loki/api/v1/push", stats.Target)
+}
+
+func TestLokiSink_GivesUp(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "entry out of order", http.StatusBadRequest)
+	}))
+	t.Cleanup(server.Close)
+
+	sink := newLokiSink(types.LogSinkConfig{Name: "loki", Type: types.LogSinkLoki, MaxRetries: 2}, types.ServiceConfig{URL: server.URL})
+	sink.minBackoff = time.Millisecond
+	t.Cleanup(func() { _ = sink.Close() })
+
+	_, err := sink.Write([]byte("rejected\n"))
+	require.NoError(t, err)
+	err = sink.Flush(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "HTTP 400: entry out of order")
+	assert.Equal(t, 1, attempts, "client errors are not retried")
+	assert.Equal(t, int64(1), sink.Stats().Failed)
+}
+
+func TestEntryLevel(t *testing.T) {
+	assert.Equal(t, "error", entryLevel(`{"level":"error","msg":"x"}`))
+	assert.Equal(t, "warn", entryLevel(`time=now level=WARNING msg=x`))
+	assert.Equal(t, "info", entryLevel(`level="info" msg=x`))
+	assert.Equal(t, "", entryLevel(`plain text`))
+}
+
+func receive(t *testing.T, received <-chan string) string {
+	t.Helper()
+	select {
+	case line := <-received:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing received")
+		return ""
+	}
+}
+
+func atoi(t *testing.T, value string) int {
+	t.Helper()
+	var n int
+	_, err := fmt.Sscan(value, &n)
+	require.NoError(t, err)
+	return n
+}
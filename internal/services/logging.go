@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"runtime"
 	"time"
 
@@ -15,38 +16,78 @@ import (
 	"github.com/nahuelsantos/argus/internal/config"
 	"github.com/nahuelsantos/argus/internal/metrics"
 	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
 )
 
 var logger *zap.Logger
 
 // LoggingService handles all logging operations
 type LoggingService struct {
-	config *config.ServiceConfig
+	config   *config.ServiceConfig
+	exporter *LogExporter
+	sinks    *LogSinkRegistry
 }
 
 // NewLoggingService creates a new logging service
 func NewLoggingService() *LoggingService {
+	serviceConfig := config.GetServiceConfig()
 	return &LoggingService{
-		config: config.GetServiceConfig(),
+		config:   serviceConfig,
+		exporter: NewLogExporter(serviceConfig),
+		sinks:    NewLogSinkRegistry(os.Stdout),
 	}
 }
 
-// InitLogger initializes the global logger
+// InitLogger initializes the global logger. Entries go to stdout and to
+// every sink configured through ARGUS_LOG_SINKS or the settings.
 func (ls *LoggingService) InitLogger() {
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
 	config.OutputPaths = []string{"stdout"}
 	config.ErrorOutputPaths = []string{"stderr"}
-	config.EncoderConfig.TimeKey = "timestamp"
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	config.EncoderConfig.CallerKey = "caller"
-	config.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	config.EncoderConfig = logEncoderConfig()
+
+	sinkCore := zapcore.NewCore(zapcore.NewJSONEncoder(config.EncoderConfig), zapcore.AddSync(ls.sinks),
+		zap.LevelEnablerFunc(func(zapcore.Level) bool { return ls.sinks.Active() }))
 
 	var err error
-	logger, err = config.Build()
+	logger, err = config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, ls.exporter.Core(), sinkCore)
+	}))
 	if err != nil {
 		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
 	}
+
+	if err := ls.exporter.Configure(types.LogExportSettingsFromEnv()); err != nil {
+		logger.Error("Failed to configure OTLP log export", zap.Error(err))
+	}
+	sinks, err := types.LogSinksFromEnv()
+	if err == nil {
+		err = ls.sinks.Configure(sinks, types.GetDefaults().Loki)
+	}
+	if err != nil {
+		logger.Error("Failed to configure log sinks", zap.Error(err))
+	}
+}
+
+// logEncoderConfig is the JSON layout of every log entry Argus writes
+func logEncoderConfig() zapcore.EncoderConfig {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "timestamp"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.CallerKey = "caller"
+	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	return encoderConfig
+}
+
+// Sinks returns the sinks log entries can be written to
+func (ls *LoggingService) Sinks() *LogSinkRegistry {
+	return ls.sinks
+}
+
+// Exporter returns the OTLP exporter log records are bridged to
+func (ls *LoggingService) Exporter() *LogExporter {
+	return ls.exporter
 }
 
 // InitTestLogger initializes a silent logger for testing
@@ -135,6 +176,26 @@ func (ls *LoggingService) extractSessionID(r *http.Request) string {
 
 // LogWithContext logs with structured context
 func (ls *LoggingService) LogWithContext(level zapcore.Level, ctx context.Context, message string, fields ...zap.Field) {
+	ls.logWithContext(logger, level, ctx, message, fields...)
+}
+
+// LogToSink logs like LogWithContext, but only to sink; a nil sink logs like
+// LogWithContext
+func (ls *LoggingService) LogToSink(sink LogSink, level zapcore.Level, ctx context.Context, message string, fields ...zap.Field) {
+	if sink == nil {
+		ls.LogWithContext(level, ctx, message, fields...)
+		return
+	}
+	ls.logWithContext(sinkLogger(sink), level, ctx, message, fields...)
+}
+
+// sinkLogger returns a logger writing JSON entries only to sink
+func sinkLogger(sink LogSink) *zap.Logger {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(logEncoderConfig()), zapcore.AddSync(sink), zap.DebugLevel)
+	return zap.New(core, zap.AddCaller())
+}
+
+func (ls *LoggingService) logWithContext(target *zap.Logger, level zapcore.Level, ctx context.Context, message string, fields ...zap.Field) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -161,15 +222,15 @@ func (ls *LoggingService) LogWithContext(level zapcore.Level, ctx context.Contex
 
 	switch level {
 	case zapcore.DebugLevel:
-		logger.Debug(message, allFields...)
+		target.Debug(message, allFields...)
 	case zapcore.InfoLevel:
-		logger.Info(message, allFields...)
+		target.Info(message, allFields...)
 	case zapcore.WarnLevel:
-		logger.Warn(message, allFields...)
+		target.Warn(message, allFields...)
 	case zapcore.ErrorLevel:
-		logger.Error(message, allFields...)
+		target.Error(message, allFields...)
 	case zapcore.FatalLevel:
-		logger.Fatal(message, allFields...)
+		target.Fatal(message, allFields...)
 	}
 
 	metrics.LogEntriesTotal.WithLabelValues(level.String(), ls.config.Name, "").Inc()
@@ -251,6 +312,20 @@ func (ls *LoggingService) LogPerformance(operation string, duration time.Duratio
 
 // LogError logs error information
 func (ls *LoggingService) LogError(ctx context.Context, errorType, errorCode, message string, err error, additionalData map[string]interface{}) {
+	ls.logError(logger, ctx, errorType, errorCode, message, err, additionalData)
+}
+
+// LogErrorToSink logs like LogError, but only to sink; a nil sink logs like
+// LogError
+func (ls *LoggingService) LogErrorToSink(sink LogSink, ctx context.Context, errorType, errorCode, message string, err error, additionalData map[string]interface{}) {
+	if sink == nil {
+		ls.LogError(ctx, errorType, errorCode, message, err, additionalData)
+		return
+	}
+	ls.logError(sinkLogger(sink), ctx, errorType, errorCode, message, err, additionalData)
+}
+
+func (ls *LoggingService) logError(target *zap.Logger, ctx context.Context, errorType, errorCode, message string, err error, additionalData map[string]interface{}) {
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start)
@@ -283,7 +358,7 @@ func (ls *LoggingService) LogError(ctx context.Context, errorType, errorCode, me
 		Data: additionalData,
 	}
 
-	logger.Error("Error logged",
+	target.Error("Error logged",
 		zap.String("error_type", errorType),
 		zap.String("error_code", errorCode),
 		zap.String("error_message", message),
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nahuelsantos/argus/internal/configs"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+const (
+	// lokiDrillAlert is the alert defined in configs/loki/rules/argus-drill.yaml
+	lokiDrillAlert = "ArgusLogDrill"
+	// lokiDrillNamespace is the ruler namespace Argus creates the drill group in
+	lokiDrillNamespace = "argus"
+)
+
+type lokiRuleGroup struct {
+	Name     string     `yaml:"name"`
+	Interval string     `yaml:"interval,omitempty"`
+	Rules    []lokiRule `yaml:"rules"`
+}
+
+type lokiRule struct {
+	Alert       string            `yaml:"alert,omitempty"`
+	Record      string            `yaml:"record,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// LokiRulerService validates the Loki ruler and drills log-based alerts end to end
+type LokiRulerService struct {
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewLokiRulerService creates a new Loki ruler validation service
+func NewLokiRulerService() *LokiRulerService {
+	return &LokiRulerService{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: 5 * time.Second,
+	}
+}
+
+// ListRuleGroups returns every rule group loaded by the Loki ruler, sorted by namespace and name
+func (ls *LokiRulerService) ListRuleGroups(ctx context.Context, loki types.ServiceConfig) ([]models.LokiRuleGroup, error) {
+	status, body, err := ls.do(ctx, loki, "GET", "/loki/api/v1/rules", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []models.LokiRuleGroup{}
+	switch status {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// Loki answers 404 "no rule groups found" when the ruler has nothing loaded
+		if strings.Contains(string(body), "no rule groups found") {
+			return groups, nil
+		}
+		return nil, fmt.Errorf("list rule groups: HTTP %d (is the ruler enabled?)", status)
+	default:
+		return nil, fmt.Errorf("list rule groups: HTTP %d", status)
+	}
+
+	var namespaces map[string][]lokiRuleGroup
+	if err := yaml.Unmarshal(body, &namespaces); err != nil {
+		return nil, fmt.Errorf("parse rule groups: %w", err)
+	}
+
+	for namespace, nsGroups := range namespaces {
+		for _, group := range nsGroups {
+			ruleGroup := models.LokiRuleGroup{
+				Namespace: namespace,
+				Name:      group.Name,
+				Interval:  group.Interval,
+				Rules:     []models.LokiRuleCheck{},
+			}
+			for _, rule := range group.Rules {
+				check := models.LokiRuleCheck{Name: rule.Alert, Type: "alert", Expr: rule.Expr}
+				if rule.Record != "" {
+					check.Name, check.Type = rule.Record, "record"
+				}
+				ruleGroup.Rules = append(ruleGroup.Rules, check)
+			}
+			groups = append(groups, ruleGroup)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Namespace != groups[j].Namespace {
+			return groups[i].Namespace < groups[j].Namespace
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+// ValidateExpr parses a LogQL expression without executing it
+func (ls *LokiRulerService) ValidateExpr(ctx context.Context, loki types.ServiceConfig, expr string) error {
+	status, body, err := ls.do(ctx, loki, "GET", "/loki/api/v1/format_query?query="+url.QueryEscape(expr), "", nil)
+	if err != nil {
+		return err
+	}
+	switch status {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest:
+		return fmt.Errorf("%s", strings.TrimSpace(string(body)))
+	default:
+		return fmt.Errorf("format_query: HTTP %d", status)
+	}
+}
+
+// Validate lists the ruler's rule groups and parses the LogQL of every rule
+func (ls *LokiRulerService) Validate(ctx context.Context, loki types.ServiceConfig) (*models.LokiRulesReport, error) {
+	groups, err := ls.ListRuleGroups(ctx, loki)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.LokiRulesReport{
+		LokiURL:    loki.URL,
+		RuleGroups: groups,
+		Problems:   []string{},
+		Timestamp:  time.Now(),
+	}
+
+	for g := range report.RuleGroups {
+		group := &report.RuleGroups[g]
+		for r := range group.Rules {
+			rule := &group.Rules[r]
+			report.TotalRules++
+
+			if err := ls.ValidateExpr(ctx, loki, rule.Expr); err != nil {
+				rule.Error = err.Error()
+				report.InvalidRules++
+				report.Problems = append(report.Problems, fmt.Sprintf("%s/%s: %s %q has invalid LogQL: %s", group.Namespace, group.Name, rule.Type, rule.Name, rule.Error))
+				continue
+			}
+			rule.Valid = true
+		}
+	}
+
+	switch {
+	case len(groups) == 0:
+		report.Status = "degraded"
+		report.Problems = append(report.Problems, "Loki ruler has no rule groups loaded")
+	case report.InvalidRules > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report, nil
+}
+
+// RunDrill emits log lines matching the ArgusLogDrill rule until the Loki
+// ruler fires the alert, then waits for the alert to show up in Alertmanager.
+// The drill rule is created through the ruler API when it is not loaded yet.
+func (ls *LokiRulerService) RunDrill(ctx context.Context, loki, alertmanager types.ServiceConfig, timeout time.Duration) models.LokiAlertDrill {
+	drill := models.LokiAlertDrill{
+		DrillID:   uuid.New().String(),
+		AlertName: lokiDrillAlert,
+	}
+	drill.Stream = map[string]string{
+		"source":   "argus",
+		"drill":    "loki-ruler",
+		"drill_id": drill.DrillID,
+	}
+
+	groups, err := ls.ListRuleGroups(ctx, loki)
+	if err != nil {
+		drill.Error = err.Error()
+		return drill
+	}
+	drill.RuleLoaded = hasLokiRule(groups, lokiDrillAlert)
+
+	if !drill.RuleLoaded {
+		if err := ls.createDrillRules(ctx, loki); err != nil {
+			drill.Error = fmt.Sprintf("%s rule is not loaded and could not be created (%v); mount configs/loki/rules/argus-drill.yaml into the ruler", lokiDrillAlert, err)
+			return drill
+		}
+		drill.RuleLoaded = true
+		drill.RuleCreated = true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	ticker := time.NewTicker(ls.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if !drill.FiredInLoki {
+			if err := ls.pushDrillLine(ctx, loki, drill.Stream, drill.LinesSent); err != nil {
+				if ctx.Err() == nil {
+					drill.Error = fmt.Sprintf("push drill log line: %v", err)
+					return drill
+				}
+				drill.Error = fmt.Sprintf("alert did not fire in Loki within %s", timeout)
+				return drill
+			}
+			drill.LinesSent++
+
+			if fired, _ := ls.lokiAlertFiring(ctx, loki, drill.DrillID); fired {
+				drill.FiredInLoki = true
+				drill.FiredAfter = time.Since(start)
+			}
+		}
+
+		if drill.FiredInLoki {
+			if found, _ := ls.alertmanagerHasAlert(ctx, alertmanager, drill.DrillID); found {
+				drill.InAlertmanager = true
+				drill.ConfirmedAfter = time.Since(start)
+				return drill
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if drill.FiredInLoki {
+				drill.Error = fmt.Sprintf("alert fired in Loki but did not reach Alertmanager within %s", timeout)
+			} else {
+				drill.Error = fmt.Sprintf("alert did not fire in Loki within %s", timeout)
+			}
+			return drill
+		}
+	}
+}
+
+// createDrillRules uploads the bundled drill rule groups to the ruler API
+func (ls *LokiRulerService) createDrillRules(ctx context.Context, loki types.ServiceConfig) error {
+	data, err := fs.ReadFile(configs.Loki, "loki/rules/argus-drill.yaml")
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Groups []lokiRuleGroup `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	for _, group := range file.Groups {
+		body, err := yaml.Marshal(group)
+		if err != nil {
+			return err
+		}
+		status, _, err := ls.do(ctx, loki, "POST", "/loki/api/v1/rules/"+lokiDrillNamespace, "application/yaml", body)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusAccepted && status != http.StatusOK {
+			return fmt.Errorf("create rule group %s: HTTP %d", group.Name, status)
+		}
+	}
+	return nil
+}
+
+// pushDrillLine sends a single matching log line through the Loki push API
+func (ls *LokiRulerService) pushDrillLine(ctx context.Context, loki types.ServiceConfig, stream map[string]string, seq int) error {
+	line := fmt.Sprintf(`ARGUS_LOG_DRILL level=warn drill_id=%s seq=%d msg="Loki ruler drill"`, stream["drill_id"], seq)
+	payload, err := json.Marshal(map[string]interface{}{
+		"streams": []map[string]interface{}{
+			{
+				"stream": stream,
+				"values": [][]string{{strconv.FormatInt(time.Now().UnixNano(), 10), line}},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	status, body, err := ls.do(ctx, loki, "POST", "/loki/api/v1/push", "application/json", payload)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusNoContent && status != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// lokiAlertFiring reports whether the ruler has the drill alert firing for this drill
+func (ls *LokiRulerService) lokiAlertFiring(ctx context.Context, loki types.ServiceConfig, drillID string) (bool, error) {
+	status, body, err := ls.do(ctx, loki, "GET", "/prometheus/api/v1/alerts", "", nil)
+	if err != nil {
+		return false, err
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("list Loki alerts: HTTP %d", status)
+	}
+
+	var result struct {
+		Data struct {
+			Alerts []struct {
+				Labels map[string]string `json:"labels"`
+				State  string            `json:"state"`
+			} `json:"alerts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return false, err
+	}
+	for _, alert := range result.Data.Alerts {
+		if alert.Labels["alertname"] == lokiDrillAlert && alert.Labels["drill_id"] == drillID && alert.State == "firing" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// alertmanagerHasAlert reports whether Alertmanager received the drill alert
+func (ls *LokiRulerService) alertmanagerHasAlert(ctx context.Context, alertmanager types.ServiceConfig, drillID string) (bool, error) {
+	filter := url.QueryEscape(fmt.Sprintf(`alertname="%s"`, lokiDrillAlert))
+	status, body, err := ls.do(ctx, alertmanager, "GET", "/api/v2/alerts?filter="+filter, "", nil)
+	if err != nil {
+		return false, err
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("list Alertmanager alerts: HTTP %d", status)
+	}
+
+	var alerts []struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(body, &alerts); err != nil {
+		return false, err
+	}
+	for _, alert := range alerts {
+		if alert.Labels["drill_id"] == drillID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// do sends a request to a stack component and returns the status and body
+func (ls *LokiRulerService) do(ctx context.Context, service types.ServiceConfig, method, endpoint, contentType string, body []byte) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(service.URL, "/")+endpoint, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := DoServiceRequest(ls.client, service, req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+func hasLokiRule(groups []models.LokiRuleGroup, name string) bool {
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			if rule.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nahuelsantos/argus/internal/configs"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeLoki is a minimal stand-in for the Loki ruler, push and alerts APIs.
+// The drill alert fires once fireAfter matching lines have been pushed.
+type fakeLoki struct {
+	mu        sync.Mutex
+	rules     string
+	created   []string
+	pushed    int
+	fireAfter int
+	drillID   string
+}
+
+func (fl *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/loki/api/v1/rules":
+		if fl.rules == "" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("no rule groups found"))
+			return
+		}
+		_, _ = w.Write([]byte(fl.rules))
+	case strings.HasPrefix(r.URL.Path, "/loki/api/v1/rules/"):
+		body, _ := io.ReadAll(r.Body)
+		fl.created = append(fl.created, string(body))
+		w.WriteHeader(http.StatusAccepted)
+	case r.URL.Path == "/loki/api/v1/format_query":
+		query := r.URL.Query().Get("query")
+		if strings.Count(query, "{") != strings.Count(query, "}") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("parse error : syntax error: unexpected $end"))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "success", "data": query})
+	case r.URL.Path == "/loki/api/v1/push":
+		var body struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+			} `json:"streams"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		fl.drillID = body.Streams[0].Stream["drill_id"]
+		fl.pushed++
+		w.WriteHeader(http.StatusNoContent)
+	case r.URL.Path == "/prometheus/api/v1/alerts":
+		alerts := []map[string]interface{}{}
+		if fl.pushed >= fl.fireAfter {
+			alerts = append(alerts, map[string]interface{}{
+				"labels": map[string]string{"alertname": lokiDrillAlert, "drill_id": fl.drillID},
+				"state":  "firing",
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": map[string]interface{}{"alerts": alerts}})
+	case r.URL.Path == "/api/v2/alerts":
+		// Served from the same stand-in to play the Alertmanager role
+		alerts := []map[string]interface{}{}
+		if fl.pushed >= fl.fireAfter {
+			alerts = append(alerts, map[string]interface{}{
+				"labels": map[string]string{"alertname": lokiDrillAlert, "drill_id": fl.drillID},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(alerts)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+const fakeLokiRules = `
+argus:
+  - name: errors
+    interval: 1m
+    rules:
+      - alert: HighErrorRate
+        expr: sum(rate({app="argus"} |= "error" [5m])) > 1
+      - record: argus:log_lines:rate1m
+        expr: sum(rate({app="argus"[1m]))
+`
+
+func TestLokiRulerService_Validate(t *testing.T) {
+	server := httptest.NewServer(&fakeLoki{rules: fakeLokiRules})
+	defer server.Close()
+
+	ls := NewLokiRulerService()
+	report, err := ls.Validate(context.Background(), types.ServiceConfig{URL: server.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, "degraded", report.Status)
+	require.Len(t, report.RuleGroups, 1)
+	group := report.RuleGroups[0]
+	assert.Equal(t, "argus", group.Namespace)
+	assert.Equal(t, "errors", group.Name)
+
+	require.Len(t, group.Rules, 2)
+	assert.Equal(t, "alert", group.Rules[0].Type)
+	assert.True(t, group.Rules[0].Valid)
+	assert.Equal(t, "record", group.Rules[1].Type)
+	assert.Equal(t, "argus:log_lines:rate1m", group.Rules[1].Name)
+	assert.False(t, group.Rules[1].Valid)
+	assert.Contains(t, group.Rules[1].Error, "parse error")
+
+	assert.Equal(t, 2, report.TotalRules)
+	assert.Equal(t, 1, report.InvalidRules)
+}
+
+func TestLokiRulerService_ValidateNoRules(t *testing.T) {
+	server := httptest.NewServer(&fakeLoki{})
+	defer server.Close()
+
+	report, err := NewLokiRulerService().Validate(context.Background(), types.ServiceConfig{URL: server.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, "degraded", report.Status)
+	assert.Empty(t, report.RuleGroups)
+	assert.Contains(t, report.Problems[0], "no rule groups")
+}
+
+func TestLokiRulerService_RunDrill(t *testing.T) {
+	loki := &fakeLoki{fireAfter: 3}
+	server := httptest.NewServer(loki)
+	defer server.Close()
+
+	ls := NewLokiRulerService()
+	ls.pollInterval = 10 * time.Millisecond
+	config := types.ServiceConfig{URL: server.URL}
+
+	drill := ls.RunDrill(context.Background(), config, config, 5*time.Second)
+
+	assert.Empty(t, drill.Error)
+	assert.True(t, drill.RuleCreated, "drill rule should be created when missing")
+	require.Len(t, loki.created, 1)
+	assert.Contains(t, loki.created[0], lokiDrillAlert)
+
+	assert.True(t, drill.FiredInLoki)
+	assert.True(t, drill.InAlertmanager)
+	assert.Equal(t, 3, drill.LinesSent, "pushing stops once the alert fires")
+	assert.Equal(t, drill.DrillID, drill.Stream["drill_id"])
+	assert.GreaterOrEqual(t, drill.ConfirmedAfter, drill.FiredAfter)
+}
+
+func TestLokiRulerService_RunDrillTimeout(t *testing.T) {
+	server := httptest.NewServer(&fakeLoki{rules: "argus:\n  - name: argus-drill\n    rules:\n      - alert: ArgusLogDrill\n        expr: vector(0)\n", fireAfter: 1000})
+	defer server.Close()
+
+	ls := NewLokiRulerService()
+	ls.pollInterval = 10 * time.Millisecond
+	config := types.ServiceConfig{URL: server.URL}
+
+	drill := ls.RunDrill(context.Background(), config, config, 50*time.Millisecond)
+
+	assert.True(t, drill.RuleLoaded)
+	assert.False(t, drill.RuleCreated)
+	assert.False(t, drill.FiredInLoki)
+	assert.Contains(t, drill.Error, "did not fire in Loki")
+}
+
+func TestLokiDrillRulesBundle(t *testing.T) {
+	data, err := fs.ReadFile(configs.Loki, "loki/rules/argus-drill.yaml")
+	require.NoError(t, err)
+
+	var file struct {
+		Groups []lokiRuleGroup `yaml:"groups"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &file))
+	require.NotEmpty(t, file.Groups)
+	assert.Equal(t, lokiDrillAlert, file.Groups[0].Rules[0].Alert)
+	assert.Contains(t, file.Groups[0].Rules[0].Expr, "drill_id")
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+var (
+	patternGaugeDesc = prometheus.NewDesc("argus_pattern_value",
+		"Generated gauge series following a configured pattern", []string{"series"}, nil)
+	patternCounterDesc = prometheus.NewDesc("argus_pattern_total",
+		"Generated counter series whose rate follows a configured pattern", []string{"series"}, nil)
+)
+
+// MetricPatternEngine generates series shaped like production data. It is a
+// Prometheus collector: every scrape evaluates each series' pattern at the
+// time elapsed since the series started, so values follow the pattern in
+// real time without a background loop.
+type MetricPatternEngine struct {
+	mu     sync.Mutex
+	series map[string]*patternSeries
+	now    func() time.Time
+}
+
+// NewMetricPatternEngine creates an engine with no series
+func NewMetricPatternEngine() *MetricPatternEngine {
+	return &MetricPatternEngine{
+		series: make(map[string]*patternSeries),
+		now:    time.Now,
+	}
+}
+
+// Set validates a series and starts it, replacing any series with its name.
+// A random seed is picked when the series has none.
+func (e *MetricPatternEngine) Set(series types.MetricSeries) (models.PatternSeries, error) {
+	if err := series.Validate(); err != nil {
+		return models.PatternSeries{}, err
+	}
+	series, _ = series.Resolve()
+	if series.Kind == "" {
+		series.Kind = "gauge"
+	}
+	if series.Seed == 0 {
+		series.Seed = rand.Int63()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ps := newPatternSeries(series, e.now())
+	e.series[series.Name] = ps
+	return ps.snapshot(), nil
+}
+
+// Remove stops a series; it reports whether the series existed
+func (e *MetricPatternEngine) Remove(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.series[name]
+	delete(e.series, name)
+	return ok
+}
+
+// Get returns a series' definition
+func (e *MetricPatternEngine) Get(name string) (types.MetricSeries, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ps, ok := e.series[name]
+	if !ok {
+		return types.MetricSeries{}, false
+	}
+	return ps.config, true
+}
+
+// List returns every series with the value of its latest evaluation, ordered by name
+func (e *MetricPatternEngine) List() []models.PatternSeries {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	list := make([]models.PatternSeries, 0, len(e.series))
+	for _, ps := range e.series {
+		list = append(list, ps.snapshot())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Describe implements prometheus.Collector
+func (e *MetricPatternEngine) Describe(ch chan<- *prometheus.Desc) {
+	ch <- patternGaugeDesc
+	ch <- patternCounterDesc
+}
+
+// Collect implements prometheus.Collector. Series inside a gap are left out.
+func (e *MetricPatternEngine) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for name, ps := range e.series {
+		value, present := ps.evaluate(now)
+		if !present {
+			continue
+		}
+		if ps.config.Kind == "counter" {
+			ch <- prometheus.MustNewConstMetric(patternCounterDesc, prometheus.CounterValue, value, name)
+		} else {
+			ch <- prometheus.MustNewConstMetric(patternGaugeDesc, prometheus.GaugeValue, value, name)
+		}
+	}
+}
+
+// patternSeries is a series and the state its stateful components carry
+// between evaluations
+type patternSeries struct {
+	config  types.MetricSeries
+	start   time.Time
+	rng     *rand.Rand
+	walks   []float64 // random walk position per component
+	elapsed float64   // seconds since start at the latest evaluation
+	counter float64
+	value   float64
+	present bool
+}
+
+func newPatternSeries(config types.MetricSeries, start time.Time) *patternSeries {
+	return &patternSeries{
+		config:  config,
+		start:   start,
+		rng:     rand.New(rand.NewSource(config.Seed)),
+		walks:   make([]float64, len(config.Pattern)),
+		present: true,
+	}
+}
+
+// evaluate computes the series at now and advances the random walks and the
+// counter by the time since the previous evaluation
+func (ps *patternSeries) evaluate(now time.Time) (float64, bool) {
+	elapsed := now.Sub(ps.start).Seconds()
+	dt := math.Max(elapsed-ps.elapsed, 0)
+
+	value, present, reset := 0.0, true, false
+	for i, c := range ps.config.Pattern {
+		switch c.Type {
+		case types.PatternConstant:
+			value += c.Value
+		case types.PatternSine:
+			value += c.Amplitude * math.Sin(2*math.Pi*(elapsed+c.PhaseSeconds)/c.PeriodSeconds)
+		case types.PatternTrend:
+			value += c.Value * elapsed / 3600
+		case types.PatternRandomWalk:
+			ps.walks[i] += ps.rng.NormFloat64() * c.Value * math.Sqrt(dt)
+			value += ps.walks[i]
+		case types.PatternStep:
+			if elapsed >= c.AtSeconds {
+				steps := 1.0
+				if c.EverySeconds > 0 {
+					steps += math.Floor((elapsed - c.AtSeconds) / c.EverySeconds)
+				}
+				value += steps * c.Value
+			}
+		case types.PatternSpikes:
+			window := c.Window(15)
+			if ps.chance(i, math.Floor(elapsed/window)) < c.Probability {
+				value += c.Value
+			}
+		case types.PatternGaps:
+			window := c.Window(60)
+			if c.EverySeconds > 0 {
+				// The gap closes each period, so a new series starts present
+				present = present && math.Mod(elapsed, c.EverySeconds) < c.EverySeconds-window
+			} else {
+				present = present && ps.chance(i, math.Floor(elapsed/window)) >= c.Probability
+			}
+		case types.PatternCounterReset:
+			if c.EverySeconds > 0 {
+				reset = reset || math.Floor(elapsed/c.EverySeconds) > math.Floor(ps.elapsed/c.EverySeconds)
+			} else {
+				window := c.Window(60)
+				bucket := math.Floor(elapsed / window)
+				reset = reset || (bucket > math.Floor(ps.elapsed/window) && ps.chance(i, bucket) < c.Probability)
+			}
+		}
+	}
+
+	if ps.config.Kind == "counter" {
+		if reset {
+			ps.counter = 0
+		}
+		ps.counter += math.Max(value, 0) * dt
+		value = ps.counter
+	}
+
+	ps.elapsed = elapsed
+	ps.value, ps.present = value, present
+	return value, present
+}
+
+// chance returns a number in [0, 1) fixed by the series' seed, the component
+// and the window, so random spikes and gaps cover whole windows and repeat
+// for the same seed
+func (ps *patternSeries) chance(component int, window float64) float64 {
+	// splitmix64 mixes the inputs so neighbouring windows are unrelated
+	x := uint64(ps.config.Seed) ^ uint64(component)*0x9e3779b97f4a7c15 ^ math.Float64bits(window)*0xbf58476d1ce4e5b9
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
+
+// snapshot describes the series for callers
+func (ps *patternSeries) snapshot() models.PatternSeries {
+	metric := "argus_pattern_value"
+	if ps.config.Kind == "counter" {
+		metric = "argus_pattern_total"
+	}
+	components := make([]string, 0, len(ps.config.Pattern))
+	for _, c := range ps.config.Pattern {
+		components = append(components, c.Type)
+	}
+	return models.PatternSeries{
+		Name:       ps.config.Name,
+		Kind:       ps.config.Kind,
+		Metric:     metric,
+		Preset:     ps.config.Preset,
+		Components: components,
+		Seed:       ps.config.Seed,
+		Started:    ps.start,
+		Value:      ps.value,
+		Present:    ps.present,
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// patternClock returns an engine whose clock is moved with the returned func
+func patternClock() (*MetricPatternEngine, func(seconds float64)) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	engine := NewMetricPatternEngine()
+	engine.now = func() time.Time { return now }
+	return engine, func(seconds float64) {
+		now = start.Add(time.Duration(seconds * float64(time.Second)))
+	}
+}
+
+// patternValues evaluates the named series at each elapsed second and
+// returns the values, with -1 where the series is hidden
+func patternValues(t *testing.T, engine *MetricPatternEngine, at func(float64), name string, seconds ...float64) []float64 {
+	values := make([]float64, 0, len(seconds))
+	for _, s := range seconds {
+		at(s)
+		engine.mu.Lock()
+		value, present := engine.series[name].evaluate(engine.now())
+		engine.mu.Unlock()
+		if !present {
+			value = -1
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func TestMetricPatternEngine_Components(t *testing.T) {
+	engine, at := patternClock()
+	set := func(series types.MetricSeries) {
+		_, err := engine.Set(series)
+		require.NoError(t, err)
+	}
+
+	set(types.MetricSeries{Name: "seasonal", Pattern: []types.PatternComponent{
+		{Type: types.PatternConstant, Value: 100},
+		{Type: types.PatternSine, Amplitude: 40, PeriodSeconds: 3600},
+		{Type: types.PatternTrend, Value: 30},
+	}})
+	values := patternValues(t, engine, at, "seasonal", 0, 900, 2700)
+	assert.InDelta(t, 100, values[0], 1e-9)
+	assert.InDelta(t, 147.5, values[1], 1e-9) // sine peak plus a quarter hour of trend
+	assert.InDelta(t, 82.5, values[2], 1e-9)  // sine trough plus three quarters
+
+	at(0)
+	set(types.MetricSeries{Name: "staircase", Pattern: []types.PatternComponent{
+		{Type: types.PatternConstant, Value: 100},
+		{Type: types.PatternStep, Value: 50, AtSeconds: 300, EverySeconds: 60},
+	}})
+	assert.Equal(t, []float64{100, 150, 150, 200, 250}, patternValues(t, engine, at, "staircase", 299, 300, 359, 360, 420))
+
+	at(0)
+	set(types.MetricSeries{Name: "gappy", Preset: "gappy"})
+	values = patternValues(t, engine, at, "gappy", 0, 479, 480, 599, 600)
+	assert.Greater(t, values[0], 0.0)
+	assert.Greater(t, values[1], 0.0)
+	assert.Equal(t, []float64{-1, -1}, values[2:4])
+	assert.Greater(t, values[4], 0.0)
+
+	at(0)
+	set(types.MetricSeries{Name: "spikes", Pattern: []types.PatternComponent{
+		{Type: types.PatternSpikes, Value: 10, Probability: 1},
+		{Type: types.PatternSpikes, Value: 1000, Probability: 0},
+	}})
+	assert.Equal(t, []float64{10, 10}, patternValues(t, engine, at, "spikes", 0, 100))
+}
+
+func TestMetricPatternEngine_CounterResets(t *testing.T) {
+	engine, at := patternClock()
+	_, err := engine.Set(types.MetricSeries{Name: "requests", Kind: "counter", Pattern: []types.PatternComponent{
+		{Type: types.PatternConstant, Value: 5},
+		{Type: types.PatternCounterReset, EverySeconds: 900},
+	}})
+	require.NoError(t, err)
+
+	// 5 per second accumulates until the counter resets at 900s
+	assert.Equal(t, []float64{0, 500, 4000, 500, 1000}, patternValues(t, engine, at, "requests", 0, 100, 800, 900, 1000))
+}
+
+func TestMetricPatternEngine_SeedsRepeat(t *testing.T) {
+	series := types.MetricSeries{Name: "noisy", Seed: 42, Pattern: []types.PatternComponent{
+		{Type: types.PatternRandomWalk, Value: 1},
+		{Type: types.PatternSpikes, Value: 100, Probability: 0.3, DurationSeconds: 10},
+	}}
+	seconds := []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90}
+
+	first, at := patternClock()
+	_, err := first.Set(series)
+	require.NoError(t, err)
+	a := patternValues(t, first, at, "noisy", seconds...)
+
+	second, at := patternClock()
+	_, err = second.Set(series)
+	require.NoError(t, err)
+	b := patternValues(t, second, at, "noisy", seconds...)
+
+	assert.Equal(t, a, b)
+	spiked := 0
+	for _, value := range a {
+		if value > 50 {
+			spiked++
+		}
+	}
+	assert.Greater(t, spiked, 0)
+	assert.Less(t, spiked, len(a))
+}
+
+func TestMetricPatternEngine_Collect(t *testing.T) {
+	engine, at := patternClock()
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(engine))
+
+	created, err := engine.Set(types.MetricSeries{Name: "orders", Preset: "counter_resets"})
+	require.NoError(t, err)
+	assert.Equal(t, "argus_pattern_total", created.Metric)
+	assert.Equal(t, []string{"constant", "sine", "counter_reset"}, created.Components)
+	assert.NotZero(t, created.Seed)
+
+	_, err = engine.Set(types.MetricSeries{Name: "latency", Pattern: []types.PatternComponent{
+		{Type: types.PatternConstant, Value: 7},
+		{Type: types.PatternGaps, EverySeconds: 100, DurationSeconds: 50},
+	}})
+	require.NoError(t, err)
+
+	at(10)
+	assert.Equal(t, 2, testutil.CollectAndCount(registry))
+	require.NoError(t, testutil.CollectAndCompare(engine, strings.NewReader(`
+# HELP argus_pattern_value Generated gauge series following a configured pattern
+# TYPE argus_pattern_value gauge
+argus_pattern_value{series="latency"} 7
+`), "argus_pattern_value"))
+
+	at(60)
+	assert.Equal(t, 1, testutil.CollectAndCount(registry), "latency is inside its gap")
+	list := engine.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "latency", list[0].Name)
+	assert.False(t, list[0].Present)
+
+	assert.True(t, engine.Remove("latency"))
+	assert.False(t, engine.Remove("latency"))
+	_, err = engine.Set(types.MetricSeries{Name: "bad", Preset: "missing"})
+	assert.EqualError(t, err, `unknown preset "missing"`)
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/nahuelsantos/argus/internal/models"
+)
+
+var (
+	metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNamePattern  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// nonBaseUnits maps unit suffixes to the base unit Prometheus recommends instead
+var nonBaseUnits = map[string]string{
+	"nanoseconds":  "seconds",
+	"microseconds": "seconds",
+	"milliseconds": "seconds",
+	"minutes":      "seconds",
+	"hours":        "seconds",
+	"days":         "seconds",
+	"bits":         "bytes",
+	"kilobytes":    "bytes",
+	"megabytes":    "bytes",
+	"gigabytes":    "bytes",
+	"kibibytes":    "bytes",
+	"mebibytes":    "bytes",
+	"gibibytes":    "bytes",
+	"percent":      "ratio",
+	"fahrenheit":   "celsius",
+}
+
+// maxMetricsBody caps the exposition read from a target
+const maxMetricsBody = 32 << 20
+
+// MetricsLintService checks metrics expositions against the Prometheus
+// naming and format conventions
+type MetricsLintService struct {
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+// NewMetricsLintService creates a linter whose own exposition comes from gatherer
+func NewMetricsLintService(gatherer prometheus.Gatherer) *MetricsLintService {
+	return &MetricsLintService{
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LintSelf lints the metrics Argus exposes on /metrics
+func (ms *MetricsLintService) LintSelf() *models.MetricsLintReport {
+	families, err := ms.gatherer.Gather()
+	var buf bytes.Buffer
+	for _, family := range families {
+		if _, encodeErr := expfmt.MetricFamilyToText(&buf, family); encodeErr != nil && err == nil {
+			err = encodeErr
+		}
+	}
+
+	report := lintExposition("argus", buf.Bytes())
+	report.ContentType = string(expfmt.FmtText)
+	if err != nil {
+		report.Problems = append(report.Problems, "gather: "+err.Error())
+		report.Status = "failed"
+	}
+	return report
+}
+
+// LintURL fetches a target's exposition in the text format and lints it
+func (ms *MetricsLintService) LintURL(ctx context.Context, target string) *models.MetricsLintReport {
+	fail := func(err error) *models.MetricsLintReport {
+		report := lintExposition(target, nil)
+		report.Status = "failed"
+		report.Problems = append(report.Problems, err.Error())
+		return report
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return fail(err)
+	}
+	// Ask for the classic text format, which the parser understands
+	req.Header.Set("Accept", string(expfmt.FmtText))
+	resp, err := ms.client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("fetch %s: HTTP %d", target, resp.StatusCode))
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetricsBody))
+	if err != nil {
+		return fail(fmt.Errorf("read %s: %w", target, err))
+	}
+
+	report := lintExposition(target, data)
+	report.ContentType = resp.Header.Get("Content-Type")
+	return report
+}
+
+// lintExposition parses text-format metrics and reports every family that
+// breaks a convention
+func lintExposition(target string, data []byte) *models.MetricsLintReport {
+	report := &models.MetricsLintReport{
+		Target:    target,
+		Findings:  []models.MetricLintFinding{},
+		Rules:     map[string]int{},
+		Problems:  []string{},
+		Timestamp: time.Now(),
+	}
+
+	// The parser fills in defaults, so declared HELP and TYPE lines are read from the text
+	helped, typed := map[string]bool{}, map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxMetricsBody)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == "#" {
+			switch fields[1] {
+			case "HELP":
+				helped[fields[2]] = len(fields) > 3
+			case "TYPE":
+				typed[fields[2]] = true
+			}
+		}
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		report.Problems = append(report.Problems, "parse: "+err.Error())
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := families[name]
+		report.Families++
+		report.Series += len(family.GetMetric())
+
+		var findings []models.MetricLintFinding
+		add := func(label, rule, severity, format string, args ...interface{}) {
+			findings = append(findings, models.MetricLintFinding{
+				Metric: name, Label: label, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		if !helped[name] {
+			add("", "help_missing", "warning", "no HELP text")
+		}
+		if !typed[name] {
+			add("", "type_missing", "warning", "no TYPE line, so the family is untyped")
+		}
+		lintMetricName(name, family.GetType(), add)
+		lintLabelNames(family, add)
+		if family.GetType() == dto.MetricType_HISTOGRAM {
+			lintHistogram(family, add)
+		}
+
+		for _, finding := range findings {
+			report.Rules[finding.Rule]++
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	severe := 0
+	for _, finding := range report.Findings {
+		if finding.Severity == "error" {
+			severe++
+		}
+	}
+	switch {
+	case len(report.Problems) > 0 || severe > 0:
+		report.Status = "failed"
+	case len(report.Findings) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report
+}
+
+type lintFunc func(label, rule, severity, format string, args ...interface{})
+
+// lintMetricName checks the name's characters, _total and unit suffixes
+func lintMetricName(name string, metricType dto.MetricType, add lintFunc) {
+	if !metricNamePattern.MatchString(name) {
+		add("", "metric_name", "error", "name is not a valid metric name")
+		return
+	}
+	if strings.ToLower(name) != name {
+		add("", "metric_name", "warning", "name should be snake_case, not camelCase")
+	}
+	if strings.Contains(name, ":") {
+		add("", "metric_name", "warning", "colons are reserved for recording rules")
+	}
+
+	switch {
+	case metricType == dto.MetricType_COUNTER && !strings.HasSuffix(name, "_total"):
+		add("", "counter_suffix", "warning", "counter names should end in _total")
+	case metricType != dto.MetricType_COUNTER && metricType != dto.MetricType_UNTYPED && strings.HasSuffix(name, "_total"):
+		add("", "counter_suffix", "warning", "only counter names should end in _total")
+	case strings.Contains(name, "_total_"):
+		add("", "counter_suffix", "warning", "_total should be the last suffix, after the unit")
+	}
+
+	if metricType != dto.MetricType_HISTOGRAM && metricType != dto.MetricType_SUMMARY {
+		for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+			if strings.HasSuffix(name, suffix) {
+				add("", "reserved_suffix", "warning", "the %s suffix is reserved for histogram and summary series", suffix)
+			}
+		}
+	}
+
+	for _, part := range strings.Split(strings.ToLower(name), "_") {
+		if base, ok := nonBaseUnits[part]; ok {
+			add("", "unit_suffix", "warning", "%s is not a base unit, use %s", part, base)
+		}
+	}
+}
+
+// lintLabelNames checks every label name used by the family's series once
+func lintLabelNames(family *dto.MetricFamily, add lintFunc) {
+	seen := map[string]bool{}
+	for _, metric := range family.GetMetric() {
+		for _, pair := range metric.GetLabel() {
+			label := pair.GetName()
+			if seen[label] {
+				continue
+			}
+			seen[label] = true
+
+			switch {
+			case !labelNamePattern.MatchString(label):
+				add(label, "label_name", "error", "label name is not valid")
+			case strings.HasPrefix(label, "__"):
+				add(label, "label_name", "error", "label names starting with __ are reserved")
+			case strings.ToLower(label) != label:
+				add(label, "label_name", "warning", "label name should be snake_case, not camelCase")
+			case label == "le" && family.GetType() != dto.MetricType_HISTOGRAM:
+				add(label, "label_name", "warning", "le is reserved for histogram buckets")
+			case label == "quantile" && family.GetType() != dto.MetricType_SUMMARY:
+				add(label, "label_name", "warning", "quantile is reserved for summary quantiles")
+			}
+		}
+	}
+}
+
+// lintHistogram checks that every series' buckets are ordered, cumulative and
+// end in +Inf matching _count, and that all series share one bucket layout
+func lintHistogram(family *dto.MetricFamily, add lintFunc) {
+	issues := map[string]bool{}
+	issue := func(format string, args ...interface{}) {
+		message := fmt.Sprintf(format, args...)
+		if !issues[message] {
+			issues[message] = true
+			add("", "histogram_buckets", "error", "%s", message)
+		}
+	}
+
+	var layout []float64
+	for _, metric := range family.GetMetric() {
+		histogram := metric.GetHistogram()
+		buckets := histogram.GetBucket()
+		bounds := make([]float64, 0, len(buckets))
+
+		for i, bucket := range buckets {
+			bounds = append(bounds, bucket.GetUpperBound())
+			if i == 0 {
+				continue
+			}
+			if bucket.GetUpperBound() <= buckets[i-1].GetUpperBound() {
+				issue("bucket boundaries are not in increasing order")
+			}
+			if bucket.GetCumulativeCount() < buckets[i-1].GetCumulativeCount() {
+				issue("bucket counts decrease, so they are not cumulative")
+			}
+		}
+
+		if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].GetUpperBound(), 1) {
+			issue("the +Inf bucket is missing")
+		} else if count := buckets[len(buckets)-1].GetCumulativeCount(); count != histogram.GetSampleCount() {
+			issue("the +Inf bucket counts %d observations but _count is %d", count, histogram.GetSampleCount())
+		}
+
+		if layout == nil {
+			layout = bounds
+		} else if !sameBounds(layout, bounds) && !issues["layout"] {
+			issues["layout"] = true
+			add("", "bucket_layout", "warning", "series use different bucket boundaries, so they cannot be aggregated")
+		}
+	}
+}
+
+func sameBounds(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+)
+
+const lintExpositionText = `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{method="GET"} 10
+# HELP requestLatency_milliseconds Request latency.
+# TYPE requestLatency_milliseconds histogram
+requestLatency_milliseconds_bucket{path="/a",le="10"} 4
+requestLatency_milliseconds_bucket{path="/a",le="5"} 6
+requestLatency_milliseconds_bucket{path="/a",le="+Inf"} 5
+requestLatency_milliseconds_sum{path="/a"} 30
+requestLatency_milliseconds_count{path="/a"} 7
+requestLatency_milliseconds_bucket{path="/b",le="10"} 1
+requestLatency_milliseconds_sum{path="/b"} 3
+requestLatency_milliseconds_count{path="/b"} 1
+# HELP jobs_processed Jobs processed.
+# TYPE jobs_processed counter
+jobs_processed{workerID="1",le="x"} 3
+# TYPE queue_size_total gauge
+queue_size_total 4
+disk_usage_percent{__mount="/"} 40
+`
+
+func TestLintExposition(t *testing.T) {
+	report := lintExposition("test", []byte(lintExpositionText))
+
+	assert.Equal(t, "failed", report.Status)
+	assert.Empty(t, report.Problems)
+	assert.Equal(t, 5, report.Families)
+	assert.Equal(t, 6, report.Series)
+
+	byMetric := map[string][]string{}
+	for _, finding := range report.Findings {
+		key := finding.Rule
+		if finding.Label != "" {
+			key += ":" + finding.Label
+		}
+		byMetric[finding.Metric] = append(byMetric[finding.Metric], key+" "+finding.Severity)
+	}
+	assert.NotContains(t, byMetric, "http_requests_total")
+	assert.ElementsMatch(t, []string{
+		"metric_name warning",
+		"unit_suffix warning",
+		"histogram_buckets error", // order
+		"histogram_buckets error", // counts
+		"histogram_buckets error", // +Inf vs _count
+		"histogram_buckets error", // missing +Inf
+		"bucket_layout warning",
+	}, byMetric["requestLatency_milliseconds"])
+	assert.ElementsMatch(t, []string{"counter_suffix warning", "label_name:workerID warning", "label_name:le warning"}, byMetric["jobs_processed"])
+	assert.ElementsMatch(t, []string{"help_missing warning", "counter_suffix warning"}, byMetric["queue_size_total"])
+	assert.ElementsMatch(t, []string{"help_missing warning", "type_missing warning", "unit_suffix warning", "label_name:__mount error"}, byMetric["disk_usage_percent"])
+	assert.Equal(t, 4, report.Rules["histogram_buckets"])
+
+	for _, finding := range report.Findings {
+		if finding.Rule == "histogram_buckets" && finding.Message != "bucket boundaries are not in increasing order" &&
+			finding.Message != "bucket counts decrease, so they are not cumulative" && finding.Message != "the +Inf bucket is missing" {
+			assert.Equal(t, "the +Inf bucket counts 5 observations but _count is 7", finding.Message)
+		}
+	}
+
+	clean := lintExposition("test", []byte("# HELP up Target is up.\n# TYPE up gauge\nup 1\n"))
+	assert.Equal(t, "healthy", clean.Status)
+	assert.Empty(t, clean.Findings)
+
+	broken := lintExposition("test", []byte("up{job=\"a\" 1\n"))
+	assert.Equal(t, "failed", broken.Status)
+	require.Len(t, broken.Problems, 1)
+	assert.Contains(t, broken.Problems[0], "parse: ")
+}
+
+func TestMetricsLintService(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "argus_checks_total", Help: "Checks run."}))
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{Name: "argus_cache_megabytes", Help: "Cache size."}))
+	ms := NewMetricsLintService(registry)
+
+	report := ms.LintSelf()
+	assert.Equal(t, "degraded", report.Status)
+	assert.Equal(t, "argus", report.Target)
+	assert.Equal(t, 2, report.Families)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, models.MetricLintFinding{Metric: "argus_cache_megabytes", Rule: "unit_suffix", Severity: "warning", Message: "megabytes is not a base unit, use bytes"}, report.Findings[0])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Accept"), "text/plain")
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(lintExpositionText))
+	}))
+	defer server.Close()
+
+	report = ms.LintURL(context.Background(), server.URL+"/metrics")
+	assert.Equal(t, "failed", report.Status)
+	assert.Equal(t, "text/plain; version=0.0.4", report.ContentType)
+	assert.Equal(t, 5, report.Families)
+
+	report = ms.LintURL(context.Background(), server.URL+"/missing")
+	assert.Equal(t, "failed", report.Status)
+	assert.Equal(t, []string{"fetch " + server.URL + "/missing: HTTP 404"}, report.Problems)
+}
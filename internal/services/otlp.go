@@ -0,0 +1,373 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/trace"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// newOTLPTLSConfig builds the TLS client configuration for an exporter, or
+// returns nil when the system defaults are enough
+func newOTLPTLSConfig(config types.OTLPExporterConfig) (*tls.Config, error) {
+	if config.CAFile == "" && config.CertFile == "" {
+		return nil, nil
+	}
+
+	return NewServiceTLSConfig(types.TLSConfig{CAFile: config.CAFile, CertFile: config.CertFile, KeyFile: config.KeyFile})
+}
+
+// newTraceExporter creates an OTLP span exporter over HTTP or gRPC
+func newTraceExporter(ctx context.Context, config types.OTLPExporterConfig) (trace.SpanExporter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	hostPort, path, insecure, _ := config.Target()
+
+	tlsConfig, err := newOTLPTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Protocol == types.OTLPProtocolGRPC {
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(hostPort)}
+		switch {
+		case insecure:
+			options = append(options, otlptracegrpc.WithInsecure())
+		case tlsConfig != nil:
+			options = append(options, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsConfig)))
+		}
+		if len(config.Headers) > 0 {
+			options = append(options, otlptracegrpc.WithHeaders(config.Headers))
+		}
+		if config.Compression == "gzip" {
+			options = append(options, otlptracegrpc.WithCompressor("gzip"))
+		}
+		if config.TimeoutMs > 0 {
+			options = append(options, otlptracegrpc.WithTimeout(time.Duration(config.TimeoutMs)*time.Millisecond))
+		}
+		return otlptracegrpc.New(ctx, options...)
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(hostPort)}
+	if path != "" {
+		options = append(options, otlptracehttp.WithURLPath(path))
+	}
+	switch {
+	case insecure:
+		options = append(options, otlptracehttp.WithInsecure())
+	case tlsConfig != nil:
+		options = append(options, otlptracehttp.WithTLSClientConfig(tlsConfig))
+	}
+	if len(config.Headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(config.Headers))
+	}
+	if config.Compression == "gzip" {
+		options = append(options, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+	} else {
+		options = append(options, otlptracehttp.WithCompression(otlptracehttp.NoCompression))
+	}
+	if config.TimeoutMs > 0 {
+		options = append(options, otlptracehttp.WithTimeout(time.Duration(config.TimeoutMs)*time.Millisecond))
+	}
+	return otlptracehttp.New(ctx, options...)
+}
+
+// otlpClient sends raw OTLP export requests for the signals Argus bridges
+// itself (logs and metrics), over HTTP/protobuf or gRPC
+type otlpClient struct {
+	config  types.OTLPExporterConfig
+	url     string
+	http    *http.Client
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// newOTLPClient creates a client for one signal. defaultPath is posted to when
+// neither the endpoint nor URLPath carries a path.
+func newOTLPClient(config types.OTLPExporterConfig, defaultPath string) (*otlpClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	hostPort, path, plaintext, _ := config.Target()
+
+	tlsConfig, err := newOTLPTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &otlpClient{config: config, timeout: 10 * time.Second}
+	if config.TimeoutMs > 0 {
+		client.timeout = time.Duration(config.TimeoutMs) * time.Millisecond
+	}
+
+	if config.Protocol == types.OTLPProtocolGRPC {
+		creds := insecure.NewCredentials()
+		if !plaintext {
+			if tlsConfig == nil {
+				tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			creds = credentials.NewTLS(tlsConfig)
+		}
+		client.conn, err = grpc.Dial(hostPort, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("dial %s: %w", hostPort, err)
+		}
+		return client, nil
+	}
+
+	scheme := "https"
+	if plaintext {
+		scheme = "http"
+	}
+	if path == "" {
+		path = defaultPath
+	}
+	client.url = scheme + "://" + hostPort + path
+	client.http = &http.Client{Timeout: client.timeout}
+	if tlsConfig != nil {
+		client.http.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return client, nil
+}
+
+// export sends request and decodes the collector's reply into response.
+// grpcMethod is the full method name used when the protocol is gRPC.
+func (c *otlpClient) export(ctx context.Context, grpcMethod string, request, response proto.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if c.conn != nil {
+		for key, value := range c.config.Headers {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+		var options []grpc.CallOption
+		if c.config.Compression == "gzip" {
+			options = append(options, grpc.UseCompressor(grpcgzip.Name))
+		}
+		return c.conn.Invoke(ctx, grpcMethod, request, response, options...)
+	}
+
+	payload, err := proto.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	if c.config.Compression == "gzip" {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(payload); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if c.config.Compression == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	if len(body) > 0 && resp.Header.Get("Content-Type") == "application/x-protobuf" {
+		if err := proto.Unmarshal(body, response); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// endpoint returns where the client sends requests, for reporting
+func (c *otlpClient) endpoint() string {
+	if c.conn != nil {
+		return c.conn.Target()
+	}
+	return c.url
+}
+
+func (c *otlpClient) close() error {
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// otlpScope is the instrumentation scope of the records Argus bridges itself
+var otlpScope = &commonpb.InstrumentationScope{Name: "github.com/nahuelsantos/argus"}
+
+// newOTLPResource describes Argus with the same attributes as its traces
+func newOTLPResource(serviceConfig *config.ServiceConfig) *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: otlpAttributes(map[string]interface{}{
+		"service.name":           serviceConfig.Name,
+		"service.version":        serviceConfig.Version,
+		"deployment.environment": serviceConfig.Environment,
+	})}
+}
+
+// otlpAttributes converts a map to OTLP attributes, sorted by key
+func otlpAttributes(values map[string]interface{}) []*commonpb.KeyValue {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := make([]*commonpb.KeyValue, 0, len(keys))
+	for _, key := range keys {
+		attributes = append(attributes, &commonpb.KeyValue{Key: key, Value: otlpAnyValue(values[key])})
+	}
+	return attributes
+}
+
+// otlpAnyValue converts the values zap's map encoder produces
+func otlpAnyValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int8:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+	case uint:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint8:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint16:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case uint64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(v)}}
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case time.Duration:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	case []interface{}:
+		array := &commonpb.ArrayValue{}
+		for _, item := range v {
+			array.Values = append(array.Values, otlpAnyValue(item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	case map[string]interface{}:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: otlpAttributes(v)}}}
+	case nil:
+		return &commonpb.AnyValue{}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
+
+// recordExport updates the totals after one request carrying items records,
+// of which the collector reported rejected through partial_success
+func recordExport(stats *models.OTLPExportStats, items, rejected int64, message string, err error) {
+	switch {
+	case err != nil:
+		stats.Failed += items
+		stats.LastError = err.Error()
+	case rejected > 0:
+		stats.Exported += items - rejected
+		stats.Rejected += rejected
+		stats.LastError = fmt.Sprintf("collector rejected %d of %d items: %s", rejected, items, message)
+	default:
+		stats.Exported += items
+		stats.LastError = ""
+	}
+	stats.LastExport = time.Now()
+}
+
+// exportWorker calls flush every interval, and whenever wake fires, until it
+// PendingConfig is a new configuration whose exporters or sinks are built
+// but not yet in use. Apply swaps it in; Discard releases what was built.
+type PendingConfig interface {
+	Apply()
+	Discard()
+}
+
+// is shut down
+type exportWorker struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func startExportWorker(interval time.Duration, wake <-chan struct{}, flush func()) *exportWorker {
+	w := &exportWorker{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-wake:
+			case <-w.stop:
+				flush()
+				return
+			}
+			flush()
+		}
+	}()
+	return w
+}
+
+// shutdown stops the worker after a final flush; later calls only wait
+func (w *exportWorker) shutdown() {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+}
@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+const logsExportMethod = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+// LogExporter batches log records and ships them to an OTLP collector. It is
+// fed by a zap core, so everything LoggingService writes is bridged with its
+// trace and span IDs.
+type LogExporter struct {
+	resource *resourcepb.Resource
+	enabled  atomic.Bool
+	wake     chan struct{}
+
+	mu       sync.Mutex
+	settings types.LogExportSettings
+	client   *otlpClient
+	worker   *exportWorker
+	queue    []*logspb.LogRecord
+	stats    models.OTLPExportStats
+
+	// exportMu keeps batches in order when a flush races the worker
+	exportMu sync.Mutex
+}
+
+// NewLogExporter creates a disabled log exporter; call Configure to start it
+func NewLogExporter(serviceConfig *config.ServiceConfig) *LogExporter {
+	return &LogExporter{
+		resource: newOTLPResource(serviceConfig),
+		wake:     make(chan struct{}, 1),
+		stats:    models.OTLPExportStats{Signal: "logs"},
+	}
+}
+
+// Configure replaces the exporter settings. Records queued for the previous
+// endpoint are flushed to it before it is closed.
+func (le *LogExporter) Configure(settings types.LogExportSettings) error {
+	pending, err := le.Prepare(settings)
+	if err != nil {
+		return err
+	}
+	pending.Apply()
+	return nil
+}
+
+// Prepare validates the settings and builds their client without using it yet
+func (le *LogExporter) Prepare(settings types.LogExportSettings) (PendingConfig, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	var client *otlpClient
+	if settings.Enabled {
+		var err error
+		if client, err = newOTLPClient(settings.Exporter, "/v1/logs"); err != nil {
+			return nil, err
+		}
+	}
+	return &pendingLogExporter{le: le, settings: settings, client: client}, nil
+}
+
+// pendingLogExporter is an export client waiting to replace the current one
+type pendingLogExporter struct {
+	le       *LogExporter
+	settings types.LogExportSettings
+	client   *otlpClient
+}
+
+func (p *pendingLogExporter) Apply() {
+	le, settings, client := p.le, p.settings, p.client
+	le.Shutdown()
+
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	le.settings = settings
+	le.client = client
+	le.stats.Enabled = client != nil
+	le.stats.Endpoint = ""
+	if client != nil {
+		le.stats.Endpoint = client.endpoint()
+		interval := time.Duration(settings.Batch.ScheduleDelayMs) * time.Millisecond
+		if interval <= 0 {
+			interval = time.Second
+		}
+		le.worker = startExportWorker(interval, le.wake, func() { _ = le.Flush(context.Background()) })
+	}
+	le.enabled.Store(client != nil)
+}
+
+func (p *pendingLogExporter) Discard() {
+	if p.client != nil {
+		_ = p.client.close()
+	}
+}
+
+// Shutdown flushes what is queued and stops exporting
+func (le *LogExporter) Shutdown() {
+	le.enabled.Store(false)
+
+	le.mu.Lock()
+	worker := le.worker
+	le.worker = nil
+	le.mu.Unlock()
+	if worker != nil {
+		worker.shutdown()
+	}
+
+	le.mu.Lock()
+	client := le.client
+	le.client = nil
+	le.queue = nil
+	le.stats.Enabled = false
+	le.mu.Unlock()
+	if client != nil {
+		_ = client.close()
+	}
+}
+
+// Settings returns the log export settings currently in effect
+func (le *LogExporter) Settings() types.LogExportSettings {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.settings
+}
+
+// Stats returns the export totals so far
+func (le *LogExporter) Stats() models.OTLPExportStats {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.stats
+}
+
+// Flush exports every queued record in batches and returns the last error
+func (le *LogExporter) Flush(ctx context.Context) error {
+	le.exportMu.Lock()
+	defer le.exportMu.Unlock()
+
+	var lastErr error
+	for {
+		le.mu.Lock()
+		client := le.client
+		timeout := time.Duration(le.settings.Batch.ExportTimeoutMs) * time.Millisecond
+		size := le.settings.Batch.MaxExportBatchSize
+		if size <= 0 || size > len(le.queue) {
+			size = len(le.queue)
+		}
+		batch := le.queue[:size:size]
+		le.queue = le.queue[size:]
+		le.mu.Unlock()
+
+		if client == nil || len(batch) == 0 {
+			return lastErr
+		}
+
+		request := &collogspb.ExportLogsServiceRequest{
+			ResourceLogs: []*logspb.ResourceLogs{{
+				Resource: le.resource,
+				ScopeLogs: []*logspb.ScopeLogs{{
+					Scope:      otlpScope,
+					LogRecords: batch,
+				}},
+			}},
+		}
+		response := &collogspb.ExportLogsServiceResponse{}
+		err := le.exportBatch(ctx, client, timeout, request, response)
+
+		le.mu.Lock()
+		partial := response.GetPartialSuccess()
+		recordExport(&le.stats, int64(len(batch)), partial.GetRejectedLogRecords(), partial.GetErrorMessage(), err)
+		le.mu.Unlock()
+		if err != nil {
+			lastErr = fmt.Errorf("export logs: %w", err)
+		}
+	}
+}
+
+// exportBatch sends one batch, giving up after the batch export timeout when one is set
+func (le *LogExporter) exportBatch(ctx context.Context, client *otlpClient, timeout time.Duration, request *collogspb.ExportLogsServiceRequest, response *collogspb.ExportLogsServiceResponse) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return client.export(ctx, logsExportMethod, request, response)
+}
+
+// Core returns a zap core that queues every entry it is given for export
+func (le *LogExporter) Core() zapcore.Core {
+	return &otlpLogCore{exporter: le}
+}
+
+// enqueue adds a record, dropping it when the queue is full
+func (le *LogExporter) enqueue(record *logspb.LogRecord) {
+	le.mu.Lock()
+	if limit := le.settings.Batch.MaxQueueSize; limit > 0 && len(le.queue) >= limit {
+		le.stats.Dropped++
+		le.mu.Unlock()
+		return
+	}
+	le.queue = append(le.queue, record)
+	full := le.settings.Batch.MaxExportBatchSize > 0 && len(le.queue) >= le.settings.Batch.MaxExportBatchSize
+	le.mu.Unlock()
+
+	if full {
+		select {
+		case le.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// otlpLogCore is the zap core that bridges log entries to the LogExporter
+type otlpLogCore struct {
+	exporter *LogExporter
+	fields   []zapcore.Field
+}
+
+func (c *otlpLogCore) Enabled(zapcore.Level) bool {
+	return c.exporter.enabled.Load()
+}
+
+func (c *otlpLogCore) With(fields []zapcore.Field) zapcore.Core {
+	combined := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	combined = append(combined, c.fields...)
+	return &otlpLogCore{exporter: c.exporter, fields: append(combined, fields...)}
+}
+
+func (c *otlpLogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *otlpLogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	c.exporter.enqueue(newLogRecord(entry, append(all, fields...)))
+	return nil
+}
+
+func (c *otlpLogCore) Sync() error {
+	return nil
+}
+
+// newLogRecord converts a zap entry to an OTLP log record. The trace_id and
+// span_id fields LoggingService adds become the record's trace context.
+func newLogRecord(entry zapcore.Entry, fields []zapcore.Field) *logspb.LogRecord {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	record := &logspb.LogRecord{
+		TimeUnixNano:         uint64(entry.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(time.Now().UnixNano()),
+		SeverityNumber:       severityNumber(entry.Level),
+		SeverityText:         strings.ToUpper(entry.Level.String()),
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: entry.Message}},
+	}
+
+	if id, ok := decodeID(encoder.Fields["trace_id"], 16); ok {
+		record.TraceId = id
+		delete(encoder.Fields, "trace_id")
+	}
+	if id, ok := decodeID(encoder.Fields["span_id"], 8); ok {
+		record.SpanId = id
+		delete(encoder.Fields, "span_id")
+	}
+	if entry.LoggerName != "" {
+		encoder.Fields["logger.name"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		encoder.Fields["code.caller"] = entry.Caller.TrimmedPath()
+	}
+	record.Attributes = otlpAttributes(encoder.Fields)
+	return record
+}
+
+// decodeID parses a hex trace or span ID of the given byte length
+func decodeID(value interface{}, size int) ([]byte, bool) {
+	text, ok := value.(string)
+	if !ok || len(text) != size*2 {
+		return nil, false
+	}
+	id, err := hex.DecodeString(text)
+	if err != nil {
+		return nil, false
+	}
+	for _, b := range id {
+		if b != 0 {
+			return id, true
+		}
+	}
+	return nil, false
+}
+
+func severityNumber(level zapcore.Level) logspb.SeverityNumber {
+	switch level {
+	case zapcore.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case zapcore.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case zapcore.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case zapcore.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR4
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	}
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeLogsCollector decodes OTLP/HTTP log requests and optionally rejects records
+type fakeLogsCollector struct {
+	requests chan *collogspb.ExportLogsServiceRequest
+	tenants  chan string
+	rejected int64
+}
+
+func newFakeLogsCollector(t *testing.T, rejected int64) (*fakeLogsCollector, *httptest.Server) {
+	c := &fakeLogsCollector{
+		requests: make(chan *collogspb.ExportLogsServiceRequest, 10),
+		tenants:  make(chan string, 10),
+		rejected: rejected,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		request := &collogspb.ExportLogsServiceRequest{}
+		if err := proto.Unmarshal(body, request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.requests <- request
+		c.tenants <- r.Header.Get("X-Scope-OrgID")
+
+		response := &collogspb.ExportLogsServiceResponse{}
+		if c.rejected > 0 {
+			response.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: c.rejected, ErrorMessage: "too old"}
+		}
+		payload, _ := proto.Marshal(response)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(payload)
+	}))
+	t.Cleanup(server.Close)
+	return c, server
+}
+
+func logExportSettings(endpoint string) types.LogExportSettings {
+	return types.LogExportSettings{
+		Enabled: true,
+		Exporter: types.OTLPExporterConfig{
+			Protocol: types.OTLPProtocolHTTP,
+			Endpoint: endpoint,
+			Headers:  map[string]string{"X-Scope-OrgID": "tenant-a"},
+		},
+		Batch: types.BatchConfig{MaxQueueSize: 100, MaxExportBatchSize: 10, ScheduleDelayMs: 60000},
+	}
+}
+
+func TestLogExporter_BridgesLoggingService(t *testing.T) {
+	collector, server := newFakeLogsCollector(t, 0)
+
+	ls := NewLoggingService()
+	exporter := ls.Exporter()
+	require.NoError(t, exporter.Configure(logExportSettings(server.URL)))
+	defer exporter.Shutdown()
+
+	previous := logger
+	logger = zap.New(exporter.Core())
+	t.Cleanup(func() { logger = previous })
+
+	traceID, _ := oteltrace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := oteltrace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+
+	ls.LogWithContext(zapcore.WarnLevel, ctx, "disk almost full", zap.Int("percent", 91))
+	require.NoError(t, exporter.Flush(context.Background()))
+
+	request := <-collector.requests
+	assert.Equal(t, "tenant-a", <-collector.tenants)
+	require.Len(t, request.ResourceLogs, 1)
+	assert.Contains(t, request.ResourceLogs[0].Resource.String(), "argus")
+
+	records := request.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 1)
+	record := records[0]
+	assert.Equal(t, "disk almost full", record.Body.GetStringValue())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, record.SeverityNumber)
+	assert.Equal(t, traceID[:], record.TraceId)
+	assert.Equal(t, spanID[:], record.SpanId)
+
+	attributes := make(map[string]interface{})
+	for _, kv := range record.Attributes {
+		if kv.Value.GetStringValue() != "" {
+			attributes[kv.Key] = kv.Value.GetStringValue()
+		} else {
+			attributes[kv.Key] = kv.Value.GetIntValue()
+		}
+	}
+	assert.Equal(t, int64(91), attributes["percent"])
+	assert.Equal(t, "argus", attributes["service_name"])
+	assert.NotContains(t, attributes, "trace_id")
+
+	stats := exporter.Stats()
+	assert.True(t, stats.Enabled)
+	assert.Equal(t, int64(1), stats.Exported)
+	assert.Empty(t, stats.LastError)
+}
+
+func TestLogExporter_Batching(t *testing.T) {
+	t.Run("full batch wakes the worker", func(t *testing.T) {
+		collector, server := newFakeLogsCollector(t, 0)
+		exporter := NewLogExporter(NewLoggingService().config)
+		require.NoError(t, exporter.Configure(logExportSettings(server.URL)))
+		defer exporter.Shutdown()
+
+		log := zap.New(exporter.Core())
+		for i := 0; i < 10; i++ {
+			log.Info("batched")
+		}
+
+		select {
+		case request := <-collector.requests:
+			assert.Len(t, request.ResourceLogs[0].ScopeLogs[0].LogRecords, 10)
+		case <-time.After(5 * time.Second):
+			t.Fatal("worker did not export the full batch")
+		}
+	})
+
+	t.Run("queue overflow and partial success are counted", func(t *testing.T) {
+		_, server := newFakeLogsCollector(t, 2)
+		exporter := NewLogExporter(NewLoggingService().config)
+		settings := logExportSettings(server.URL)
+		settings.Batch = types.BatchConfig{MaxQueueSize: 5, ScheduleDelayMs: 60000}
+		require.NoError(t, exporter.Configure(settings))
+		defer exporter.Shutdown()
+
+		log := zap.New(exporter.Core())
+		for i := 0; i < 8; i++ {
+			log.Info("overflow")
+		}
+		require.NoError(t, exporter.Flush(context.Background()))
+
+		stats := exporter.Stats()
+		assert.Equal(t, int64(3), stats.Dropped)
+		assert.Equal(t, int64(3), stats.Exported)
+		assert.Equal(t, int64(2), stats.Rejected)
+		assert.Contains(t, stats.LastError, "too old")
+	})
+
+	t.Run("disabled exporter ignores entries", func(t *testing.T) {
+		exporter := NewLogExporter(NewLoggingService().config)
+		require.NoError(t, exporter.Configure(types.LogExportSettings{}))
+
+		core := exporter.Core()
+		assert.False(t, core.Enabled(zapcore.ErrorLevel))
+		zap.New(core).Error("not exported")
+		assert.NoError(t, exporter.Flush(context.Background()))
+		assert.Zero(t, exporter.Stats().Exported)
+	})
+
+	t.Run("unreachable collector counts failures", func(t *testing.T) {
+		exporter := NewLogExporter(NewLoggingService().config)
+		require.NoError(t, exporter.Configure(logExportSettings("http://127.0.0.1:1")))
+		defer exporter.Shutdown()
+
+		zap.New(exporter.Core()).Info("lost")
+		assert.Error(t, exporter.Flush(context.Background()))
+		assert.Equal(t, int64(1), exporter.Stats().Failed)
+	})
+
+	t.Run("batch export timeout cuts slow exports short", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		t.Cleanup(server.Close)
+		defer close(release)
+
+		exporter := NewLogExporter(NewLoggingService().config)
+		settings := logExportSettings(server.URL)
+		settings.Batch.ExportTimeoutMs = 50
+		require.NoError(t, exporter.Configure(settings))
+		defer exporter.Shutdown()
+
+		zap.New(exporter.Core()).Info("slow")
+		start := time.Now()
+		assert.ErrorIs(t, exporter.Flush(context.Background()), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
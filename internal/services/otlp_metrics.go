@@ -0,0 +1,396 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+
+	"github.com/nahuelsantos/argus/internal/config"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+const metricsExportMethod = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+// MetricExporter periodically gathers the registered Prometheus collectors and
+// pushes them to an OTLP collector as cumulative metrics
+type MetricExporter struct {
+	gatherer prometheus.Gatherer
+	resource *resourcepb.Resource
+	start    time.Time
+
+	mu       sync.Mutex
+	settings types.MetricExportSettings
+	client   *otlpClient
+	worker   *exportWorker
+	stats    models.OTLPExportStats
+
+	exportMu sync.Mutex
+}
+
+// NewMetricExporter creates a disabled exporter for the metrics of gatherer;
+// call Configure to start it
+func NewMetricExporter(gatherer prometheus.Gatherer) *MetricExporter {
+	return &MetricExporter{
+		gatherer: gatherer,
+		resource: newOTLPResource(config.GetServiceConfig()),
+		start:    time.Now(),
+		stats:    models.OTLPExportStats{Signal: "metrics"},
+	}
+}
+
+// Configure replaces the exporter settings. The previous endpoint receives one
+// last collection before it is closed.
+func (me *MetricExporter) Configure(settings types.MetricExportSettings) error {
+	pending, err := me.Prepare(settings)
+	if err != nil {
+		return err
+	}
+	pending.Apply()
+	return nil
+}
+
+// Prepare validates the settings and builds their client without using it yet
+func (me *MetricExporter) Prepare(settings types.MetricExportSettings) (PendingConfig, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	var client *otlpClient
+	if settings.Enabled {
+		var err error
+		if client, err = newOTLPClient(settings.Exporter, "/v1/metrics"); err != nil {
+			return nil, err
+		}
+	}
+	return &pendingMetricExporter{me: me, settings: settings, client: client}, nil
+}
+
+// pendingMetricExporter is an export client waiting to replace the current one
+type pendingMetricExporter struct {
+	me       *MetricExporter
+	settings types.MetricExportSettings
+	client   *otlpClient
+}
+
+func (p *pendingMetricExporter) Apply() {
+	me, settings, client := p.me, p.settings, p.client
+	me.Shutdown()
+
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	me.settings = settings
+	me.client = client
+	me.stats.Enabled = client != nil
+	me.stats.Endpoint = ""
+	if client != nil {
+		me.stats.Endpoint = client.endpoint()
+		interval := time.Duration(settings.IntervalMs) * time.Millisecond
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		me.worker = startExportWorker(interval, nil, func() { _ = me.Flush(context.Background()) })
+	}
+}
+
+func (p *pendingMetricExporter) Discard() {
+	if p.client != nil {
+		_ = p.client.close()
+	}
+}
+
+// Shutdown exports a final collection and stops exporting
+func (me *MetricExporter) Shutdown() {
+	me.mu.Lock()
+	worker := me.worker
+	me.worker = nil
+	me.mu.Unlock()
+	if worker != nil {
+		worker.shutdown()
+	}
+
+	me.mu.Lock()
+	client := me.client
+	me.client = nil
+	me.stats.Enabled = false
+	me.mu.Unlock()
+	if client != nil {
+		_ = client.close()
+	}
+}
+
+// Settings returns the metric export settings currently in effect
+func (me *MetricExporter) Settings() types.MetricExportSettings {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.settings
+}
+
+// Stats returns the export totals so far, counted in data points
+func (me *MetricExporter) Stats() models.OTLPExportStats {
+	me.mu.Lock()
+	defer me.mu.Unlock()
+	return me.stats
+}
+
+// Flush gathers the current metric values and exports them immediately
+func (me *MetricExporter) Flush(ctx context.Context) error {
+	me.exportMu.Lock()
+	defer me.exportMu.Unlock()
+
+	me.mu.Lock()
+	client := me.client
+	me.mu.Unlock()
+	if client == nil {
+		return nil
+	}
+
+	families, err := me.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return fmt.Errorf("gather metrics: %w", err)
+	}
+	metrics, points := otlpMetrics(families, me.start, time.Now())
+
+	request := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: me.resource,
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:   otlpScope,
+				Metrics: metrics,
+			}},
+		}},
+	}
+	response := &colmetricspb.ExportMetricsServiceResponse{}
+	err = client.export(ctx, metricsExportMethod, request, response)
+
+	me.mu.Lock()
+	partial := response.GetPartialSuccess()
+	recordExport(&me.stats, int64(points), partial.GetRejectedDataPoints(), partial.GetErrorMessage(), err)
+	me.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("export metrics: %w", err)
+	}
+	return nil
+}
+
+// otlpMetrics converts gathered metric families to OTLP metrics and returns
+// them with the number of data points. Counters become monotonic cumulative
+// sums starting at start; untyped metrics are exported as gauges and native
+// histograms as exponential histograms.
+func otlpMetrics(families []*dto.MetricFamily, start, now time.Time) ([]*metricspb.Metric, int) {
+	startNano := uint64(start.UnixNano())
+	metrics := make([]*metricspb.Metric, 0, len(families))
+	points := 0
+
+	for _, family := range families {
+		metric := &metricspb.Metric{Name: family.GetName(), Description: family.GetHelp()}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := &metricspb.Sum{
+				IsMonotonic:            true,
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			}
+			for _, m := range family.GetMetric() {
+				sum.DataPoints = append(sum.DataPoints, numberDataPoint(m, m.GetCounter().GetValue(), startNano, now))
+			}
+			metric.Data = &metricspb.Metric_Sum{Sum: sum}
+			points += len(sum.DataPoints)
+
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			gauge := &metricspb.Gauge{}
+			for _, m := range family.GetMetric() {
+				value := m.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				gauge.DataPoints = append(gauge.DataPoints, numberDataPoint(m, value, 0, now))
+			}
+			metric.Data = &metricspb.Metric_Gauge{Gauge: gauge}
+			points += len(gauge.DataPoints)
+
+		case dto.MetricType_HISTOGRAM:
+			if nativeHistogramFamily(family) {
+				histogram := &metricspb.ExponentialHistogram{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				}
+				for _, m := range family.GetMetric() {
+					histogram.DataPoints = append(histogram.DataPoints, exponentialDataPoint(m, startNano, now))
+				}
+				metric.Data = &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: histogram}
+				points += len(histogram.DataPoints)
+				break
+			}
+			histogram := &metricspb.Histogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			}
+			for _, m := range family.GetMetric() {
+				histogram.DataPoints = append(histogram.DataPoints, histogramDataPoint(m, startNano, now))
+			}
+			metric.Data = &metricspb.Metric_Histogram{Histogram: histogram}
+			points += len(histogram.DataPoints)
+
+		case dto.MetricType_SUMMARY:
+			summary := &metricspb.Summary{}
+			for _, m := range family.GetMetric() {
+				s := m.GetSummary()
+				point := &metricspb.SummaryDataPoint{
+					Attributes:        labelAttributes(m),
+					StartTimeUnixNano: startNano,
+					TimeUnixNano:      pointTime(m, now),
+					Count:             s.GetSampleCount(),
+					Sum:               s.GetSampleSum(),
+				}
+				for _, q := range s.GetQuantile() {
+					point.QuantileValues = append(point.QuantileValues, &metricspb.SummaryDataPoint_ValueAtQuantile{
+						Quantile: q.GetQuantile(),
+						Value:    q.GetValue(),
+					})
+				}
+				summary.DataPoints = append(summary.DataPoints, point)
+			}
+			metric.Data = &metricspb.Metric_Summary{Summary: summary}
+			points += len(summary.DataPoints)
+
+		default:
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, points
+}
+
+func numberDataPoint(m *dto.Metric, value float64, startNano uint64, now time.Time) *metricspb.NumberDataPoint {
+	return &metricspb.NumberDataPoint{
+		Attributes:        labelAttributes(m),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      pointTime(m, now),
+		Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+}
+
+// histogramDataPoint turns Prometheus' cumulative buckets into OTLP's
+// per-bucket counts; the +Inf bucket becomes the implicit overflow bucket
+func histogramDataPoint(m *dto.Metric, startNano uint64, now time.Time) *metricspb.HistogramDataPoint {
+	h := m.GetHistogram()
+	sum := h.GetSampleSum()
+	point := &metricspb.HistogramDataPoint{
+		Attributes:        labelAttributes(m),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      pointTime(m, now),
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+	}
+
+	var previous uint64
+	for _, bucket := range h.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-previous)
+	return point
+}
+
+// nativeHistogramFamily reports whether a histogram family only has native
+// buckets. Histograms that also keep classic buckets are exported with those.
+func nativeHistogramFamily(family *dto.MetricFamily) bool {
+	for _, m := range family.GetMetric() {
+		h := m.GetHistogram()
+		if len(h.GetBucket()) > 0 {
+			return false
+		}
+		if len(h.GetPositiveSpan()) == 0 && len(h.GetNegativeSpan()) == 0 && h.GetZeroThreshold() == 0 {
+			return false
+		}
+	}
+	return len(family.GetMetric()) > 0
+}
+
+// exponentialDataPoint turns a Prometheus native histogram into an OTLP
+// exponential histogram. Both use base 2^(2^-schema) buckets, so the schema
+// is the scale; only the bucket indexes differ by one, since Prometheus
+// buckets are (base^(i-1), base^i] and OTLP ones (base^i, base^(i+1)].
+func exponentialDataPoint(m *dto.Metric, startNano uint64, now time.Time) *metricspb.ExponentialHistogramDataPoint {
+	h := m.GetHistogram()
+	sum := h.GetSampleSum()
+	point := &metricspb.ExponentialHistogramDataPoint{
+		Attributes:        labelAttributes(m),
+		StartTimeUnixNano: startNano,
+		TimeUnixNano:      pointTime(m, now),
+		Count:             h.GetSampleCount(),
+		Sum:               &sum,
+		Scale:             h.GetSchema(),
+		ZeroCount:         h.GetZeroCount(),
+		ZeroThreshold:     h.GetZeroThreshold(),
+		Positive:          exponentialBuckets(h.GetPositiveSpan(), h.GetPositiveDelta(), h.GetPositiveCount()),
+		Negative:          exponentialBuckets(h.GetNegativeSpan(), h.GetNegativeDelta(), h.GetNegativeCount()),
+	}
+	if point.Count == 0 {
+		point.Count = uint64(h.GetSampleCountFloat())
+		point.ZeroCount = uint64(h.GetZeroCountFloat())
+	}
+	return point
+}
+
+// exponentialBuckets expands Prometheus' sparse spans into OTLP's dense bucket
+// counts. Integer histograms carry deltas between buckets, float ones counts.
+func exponentialBuckets(spans []*dto.BucketSpan, deltas []int64, counts []float64) *metricspb.ExponentialHistogramDataPoint_Buckets {
+	buckets := &metricspb.ExponentialHistogramDataPoint_Buckets{}
+	var index, first int32
+	var current int64
+	n := 0
+	for i, span := range spans {
+		index += span.GetOffset()
+		if i == 0 {
+			first = index
+		}
+		for int(index-first) > len(buckets.BucketCounts) {
+			buckets.BucketCounts = append(buckets.BucketCounts, 0)
+		}
+		for j := uint32(0); j < span.GetLength(); j++ {
+			var count uint64
+			switch {
+			case n < len(deltas):
+				current += deltas[n]
+				count = uint64(current)
+			case n < len(counts):
+				count = uint64(counts[n])
+			}
+			buckets.BucketCounts = append(buckets.BucketCounts, count)
+			n++
+		}
+		index += int32(span.GetLength())
+	}
+	if len(buckets.BucketCounts) > 0 {
+		buckets.Offset = first - 1
+	}
+	return buckets
+}
+
+func labelAttributes(m *dto.Metric) []*commonpb.KeyValue {
+	labels := make(map[string]interface{}, len(m.GetLabel()))
+	for _, label := range m.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return otlpAttributes(labels)
+}
+
+func pointTime(m *dto.Metric, now time.Time) uint64 {
+	if m.TimestampMs != nil {
+		return uint64(m.GetTimestampMs()) * uint64(time.Millisecond)
+	}
+	return uint64(now.UnixNano())
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeMetricsCollector is an OTLP/gRPC metrics receiver
+type fakeMetricsCollector struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	requests chan *colmetricspb.ExportMetricsServiceRequest
+	tenants  chan string
+}
+
+func (c *fakeMetricsCollector) Export(ctx context.Context, request *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.tenants <- md.Get("x-scope-orgid")[0]
+	c.requests <- request
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func newTestRegistry(t *testing.T) *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "argus_test_requests_total", Help: "Requests"}, []string{"method"})
+	counter.WithLabelValues("GET").Add(3)
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "argus_test_queue_depth", Help: "Queue depth"})
+	gauge.Set(7)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "argus_test_duration_seconds", Help: "Duration", Buckets: []float64{0.1, 1}})
+	for _, v := range []float64{0.05, 0.5, 0.7, 5} {
+		histogram.Observe(v)
+	}
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "argus_test_size_bytes", Help: "Size", Objectives: map[float64]float64{0.5: 0.05}})
+	summary.Observe(10)
+
+	require.NoError(t, registry.Register(counter))
+	require.NoError(t, registry.Register(gauge))
+	require.NoError(t, registry.Register(histogram))
+	require.NoError(t, registry.Register(summary))
+	return registry
+}
+
+func TestOTLPMetrics(t *testing.T) {
+	families, err := newTestRegistry(t).Gather()
+	require.NoError(t, err)
+
+	start := time.Now().Add(-time.Minute)
+	metrics, points := otlpMetrics(families, start, time.Now())
+	assert.Equal(t, 4, points)
+
+	byName := make(map[string]*metricspb.Metric)
+	for _, m := range metrics {
+		byName[m.Name] = m
+	}
+
+	sum := byName["argus_test_requests_total"].GetSum()
+	require.NotNil(t, sum)
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.AggregationTemporality)
+	assert.Equal(t, 3.0, sum.DataPoints[0].GetAsDouble())
+	assert.Equal(t, uint64(start.UnixNano()), sum.DataPoints[0].StartTimeUnixNano)
+	assert.Equal(t, "method", sum.DataPoints[0].Attributes[0].Key)
+	assert.Equal(t, "GET", sum.DataPoints[0].Attributes[0].Value.GetStringValue())
+
+	gauge := byName["argus_test_queue_depth"].GetGauge()
+	require.NotNil(t, gauge)
+	assert.Equal(t, 7.0, gauge.DataPoints[0].GetAsDouble())
+
+	histogram := byName["argus_test_duration_seconds"].GetHistogram()
+	require.NotNil(t, histogram)
+	point := histogram.DataPoints[0]
+	assert.Equal(t, uint64(4), point.Count)
+	assert.Equal(t, []float64{0.1, 1}, point.ExplicitBounds)
+	assert.Equal(t, []uint64{1, 2, 1}, point.BucketCounts)
+	assert.InDelta(t, 6.25, point.GetSum(), 0.001)
+
+	summary := byName["argus_test_size_bytes"].GetSummary()
+	require.NotNil(t, summary)
+	assert.Equal(t, uint64(1), summary.DataPoints[0].Count)
+	assert.Equal(t, 0.5, summary.DataPoints[0].QuantileValues[0].Quantile)
+}
+
+func TestOTLPMetrics_NativeHistogram(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "argus_test_latency_seconds", Help: "Latency", NativeHistogramBucketFactor: 1.1})
+	for _, v := range []float64{0, 1, 2, 2, 4} {
+		histogram.Observe(v)
+	}
+	require.NoError(t, registry.Register(histogram))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	metrics, points := otlpMetrics(families, time.Now().Add(-time.Minute), time.Now())
+	require.Len(t, metrics, 1)
+	assert.Equal(t, 1, points)
+	assert.Nil(t, metrics[0].GetHistogram())
+	exponential := metrics[0].GetExponentialHistogram()
+	require.NotNil(t, exponential)
+
+	// A factor of 1.1 is schema 3: 1, 2 and 4 fall in the OTLP buckets
+	// (base^i, base^(i+1)] with base 2^(1/8) at indexes -1, 7 and 15
+	point := exponential.DataPoints[0]
+	assert.Equal(t, uint64(5), point.Count)
+	assert.Equal(t, 9.0, point.GetSum())
+	assert.Equal(t, int32(3), point.Scale)
+	assert.Equal(t, uint64(1), point.ZeroCount)
+	assert.Equal(t, int32(-1), point.Positive.Offset)
+	expected := make([]uint64, 17)
+	expected[0], expected[8], expected[16] = 1, 2, 1
+	assert.Equal(t, expected, point.Positive.BucketCounts)
+	assert.Empty(t, point.Negative.BucketCounts)
+}
+
+func TestMetricExporter_GRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	collector := &fakeMetricsCollector{
+		requests: make(chan *colmetricspb.ExportMetricsServiceRequest, 10),
+		tenants:  make(chan string, 10),
+	}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, collector)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	exporter := NewMetricExporter(newTestRegistry(t))
+	require.NoError(t, exporter.Configure(types.MetricExportSettings{
+		Enabled: true,
+		Exporter: types.OTLPExporterConfig{
+			Protocol:    types.OTLPProtocolGRPC,
+			Endpoint:    listener.Addr().String(),
+			Insecure:    true,
+			Headers:     map[string]string{"X-Scope-OrgID": "tenant-b"},
+			Compression: "gzip",
+		},
+		IntervalMs: 60000,
+	}))
+
+	require.NoError(t, exporter.Flush(context.Background()))
+	request := <-collector.requests
+	assert.Equal(t, "tenant-b", <-collector.tenants)
+	assert.Len(t, request.ResourceMetrics[0].ScopeMetrics[0].Metrics, 4)
+	assert.Equal(t, int64(4), exporter.Stats().Exported)
+
+	// Shutdown exports one last collection
+	exporter.Shutdown()
+	select {
+	case <-collector.requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no final export on shutdown")
+	}
+	assert.False(t, exporter.Stats().Enabled)
+	assert.NoError(t, exporter.Flush(context.Background()), "flush after shutdown is a no-op")
+}
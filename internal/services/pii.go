@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// PIIRunField is the log field that tags generated lines with their PII run,
+// so the redaction check can find them in Loki
+const PIIRunField = "pii_run"
+
+// DefaultPIISelector is the LogQL stream selector searched for PII runs,
+// every stream unless narrowed
+const DefaultPIISelector = `{service_name=~".+"}`
+
+// maxPIIRuns is how many runs the PII service keeps
+const maxPIIRuns = 20
+
+// ErrPIIRunNotFound is returned for an unknown PII run ID
+var ErrPIIRunNotFound = errors.New("pii run not found")
+
+var (
+	piiFirstNames = []string{"jane", "john", "maria", "wei", "fatima", "lucas", "aisha", "oliver", "sofia", "kenji"}
+	piiLastNames  = []string{"doe", "smith", "garcia", "chen", "khan", "silva", "okafor", "brown", "rossi", "tanaka"}
+	piiDomains    = []string{"example.com", "example.org", "example.net", "mail.example.com"}
+
+	// piiCardBrands are issuer prefixes and the digit groups cards are printed in,
+	// which add up to the card length
+	piiCardBrands = []struct {
+		prefixes []string
+		groups   []int
+	}{
+		{prefixes: []string{"4"}, groups: []int{4, 4, 4, 4}},                          // Visa
+		{prefixes: []string{"51", "52", "53", "54", "55"}, groups: []int{4, 4, 4, 4}}, // Mastercard
+		{prefixes: []string{"34", "37"}, groups: []int{4, 6, 5}},                      // American Express
+		{prefixes: []string{"6011"}, groups: []int{4, 4, 4, 4}},                       // Discover
+	}
+
+	// piiFieldNames are the keys PII is logged under in structured lines
+	piiFieldNames = map[string]string{
+		types.PIIEmail:      "email",
+		types.PIICreditCard: "card_number",
+		types.PIIIP:         "client_ip",
+		types.PIIToken:      "auth_token",
+	}
+)
+
+const (
+	piiAlphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	piiJWTHeader    = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9" // {"alg":"HS256","typ":"JWT"}
+)
+
+// PIIGenerator draws fake but realistic PII for the lines of one generator
+// request and records which lines got which values
+type PIIGenerator struct {
+	spec types.PIISpec
+	rng  *rand.Rand
+	run  models.PIIRun
+}
+
+// NewPIIGenerator creates a generator for a run of the named log generator
+func NewPIIGenerator(spec types.PIISpec, generator string, seed int64) *PIIGenerator {
+	if len(spec.Types) == 0 {
+		spec.Types = types.PIITypes
+	}
+	if spec.Rate == 0 {
+		spec.Rate = 0.5
+	}
+	return &PIIGenerator{
+		spec: spec,
+		rng:  rand.New(rand.NewSource(seed)),
+		run: models.PIIRun{
+			ID:        uuid.New().String(),
+			Generator: generator,
+			Lines:     []models.PIILine{},
+			StartedAt: time.Now(),
+		},
+	}
+}
+
+// RunID returns the ID generated lines are tagged with
+func (g *PIIGenerator) RunID() string {
+	return g.run.ID
+}
+
+// Next draws the PII for the next line: each kind with the spec's rate
+func (g *PIIGenerator) Next() []models.PIIValue {
+	values := []models.PIIValue{}
+	for _, kind := range g.spec.Types {
+		if g.rng.Float64() < g.spec.Rate {
+			values = append(values, models.PIIValue{Type: kind, Value: g.value(kind)})
+		}
+	}
+	return values
+}
+
+// Record adds a generated line and the PII embedded in it to the run
+func (g *PIIGenerator) Record(line string, values []models.PIIValue) {
+	g.run.Lines = append(g.run.Lines, models.PIILine{Index: len(g.run.Lines), Line: line, PII: values})
+}
+
+// Run returns the recorded run with its values counted per type
+func (g *PIIGenerator) Run() models.PIIRun {
+	run := g.run
+	run.Values = make(map[string]int)
+	for _, line := range run.Lines {
+		for _, value := range line.PII {
+			run.Values[value.Type]++
+		}
+	}
+	return run
+}
+
+func (g *PIIGenerator) value(kind string) string {
+	switch kind {
+	case types.PIIEmail:
+		return fmt.Sprintf("%s.%s%d@%s", g.pick(piiFirstNames), g.pick(piiLastNames), g.rng.Intn(100), g.pick(piiDomains))
+	case types.PIICreditCard:
+		return g.creditCard()
+	case types.PIIIP:
+		if g.rng.Intn(4) == 0 {
+			return fmt.Sprintf("2001:db8:%x:%x::%x", g.rng.Intn(0x10000), g.rng.Intn(0x10000), g.rng.Intn(0xffff)+1)
+		}
+		first := 1 + g.rng.Intn(223)
+		if first == 127 {
+			first = 128
+		}
+		return fmt.Sprintf("%d.%d.%d.%d", first, g.rng.Intn(256), g.rng.Intn(256), 1+g.rng.Intn(254))
+	default:
+		return g.token()
+	}
+}
+
+// creditCard returns a Luhn-valid card number, printed plain or in groups
+// separated by spaces or dashes
+func (g *PIIGenerator) creditCard() string {
+	brand := piiCardBrands[g.rng.Intn(len(piiCardBrands))]
+	length := 0
+	for _, group := range brand.groups {
+		length += group
+	}
+
+	digits := []byte(g.pick(brand.prefixes))
+	for len(digits) < length-1 {
+		digits = append(digits, byte('0'+g.rng.Intn(10)))
+	}
+	digits = append(digits, luhnCheckDigit(digits))
+
+	separator := []string{"", " ", "-"}[g.rng.Intn(3)]
+	if separator == "" {
+		return string(digits)
+	}
+	groups := make([]string, 0, len(brand.groups))
+	for _, size := range brand.groups {
+		groups = append(groups, string(digits[:size]))
+		digits = digits[size:]
+	}
+	return strings.Join(groups, separator)
+}
+
+// token returns a JWT or an API key in the shape of a common provider's
+func (g *PIIGenerator) token() string {
+	switch g.rng.Intn(4) {
+	case 0:
+		payload := fmt.Sprintf(`{"sub":"user-%d","iat":%d}`, g.rng.Intn(100000), time.Now().Unix())
+		signature := make([]byte, 32)
+		g.rng.Read(signature)
+		return piiJWTHeader + "." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(signature)
+	case 1:
+		return "ghp_" + g.randomString(piiAlphanumeric, 36)
+	case 2:
+		return "sk_live_" + g.randomString(piiAlphanumeric, 24)
+	default:
+		return "AKIA" + g.randomString(piiAlphanumeric[:26]+piiAlphanumeric[52:], 16)
+	}
+}
+
+func (g *PIIGenerator) pick(values []string) string {
+	return values[g.rng.Intn(len(values))]
+}
+
+func (g *PIIGenerator) randomString(alphabet string, n int) string {
+	out := make([]byte, n)
+	for i := range out {
+		out[i] = alphabet[g.rng.Intn(len(alphabet))]
+	}
+	return string(out)
+}
+
+// luhnCheckDigit returns the digit that makes digits a valid Luhn number
+func luhnCheckDigit(digits []byte) byte {
+	sum := 0
+	// The check digit goes last, so doubling starts with the rightmost digit
+	for i, double := len(digits)-1, true; i >= 0; i, double = i-1, !double {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// WithPIIFields returns a copy of a structured log entry with values added
+func WithPIIFields(entry map[string]interface{}, values []models.PIIValue) map[string]interface{} {
+	copied := make(map[string]interface{}, len(entry)+len(values))
+	for key, value := range entry {
+		copied[key] = value
+	}
+	for _, value := range values {
+		copied[piiFieldNames[value.Type]] = value.Value
+	}
+	return copied
+}
+
+// PIIKeyValues renders values as key=value pairs to append to a logfmt line
+func PIIKeyValues(values []models.PIIValue) string {
+	var out strings.Builder
+	for _, value := range values {
+		if strings.Contains(value.Value, " ") {
+			fmt.Fprintf(&out, " %s=%q", piiFieldNames[value.Type], value.Value)
+		} else {
+			fmt.Fprintf(&out, " %s=%s", piiFieldNames[value.Type], value.Value)
+		}
+	}
+	return out.String()
+}
+
+// PIIText renders values the way plain text logs mention them, to append
+// to a line
+func PIIText(values []models.PIIValue) string {
+	if len(values) == 0 {
+		return ""
+	}
+	phrases := make([]string, 0, len(values))
+	for _, value := range values {
+		switch {
+		case value.Type == types.PIIEmail:
+			phrases = append(phrases, "user "+value.Value)
+		case value.Type == types.PIICreditCard:
+			phrases = append(phrases, "card "+value.Value)
+		case value.Type == types.PIIIP:
+			phrases = append(phrases, "from "+value.Value)
+		case strings.HasPrefix(value.Value, piiJWTHeader):
+			phrases = append(phrases, "Authorization: Bearer "+value.Value)
+		default:
+			phrases = append(phrases, "api_key="+value.Value)
+		}
+	}
+	return " (" + strings.Join(phrases, ", ") + ")"
+}
+
+// PIIService keeps the latest PII runs and checks that their PII was
+// redacted before it reached Loki
+type PIIService struct {
+	client       *http.Client
+	pollInterval time.Duration
+
+	mu   sync.Mutex
+	runs []models.PIIRun // oldest first
+}
+
+// NewPIIService creates a new PII redaction service
+func NewPIIService() *PIIService {
+	return &PIIService{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: 5 * time.Second,
+	}
+}
+
+// Record keeps a run, forgetting the oldest beyond the last 20
+func (ps *PIIService) Record(run models.PIIRun) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.runs = append(ps.runs, run)
+	if len(ps.runs) > maxPIIRuns {
+		ps.runs = ps.runs[len(ps.runs)-maxPIIRuns:]
+	}
+}
+
+// Get returns the run with the given ID, or the latest one when id is empty
+func (ps *PIIService) Get(id string) (models.PIIRun, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for i := len(ps.runs) - 1; i >= 0; i-- {
+		if id == "" || ps.runs[i].ID == id {
+			return ps.runs[i], nil
+		}
+	}
+	return models.PIIRun{}, ErrPIIRunNotFound
+}
+
+type lokiStreams struct {
+	Result []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"result"`
+}
+
+// Verify queries Loki for the run's lines until all of them arrived or
+// timeout passes, and reports every PII value found in them unchanged
+func (ps *PIIService) Verify(ctx context.Context, loki types.ServiceConfig, selector string, run models.PIIRun, timeout time.Duration) *models.PIIRedactionReport {
+	report := &models.PIIRedactionReport{
+		RunID:     run.ID,
+		LokiURL:   loki.URL,
+		Query:     fmt.Sprintf("%s |= %q |= %q", selector, PIIRunField, run.ID),
+		Generated: len(run.Lines),
+		ByType:    make(map[string]models.PIITypeResult),
+		Leaks:     []models.PIILeak{},
+		Problems:  []string{},
+		Timestamp: time.Now(),
+	}
+	start := time.Now()
+
+	params := url.Values{}
+	params.Set("query", report.Query)
+	params.Set("start", strconv.FormatInt(run.StartedAt.Add(-time.Minute).UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(2*len(run.Lines)+100))
+	params.Set("direction", "forward")
+
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	lines, attempts, queryErr := ps.linesUntilFound(pollCtx, loki, params, len(run.Lines))
+	report.Attempts = attempts
+	report.Elapsed = time.Since(start)
+	report.Found = len(lines)
+
+	for _, line := range run.Lines {
+		for _, value := range line.PII {
+			result := report.ByType[value.Type]
+			result.Values++
+			report.Values++
+			for _, found := range lines {
+				if strings.Contains(found, value.Value) {
+					result.Leaked++
+					report.Leaked++
+					report.Leaks = append(report.Leaks, models.PIILeak{Line: line.Index, Type: value.Type, Value: value.Value, LokiLine: found})
+					break
+				}
+			}
+			report.ByType[value.Type] = result
+		}
+	}
+
+	switch {
+	case queryErr != nil && len(lines) == 0:
+		report.Problems = append(report.Problems, "Loki query failed: "+queryErr.Error())
+	case len(lines) == 0:
+		report.Problems = append(report.Problems, fmt.Sprintf("No line of run %s reached Loki after %d attempts - are Argus' logs collected?", run.ID, report.Attempts))
+	case len(lines) < len(run.Lines):
+		report.Problems = append(report.Problems, fmt.Sprintf("Only %d of %d lines reached Loki", len(lines), len(run.Lines)))
+	}
+	if report.Leaked > 0 {
+		report.Problems = append(report.Problems, fmt.Sprintf("%d of %d PII values reached Loki unredacted", report.Leaked, report.Values))
+	}
+	if report.Values == 0 {
+		report.Problems = append(report.Problems, "The run has no PII values to check")
+	}
+
+	switch {
+	case len(lines) == 0 || report.Leaked > 0:
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report
+}
+
+// linesUntilFound repeats a Loki query until it returns want lines or ctx
+// ends, and returns the last lines found
+func (ps *PIIService) linesUntilFound(ctx context.Context, loki types.ServiceConfig, params url.Values, want int) ([]string, int, error) {
+	var lines []string
+	var err error
+	for attempts := 1; ; attempts++ {
+		params.Set("end", strconv.FormatInt(time.Now().Add(time.Minute).UnixNano(), 10))
+		// Loki's query API answers in Prometheus' envelope
+		var streams lokiStreams
+		if err = prometheusAPI(ctx, ps.client, loki, "/loki/api/v1/query_range?"+params.Encode(), &streams); err == nil {
+			lines = lines[:0]
+			for _, stream := range streams.Result {
+				for _, value := range stream.Values {
+					lines = append(lines, value[1])
+				}
+			}
+			if len(lines) >= want {
+				return lines, attempts, nil
+			}
+		}
+
+		select {
+		case <-time.After(ps.pollInterval):
+		case <-ctx.Done():
+			return lines, attempts, err
+		}
+	}
+}
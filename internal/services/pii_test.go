@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestPIIGenerator_Values(t *testing.T) {
+	generator := NewPIIGenerator(types.PIISpec{Rate: 1}, "json", 42)
+	email := regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@(mail\.)?example\.(com|org|net)$`)
+	token := regexp.MustCompile(`^(eyJ[\w-]+\.[\w-]+\.[\w-]+|ghp_[A-Za-z0-9]{36}|sk_live_[A-Za-z0-9]{24}|AKIA[A-Z0-9]{16})$`)
+
+	for i := 0; i < 200; i++ {
+		values := generator.Next()
+		require.Len(t, values, 4, "a rate of 1 puts every kind in every line")
+		for _, value := range values {
+			switch value.Type {
+			case types.PIIEmail:
+				assert.Regexp(t, email, value.Value)
+			case types.PIICreditCard:
+				digits := strings.NewReplacer(" ", "", "-", "").Replace(value.Value)
+				assert.Regexp(t, `^(4\d{15}|5[1-5]\d{14}|3[47]\d{13}|6011\d{12})$`, digits)
+				assert.True(t, luhnValid(digits), value.Value)
+			case types.PIIIP:
+				assert.NotNil(t, net.ParseIP(value.Value), value.Value)
+			case types.PIIToken:
+				assert.Regexp(t, token, value.Value)
+			}
+		}
+		generator.Record(fmt.Sprintf("line %d", i), values)
+	}
+
+	run := generator.Run()
+	assert.Equal(t, "json", run.Generator)
+	assert.Equal(t, generator.RunID(), run.ID)
+	require.Len(t, run.Lines, 200)
+	assert.Equal(t, 199, run.Lines[199].Index)
+	assert.Equal(t, map[string]int{"email": 200, "credit_card": 200, "ip": 200, "token": 200}, run.Values)
+
+	// Only the requested kinds, and some lines stay clean at a lower rate
+	generator = NewPIIGenerator(types.PIISpec{Types: []string{types.PIIEmail}, Rate: 0.5}, "mixed", 7)
+	clean := 0
+	for i := 0; i < 100; i++ {
+		values := generator.Next()
+		if len(values) == 0 {
+			clean++
+		}
+		for _, value := range values {
+			assert.Equal(t, types.PIIEmail, value.Type)
+		}
+	}
+	assert.InDelta(t, 50, clean, 20)
+}
+
+func TestPIIRendering(t *testing.T) {
+	values := []models.PIIValue{
+		{Type: types.PIIEmail, Value: "jane.doe1@example.com"},
+		{Type: types.PIICreditCard, Value: "4111 1111 1111 1111"},
+		{Type: types.PIIIP, Value: "203.0.113.7"},
+		{Type: types.PIIToken, Value: piiJWTHeader + ".e30.sig"},
+	}
+
+	entry := map[string]interface{}{"message": "User login successful"}
+	withPII := WithPIIFields(entry, values)
+	assert.Len(t, entry, 1, "the original entry is not changed")
+	assert.Equal(t, "4111 1111 1111 1111", withPII["card_number"])
+	assert.Equal(t, "203.0.113.7", withPII["client_ip"])
+
+	assert.Equal(t, ` email=jane.doe1@example.com card_number="4111 1111 1111 1111" client_ip=203.0.113.7 auth_token=`+piiJWTHeader+".e30.sig", PIIKeyValues(values))
+	assert.Equal(t, " (user jane.doe1@example.com, card 4111 1111 1111 1111, from 203.0.113.7, Authorization: Bearer "+piiJWTHeader+".e30.sig)", PIIText(values))
+	assert.Equal(t, " (api_key=ghp_x)", PIIText([]models.PIIValue{{Type: types.PIIToken, Value: "ghp_x"}}))
+	assert.Empty(t, PIIText(nil))
+	assert.Equal(t, byte('1'), luhnCheckDigit([]byte("411111111111111")))
+}
+
+func TestPIIService_Runs(t *testing.T) {
+	ps := NewPIIService()
+	_, err := ps.Get("")
+	assert.ErrorIs(t, err, ErrPIIRunNotFound)
+
+	for i := 0; i < maxPIIRuns+5; i++ {
+		ps.Record(models.PIIRun{ID: fmt.Sprintf("run-%d", i)})
+	}
+	latest, err := ps.Get("")
+	require.NoError(t, err)
+	assert.Equal(t, "run-24", latest.ID)
+	_, err = ps.Get("run-5")
+	require.NoError(t, err)
+	_, err = ps.Get("run-4")
+	assert.ErrorIs(t, err, ErrPIIRunNotFound, "the oldest runs are forgotten")
+}
+
+func TestPIIService_Verify(t *testing.T) {
+	run := models.PIIRun{
+		ID: "run-1",
+		Lines: []models.PIILine{
+			{Index: 0, Line: "login", PII: []models.PIIValue{{Type: types.PIIEmail, Value: "jane.doe1@example.com"}, {Type: types.PIIIP, Value: "203.0.113.7"}}},
+			{Index: 1, Line: "payment", PII: []models.PIIValue{{Type: types.PIICreditCard, Value: "4111-1111-1111-1111"}}},
+			{Index: 2, Line: "clean", PII: []models.PIIValue{}},
+		},
+		StartedAt: time.Now(),
+	}
+
+	var lines []string
+	var query string
+	loki := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/loki/api/v1/query_range", r.URL.Path)
+		query = r.URL.Query().Get("query")
+		values := [][2]string{}
+		for _, line := range lines {
+			values = append(values, [2]string{"1700000000000000000", line})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "streams",
+				"result":     []interface{}{map[string]interface{}{"stream": map[string]string{"job": "argus"}, "values": values}},
+			},
+		})
+	}))
+	t.Cleanup(loki.Close)
+
+	ps := NewPIIService()
+	ps.pollInterval = 10 * time.Millisecond
+	config := types.ServiceConfig{URL: loki.URL}
+
+	// The email was redacted, the IP and the card were not
+	lines = []string{
+		`{"msg":"login user=[REDACTED] from 203.0.113.7","pii_run":"run-1"}`,
+		`{"msg":"payment card 4111-1111-1111-1111","pii_run":"run-1"}`,
+		`{"msg":"clean","pii_run":"run-1"}`,
+	}
+	report := ps.Verify(context.Background(), config, DefaultPIISelector, run, time.Second)
+	assert.Equal(t, `{service_name=~".+"} |= "pii_run" |= "run-1"`, query)
+	assert.Equal(t, "failed", report.Status)
+	assert.Equal(t, 3, report.Generated)
+	assert.Equal(t, 3, report.Found)
+	assert.Equal(t, 3, report.Values)
+	assert.Equal(t, 2, report.Leaked)
+	assert.Equal(t, models.PIITypeResult{Values: 1, Leaked: 0}, report.ByType["email"])
+	assert.Equal(t, models.PIITypeResult{Values: 1, Leaked: 1}, report.ByType["credit_card"])
+	require.Len(t, report.Leaks, 2)
+	assert.Equal(t, models.PIILeak{Line: 1, Type: "credit_card", Value: "4111-1111-1111-1111", LokiLine: lines[1]}, report.Leaks[1])
+	assert.Contains(t, report.Problems, "2 of 3 PII values reached Loki unredacted")
+
+	lines = []string{`{"msg":"login [EMAIL] [IP]"}`, `{"msg":"payment card ****-****-****-1111"}`, `{"msg":"clean"}`}
+	report = ps.Verify(context.Background(), config, `{job="argus"}`, run, time.Second)
+	assert.Equal(t, "healthy", report.Status, report.Problems)
+	assert.Zero(t, report.Leaked)
+	assert.Empty(t, report.Leaks)
+
+	// Lines still missing when the timeout passes
+	lines = lines[:1]
+	report = ps.Verify(context.Background(), config, DefaultPIISelector, run, 50*time.Millisecond)
+	assert.Equal(t, "degraded", report.Status)
+	assert.Greater(t, report.Attempts, 1)
+	assert.Contains(t, report.Problems, "Only 1 of 3 lines reached Loki")
+
+	lines = nil
+	report = ps.Verify(context.Background(), config, DefaultPIISelector, run, 50*time.Millisecond)
+	assert.Equal(t, "failed", report.Status)
+
+	report = ps.Verify(context.Background(), types.ServiceConfig{URL: "http://127.0.0.1:1"}, DefaultPIISelector, run, 50*time.Millisecond)
+	assert.Equal(t, "failed", report.Status)
+	require.NotEmpty(t, report.Problems)
+	assert.Contains(t, report.Problems[0], "Loki query failed")
+}
+
+func luhnValid(number string) bool {
+	sum := 0
+	for i := range number {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
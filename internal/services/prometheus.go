@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// promSample is one element of an instant vector returned by the Prometheus query API
+type promSample struct {
+	Metric map[string]string
+	Value  float64
+}
+
+// prometheusQuery runs an instant PromQL query and returns the resulting vector
+func prometheusQuery(ctx context.Context, client *http.Client, prometheus types.ServiceConfig, query string) ([]promSample, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(prometheus.URL, "/")+"/api/v1/query?query="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := DoServiceRequest(client, prometheus, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]interface{}    `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode query response: HTTP %d: %w", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("query %q: %s", query, result.Error)
+	}
+	if result.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("query %q: unexpected result type %s", query, result.Data.ResultType)
+	}
+
+	samples := make([]promSample, 0, len(result.Data.Result))
+	for _, r := range result.Data.Result {
+		raw, _ := r.Value[1].(string)
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query %q: bad sample value %q", query, raw)
+		}
+		samples = append(samples, promSample{Metric: r.Metric, Value: value})
+	}
+	return samples, nil
+}
+
+// promTarget is an active target returned by the Prometheus targets API
+type promTarget struct {
+	DiscoveredLabels   map[string]string `json:"discoveredLabels"`
+	Labels             map[string]string `json:"labels"`
+	ScrapePool         string            `json:"scrapePool"`
+	ScrapeURL          string            `json:"scrapeUrl"`
+	LastError          string            `json:"lastError"`
+	LastScrape         time.Time         `json:"lastScrape"`
+	LastScrapeDuration float64           `json:"lastScrapeDuration"`
+	Health             string            `json:"health"`
+	ScrapeInterval     string            `json:"scrapeInterval"`
+	ScrapeTimeout      string            `json:"scrapeTimeout"`
+}
+
+// prometheusTargets returns the active targets Prometheus is scraping
+func prometheusTargets(ctx context.Context, client *http.Client, prometheus types.ServiceConfig) ([]promTarget, error) {
+	var data struct {
+		ActiveTargets []promTarget `json:"activeTargets"`
+	}
+	if err := prometheusAPI(ctx, client, prometheus, "/api/v1/targets?state=active", &data); err != nil {
+		return nil, err
+	}
+	return data.ActiveTargets, nil
+}
+
+// prometheusAPI requests an endpoint of the Prometheus HTTP API and decodes
+// the "data" field of a successful response into data
+func prometheusAPI(ctx context.Context, client *http.Client, prometheus types.ServiceConfig, endpoint string, data interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(prometheus.URL, "/")+endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := DoServiceRequest(client, prometheus, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string          `json:"status"`
+		Error  string          `json:"error"`
+		Data   json.RawMessage `json:"data"`
+	}
+	path, _, _ := strings.Cut(endpoint, "?")
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode %s response: HTTP %d: %w", path, resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return fmt.Errorf("%s: %s", path, result.Error)
+	}
+	return json.Unmarshal(result.Data, data)
+}
+
+// formatLabels renders a label set as {a="1", b="2"} without the metric name
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		if key != "__name__" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, labels[key]))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// maxRouteProblems limits the distinct problems reported per route
+const maxRouteProblems = 5
+
+// ReverseProxyService sends requests through a reverse proxy's routes and
+// checks which backend served them and what the backend received
+type ReverseProxyService struct {
+	timeout  time.Duration
+	requests int
+}
+
+// NewReverseProxyService creates a new reverse proxy service
+func NewReverseProxyService() *ReverseProxyService {
+	return &ReverseProxyService{timeout: 10 * time.Second, requests: 10}
+}
+
+// CheckRoutes sends every route's requests to the proxy's entrypoints. A
+// route passes when every request is answered 2xx by an expected backend,
+// over verified TLS when the route expects it, with the forwarding headers
+// reaching the backend and, for several expected backends, every one of
+// them serving at least one request.
+func (rs *ReverseProxyService) CheckRoutes(ctx context.Context, config types.ProxyConfig) *models.ProxyReport {
+	report := &models.ProxyReport{
+		Routes:    []models.ProxyRouteResult{},
+		Problems:  []string{},
+		Timestamp: time.Now(),
+	}
+	if len(config.Routes) == 0 {
+		report.Status = "not_configured"
+		return report
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLS != nil {
+		var err error
+		if tlsConfig, err = NewServiceTLSConfig(*config.TLS); err != nil {
+			report.Status = "failed"
+			report.Problems = append(report.Problems, fmt.Sprintf("proxy TLS settings: %v", err))
+			return report
+		}
+	}
+	plain := rs.proxyClient(config.Address, nil)
+	secure := rs.proxyClient(config.TLSAddress, tlsConfig)
+	defer plain.CloseIdleConnections()
+	defer secure.CloseIdleConnections()
+
+	passed := 0
+	for _, route := range config.Routes {
+		client := plain
+		if route.ExpectTLS {
+			client = secure
+		}
+		result := rs.checkRoute(ctx, client, route)
+		report.Routes = append(report.Routes, result)
+
+		if result.Passed {
+			passed++
+		}
+		for _, problem := range result.Problems {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: %s", result.Name, problem))
+		}
+	}
+
+	switch {
+	case passed == len(config.Routes):
+		report.Status = "healthy"
+	case passed > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "failed"
+	}
+	return report
+}
+
+// proxyClient returns a client that connects to address whatever the
+// request's host, so requests reach the proxy with the route's Host header
+// and TLS server name. Redirects are returned rather than followed.
+func (rs *ReverseProxyService) proxyClient(address string, tlsConfig *tls.Config) *http.Client {
+	dialer := &net.Dialer{Timeout: rs.timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.TLSClientConfig = tlsConfig
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{
+		Timeout:   rs.timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkRoute sends the route's requests and evaluates the responses
+func (rs *ReverseProxyService) checkRoute(ctx context.Context, client *http.Client, route types.ProxyRoute) models.ProxyRouteResult {
+	scheme, path := "http", route.Path
+	if route.ExpectTLS {
+		scheme = "https"
+	}
+	if path == "" {
+		path = "/"
+	}
+	result := models.ProxyRouteResult{
+		Name:        route.Name,
+		URL:         scheme + "://" + route.Host + path,
+		TLS:         route.ExpectTLS,
+		Requests:    route.Requests,
+		StatusCodes: map[int]int{},
+		Backends:    map[string]int{},
+		Problems:    []string{},
+	}
+	if result.Name == "" {
+		result.Name = route.Host + path
+	}
+	if result.Requests == 0 {
+		result.Requests = rs.requests
+	}
+
+	expected := map[string]bool{}
+	for _, backend := range route.ExpectedBackends {
+		expected[backend] = true
+	}
+	problems := map[string]bool{}
+	addProblem := func(problem string) {
+		if !problems[problem] && len(result.Problems) < maxRouteProblems {
+			problems[problem] = true
+			result.Problems = append(result.Problems, problem)
+		}
+	}
+
+	tlsVerified, forwardedFor, requestIDPropagated := true, true, true
+	var totalLatency time.Duration
+	for i := 0; i < result.Requests; i++ {
+		exchange, err := rs.send(ctx, client, result.URL)
+		if err != nil {
+			// A proxy that can't be reached or times out would fail every
+			// request the same way, so the rest are not sent
+			addProblem(err.Error())
+			tlsVerified, forwardedFor, requestIDPropagated = false, false, false
+			break
+		}
+		totalLatency += exchange.latency
+		result.StatusCodes[exchange.statusCode]++
+
+		ok := exchange.statusCode >= 200 && exchange.statusCode < 300
+		if !ok {
+			addProblem(fmt.Sprintf("HTTP %d", exchange.statusCode))
+		}
+		if route.BackendHeader != "" {
+			backend := exchange.header.Get(route.BackendHeader)
+			switch {
+			case backend == "":
+				ok = false
+				addProblem(fmt.Sprintf("response has no %s header", route.BackendHeader))
+			case len(expected) > 0 && !expected[backend]:
+				ok = false
+				addProblem(fmt.Sprintf("served by unexpected backend %q", backend))
+			}
+			if backend != "" {
+				result.Backends[backend]++
+			}
+		}
+		if route.BodyMarker != "" && !strings.Contains(exchange.body, route.BodyMarker) {
+			ok = false
+			addProblem(fmt.Sprintf("body does not contain %q", route.BodyMarker))
+		}
+		if route.ExpectTLS && !exchange.tls {
+			tlsVerified = false
+		}
+		if route.EchoesHeaders {
+			if !forwardedForContains(exchange.body, exchange.clientIP) {
+				forwardedFor = false
+				addProblem(fmt.Sprintf("backend did not receive X-Forwarded-For with the client address %s", exchange.clientIP))
+			}
+			if !strings.Contains(exchange.body, exchange.requestID) {
+				requestIDPropagated = false
+				addProblem("backend did not receive the request's X-Request-ID")
+			}
+		}
+		if ok {
+			result.Succeeded++
+		}
+	}
+
+	answered := 0
+	for _, count := range result.StatusCodes {
+		answered += count
+	}
+	if answered > 0 {
+		result.AvgLatency = totalLatency / time.Duration(answered)
+	}
+	result.Routed = result.Succeeded == result.Requests
+	result.Passed = result.Routed
+
+	if route.ExpectTLS {
+		result.TLSVerified = &tlsVerified
+		result.Passed = result.Passed && tlsVerified
+	}
+	if route.EchoesHeaders {
+		result.ForwardedFor = &forwardedFor
+		result.RequestIDPropagated = &requestIDPropagated
+		result.Passed = result.Passed && forwardedFor && requestIDPropagated
+	}
+	if len(expected) > 1 {
+		var idle []string
+		for backend := range expected {
+			if result.Backends[backend] == 0 {
+				idle = append(idle, backend)
+			}
+		}
+		sort.Strings(idle)
+		balanced := len(idle) == 0
+		if !balanced {
+			addProblem(fmt.Sprintf("no requests reached %s", strings.Join(idle, ", ")))
+		}
+		result.Balanced = &balanced
+		result.Passed = result.Passed && balanced
+	}
+	return result
+}
+
+// forwardedForContains reports whether an echoed X-Forwarded-For header lists
+// ip, whether the backend writes headers as "Name: value" lines or as JSON
+func forwardedForContains(body, ip string) bool {
+	if ip == "" {
+		return false
+	}
+	const name = "x-forwarded-for"
+	lower := strings.ToLower(body)
+	for offset := 0; ; {
+		i := strings.Index(lower[offset:], name)
+		if i < 0 {
+			return false
+		}
+		offset += i + len(name)
+
+		// The value is the run of address characters after the name
+		value := strings.TrimPrefix(strings.TrimPrefix(body[offset:], `"`), ":")
+		value = strings.TrimLeft(value, ` ["`)
+		if end := strings.IndexFunc(value, func(r rune) bool {
+			return !strings.ContainsRune("0123456789abcdefABCDEF.:, ", r)
+		}); end >= 0 {
+			value = value[:end]
+		}
+		for _, entry := range strings.Split(value, ",") {
+			if strings.TrimSpace(entry) == ip {
+				return true
+			}
+		}
+	}
+}
+
+// proxyExchange is one request through the proxy and its response
+type proxyExchange struct {
+	clientIP   string // the address Argus connected to the proxy from
+	requestID  string
+	statusCode int
+	header     http.Header
+	body       string
+	tls        bool
+	latency    time.Duration
+}
+
+// send requests url with a fresh X-Request-ID
+func (rs *ReverseProxyService) send(ctx context.Context, client *http.Client, url string) (*proxyExchange, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	exchange := &proxyExchange{requestID: uuid.New().String()}
+	req.Header.Set("X-Request-ID", exchange.requestID)
+	req.Header.Set("User-Agent", "argus-proxy-check")
+
+	// The proxy should add the address Argus connected from to X-Forwarded-For
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.LocalAddr().String()); err == nil {
+				exchange.clientIP = host
+			}
+		},
+	}))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+	exchange.latency = time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	exchange.statusCode = resp.StatusCode
+	exchange.header = resp.Header
+	exchange.body = string(body)
+	exchange.tls = resp.TLS != nil && len(resp.TLS.VerifiedChains) > 0
+	return exchange, nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// startEchoBackend starts a backend that names itself in X-Backend and writes
+// the request headers it received into the body, like traefik/whoami
+func startEchoBackend(t *testing.T, name, page string) *url.URL {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend", name)
+		fmt.Fprintf(w, "Hostname: %s\n%s\n", name, page)
+		names := make([]string, 0, len(r.Header))
+		for header := range r.Header {
+			names = append(names, header)
+		}
+		sort.Strings(names)
+		for _, header := range names {
+			fmt.Fprintf(w, "%s: %s\n", header, strings.Join(r.Header[header], ","))
+		}
+	}))
+	t.Cleanup(server.Close)
+	backend, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return backend
+}
+
+// proxyStandIn routes by host to backends in round robin, as Traefik does
+type proxyStandIn struct {
+	mu       sync.Mutex
+	routes   map[string][]*url.URL
+	next     map[string]int
+	stripIDs map[string]bool // hosts whose X-Request-ID is dropped
+}
+
+func (p *proxyStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+
+	p.mu.Lock()
+	backends := p.routes[host]
+	var backend *url.URL
+	if len(backends) > 0 {
+		backend = backends[p.next[host]%len(backends)]
+		p.next[host]++
+	}
+	p.mu.Unlock()
+
+	if backend == nil {
+		http.NotFound(w, r)
+		return
+	}
+	proxy := httputil.NewSingleHostReverseProxy(backend)
+	if p.stripIDs[host] {
+		director := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			director(req)
+			req.Header.Del("X-Request-ID")
+		}
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+func TestReverseProxyService_CheckRoutes(t *testing.T) {
+	b1 := startEchoBackend(t, "b1", "api")
+	b2 := startEchoBackend(t, "b2", "api")
+	b3 := startEchoBackend(t, "b3", "welcome to the blog")
+
+	standIn := &proxyStandIn{
+		routes: map[string][]*url.URL{
+			"api.example.com":      {b1, b2},
+			"blog.example.com":     {b3},
+			"secure.example.com":   {b1},
+			"sticky.example.com":   {b1},
+			"stripped.example.com": {b2},
+		},
+		next:     map[string]int{},
+		stripIDs: map[string]bool{"stripped.example.com": true},
+	}
+	plain := httptest.NewServer(standIn)
+	defer plain.Close()
+
+	ca := newTestCA(t)
+	cert, _, _ := ca.issue(t, "proxy", x509.ExtKeyUsageServerAuth, "*.example.com")
+	secure := httptest.NewUnstartedServer(standIn)
+	secure.Config.ErrorLog = log.New(io.Discard, "", 0) // the untrusted case fails its handshakes
+	secure.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	secure.StartTLS()
+	defer secure.Close()
+
+	config := types.ProxyConfig{
+		Address:    plain.Listener.Addr().String(),
+		TLSAddress: secure.Listener.Addr().String(),
+		TLS:        &types.TLSConfig{CAFile: ca.caFile(t)},
+		Routes: []types.ProxyRoute{
+			{Name: "api", Host: "api.example.com", Path: "/users", BackendHeader: "X-Backend", ExpectedBackends: []string{"b1", "b2"}, EchoesHeaders: true, Requests: 6},
+			{Name: "blog", Host: "blog.example.com", BodyMarker: "welcome to the blog", Requests: 2},
+			{Name: "secure", Host: "secure.example.com", BackendHeader: "X-Backend", ExpectedBackends: []string{"b1"}, ExpectTLS: true, Requests: 2},
+			{Name: "admin", Host: "admin.example.com", Path: "/dashboard", Requests: 2},
+			{Name: "sticky", Host: "sticky.example.com", BackendHeader: "X-Backend", ExpectedBackends: []string{"b1", "b2"}, Requests: 4},
+			{Name: "stripped", Host: "stripped.example.com", EchoesHeaders: true, Requests: 2},
+		},
+	}
+	require.NoError(t, config.Validate())
+
+	report := NewReverseProxyService().CheckRoutes(context.Background(), config)
+	require.Len(t, report.Routes, 6)
+	assert.Equal(t, "degraded", report.Status)
+	results := map[string]int{}
+	for i, route := range report.Routes {
+		results[route.Name] = i
+	}
+
+	t.Run("load balanced route with forwarded headers", func(t *testing.T) {
+		api := report.Routes[results["api"]]
+		assert.True(t, api.Passed, api.Problems)
+		assert.Equal(t, "http://api.example.com/users", api.URL)
+		assert.Equal(t, 6, api.Succeeded)
+		assert.Equal(t, map[string]int{"b1": 3, "b2": 3}, api.Backends)
+		require.NotNil(t, api.Balanced)
+		assert.True(t, *api.Balanced)
+		require.NotNil(t, api.ForwardedFor)
+		assert.True(t, *api.ForwardedFor)
+		require.NotNil(t, api.RequestIDPropagated)
+		assert.True(t, *api.RequestIDPropagated)
+		assert.Nil(t, api.TLSVerified)
+	})
+
+	t.Run("body marker identifies the backend", func(t *testing.T) {
+		blog := report.Routes[results["blog"]]
+		assert.True(t, blog.Passed, blog.Problems)
+		assert.Equal(t, "http://blog.example.com/", blog.URL)
+		assert.Nil(t, blog.Balanced)
+	})
+
+	t.Run("route over verified TLS", func(t *testing.T) {
+		route := report.Routes[results["secure"]]
+		assert.True(t, route.Passed, route.Problems)
+		require.NotNil(t, route.TLSVerified)
+		assert.True(t, *route.TLSVerified)
+		assert.Equal(t, map[string]int{"b1": 2}, route.Backends)
+	})
+
+	t.Run("unrouted host", func(t *testing.T) {
+		admin := report.Routes[results["admin"]]
+		assert.False(t, admin.Passed)
+		assert.False(t, admin.Routed)
+		assert.Equal(t, map[int]int{404: 2}, admin.StatusCodes)
+		assert.Equal(t, []string{"HTTP 404"}, admin.Problems)
+	})
+
+	t.Run("backend that receives no traffic", func(t *testing.T) {
+		sticky := report.Routes[results["sticky"]]
+		assert.False(t, sticky.Passed)
+		assert.True(t, sticky.Routed)
+		require.NotNil(t, sticky.Balanced)
+		assert.False(t, *sticky.Balanced)
+		assert.Contains(t, sticky.Problems, "no requests reached b2")
+	})
+
+	t.Run("request ID dropped by the proxy", func(t *testing.T) {
+		stripped := report.Routes[results["stripped"]]
+		assert.False(t, stripped.Passed)
+		assert.True(t, *stripped.ForwardedFor)
+		assert.False(t, *stripped.RequestIDPropagated)
+		assert.Contains(t, report.Problems, "stripped: backend did not receive the request's X-Request-ID")
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		untrusted := config
+		untrusted.TLS = nil
+		untrusted.Routes = []types.ProxyRoute{{Host: "secure.example.com", ExpectTLS: true, Requests: 1}}
+		report := NewReverseProxyService().CheckRoutes(context.Background(), untrusted)
+		assert.Equal(t, "failed", report.Status)
+		assert.False(t, *report.Routes[0].TLSVerified)
+		assert.Contains(t, report.Problems[0], "certificate")
+	})
+
+	t.Run("route stops at the first transport error", func(t *testing.T) {
+		// Accepts connections but never answers
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		stalled := &ReverseProxyService{timeout: 300 * time.Millisecond, requests: 10}
+
+		start := time.Now()
+		report := stalled.CheckRoutes(context.Background(), types.ProxyConfig{
+			Address: listener.Addr().String(),
+			Routes:  []types.ProxyRoute{{Host: "api.example.com"}},
+		})
+		assert.Less(t, time.Since(start), 3*stalled.timeout)
+		assert.Equal(t, "failed", report.Status)
+		assert.Equal(t, 10, report.Routes[0].Requests)
+		assert.Zero(t, report.Routes[0].Succeeded)
+		assert.Len(t, report.Problems, 1)
+	})
+
+	t.Run("no routes", func(t *testing.T) {
+		report := NewReverseProxyService().CheckRoutes(context.Background(), types.ProxyConfig{Address: config.Address})
+		assert.Equal(t, "not_configured", report.Status)
+		assert.Empty(t, report.Routes)
+	})
+}
+
+func TestForwardedForContains(t *testing.T) {
+	assert.True(t, forwardedForContains("Hostname: api-1\nX-Forwarded-For: 127.0.0.1\nX-Forwarded-Host: api", "127.0.0.1"))
+	assert.True(t, forwardedForContains("X-Forwarded-For: 203.0.113.9, 10.0.0.7\n", "10.0.0.7"))
+	assert.True(t, forwardedForContains(`{"headers":{"x-forwarded-for":["::1"],"X-Real-Ip":["10.0.0.1"]}}`, "::1"))
+	assert.False(t, forwardedForContains("X-Forwarded-For: 10.0.0.70\n", "10.0.0.7"), "only whole addresses match")
+	assert.False(t, forwardedForContains("X-Forwarded-For: 203.0.113.9\nX-Real-Ip: 10.0.0.7\n", "10.0.0.7"))
+	assert.False(t, forwardedForContains("X-Real-Ip: 10.0.0.7\n", "10.0.0.7"))
+	assert.False(t, forwardedForContains("X-Forwarded-For: 10.0.0.7\n", ""))
+}
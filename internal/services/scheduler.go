@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// ErrScheduleNotFound is returned for operations on an unknown schedule ID
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// CheckFunc runs one check with a schedule's parameters and returns an error
+// when the check fails
+type CheckFunc func(ctx context.Context, params map[string]string) error
+
+// Scheduler runs registered checks on schedules and exports each run's
+// outcome as argus_check_success and argus_check_duration_seconds
+type Scheduler struct {
+	mu        sync.Mutex
+	checks    map[string]CheckFunc
+	schedules map[string]*scheduledCheck
+	wg        sync.WaitGroup
+}
+
+// scheduledCheck is a schedule, its run state and the cancel func of its loop
+type scheduledCheck struct {
+	state  models.ScheduledCheck
+	cancel context.CancelFunc
+}
+
+// NewScheduler creates a new scheduler with no checks or schedules
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		checks:    make(map[string]CheckFunc),
+		schedules: make(map[string]*scheduledCheck),
+	}
+}
+
+// RegisterCheck makes a check available to schedules under name
+func (s *Scheduler) RegisterCheck(name string, check CheckFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// Checks returns the names of the registered checks
+func (s *Scheduler) Checks() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Add validates and starts a schedule. An ID is generated when it has none.
+func (s *Scheduler) Add(schedule types.Schedule) (models.ScheduledCheck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if schedule.ID == "" {
+		schedule.ID = uuid.New().String()
+	}
+	if _, exists := s.schedules[schedule.ID]; exists {
+		return models.ScheduledCheck{}, fmt.Errorf("schedule %q already exists", schedule.ID)
+	}
+	if err := s.validate(schedule); err != nil {
+		return models.ScheduledCheck{}, err
+	}
+
+	sc := &scheduledCheck{}
+	s.schedules[schedule.ID] = sc
+	s.apply(sc, schedule)
+	return s.snapshot(sc), nil
+}
+
+// Update replaces a schedule's definition and restarts its timer. Run
+// counters are kept unless the check or profile changes.
+func (s *Scheduler) Update(id string, schedule types.Schedule) (models.ScheduledCheck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return models.ScheduledCheck{}, ErrScheduleNotFound
+	}
+	schedule.ID = id
+	if err := s.validate(schedule); err != nil {
+		return models.ScheduledCheck{}, err
+	}
+
+	sc.cancel()
+	if schedule.Check != sc.state.Check || s.profile(schedule) != sc.state.Profile {
+		deleteCheckMetrics(sc.state.Check, sc.state.Profile)
+		sc.state.Runs, sc.state.Failures, sc.state.LastRun = 0, 0, nil
+	}
+	s.apply(sc, schedule)
+	return s.snapshot(sc), nil
+}
+
+// Remove stops a schedule and drops its metrics
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	sc.cancel()
+	delete(s.schedules, id)
+	deleteCheckMetrics(sc.state.Check, sc.state.Profile)
+	return nil
+}
+
+// Get returns one schedule
+func (s *Scheduler) Get(id string) (models.ScheduledCheck, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc, ok := s.schedules[id]
+	if !ok {
+		return models.ScheduledCheck{}, ErrScheduleNotFound
+	}
+	return s.snapshot(sc), nil
+}
+
+// List returns every schedule, ordered by check and profile
+func (s *Scheduler) List() []models.ScheduledCheck {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]models.ScheduledCheck, 0, len(s.schedules))
+	for _, sc := range s.schedules {
+		list = append(list, s.snapshot(sc))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Check != list[j].Check {
+			return list[i].Check < list[j].Check
+		}
+		return list[i].Profile < list[j].Profile
+	})
+	return list
+}
+
+// RunNow runs a schedule's check immediately, outside its timer, and returns the run
+func (s *Scheduler) RunNow(ctx context.Context, id string) (models.CheckRun, error) {
+	s.mu.Lock()
+	sc, ok := s.schedules[id]
+	s.mu.Unlock()
+	if !ok {
+		return models.CheckRun{}, ErrScheduleNotFound
+	}
+	return s.run(ctx, sc), nil
+}
+
+// Stop stops every schedule and waits for running checks to return
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	for _, sc := range s.schedules {
+		sc.cancel()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// validate checks a schedule against the registered checks and the other
+// schedules. Callers hold s.mu.
+func (s *Scheduler) validate(schedule types.Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	if _, ok := s.checks[schedule.Check]; !ok {
+		return fmt.Errorf("unknown check %q", schedule.Check)
+	}
+	// check and profile label the metrics, so they must identify one schedule
+	profile := s.profile(schedule)
+	for id, other := range s.schedules {
+		if id != schedule.ID && other.state.Check == schedule.Check && other.state.Profile == profile {
+			return fmt.Errorf("check %q already has a schedule for profile %q", schedule.Check, profile)
+		}
+	}
+	return nil
+}
+
+// profile returns the schedule's profile, "default" when it has none
+func (s *Scheduler) profile(schedule types.Schedule) string {
+	if schedule.Profile == "" {
+		return "default"
+	}
+	return schedule.Profile
+}
+
+// apply stores the schedule's definition and starts its loop unless it is
+// paused. Callers hold s.mu.
+func (s *Scheduler) apply(sc *scheduledCheck, schedule types.Schedule) {
+	sc.state.ID = schedule.ID
+	sc.state.Check = schedule.Check
+	sc.state.Profile = s.profile(schedule)
+	sc.state.IntervalSeconds = schedule.IntervalSeconds
+	sc.state.JitterSeconds = schedule.JitterSeconds
+	sc.state.TimeoutSeconds = schedule.TimeoutSeconds
+	if sc.state.TimeoutSeconds == 0 {
+		sc.state.TimeoutSeconds = schedule.IntervalSeconds
+	}
+	sc.state.Params = schedule.Params
+	sc.state.Paused = schedule.Paused
+	sc.state.NextRun = nil
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sc.cancel = cancel
+	if schedule.Paused {
+		return
+	}
+	s.wg.Add(1)
+	go s.loop(ctx, sc)
+}
+
+// loop runs the check after a random part of the jitter, then every interval
+// plus jitter, until ctx is cancelled
+func (s *Scheduler) loop(ctx context.Context, sc *scheduledCheck) {
+	defer s.wg.Done()
+
+	s.mu.Lock()
+	interval := time.Duration(sc.state.IntervalSeconds) * time.Second
+	jitter := time.Duration(sc.state.JitterSeconds) * time.Second
+	s.mu.Unlock()
+
+	delay := randomJitter(jitter)
+	for {
+		next := time.Now().Add(delay)
+		s.mu.Lock()
+		if ctx.Err() == nil {
+			sc.state.NextRun = &next
+		}
+		s.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, sc)
+		delay = interval + randomJitter(jitter)
+	}
+}
+
+// run executes the check once, records the run and exports its metrics
+func (s *Scheduler) run(ctx context.Context, sc *scheduledCheck) models.CheckRun {
+	s.mu.Lock()
+	check := s.checks[sc.state.Check]
+	name, profile, params := sc.state.Check, sc.state.Profile, sc.state.Params
+	timeout := time.Duration(sc.state.TimeoutSeconds) * time.Second
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	run := models.CheckRun{StartedAt: time.Now()}
+	err := check(ctx, params)
+	run.Duration = time.Since(run.StartedAt)
+	run.Success = err == nil
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop the outcome of runs that were cancelled, or that finished after
+	// their schedule was removed or redefined
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return run
+	}
+	if current, ok := s.schedules[sc.state.ID]; !ok || current != sc || sc.state.Check != name || sc.state.Profile != profile {
+		return run
+	}
+	sc.state.Runs++
+	if !run.Success {
+		sc.state.Failures++
+	}
+	sc.state.LastRun = &run
+
+	success := 0.0
+	if run.Success {
+		success = 1
+	}
+	metrics.CheckSuccess.WithLabelValues(name, profile).Set(success)
+	metrics.CheckDuration.WithLabelValues(name, profile).Observe(run.Duration.Seconds())
+	return run
+}
+
+// snapshot copies a schedule's state for callers. Callers hold s.mu.
+func (s *Scheduler) snapshot(sc *scheduledCheck) models.ScheduledCheck {
+	state := sc.state
+	if state.LastRun != nil {
+		lastRun := *state.LastRun
+		state.LastRun = &lastRun
+	}
+	if state.NextRun != nil {
+		nextRun := *state.NextRun
+		state.NextRun = &nextRun
+	}
+	return state
+}
+
+// randomJitter returns a random duration in [0, jitter)
+func randomJitter(jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// deleteCheckMetrics drops the series of a schedule that no longer exists
+func deleteCheckMetrics(check, profile string) {
+	metrics.CheckSuccess.DeleteLabelValues(check, profile)
+	metrics.CheckDuration.DeleteLabelValues(check, profile)
+}
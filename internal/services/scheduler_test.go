@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestScheduler(t *testing.T) {
+	scheduler := NewScheduler()
+	t.Cleanup(scheduler.Stop)
+
+	var runs atomic.Int32
+	scheduler.RegisterCheck("counter", func(ctx context.Context, params map[string]string) error {
+		runs.Add(1)
+		return nil
+	})
+	scheduler.RegisterCheck("failing", func(ctx context.Context, params map[string]string) error {
+		return errors.New("target " + params["target"] + " is down")
+	})
+	assert.Equal(t, []string{"counter", "failing"}, scheduler.Checks())
+
+	t.Run("validates schedules", func(t *testing.T) {
+		_, err := scheduler.Add(types.Schedule{Check: "unknown", IntervalSeconds: 60})
+		assert.EqualError(t, err, `unknown check "unknown"`)
+		_, err = scheduler.Add(types.Schedule{Check: "counter", IntervalSeconds: 10, JitterSeconds: 20})
+		assert.EqualError(t, err, "jitter_seconds must be between 0 and interval_seconds")
+		_, err = scheduler.Add(types.Schedule{Check: "counter"})
+		assert.EqualError(t, err, "interval_seconds must be at least 1")
+	})
+
+	t.Run("runs on its interval and exports the outcome", func(t *testing.T) {
+		schedule, err := scheduler.Add(types.Schedule{ID: "tick", Check: "counter", Profile: "canary", IntervalSeconds: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 1, schedule.TimeoutSeconds)
+
+		// Without jitter the first run is immediate and the next one follows a second later
+		assert.Eventually(t, func() bool { return runs.Load() >= 2 }, 3*time.Second, 20*time.Millisecond)
+		state, err := scheduler.Get("tick")
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, state.Runs, 2)
+		assert.Zero(t, state.Failures)
+		require.NotNil(t, state.LastRun)
+		assert.True(t, state.LastRun.Success)
+		assert.NotNil(t, state.NextRun)
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(metrics.CheckSuccess.WithLabelValues("counter", "canary")))
+		assert.Equal(t, 1, testutil.CollectAndCount(metrics.CheckDuration, "argus_check_duration_seconds"))
+
+		_, err = scheduler.Add(types.Schedule{Check: "counter", Profile: "canary", IntervalSeconds: 30})
+		assert.EqualError(t, err, `check "counter" already has a schedule for profile "canary"`)
+
+		require.NoError(t, scheduler.Remove("tick"))
+		assert.Equal(t, 0, testutil.CollectAndCount(metrics.CheckSuccess, "argus_check_success"))
+		assert.ErrorIs(t, scheduler.Remove("tick"), ErrScheduleNotFound)
+	})
+
+	t.Run("paused schedules only run on demand", func(t *testing.T) {
+		schedule, err := scheduler.Add(types.Schedule{Check: "failing", IntervalSeconds: 1, Paused: true, Params: map[string]string{"target": "db"}})
+		require.NoError(t, err)
+		assert.Equal(t, "default", schedule.Profile)
+		assert.Nil(t, schedule.NextRun)
+
+		run, err := scheduler.RunNow(context.Background(), schedule.ID)
+		require.NoError(t, err)
+		assert.False(t, run.Success)
+		assert.Equal(t, "target db is down", run.Error)
+		assert.Equal(t, 0.0, testutil.ToFloat64(metrics.CheckSuccess.WithLabelValues("failing", "default")))
+
+		// Moving the schedule to another profile starts its counters afresh
+		updated, err := scheduler.Update(schedule.ID, types.Schedule{Check: "failing", Profile: "staging", IntervalSeconds: 120, Paused: true})
+		require.NoError(t, err)
+		assert.Zero(t, updated.Failures)
+		assert.Equal(t, 120, updated.TimeoutSeconds)
+		assert.Len(t, scheduler.List(), 1)
+
+		_, err = scheduler.Update("missing", types.Schedule{Check: "failing", IntervalSeconds: 60})
+		assert.ErrorIs(t, err, ErrScheduleNotFound)
+	})
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// ScrapeCoverageService analyses Prometheus' active scrape targets and turns
+// what it finds into recommendations
+type ScrapeCoverageService struct {
+	client *http.Client
+	now    func() time.Time
+
+	// slowRatio flags scrapes taking this share of their timeout
+	slowRatio float64
+	// sampleRatio flags targets scraping this share of their sample_limit
+	sampleRatio float64
+	// staleIntervals flags targets not scraped for this many intervals
+	staleIntervals float64
+
+	// unscraped remembers when targets without a scrape were first seen, so
+	// new targets get one interval before being reported as never scraped
+	mu        sync.Mutex
+	unscraped map[targetID]time.Time
+}
+
+// NewScrapeCoverageService creates a new scrape coverage service
+func NewScrapeCoverageService() *ScrapeCoverageService {
+	return &ScrapeCoverageService{
+		client:         &http.Client{Timeout: 15 * time.Second},
+		now:            time.Now,
+		slowRatio:      0.8,
+		sampleRatio:    0.9,
+		staleIntervals: 3,
+		unscraped:      map[targetID]time.Time{},
+	}
+}
+
+// promMetadata is an entry of the Prometheus target metadata API
+type promMetadata struct {
+	Target map[string]string `json:"target"`
+	Metric string            `json:"metric"`
+}
+
+// Analyze builds the report from /api/v1/targets, enriched with the metric
+// families from /api/v1/targets/metadata, the sample_limit of each job from
+// /api/v1/status/config and the samples of each target. Only the targets API
+// is required; the rest is reported under Problems when unavailable.
+func (ss *ScrapeCoverageService) Analyze(ctx context.Context, prometheus types.ServiceConfig) (*models.ScrapeCoverageReport, error) {
+	active, err := prometheusTargets(ctx, ss.client, prometheus)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ScrapeCoverageReport{
+		Jobs:            []models.ScrapeJob{},
+		Targets:         []models.ScrapeTarget{},
+		Issues:          []models.ScrapeIssue{},
+		Recommendations: []models.Recommendation{},
+		Problems:        []string{},
+		Timestamp:       ss.now(),
+	}
+
+	families := map[targetID]int{}
+	var metadata []promMetadata
+	if err := prometheusAPI(ctx, ss.client, prometheus, "/api/v1/targets/metadata", &metadata); err != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("target metadata unavailable: %v", err))
+	}
+	for _, entry := range metadata {
+		families[targetKey(entry.Target["job"], entry.Target["instance"])]++
+	}
+
+	limits, err := ss.sampleLimits(ctx, prometheus)
+	if err != nil {
+		report.Problems = append(report.Problems, fmt.Sprintf("scrape config unavailable: %v", err))
+	}
+	samples := map[targetID]int{}
+	if len(limits) > 0 {
+		results, err := prometheusQuery(ctx, ss.client, prometheus, "scrape_samples_post_metric_relabeling")
+		if err != nil {
+			report.Problems = append(report.Problems, fmt.Sprintf("scraped samples unavailable: %v", err))
+		}
+		for _, sample := range results {
+			samples[targetKey(sample.Metric["job"], sample.Metric["instance"])] = int(sample.Value)
+		}
+	}
+
+	for _, target := range active {
+		job := target.Labels["job"]
+		key := targetKey(job, target.Labels["instance"])
+		report.Targets = append(report.Targets, models.ScrapeTarget{
+			Job:            job,
+			Instance:       target.Labels["instance"],
+			ScrapeURL:      target.ScrapeURL,
+			Health:         target.Health,
+			LastError:      target.LastError,
+			LastScrape:     target.LastScrape,
+			ScrapeDuration: time.Duration(target.LastScrapeDuration * float64(time.Second)),
+			ScrapeInterval: parsePromDuration(target.ScrapeInterval),
+			ScrapeTimeout:  parsePromDuration(target.ScrapeTimeout),
+			Samples:        samples[key],
+			SampleLimit:    limits[target.ScrapePool],
+			MetricFamilies: families[key],
+		})
+	}
+	sort.SliceStable(report.Targets, func(i, j int) bool {
+		a, b := report.Targets[i], report.Targets[j]
+		if a.Job != b.Job {
+			return a.Job < b.Job
+		}
+		return a.Instance < b.Instance
+	})
+
+	ss.findIssues(report)
+	report.Recommendations = ss.recommend(report.Issues, report.Timestamp)
+
+	report.TotalTargets = len(report.Targets)
+	switch {
+	case report.TotalTargets == 0:
+		report.Status = "failed"
+		report.Problems = append(report.Problems, "Prometheus has no active targets")
+	case len(report.Issues) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report, nil
+}
+
+// findIssues checks every target and job against the thresholds
+func (ss *ScrapeCoverageService) findIssues(report *models.ScrapeCoverageReport) {
+	jobs := map[string]*models.ScrapeJob{}
+	instances := map[targetID][]string{}
+	now := report.Timestamp
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	unscraped := map[targetID]time.Time{}
+
+	for _, target := range report.Targets {
+		job, ok := jobs[target.Job]
+		if !ok {
+			job = &models.ScrapeJob{Job: target.Job}
+			jobs[target.Job] = job
+		}
+		job.Targets++
+		key := targetKey(target.Job, target.Instance)
+		instances[key] = append(instances[key], target.ScrapeURL)
+
+		issue := func(kind, format string, args ...interface{}) {
+			report.Issues = append(report.Issues, models.ScrapeIssue{
+				Type: kind, Job: target.Job, Instance: target.Instance, Message: fmt.Sprintf(format, args...),
+			})
+		}
+
+		switch target.Health {
+		case "up":
+			job.Up++
+			report.UpTargets++
+		case "down":
+			report.DownTargets++
+			issue("target_down", "%s is down: %s", target.Instance, target.LastError)
+		}
+
+		if target.ScrapeTimeout > 0 && target.ScrapeDuration.Seconds() >= ss.slowRatio*target.ScrapeTimeout.Seconds() {
+			issue("slow_scrape", "%s took %s to scrape, %.0f%% of its %s timeout",
+				target.Instance, target.ScrapeDuration.Round(time.Millisecond),
+				100*target.ScrapeDuration.Seconds()/target.ScrapeTimeout.Seconds(), target.ScrapeTimeout)
+		}
+		if target.SampleLimit > 0 && float64(target.Samples) >= ss.sampleRatio*float64(target.SampleLimit) {
+			issue("sample_limit", "%s returns %d samples, %.0f%% of its sample_limit of %d",
+				target.Instance, target.Samples, 100*float64(target.Samples)/float64(target.SampleLimit), target.SampleLimit)
+		}
+		if target.ScrapeInterval > 0 {
+			age := now.Sub(target.LastScrape)
+			if target.LastScrape.IsZero() {
+				seen, ok := ss.unscraped[key]
+				if !ok {
+					seen = now
+				}
+				unscraped[key] = seen
+				if now.Sub(seen) >= target.ScrapeInterval {
+					issue("stale_target", "%s has never been scraped", target.Instance)
+				}
+			} else if age.Seconds() > ss.staleIntervals*target.ScrapeInterval.Seconds() {
+				issue("stale_target", "%s was last scraped %s ago, every %s expected",
+					target.Instance, age.Round(time.Second), target.ScrapeInterval)
+			}
+		}
+	}
+
+	ss.unscraped = unscraped
+
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		job := jobs[name]
+		report.Jobs = append(report.Jobs, *job)
+		if job.Up == 0 {
+			report.Issues = append(report.Issues, models.ScrapeIssue{
+				Type: "job_down", Job: name,
+				Message: fmt.Sprintf("job %s has no healthy instance among %d targets", name, job.Targets),
+			})
+		}
+	}
+
+	keys := make([]targetID, 0, len(instances))
+	for key, urls := range instances {
+		if len(urls) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].job != keys[j].job {
+			return keys[i].job < keys[j].job
+		}
+		return keys[i].instance < keys[j].instance
+	})
+	for _, key := range keys {
+		report.Issues = append(report.Issues, models.ScrapeIssue{
+			Type: "duplicate_instance", Job: key.job, Instance: key.instance,
+			Message: fmt.Sprintf("instance %q is shared by %d targets of job %s: %s", key.instance, len(instances[key]), key.job, strings.Join(instances[key], ", ")),
+		})
+	}
+}
+
+// recommendation describes how to act on one type of issue
+type recommendation struct {
+	kind, priority, title, impact, effort, action string
+}
+
+var scrapeRecommendations = map[string]recommendation{
+	"target_down": {"configuration", "high", "Restore down targets",
+		"Metrics and alerts for these targets are missing", "medium",
+		"Check that the exporters are running and reachable from Prometheus, and fix the scrape errors"},
+	"slow_scrape": {"optimization", "medium", "Speed up slow scrapes",
+		"Scrapes close to their timeout will start failing and leave gaps", "medium",
+		"Reduce the series these targets expose or raise scrape_timeout (up to scrape_interval)"},
+	"sample_limit": {"scaling", "high", "Raise sample_limit or cut cardinality",
+		"Prometheus rejects the whole scrape once sample_limit is exceeded", "medium",
+		"Drop unneeded series with metric_relabel_configs or raise sample_limit for the job"},
+	"stale_target": {"configuration", "medium", "Investigate stale targets",
+		"These targets are discovered but their data is not being refreshed", "low",
+		"Check that the scrape loop is running and that Prometheus is not overloaded"},
+	"job_down": {"configuration", "high", "Bring back a healthy instance",
+		"The whole job is unmonitored and its up-based alerts fire", "medium",
+		"Check service discovery and the targets of the job"},
+	"duplicate_instance": {"configuration", "medium", "Make instance labels unique",
+		"Series from different targets collide and overwrite each other", "low",
+		"Fix the relabel_configs that set instance so each target keeps its own address"},
+}
+
+// recommend turns the issues into one recommendation per issue type and job
+func (ss *ScrapeCoverageService) recommend(issues []models.ScrapeIssue, now time.Time) []models.Recommendation {
+	type group struct {
+		kind, job string
+		messages  []string
+	}
+	var groups []*group
+	byKey := map[string]*group{}
+	for _, issue := range issues {
+		key := issue.Type + "/" + issue.Job
+		g, ok := byKey[key]
+		if !ok {
+			g = &group{kind: issue.Type, job: issue.Job}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.messages = append(g.messages, issue.Message)
+	}
+
+	recommendations := make([]models.Recommendation, 0, len(groups))
+	for _, g := range groups {
+		rec := scrapeRecommendations[g.kind]
+		recommendations = append(recommendations, models.Recommendation{
+			ID:          "scrape-" + strings.ReplaceAll(g.kind, "_", "-") + "-" + g.job,
+			Type:        rec.kind,
+			Priority:    rec.priority,
+			Title:       fmt.Sprintf("%s (job %s)", rec.title, g.job),
+			Description: rec.action + ". " + strings.Join(g.messages, "; "),
+			Impact:      rec.impact,
+			Effort:      rec.effort,
+			CreatedAt:   now,
+		})
+	}
+
+	priority := map[string]int{"high": 0, "medium": 1, "low": 2}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return priority[recommendations[i].Priority] < priority[recommendations[j].Priority]
+	})
+	return recommendations
+}
+
+// sampleLimits returns the sample_limit of every scrape job from the loaded
+// configuration, falling back to the global limit
+func (ss *ScrapeCoverageService) sampleLimits(ctx context.Context, prometheus types.ServiceConfig) (map[string]int, error) {
+	var status struct {
+		YAML string `json:"yaml"`
+	}
+	if err := prometheusAPI(ctx, ss.client, prometheus, "/api/v1/status/config", &status); err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Global struct {
+			SampleLimit int `yaml:"sample_limit"`
+		} `yaml:"global"`
+		ScrapeConfigs []struct {
+			JobName     string `yaml:"job_name"`
+			SampleLimit int    `yaml:"sample_limit"`
+		} `yaml:"scrape_configs"`
+	}
+	if err := yaml.Unmarshal([]byte(status.YAML), &config); err != nil {
+		return nil, fmt.Errorf("parse configuration: %w", err)
+	}
+
+	limits := map[string]int{}
+	for _, scrape := range config.ScrapeConfigs {
+		limit := scrape.SampleLimit
+		if limit == 0 {
+			limit = config.Global.SampleLimit
+		}
+		if limit > 0 {
+			limits[scrape.JobName] = limit
+		}
+	}
+	return limits, nil
+}
+
+// targetID identifies a target by job and instance; both may contain any
+// character, so they are kept apart rather than joined into one string
+type targetID struct {
+	job, instance string
+}
+
+// targetKey identifies a target by job and instance
+func targetKey(job, instance string) targetID {
+	return targetID{job: job, instance: instance}
+}
+
+// parsePromDuration parses a Prometheus duration such as "15s" or "1m", or returns 0
+func parsePromDuration(value string) time.Duration {
+	duration, err := model.ParseDuration(value)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(duration)
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+func TestScrapeCoverageService_Analyze(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Add(-10 * time.Second).Format(time.RFC3339)
+	old := now.Add(-10 * time.Minute).Format(time.RFC3339)
+
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/targets":
+			fmt.Fprintf(w, `{"status": "success", "data": {"activeTargets": [
+				{"labels": {"job": "node", "instance": "node-1:9100"}, "scrapePool": "node", "scrapeUrl": "http://node-1:9100/metrics",
+				 "health": "up", "lastScrape": %[1]q, "lastScrapeDuration": 0.05, "scrapeInterval": "15s", "scrapeTimeout": "10s"},
+				{"labels": {"job": "node", "instance": "node-2:9100"}, "scrapePool": "node", "scrapeUrl": "http://node-2:9100/metrics",
+				 "health": "up", "lastScrape": %[1]q, "lastScrapeDuration": 9.2, "scrapeInterval": "15s", "scrapeTimeout": "10s"},
+				{"labels": {"job": "api", "instance": "api"}, "scrapePool": "api", "scrapeUrl": "http://10.0.0.1:8080/metrics",
+				 "health": "down", "lastError": "connection refused", "lastScrape": %[1]q, "lastScrapeDuration": 0.001, "scrapeInterval": "30s", "scrapeTimeout": "10s"},
+				{"labels": {"job": "api", "instance": "api"}, "scrapePool": "api", "scrapeUrl": "http://10.0.0.2:8080/metrics",
+				 "health": "down", "lastError": "context deadline exceeded", "lastScrape": %[2]q, "lastScrapeDuration": 10, "scrapeInterval": "30s", "scrapeTimeout": "10s"}
+			]}}`, recent, old)
+		case "/api/v1/targets/metadata":
+			_, _ = w.Write([]byte(`{"status": "success", "data": [
+				{"target": {"job": "node", "instance": "node-1:9100"}, "metric": "node_cpu_seconds_total", "type": "counter"},
+				{"target": {"job": "node", "instance": "node-1:9100"}, "metric": "node_load1", "type": "gauge"},
+				{"target": {"job": "node", "instance": "node-2:9100"}, "metric": "node_load1", "type": "gauge"}
+			]}`))
+		case "/api/v1/status/config":
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"yaml": "global:\n  scrape_interval: 15s\nscrape_configs:\n- job_name: node\n  sample_limit: 1000\n- job_name: api\n"}}`))
+		case "/api/v1/query":
+			assert.Equal(t, "scrape_samples_post_metric_relabeling", r.URL.Query().Get("query"))
+			_, _ = w.Write([]byte(`{"status": "success", "data": {"resultType": "vector", "result": [
+				{"metric": {"job": "node", "instance": "node-1:9100"}, "value": [1, "950"]},
+				{"metric": {"job": "node", "instance": "node-2:9100"}, "value": [1, "120"]}
+			]}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer prometheus.Close()
+
+	ss := NewScrapeCoverageService()
+	ss.now = func() time.Time { return now }
+	report, err := ss.Analyze(context.Background(), types.ServiceConfig{URL: prometheus.URL})
+	require.NoError(t, err)
+
+	assert.Equal(t, "degraded", report.Status)
+	assert.Empty(t, report.Problems)
+	assert.Equal(t, 4, report.TotalTargets)
+	assert.Equal(t, 2, report.UpTargets)
+	assert.Equal(t, 2, report.DownTargets)
+	assert.Equal(t, []models.ScrapeJob{{Job: "api", Targets: 2, Up: 0}, {Job: "node", Targets: 2, Up: 2}}, report.Jobs)
+
+	node1 := report.Targets[2]
+	assert.Equal(t, "node-1:9100", node1.Instance)
+	assert.Equal(t, 950, node1.Samples)
+	assert.Equal(t, 1000, node1.SampleLimit)
+	assert.Equal(t, 2, node1.MetricFamilies)
+	assert.Equal(t, 15*time.Second, node1.ScrapeInterval)
+
+	issues := map[string][]string{}
+	for _, issue := range report.Issues {
+		issues[issue.Type] = append(issues[issue.Type], issue.Job+" "+issue.Instance)
+	}
+	assert.Equal(t, map[string][]string{
+		"target_down":        {"api api", "api api"},
+		"slow_scrape":        {"api api", "node node-2:9100"},
+		"sample_limit":       {"node node-1:9100"},
+		"stale_target":       {"api api"},
+		"job_down":           {"api "},
+		"duplicate_instance": {"api api"},
+	}, issues)
+
+	require.Len(t, report.Recommendations, 7)
+	for _, rec := range report.Recommendations[:3] {
+		assert.Equal(t, "high", rec.Priority)
+	}
+	byID := map[string]models.Recommendation{}
+	for _, rec := range report.Recommendations {
+		byID[rec.ID] = rec
+		assert.Equal(t, now, rec.CreatedAt)
+	}
+	down := byID["scrape-target-down-api"]
+	assert.Equal(t, "configuration", down.Type)
+	assert.Contains(t, down.Description, "connection refused")
+	assert.Contains(t, down.Description, "context deadline exceeded")
+	assert.Equal(t, "scaling", byID["scrape-sample-limit-node"].Type)
+	assert.True(t, strings.HasPrefix(byID["scrape-duplicate-instance-api"].Title, "Make instance labels unique"))
+}
+
+func TestScrapeCoverageService_OptionalEndpoints(t *testing.T) {
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/targets" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status": "error", "error": "not supported"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "success", "data": {"activeTargets": [
+			{"labels": {"job": "self", "instance": "localhost:9090"}, "health": "up", "scrapeInterval": "15s", "scrapeTimeout": "10s", "lastScrape": "` + time.Now().Format(time.RFC3339) + `"}
+		]}}`))
+	}))
+	defer prometheus.Close()
+
+	report, err := NewScrapeCoverageService().Analyze(context.Background(), types.ServiceConfig{URL: prometheus.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "healthy", report.Status)
+	assert.Len(t, report.Problems, 2) // metadata and config
+	assert.Empty(t, report.Recommendations)
+
+	_, err = NewScrapeCoverageService().Analyze(context.Background(), types.ServiceConfig{URL: "http://127.0.0.1:1"})
+	assert.Error(t, err)
+}
+
+func TestScrapeCoverageService_NewAndSlashedTargets(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	recent := now.Format(time.RFC3339)
+	prometheus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/targets" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"status": "success", "data": {"activeTargets": [
+			{"labels": {"job": "kube/pods", "instance": "a"}, "scrapeUrl": "http://10.0.0.1/metrics", "health": "up", "lastScrape": %[1]q, "scrapeInterval": "15s"},
+			{"labels": {"job": "kube/pods", "instance": "a"}, "scrapeUrl": "http://10.0.0.2/metrics", "health": "up", "lastScrape": %[1]q, "scrapeInterval": "15s"},
+			{"labels": {"job": "new", "instance": "b"}, "scrapeUrl": "http://10.0.0.3/metrics", "health": "unknown", "scrapeInterval": "15s"}
+		]}}`, recent)
+	}))
+	defer prometheus.Close()
+
+	ss := NewScrapeCoverageService()
+	ss.now = func() time.Time { return now }
+	report, err := ss.Analyze(context.Background(), types.ServiceConfig{URL: prometheus.URL})
+	require.NoError(t, err)
+
+	var duplicate, stale []models.ScrapeIssue
+	for _, issue := range report.Issues {
+		switch issue.Type {
+		case "duplicate_instance":
+			duplicate = append(duplicate, issue)
+		case "stale_target":
+			stale = append(stale, issue)
+		}
+	}
+	require.Len(t, duplicate, 1)
+	assert.Equal(t, "kube/pods", duplicate[0].Job)
+	assert.Equal(t, "a", duplicate[0].Instance)
+	assert.Empty(t, stale, "a target younger than its interval is not stale yet")
+
+	// still unscraped one interval later
+	now = now.Add(15 * time.Second)
+	report, err = ss.Analyze(context.Background(), types.ServiceConfig{URL: prometheus.URL})
+	require.NoError(t, err)
+	var never []string
+	for _, issue := range report.Issues {
+		if issue.Type == "stale_target" {
+			never = append(never, issue.Message)
+		}
+	}
+	assert.Equal(t, []string{"b has never been scraped"}, never)
+}
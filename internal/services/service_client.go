@@ -0,0 +1,118 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// serviceTransports holds one transport per TLS configuration so connections
+// to a service are reused across requests
+var serviceTransports sync.Map // types.TLSConfig -> *cachedTransport
+
+// cachedTransport is a transport with the state of the files it was built
+// from, so rotated certificates and CAs are picked up
+type cachedTransport struct {
+	files     [3]fileStamp
+	transport *http.Transport
+}
+
+// fileStamp identifies a version of a file by its modification time and size
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func tlsFileStamps(config types.TLSConfig) [3]fileStamp {
+	var stamps [3]fileStamp
+	for i, path := range []string{config.CAFile, config.CertFile, config.KeyFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// DoServiceRequest sends req to a configured service: its credentials, tenant
+// and headers are applied, and it is sent over the service's TLS settings
+// using client's timeout.
+func DoServiceRequest(client *http.Client, service types.ServiceConfig, req *http.Request) (*http.Response, error) {
+	service.Apply(req)
+	if service.TLS == nil {
+		return client.Do(req)
+	}
+
+	transport, err := serviceTransport(*service.TLS)
+	if err != nil {
+		return nil, err
+	}
+	tlsClient := *client
+	tlsClient.Transport = transport
+	return tlsClient.Do(req)
+}
+
+// serviceTransport returns the cached transport for a TLS configuration,
+// building a new one when its CA, certificate or key file changed
+func serviceTransport(config types.TLSConfig) (*http.Transport, error) {
+	files := tlsFileStamps(config)
+	cached, ok := serviceTransports.Load(config)
+	if ok && cached.(*cachedTransport).files == files {
+		return cached.(*cachedTransport).transport, nil
+	}
+
+	tlsConfig, err := NewServiceTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	serviceTransports.Store(config, &cachedTransport{files: files, transport: transport})
+	if ok {
+		cached.(*cachedTransport).transport.CloseIdleConnections()
+	}
+	return transport, nil
+}
+
+// NewServiceTLSConfig builds the client TLS configuration for a service
+func NewServiceTLSConfig(config types.TLSConfig) (*tls.Config, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no PEM certificates", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
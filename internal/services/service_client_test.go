@@ -0,0 +1,188 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Argus Test CA"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, dir: t.TempDir()}
+}
+
+// issue returns a certificate for the given names, with its PEM files written to disk
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage, dnsNames ...string) (tls.Certificate, string, string) {
+	return ca.issueUntil(t, name, usage, time.Now().Add(24*time.Hour), dnsNames...)
+}
+
+// issueUntil is issue with an explicit expiry
+func (ca *testCA) issueUntil(t *testing.T, name string, usage x509.ExtKeyUsage, notAfter time.Time, dnsNames ...string) (tls.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(ca.dir, name+".pem")
+	keyFile := filepath.Join(ca.dir, name+"-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	cert.Leaf, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, certFile, keyFile
+}
+
+// caFile writes the CA certificate and returns its path
+func (ca *testCA) caFile(t *testing.T) string {
+	path := filepath.Join(ca.dir, "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), 0o600))
+	return path
+}
+
+func TestDoServiceRequest_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, _, _ := ca.issue(t, "loki", x509.ExtKeyUsageServerAuth, "loki.internal")
+	_, clientCertFile, clientKeyFile := ca.issue(t, "argus", x509.ExtKeyUsageClientAuth)
+	caFile := ca.caFile(t)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "team-a", r.Header.Get(types.TenantHeader))
+		_, _ = w.Write([]byte("ready"))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are expected
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	get := func(tlsConfig *types.TLSConfig) (*http.Response, error) {
+		req, err := http.NewRequest("GET", server.URL+"/ready", nil)
+		require.NoError(t, err)
+		return DoServiceRequest(client, types.ServiceConfig{URL: server.URL, TenantID: "team-a", TLS: tlsConfig}, req)
+	}
+
+	t.Run("mTLS with the internal CA", func(t *testing.T) {
+		resp, err := get(&types.TLSConfig{CAFile: caFile, CertFile: clientCertFile, KeyFile: clientKeyFile})
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotNil(t, resp.TLS)
+		assert.Equal(t, "loki", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	t.Run("server name override", func(t *testing.T) {
+		resp, err := get(&types.TLSConfig{CAFile: caFile, CertFile: clientCertFile, KeyFile: clientKeyFile, ServerName: "loki.internal"})
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		_, err = get(&types.TLSConfig{CAFile: caFile, CertFile: clientCertFile, KeyFile: clientKeyFile, ServerName: "tempo.internal"})
+		var hostnameErr x509.HostnameError
+		assert.ErrorAs(t, err, &hostnameErr)
+	})
+
+	t.Run("system roots do not trust the internal CA", func(t *testing.T) {
+		_, err := get(nil)
+		var verifyErr *tls.CertificateVerificationError
+		assert.ErrorAs(t, err, &verifyErr)
+	})
+
+	t.Run("insecure_skip_verify still needs the client certificate", func(t *testing.T) {
+		_, err := get(&types.TLSConfig{InsecureSkipVerify: true})
+		assert.Error(t, err)
+
+		resp, err := get(&types.TLSConfig{InsecureSkipVerify: true, CertFile: clientCertFile, KeyFile: clientKeyFile})
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		_, err := get(&types.TLSConfig{CertFile: clientCertFile})
+		assert.ErrorContains(t, err, "cert_file and key_file must be set together")
+
+		_, err = get(&types.TLSConfig{CAFile: filepath.Join(ca.dir, "missing.pem")})
+		assert.ErrorContains(t, err, "read CA file")
+	})
+}
+
+func TestServiceTransport_ReloadsRotatedFiles(t *testing.T) {
+	ca := newTestCA(t)
+	_, certFile, keyFile := ca.issue(t, "rotating", x509.ExtKeyUsageClientAuth)
+	config := types.TLSConfig{CAFile: ca.caFile(t), CertFile: certFile, KeyFile: keyFile}
+
+	first, err := serviceTransport(config)
+	require.NoError(t, err)
+	again, err := serviceTransport(config)
+	require.NoError(t, err)
+	assert.Same(t, first, again, "unchanged files reuse the transport")
+
+	// Rotate the client certificate in place
+	rotated, _, _ := ca.issue(t, "rotating", x509.ExtKeyUsageClientAuth)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	second, err := serviceTransport(config)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+	assert.Equal(t, rotated.Certificate[0], second.TLSClientConfig.Certificates[0].Certificate[0])
+}
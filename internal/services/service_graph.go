@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+const (
+	serviceGraphMetric = "traces_service_graph_request_total"
+	spanMetricsMetric  = "traces_spanmetrics_calls_total"
+	spanKindServer     = "SPAN_KIND_SERVER"
+)
+
+// CrossServiceScenarios are the request flows Argus simulates across services
+var CrossServiceScenarios = []models.TraceScenario{
+	{
+		Name:     "e-commerce-checkout",
+		Services: []string{"frontend", "user-api", "product-api", "payment-api", "database"},
+		Flow:     "User checkout process",
+	},
+	{
+		Name:     "content-delivery",
+		Services: []string{"cdn", "origin-server", "api-gateway", "content-service", "database"},
+		Flow:     "Content delivery pipeline",
+	},
+	{
+		Name:     "authentication-flow",
+		Services: []string{"auth-service", "user-service", "session-service", "database"},
+		Flow:     "User authentication process",
+	},
+}
+
+// ServiceGraphService verifies the series Tempo's metrics-generator writes to Prometheus
+type ServiceGraphService struct {
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewServiceGraphService creates a new service-graph verification service
+func NewServiceGraphService() *ServiceGraphService {
+	return &ServiceGraphService{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: 5 * time.Second,
+	}
+}
+
+// ExpectedEdges returns the distinct client -> server edges of the scenarios
+func ExpectedEdges(scenarios []models.TraceScenario) []models.ServiceGraphEdge {
+	edges := []models.ServiceGraphEdge{}
+	seen := make(map[string]bool)
+	for _, scenario := range scenarios {
+		for i := 0; i+1 < len(scenario.Services); i++ {
+			client, server := scenario.Services[i], scenario.Services[i+1]
+			if seen[client+"->"+server] {
+				continue
+			}
+			seen[client+"->"+server] = true
+			edges = append(edges, models.ServiceGraphEdge{Client: client, Server: server})
+		}
+	}
+	return edges
+}
+
+// ExpectedSpanMetrics returns the server span series every service should produce
+func ExpectedSpanMetrics(scenarios []models.TraceScenario) []models.SpanMetricSeries {
+	series := []models.SpanMetricSeries{}
+	for _, scenario := range scenarios {
+		for _, service := range scenario.Services {
+			series = append(series, models.SpanMetricSeries{
+				Service:  service,
+				SpanName: TopologySpanName(scenario, service),
+				SpanKind: spanKindServer,
+			})
+		}
+	}
+	return series
+}
+
+// Verify records the current counter values, calls emit to send the scenario
+// spans, then polls Prometheus until every expected edge and span-metrics
+// series has increased or timeout elapses. Series that never increase are
+// matched loosely to report label mismatches.
+func (sg *ServiceGraphService) Verify(ctx context.Context, prometheus types.ServiceConfig, scenarios []models.TraceScenario, runID string, iterations int, emit func(context.Context) (int, error), timeout time.Duration) (*models.ServiceGraphReport, error) {
+	report := &models.ServiceGraphReport{
+		PrometheusURL: prometheus.URL,
+		RunID:         runID,
+		Scenarios:     []string{},
+		Iterations:    iterations,
+		Edges:         ExpectedEdges(scenarios),
+		SpanMetrics:   ExpectedSpanMetrics(scenarios),
+		Problems:      []string{},
+		Timestamp:     time.Now(),
+	}
+	for _, scenario := range scenarios {
+		report.Scenarios = append(report.Scenarios, scenario.Name)
+	}
+
+	// Baseline first, so leftovers from earlier runs do not count as found
+	for i := range report.Edges {
+		value, err := sg.sum(ctx, prometheus, edgeSelector(report.Edges[i]))
+		if err != nil {
+			return nil, err
+		}
+		report.Edges[i].Before = value
+	}
+	for i := range report.SpanMetrics {
+		value, err := sg.sum(ctx, prometheus, spanMetricSelector(report.SpanMetrics[i]))
+		if err != nil {
+			return nil, err
+		}
+		report.SpanMetrics[i].Before = value
+	}
+
+	spans, err := emit(ctx)
+	report.SpansEmitted = spans
+	if err != nil {
+		return nil, fmt.Errorf("emit topology: %w", err)
+	}
+
+	start := time.Now()
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(sg.pollInterval)
+	defer ticker.Stop()
+	for pending := sg.refresh(pollCtx, prometheus, report); pending > 0 && pollCtx.Err() == nil; pending = sg.refresh(pollCtx, prometheus, report) {
+		select {
+		case <-ticker.C:
+		case <-pollCtx.Done():
+		}
+	}
+	report.Elapsed = time.Since(start)
+
+	for i := range report.Edges {
+		edge := &report.Edges[i]
+		if edge.Found {
+			continue
+		}
+		report.MissingEdges++
+		exact := func(labels map[string]string) bool {
+			return labels["client"] == edge.Client && labels["server"] == edge.Server
+		}
+		var stale bool
+		edge.Mismatches, stale = sg.nearMisses(ctx, prometheus, exact,
+			fmt.Sprintf(`%s{server=%q}`, serviceGraphMetric, edge.Server),
+			fmt.Sprintf(`%s{client=%q}`, serviceGraphMetric, edge.Client))
+		report.Problems = append(report.Problems, describeMissing(fmt.Sprintf("Service graph edge %s -> %s", edge.Client, edge.Server), edge.Mismatches, stale))
+	}
+	for i := range report.SpanMetrics {
+		series := &report.SpanMetrics[i]
+		if series.Found {
+			continue
+		}
+		report.MissingSeries++
+		exact := func(labels map[string]string) bool {
+			return labels["service"] == series.Service && labels["span_kind"] == series.SpanKind
+		}
+		var stale bool
+		series.Mismatches, stale = sg.nearMisses(ctx, prometheus, exact,
+			fmt.Sprintf(`%s{span_name=%q}`, spanMetricsMetric, series.SpanName))
+		report.Problems = append(report.Problems, describeMissing(fmt.Sprintf("Span metrics for %s %q", series.Service, series.SpanName), series.Mismatches, stale))
+	}
+
+	switch {
+	case report.MissingEdges == len(report.Edges) && report.MissingSeries == len(report.SpanMetrics):
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report, nil
+}
+
+// refresh re-reads every series not found yet and returns how many are still pending
+func (sg *ServiceGraphService) refresh(ctx context.Context, prometheus types.ServiceConfig, report *models.ServiceGraphReport) int {
+	pending := 0
+	for i := range report.Edges {
+		edge := &report.Edges[i]
+		if edge.Found {
+			continue
+		}
+		if value, err := sg.sum(ctx, prometheus, edgeSelector(*edge)); err == nil {
+			edge.After = value
+			edge.Found = value > edge.Before
+		}
+		if !edge.Found {
+			pending++
+		}
+	}
+	for i := range report.SpanMetrics {
+		series := &report.SpanMetrics[i]
+		if series.Found {
+			continue
+		}
+		if value, err := sg.sum(ctx, prometheus, spanMetricSelector(*series)); err == nil {
+			series.After = value
+			series.Found = value > series.Before
+		}
+		if !series.Found {
+			pending++
+		}
+	}
+	return pending
+}
+
+// sum returns the summed value of the selector, zero when no series match
+func (sg *ServiceGraphService) sum(ctx context.Context, prometheus types.ServiceConfig, selector string) (float64, error) {
+	samples, err := prometheusQuery(ctx, sg.client, prometheus, "sum("+selector+")")
+	if err != nil {
+		return 0, err
+	}
+	if len(samples) == 0 {
+		return 0, nil
+	}
+	return samples[0].Value, nil
+}
+
+// nearMisses returns the label sets of series matched by looser selectors.
+// Series that match exactly are not mismatches; they only mean the expected
+// series exists but received no new samples, which is reported as stale.
+func (sg *ServiceGraphService) nearMisses(ctx context.Context, prometheus types.ServiceConfig, exact func(map[string]string) bool, selectors ...string) ([]string, bool) {
+	seen := make(map[string]bool)
+	var mismatches []string
+	stale := false
+	for _, selector := range selectors {
+		samples, err := prometheusQuery(ctx, sg.client, prometheus, selector)
+		if err != nil {
+			continue
+		}
+		for _, sample := range samples {
+			if exact(sample.Metric) {
+				stale = true
+				continue
+			}
+			labels := formatLabels(sample.Metric)
+			if !seen[labels] {
+				seen[labels] = true
+				mismatches = append(mismatches, labels)
+			}
+		}
+	}
+	return mismatches, stale
+}
+
+func edgeSelector(edge models.ServiceGraphEdge) string {
+	return fmt.Sprintf(`%s{client=%q, server=%q}`, serviceGraphMetric, edge.Client, edge.Server)
+}
+
+func spanMetricSelector(series models.SpanMetricSeries) string {
+	return fmt.Sprintf(`%s{service=%q, span_name=%q, span_kind=%q}`, spanMetricsMetric, series.Service, series.SpanName, series.SpanKind)
+}
+
+func describeMissing(what string, mismatches []string, stale bool) string {
+	switch {
+	case stale:
+		return what + " exists but received no new samples"
+	case len(mismatches) > 0:
+		return fmt.Sprintf("%s missing, found series with different labels: %v", what, mismatches)
+	default:
+		return what + " missing"
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeMetricsPrometheus answers instant queries from a fixed set of series
+type fakeMetricsPrometheus struct {
+	mu     sync.Mutex
+	series []promSample
+}
+
+func (fp *fakeMetricsPrometheus) set(series []promSample) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.series = series
+}
+
+func (fp *fakeMetricsPrometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	query := r.URL.Query().Get("query")
+	aggregate := strings.HasPrefix(query, "sum(")
+	selector := strings.TrimSuffix(strings.TrimPrefix(query, "sum("), ")")
+	name, matchers := parseTestSelector(selector)
+
+	result := []map[string]interface{}{}
+	total, matched := 0.0, false
+	for _, sample := range fp.series {
+		if sample.Metric["__name__"] != name || !labelsMatch(sample.Metric, matchers) {
+			continue
+		}
+		matched = true
+		total += sample.Value
+		if !aggregate {
+			result = append(result, map[string]interface{}{"metric": sample.Metric, "value": []interface{}{1, "1"}})
+		}
+	}
+	if aggregate && matched {
+		result = append(result, map[string]interface{}{"metric": map[string]string{}, "value": []interface{}{1, formatFloat(total)}})
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "vector", "result": result},
+	})
+}
+
+// parseTestSelector handles the name{a="b", c="d"} selectors the services build
+func parseTestSelector(selector string) (string, map[string]string) {
+	name, rest, _ := strings.Cut(selector, "{")
+	matchers := make(map[string]string)
+	for _, pair := range strings.Split(strings.TrimSuffix(rest, "}"), ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok {
+			matchers[key] = strings.Trim(value, `"`)
+		}
+	}
+	return name, matchers
+}
+
+func labelsMatch(labels, matchers map[string]string) bool {
+	for key, value := range matchers {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func formatFloat(v float64) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func edgeSample(client, server string, value float64) promSample {
+	return promSample{Metric: map[string]string{"__name__": serviceGraphMetric, "client": client, "server": server}, Value: value}
+}
+
+func spanSample(service, spanName string, value float64) promSample {
+	return promSample{Metric: map[string]string{"__name__": spanMetricsMetric, "service": service, "span_name": spanName, "span_kind": spanKindServer}, Value: value}
+}
+
+func TestExpectedEdges(t *testing.T) {
+	edges := ExpectedEdges(CrossServiceScenarios)
+
+	assert.Len(t, edges, 11)
+	assert.Equal(t, models.ServiceGraphEdge{Client: "frontend", Server: "user-api"}, edges[0])
+	assert.Len(t, ExpectedSpanMetrics(CrossServiceScenarios), 14)
+}
+
+func TestServiceGraphService_Verify(t *testing.T) {
+	scenario := models.TraceScenario{Name: "checkout", Services: []string{"frontend", "api", "database"}}
+	prometheus := &fakeMetricsPrometheus{}
+	// A stale series from an earlier run must not count as found
+	prometheus.set([]promSample{edgeSample("frontend", "api", 10)})
+	server := httptest.NewServer(prometheus)
+	defer server.Close()
+
+	sg := NewServiceGraphService()
+	sg.pollInterval = 5 * time.Millisecond
+
+	emitted := false
+	emit := func(ctx context.Context) (int, error) {
+		emitted = true
+		prometheus.set([]promSample{
+			edgeSample("frontend", "api", 15),
+			// The metrics-generator could not pair this edge's client span
+			edgeSample("unknown", "database", 5),
+			spanSample("frontend", "checkout:frontend", 5),
+			spanSample("api", "checkout:api", 5),
+			spanSample("database", "checkout:database", 5),
+		})
+		return 5, nil
+	}
+
+	report, err := sg.Verify(context.Background(), types.ServiceConfig{URL: server.URL}, []models.TraceScenario{scenario}, "run-1", 1, emit, 100*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, emitted)
+
+	assert.Equal(t, "degraded", report.Status)
+	assert.Equal(t, 5, report.SpansEmitted)
+
+	require.Len(t, report.Edges, 2)
+	assert.True(t, report.Edges[0].Found)
+	assert.Equal(t, 10.0, report.Edges[0].Before)
+	assert.Equal(t, 15.0, report.Edges[0].After)
+	assert.False(t, report.Edges[1].Found)
+	assert.Equal(t, []string{`{client="unknown", server="database"}`}, report.Edges[1].Mismatches)
+
+	assert.Equal(t, 1, report.MissingEdges)
+	assert.Zero(t, report.MissingSeries)
+	require.Len(t, report.Problems, 1)
+	assert.Contains(t, report.Problems[0], "api -> database missing, found series with different labels")
+}
+
+func TestServiceGraphService_VerifyStale(t *testing.T) {
+	scenario := models.TraceScenario{Name: "checkout", Services: []string{"frontend", "api"}}
+	prometheus := &fakeMetricsPrometheus{}
+	prometheus.set([]promSample{edgeSample("frontend", "api", 10)})
+	server := httptest.NewServer(prometheus)
+	defer server.Close()
+
+	sg := NewServiceGraphService()
+	sg.pollInterval = 5 * time.Millisecond
+
+	emit := func(ctx context.Context) (int, error) { return 3, nil }
+	report, err := sg.Verify(context.Background(), types.ServiceConfig{URL: server.URL}, []models.TraceScenario{scenario}, "run-1", 1, emit, 30*time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Equal(t, "failed", report.Status)
+	assert.Contains(t, report.Problems[0], "exists but received no new samples")
+	assert.Empty(t, report.Edges[0].Mismatches)
+}
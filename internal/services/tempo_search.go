@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// TempoSearchService checks that Tempo's search backend finds traces Argus emitted
+type TempoSearchService struct {
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewTempoSearchService creates a new Tempo search validation service
+func NewTempoSearchService() *TempoSearchService {
+	return &TempoSearchService{
+		client:       &http.Client{Timeout: 10 * time.Second},
+		pollInterval: 2 * time.Second,
+	}
+}
+
+type tempoSearchResponse struct {
+	Traces []struct {
+		TraceID         string `json:"traceID"`
+		RootServiceName string `json:"rootServiceName"`
+		RootTraceName   string `json:"rootTraceName"`
+	} `json:"traces"`
+}
+
+// Validate searches Tempo for the probe trace identified by service name and
+// run ID, once with TraceQL and once with the tag-based search, retrying each
+// until the trace shows up or timeout elapses. Tag discovery is checked last,
+// once the probe trace has been ingested.
+func (ts *TempoSearchService) Validate(ctx context.Context, tempo types.ServiceConfig, service, runID, traceID string, emitted time.Time, timeout time.Duration) *models.TempoSearchReport {
+	report := &models.TempoSearchReport{
+		TempoURL:  tempo.URL,
+		Service:   service,
+		RunID:     runID,
+		TraceID:   traceID,
+		Searches:  []models.TempoSearchCheck{},
+		Problems:  []string{},
+		Timestamp: time.Now(),
+	}
+
+	params := url.Values{}
+	// Tempo search windows are in whole seconds; pad both sides for clock skew
+	params.Set("start", strconv.FormatInt(emitted.Add(-time.Minute).Unix(), 10))
+	params.Set("end", strconv.FormatInt(emitted.Add(5*time.Minute).Unix(), 10))
+
+	traceQL := fmt.Sprintf(`{ resource.service.name = %q && span.argus.run_id = %q }`, service, runID)
+	traceQLParams := cloneValues(params)
+	traceQLParams.Set("q", traceQL)
+
+	tagQuery := fmt.Sprintf("service.name=%s argus.run_id=%s", service, runID)
+	tagParams := cloneValues(params)
+	tagParams.Set("tags", tagQuery)
+
+	searchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for _, search := range []struct {
+		name   string
+		query  string
+		params url.Values
+	}{
+		{name: "traceql", query: traceQL, params: traceQLParams},
+		{name: "tags", query: tagQuery, params: tagParams},
+	} {
+		check := ts.searchUntilFound(searchCtx, tempo, search.params, traceID)
+		check.Name = search.name
+		check.Query = search.query
+		if !check.Found {
+			reason := check.Error
+			if reason == "" {
+				reason = fmt.Sprintf("not found after %d attempts", check.Attempts)
+			}
+			report.Problems = append(report.Problems, fmt.Sprintf("%s search did not find probe trace %s: %s", search.name, traceID, reason))
+		}
+		report.Searches = append(report.Searches, check)
+	}
+
+	report.Tags = ts.checkTags(ctx, tempo, []string{"service.name", "argus.run_id"})
+	if report.Tags.Error != "" {
+		report.Problems = append(report.Problems, "Tag discovery failed: "+report.Tags.Error)
+	} else if len(report.Tags.Missing) > 0 {
+		report.Problems = append(report.Problems, fmt.Sprintf("Tag discovery is missing %v", report.Tags.Missing))
+	}
+
+	found := 0
+	for _, check := range report.Searches {
+		if check.Found {
+			found++
+		}
+	}
+	switch {
+	case found == 0:
+		report.Status = "failed"
+	case len(report.Problems) > 0:
+		report.Status = "degraded"
+	default:
+		report.Status = "healthy"
+	}
+	return report
+}
+
+// searchUntilFound repeats a search until it returns the wanted trace or ctx ends
+func (ts *TempoSearchService) searchUntilFound(ctx context.Context, tempo types.ServiceConfig, params url.Values, traceID string) models.TempoSearchCheck {
+	check := models.TempoSearchCheck{}
+	start := time.Now()
+
+	for {
+		check.Attempts++
+		requestStart := time.Now()
+		var result tempoSearchResponse
+		status, err := tempoJSON(ctx, ts.client, tempo, "/api/search?"+params.Encode(), &result)
+		check.Duration = time.Since(requestStart)
+
+		switch {
+		case err != nil && ctx.Err() == nil:
+			check.Error = err.Error()
+		case err == nil && status != http.StatusOK:
+			check.Error = fmt.Sprintf("HTTP %d", status)
+		case err == nil:
+			check.Error = ""
+			check.Traces = len(result.Traces)
+			for _, trace := range result.Traces {
+				if sameTraceID(trace.TraceID, traceID) {
+					check.Found = true
+					check.Elapsed = time.Since(start)
+					return check
+				}
+			}
+		}
+
+		// A 4xx means the query itself was rejected, retrying will not help
+		if status >= 400 && status < 500 {
+			check.Elapsed = time.Since(start)
+			return check
+		}
+
+		select {
+		case <-time.After(ts.pollInterval):
+		case <-ctx.Done():
+			check.Elapsed = time.Since(start)
+			return check
+		}
+	}
+}
+
+// checkTags verifies that tag discovery lists the expected tag names
+func (ts *TempoSearchService) checkTags(ctx context.Context, tempo types.ServiceConfig, expected []string) models.TempoTagCheck {
+	check := models.TempoTagCheck{Expected: expected}
+
+	start := time.Now()
+	var result struct {
+		TagNames []string `json:"tagNames"`
+	}
+	status, err := tempoJSON(ctx, ts.client, tempo, "/api/search/tags", &result)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if status != http.StatusOK {
+		check.Error = fmt.Sprintf("HTTP %d", status)
+		return check
+	}
+
+	check.Total = len(result.TagNames)
+	known := make(map[string]bool, len(result.TagNames))
+	for _, tag := range result.TagNames {
+		known[tag] = true
+	}
+	for _, tag := range expected {
+		if !known[tag] {
+			check.Missing = append(check.Missing, tag)
+		}
+	}
+	sort.Strings(check.Missing)
+	return check
+}
+
+// tempoJSON sends a GET request to Tempo and decodes a 200 response into out
+func tempoJSON(ctx context.Context, client *http.Client, tempo types.ServiceConfig, endpoint string, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(tempo.URL, "/")+endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := DoServiceRequest(client, tempo, req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK && out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("decode %s response: %w", endpoint, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// sameTraceID compares trace IDs ignoring case and the leading zeros Tempo strips
+func sameTraceID(a, b string) bool {
+	return strings.EqualFold(strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0"))
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := make(url.Values, len(values))
+	for key, value := range values {
+		clone[key] = append([]string(nil), value...)
+	}
+	return clone
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// fakeTempo returns the probe trace from /api/search once visibleAfter searches have been made
+type fakeTempo struct {
+	mu           sync.Mutex
+	traceID      string
+	runID        string
+	visibleAfter int
+	searches     []string
+	tags         []string
+}
+
+func (ft *fakeTempo) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/api/search":
+		query := r.URL.Query()
+		ft.searches = append(ft.searches, query.Get("q")+query.Get("tags"))
+		if query.Get("start") == "" || query.Get("end") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		traces := []map[string]string{}
+		matches := strings.Contains(query.Get("q"), ft.runID) || strings.Contains(query.Get("tags"), "argus.run_id="+ft.runID)
+		if matches && len(ft.searches) > ft.visibleAfter {
+			// Tempo drops leading zeros from trace IDs in search results
+			traces = append(traces, map[string]string{"traceID": strings.TrimLeft(ft.traceID, "0"), "rootServiceName": "argus"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"traces": traces})
+	case "/api/search/tags":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"tagNames": ft.tags})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestTempoSearchService_Validate(t *testing.T) {
+	tempo := &fakeTempo{
+		traceID:      "00af7651916cd43dd8448eb211c80319",
+		runID:        "run-1",
+		visibleAfter: 2,
+		tags:         []string{"service.name", "argus.run_id", "http.method"},
+	}
+	server := httptest.NewServer(tempo)
+	defer server.Close()
+
+	ts := NewTempoSearchService()
+	ts.pollInterval = 5 * time.Millisecond
+
+	report := ts.Validate(context.Background(), types.ServiceConfig{URL: server.URL}, "argus", "run-1", tempo.traceID, time.Now(), time.Second)
+
+	assert.Equal(t, "healthy", report.Status, report.Problems)
+	require.Len(t, report.Searches, 2)
+
+	traceQL := report.Searches[0]
+	assert.Equal(t, "traceql", traceQL.Name)
+	assert.Equal(t, `{ resource.service.name = "argus" && span.argus.run_id = "run-1" }`, traceQL.Query)
+	assert.True(t, traceQL.Found)
+	assert.Equal(t, 3, traceQL.Attempts, "search is retried until the trace is ingested")
+
+	tags := report.Searches[1]
+	assert.Equal(t, "service.name=argus argus.run_id=run-1", tags.Query)
+	assert.True(t, tags.Found)
+	assert.Equal(t, 1, tags.Attempts)
+
+	assert.Equal(t, 3, report.Tags.Total)
+	assert.Empty(t, report.Tags.Missing)
+}
+
+func TestTempoSearchService_ValidateNotFound(t *testing.T) {
+	tempo := &fakeTempo{traceID: "abc", runID: "other-run", tags: []string{"service.name"}}
+	server := httptest.NewServer(tempo)
+	defer server.Close()
+
+	ts := NewTempoSearchService()
+	ts.pollInterval = 5 * time.Millisecond
+
+	report := ts.Validate(context.Background(), types.ServiceConfig{URL: server.URL}, "argus", "run-2", "abc", time.Now(), 50*time.Millisecond)
+
+	assert.Equal(t, "failed", report.Status)
+	for _, search := range report.Searches {
+		assert.False(t, search.Found)
+	}
+	assert.Equal(t, []string{"argus.run_id"}, report.Tags.Missing)
+	assert.Len(t, report.Problems, 3)
+}
+
+func TestSameTraceID(t *testing.T) {
+	assert.True(t, sameTraceID("00af76", "af76"))
+	assert.True(t, sameTraceID("AF76", "af76"))
+	assert.False(t, sameTraceID("af76", "af77"))
+}
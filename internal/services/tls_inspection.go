@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// TLSInspectionService handshakes with TLS endpoints and reports on the
+// certificate chains they present
+type TLSInspectionService struct {
+	timeout     time.Duration
+	concurrency int
+	now         func() time.Time
+}
+
+// NewTLSInspectionService creates a new TLS inspection service
+func NewTLSInspectionService() *TLSInspectionService {
+	return &TLSInspectionService{timeout: 10 * time.Second, concurrency: 10, now: time.Now}
+}
+
+// Inspect checks every target and exports the expiry of each presented
+// certificate. Certificates expiring within warningDays are reported as
+// expiring soon.
+func (ts *TLSInspectionService) Inspect(ctx context.Context, targets []types.TLSTarget, warningDays int) *models.CertificateReport {
+	report := &models.CertificateReport{
+		WarningDays: warningDays,
+		Problems:    []string{},
+		Timestamp:   ts.now(),
+	}
+	if len(targets) == 0 {
+		report.Status = "not_configured"
+		return report
+	}
+
+	// Targets are inspected concurrently so slow ones don't add up
+	report.Targets = make([]models.CertificateCheck, len(targets))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, ts.concurrency)
+	for i, target := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, target types.TLSTarget) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			report.Targets[i] = ts.inspectTarget(ctx, target, warningDays)
+		}(i, target)
+	}
+	wg.Wait()
+
+	for _, check := range report.Targets {
+		switch check.Status {
+		case "failed":
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: handshake failed: %s", check.Target, check.Error))
+		case "expired":
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: certificate expired %s", check.Target, check.ExpiresAt.Format(time.RFC3339)))
+		case "invalid":
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: chain does not verify: %s", check.Target, check.ChainError))
+		case "expiring_soon":
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: certificate expires in %d days", check.Target, check.DaysLeft))
+		}
+		if check.OCSP.Status == "revoked" {
+			report.Problems = append(report.Problems, fmt.Sprintf("%s: stapled OCSP response reports the certificate revoked", check.Target))
+		}
+	}
+
+	report.Status = "healthy"
+	for _, check := range report.Targets {
+		switch {
+		case check.Status == "failed" || check.Status == "expired" || check.Status == "invalid" || check.OCSP.Status == "revoked":
+			report.Status = "failed"
+		case check.Status == "expiring_soon" && report.Status == "healthy":
+			report.Status = "degraded"
+		}
+	}
+	return report
+}
+
+// withDefaultPort adds port to an address without one. A bracketed IPv6
+// address such as [::1] loses its brackets first, as JoinHostPort adds them.
+func withDefaultPort(address, port string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		address = address[1 : len(address)-1]
+	}
+	return net.JoinHostPort(address, port)
+}
+
+// inspectTarget handshakes with one target. Verification is done after the
+// handshake so that invalid chains can still be described.
+func (ts *TLSInspectionService) inspectTarget(ctx context.Context, target types.TLSTarget, warningDays int) models.CertificateCheck {
+	start := ts.now()
+	address := withDefaultPort(target.Address, "443")
+	host, _, _ := net.SplitHostPort(address)
+
+	check := models.CertificateCheck{Target: address, ServerName: host, Status: "failed"}
+
+	tlsConfig := &tls.Config{}
+	if target.TLS != nil {
+		var err error
+		if tlsConfig, err = NewServiceTLSConfig(*target.TLS); err != nil {
+			check.Error = err.Error()
+			return check
+		}
+		if target.TLS.ServerName != "" {
+			check.ServerName = target.TLS.ServerName
+		}
+	}
+	roots := tlsConfig.RootCAs
+	tlsConfig.ServerName = check.ServerName
+	tlsConfig.InsecureSkipVerify = true
+
+	ctx, cancel := context.WithTimeout(ctx, ts.timeout)
+	defer cancel()
+	dialer := &tls.Dialer{Config: tlsConfig}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	check.Elapsed = ts.now().Sub(start)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	defer conn.Close()
+	state := conn.(*tls.Conn).ConnectionState()
+
+	check.TLSVersion = tls.VersionName(state.Version)
+	check.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	now := ts.now()
+
+	metrics.TLSCertificateExpiry.DeletePartialMatch(prometheus.Labels{"target": address})
+	for i, cert := range state.PeerCertificates {
+		info := certificateInfo(cert, now)
+		check.Chain = append(check.Chain, info)
+		if check.ExpiresAt.IsZero() || cert.NotAfter.Before(check.ExpiresAt) {
+			check.ExpiresAt = cert.NotAfter
+		}
+		metrics.TLSCertificateExpiry.WithLabelValues(address, cert.Subject.String(), cert.Issuer.String(), strconv.Itoa(i)).
+			Set(float64(cert.NotAfter.Unix()))
+	}
+	if len(state.PeerCertificates) == 0 {
+		check.Error = "server presented no certificates"
+		return check
+	}
+	check.DaysLeft = daysUntil(check.ExpiresAt, now)
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       check.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	check.ChainValid = err == nil
+	if err != nil {
+		check.ChainError = err.Error()
+	}
+
+	if len(state.OCSPResponse) > 0 {
+		check.OCSP = parseOCSPStaple(state.OCSPResponse)
+	}
+
+	switch {
+	case now.After(check.ExpiresAt):
+		check.Status = "expired"
+	case !check.ChainValid:
+		check.Status = "invalid"
+	case check.DaysLeft <= warningDays:
+		check.Status = "expiring_soon"
+	default:
+		check.Status = "valid"
+	}
+	return check
+}
+
+// certificateInfo describes one certificate of a chain
+func certificateInfo(cert *x509.Certificate, now time.Time) models.CertificateInfo {
+	info := models.CertificateInfo{
+		Subject:            cert.Subject.String(),
+		Issuer:             cert.Issuer.String(),
+		SANs:               append([]string{}, cert.DNSNames...),
+		SerialNumber:       cert.SerialNumber.String(),
+		NotBefore:          cert.NotBefore,
+		NotAfter:           cert.NotAfter,
+		DaysLeft:           daysUntil(cert.NotAfter, now),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		IsCA:               cert.IsCA,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		info.SANs = append(info.SANs, uri.String())
+	}
+
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		info.KeyType, info.KeySize = "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		info.KeyType, info.KeySize = "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		info.KeyType, info.KeySize = "Ed25519", 256
+	default:
+		info.KeyType = cert.PublicKeyAlgorithm.String()
+	}
+	return info
+}
+
+// daysUntil returns the whole days left until t, negative once it has passed
+func daysUntil(t, now time.Time) int {
+	return int(t.Sub(now).Hours() / 24)
+}
+
+// OCSP response structures from RFC 6960, enough to read the status of a
+// stapled response
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response ocspResponseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseBytes struct {
+	Type     asn1.ObjectIdentifier
+	Response []byte
+}
+
+type ocspBasicResponse struct {
+	TBS       ocspResponseData
+	Algorithm pkix.AlgorithmIdentifier
+	Signature asn1.BitString
+	Certs     []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponseData struct {
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []ocspSingleResponse
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspSingleResponse struct {
+	CertID     ocspCertID
+	Good       asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked    ocspRevokedInfo  `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type ocspCertID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRevokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// oidOCSPBasic identifies a basic OCSP response
+var oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+
+// parseOCSPStaple reads the certificate status from a stapled OCSP response
+func parseOCSPStaple(der []byte) models.OCSPStaple {
+	staple := models.OCSPStaple{Stapled: true}
+
+	var response ocspResponse
+	if _, err := asn1.Unmarshal(der, &response); err != nil {
+		staple.Error = fmt.Sprintf("parse OCSP response: %v", err)
+		return staple
+	}
+	if response.Status != 0 {
+		staple.Error = fmt.Sprintf("OCSP responder returned status %d", response.Status)
+		return staple
+	}
+	if !response.Response.Type.Equal(oidOCSPBasic) {
+		staple.Error = fmt.Sprintf("unsupported OCSP response type %s", response.Response.Type)
+		return staple
+	}
+
+	var basic ocspBasicResponse
+	if _, err := asn1.Unmarshal(response.Response.Response, &basic); err != nil {
+		staple.Error = fmt.Sprintf("parse basic OCSP response: %v", err)
+		return staple
+	}
+	if len(basic.TBS.Responses) == 0 {
+		staple.Error = "OCSP response contains no certificate status"
+		return staple
+	}
+
+	single := basic.TBS.Responses[0]
+	staple.ThisUpdate = single.ThisUpdate
+	staple.NextUpdate = single.NextUpdate
+	switch {
+	case bool(single.Good):
+		staple.Status = "good"
+	case !single.Revoked.RevocationTime.IsZero():
+		staple.Status = "revoked"
+		staple.RevokedAt = single.Revoked.RevocationTime
+	default:
+		staple.Status = "unknown"
+	}
+	return staple
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nahuelsantos/argus/internal/metrics"
+	"github.com/nahuelsantos/argus/internal/types"
+)
+
+// startTLSListener serves handshakes with cert until the test ends
+func startTLSListener(t *testing.T, cert tls.Certificate) string {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_ = conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// ocspStaple builds a basic OCSP response for serial; a zero revokedAt means good
+func ocspStaple(t *testing.T, serial *big.Int, revokedAt time.Time) []byte {
+	single := ocspSingleResponse{
+		CertID: ocspCertID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}},
+			NameHash:      []byte{1},
+			IssuerKeyHash: []byte{2},
+			SerialNumber:  serial,
+		},
+		ThisUpdate: time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+		NextUpdate: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+	}
+	if revokedAt.IsZero() {
+		single.Good = true
+	} else {
+		single.Revoked = ocspRevokedInfo{RevocationTime: revokedAt.UTC().Truncate(time.Second)}
+	}
+
+	responderKey, err := asn1.Marshal([]byte{3})
+	require.NoError(t, err)
+	basic, err := asn1.Marshal(ocspBasicResponse{
+		TBS: ocspResponseData{
+			ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: responderKey},
+			ProducedAt:  time.Now().UTC().Truncate(time.Second),
+			Responses:   []ocspSingleResponse{single},
+		},
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature: asn1.BitString{Bytes: []byte{0}, BitLength: 8},
+	})
+	require.NoError(t, err)
+	staple, err := asn1.Marshal(ocspResponse{Response: ocspResponseBytes{Type: oidOCSPBasic, Response: basic}})
+	require.NoError(t, err)
+	return staple
+}
+
+func TestTLSInspectionService_Inspect(t *testing.T) {
+	ca := newTestCA(t)
+	caFile := ca.caFile(t)
+	ts := NewTLSInspectionService()
+
+	valid, _, _ := ca.issueUntil(t, "grafana", x509.ExtKeyUsageServerAuth, time.Now().Add(90*24*time.Hour), "grafana.internal")
+	valid.Certificate = append(valid.Certificate, ca.cert.Raw)
+	valid.OCSPStaple = ocspStaple(t, valid.Leaf.SerialNumber, time.Time{})
+	validAddr := startTLSListener(t, valid)
+
+	expiring, _, _ := ca.issueUntil(t, "loki", x509.ExtKeyUsageServerAuth, time.Now().Add(5*24*time.Hour+time.Hour), "loki.internal")
+	expiringAddr := startTLSListener(t, expiring)
+
+	expired, _, _ := ca.issueUntil(t, "tempo", x509.ExtKeyUsageServerAuth, time.Now().Add(-24*time.Hour), "tempo.internal")
+	expiredAddr := startTLSListener(t, expired)
+
+	revoked, _, _ := ca.issueUntil(t, "mimir", x509.ExtKeyUsageServerAuth, time.Now().Add(90*24*time.Hour), "mimir.internal")
+	revoked.OCSPStaple = ocspStaple(t, revoked.Leaf.SerialNumber, time.Now().Add(-time.Hour))
+	revokedAddr := startTLSListener(t, revoked)
+
+	trusted := func(address, serverName string) types.TLSTarget {
+		return types.TLSTarget{Address: address, TLS: &types.TLSConfig{CAFile: caFile, ServerName: serverName}}
+	}
+
+	t.Run("valid chain with stapled OCSP", func(t *testing.T) {
+		report := ts.Inspect(context.Background(), []types.TLSTarget{trusted(validAddr, "grafana.internal")}, 30)
+		assert.Equal(t, "healthy", report.Status)
+		require.Len(t, report.Targets, 1)
+
+		check := report.Targets[0]
+		assert.Equal(t, "valid", check.Status)
+		assert.True(t, check.ChainValid)
+		assert.Equal(t, "grafana.internal", check.ServerName)
+		assert.Equal(t, "TLS 1.3", check.TLSVersion)
+		require.Len(t, check.Chain, 2)
+		assert.Equal(t, "CN=grafana", check.Chain[0].Subject)
+		assert.Equal(t, "CN=Argus Test CA", check.Chain[0].Issuer)
+		assert.Equal(t, []string{"grafana.internal", "127.0.0.1"}, check.Chain[0].SANs)
+		assert.Equal(t, "ECDSA", check.Chain[0].KeyType)
+		assert.Equal(t, 256, check.Chain[0].KeySize)
+		assert.True(t, check.Chain[1].IsCA)
+		assert.Equal(t, 89, check.DaysLeft)
+
+		assert.True(t, check.OCSP.Stapled)
+		assert.Equal(t, "good", check.OCSP.Status)
+		assert.Empty(t, check.OCSP.Error)
+
+		expiry := testutil.ToFloat64(metrics.TLSCertificateExpiry.WithLabelValues(validAddr, "CN=grafana", "CN=Argus Test CA", strconv.Itoa(0)))
+		assert.Equal(t, float64(valid.Leaf.NotAfter.Unix()), expiry)
+	})
+
+	t.Run("expiring, expired and revoked certificates", func(t *testing.T) {
+		report := ts.Inspect(context.Background(), []types.TLSTarget{
+			trusted(expiringAddr, "loki.internal"),
+			trusted(expiredAddr, "tempo.internal"),
+			trusted(revokedAddr, "mimir.internal"),
+		}, 30)
+		assert.Equal(t, "failed", report.Status)
+
+		assert.Equal(t, "expiring_soon", report.Targets[0].Status)
+		assert.Equal(t, 5, report.Targets[0].DaysLeft)
+		assert.Equal(t, "expired", report.Targets[1].Status)
+		assert.Equal(t, -1, report.Targets[1].DaysLeft)
+		assert.Equal(t, "revoked", report.Targets[2].OCSP.Status)
+
+		assert.Contains(t, report.Problems, expiringAddr+": certificate expires in 5 days")
+		assert.Contains(t, report.Problems, revokedAddr+": stapled OCSP response reports the certificate revoked")
+	})
+
+	t.Run("untrusted chain and wrong name are invalid", func(t *testing.T) {
+		report := ts.Inspect(context.Background(), []types.TLSTarget{
+			{Address: validAddr},
+			trusted(validAddr, "other.internal"),
+		}, 30)
+		assert.Equal(t, "invalid", report.Targets[0].Status)
+		assert.Contains(t, report.Targets[0].ChainError, "unknown authority")
+		assert.Equal(t, "invalid", report.Targets[1].Status)
+		assert.Contains(t, report.Targets[1].ChainError, "other.internal")
+		// The chain is still described
+		assert.Len(t, report.Targets[0].Chain, 2)
+	})
+
+	t.Run("handshake failures", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		closed := listener.Addr().String()
+		listener.Close()
+
+		report := ts.Inspect(context.Background(), []types.TLSTarget{{Address: closed}}, 30)
+		assert.Equal(t, "failed", report.Status)
+		assert.Equal(t, "failed", report.Targets[0].Status)
+		assert.NotEmpty(t, report.Targets[0].Error)
+	})
+
+	t.Run("stalled targets are inspected concurrently", func(t *testing.T) {
+		stalled := &TLSInspectionService{timeout: 300 * time.Millisecond, concurrency: 10, now: time.Now}
+		var targets []types.TLSTarget
+		for i := 0; i < 4; i++ {
+			// Accepts connections but never answers the handshake
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { listener.Close() })
+			targets = append(targets, types.TLSTarget{Address: listener.Addr().String()})
+		}
+		targets = append(targets, trusted(validAddr, "grafana.internal"))
+
+		start := time.Now()
+		report := stalled.Inspect(context.Background(), targets, 30)
+		assert.Less(t, time.Since(start), 4*stalled.timeout)
+		require.Len(t, report.Targets, 5)
+		assert.Equal(t, "failed", report.Targets[0].Status)
+		assert.Equal(t, "valid", report.Targets[4].Status, "results keep the target order")
+	})
+
+	t.Run("no targets", func(t *testing.T) {
+		report := ts.Inspect(context.Background(), nil, 30)
+		assert.Equal(t, "not_configured", report.Status)
+		assert.Empty(t, report.Targets)
+	})
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	assert.Equal(t, "example.com:443", withDefaultPort("example.com", "443"))
+	assert.Equal(t, "example.com:8443", withDefaultPort("example.com:8443", "443"))
+	assert.Equal(t, "[::1]:443", withDefaultPort("[::1]", "443"))
+	assert.Equal(t, "[::1]:443", withDefaultPort("::1", "443"))
+	assert.Equal(t, "[::1]:8443", withDefaultPort("[::1]:8443", "443"))
+}
+
+func TestParseOCSPStaple(t *testing.T) {
+	staple := parseOCSPStaple([]byte("not der"))
+	assert.True(t, staple.Stapled)
+	assert.Contains(t, staple.Error, "parse OCSP response")
+
+	tryLater, err := asn1.Marshal(ocspResponse{Status: 3})
+	require.NoError(t, err)
+	assert.Equal(t, "OCSP responder returned status 3", parseOCSPStaple(tryLater).Error)
+}
@@ -3,13 +3,15 @@ package services
 import (
 	"context"
 	"fmt"
+	"math"
 	"math/rand"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
@@ -19,12 +21,19 @@ import (
 	"github.com/nahuelsantos/argus/internal/config"
 	"github.com/nahuelsantos/argus/internal/metrics"
 	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
 )
 
 // TracingService handles all tracing operations
 type TracingService struct {
-	config *config.TracingConfig
-	tracer oteltrace.Tracer
+	config   *config.TracingConfig
+	tracer   oteltrace.Tracer
+	provider *trace.TracerProvider
+	sampler  *ratioSampler
+
+	mu        sync.Mutex
+	exporter  trace.SpanExporter
+	processor trace.SpanProcessor
 }
 
 // NewTracingService creates a new tracing service
@@ -36,16 +45,6 @@ func NewTracingService() *TracingService {
 
 // InitTracer initializes OpenTelemetry tracing
 func (ts *TracingService) InitTracer() {
-	exporter, err := otlptracehttp.New(
-		context.Background(),
-		otlptracehttp.WithEndpoint(ts.config.JaegerEndpoint),
-		otlptracehttp.WithInsecure(),
-	)
-	if err != nil {
-		fmt.Printf("Failed to create trace exporter: %v\n", err)
-		return
-	}
-
 	res, err := resource.New(
 		context.Background(),
 		resource.WithAttributes(
@@ -58,12 +57,13 @@ func (ts *TracingService) InitTracer() {
 		return
 	}
 
+	ts.sampler = newRatioSampler(ts.config.SamplingRate, ts.config.ParentBased)
 	tp := trace.NewTracerProvider(
-		trace.WithBatcher(exporter),
 		trace.WithResource(res),
-		trace.WithSampler(trace.TraceIDRatioBased(ts.config.SamplingRate)),
+		trace.WithSampler(ts.sampler),
 	)
 
+	ts.provider = tp
 	otel.SetTracerProvider(tp)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
 		propagation.TraceContext{},
@@ -71,6 +71,308 @@ func (ts *TracingService) InitTracer() {
 	))
 
 	ts.tracer = otel.Tracer(ts.config.ServiceName)
+
+	if err := ts.Configure(ts.config.TracingSettings); err != nil {
+		fmt.Printf("Failed to create trace exporter: %v\n", err)
+	}
+}
+
+// Configure replaces the exporter, batching and sampling settings at runtime.
+// Spans still queued for the previous exporter are flushed before it is shut down.
+func (ts *TracingService) Configure(settings types.TracingSettings) error {
+	pending, err := ts.Prepare(settings)
+	if err != nil {
+		return err
+	}
+	pending.Apply()
+	return nil
+}
+
+// Prepare validates the settings and builds their exporter without using it yet
+func (ts *TracingService) Prepare(settings types.TracingSettings) (PendingConfig, error) {
+	if ts.provider == nil {
+		return nil, fmt.Errorf("tracer not initialized")
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	exporter, err := newTraceExporter(context.Background(), settings.Exporter)
+	if err != nil {
+		return nil, err
+	}
+	return &pendingTracing{ts: ts, settings: settings, exporter: exporter}, nil
+}
+
+// pendingTracing is a trace exporter waiting to replace the current one
+type pendingTracing struct {
+	ts       *TracingService
+	settings types.TracingSettings
+	exporter trace.SpanExporter
+}
+
+func (p *pendingTracing) Apply() {
+	ts, settings := p.ts, p.settings
+	var options []trace.BatchSpanProcessorOption
+	if settings.Batch.MaxQueueSize > 0 {
+		options = append(options, trace.WithMaxQueueSize(settings.Batch.MaxQueueSize))
+	}
+	if settings.Batch.MaxExportBatchSize > 0 {
+		options = append(options, trace.WithMaxExportBatchSize(settings.Batch.MaxExportBatchSize))
+	}
+	if settings.Batch.ScheduleDelayMs > 0 {
+		options = append(options, trace.WithBatchTimeout(time.Duration(settings.Batch.ScheduleDelayMs)*time.Millisecond))
+	}
+	if settings.Batch.ExportTimeoutMs > 0 {
+		options = append(options, trace.WithExportTimeout(time.Duration(settings.Batch.ExportTimeoutMs)*time.Millisecond))
+	}
+	processor := trace.NewBatchSpanProcessor(p.exporter, options...)
+
+	ts.mu.Lock()
+	previous := ts.processor
+	ts.provider.RegisterSpanProcessor(processor)
+	ts.processor = processor
+	ts.exporter = p.exporter
+	ts.config.TracingSettings = settings
+	ts.sampler.set(settings.SamplingRate, settings.ParentBased)
+	ts.mu.Unlock()
+
+	if previous != nil {
+		// Unregistering shuts the old processor down, exporting what it still holds
+		ts.provider.UnregisterSpanProcessor(previous)
+	}
+}
+
+func (p *pendingTracing) Discard() {
+	_ = p.exporter.Shutdown(context.Background())
+}
+
+// Settings returns the trace export settings currently in effect
+func (ts *TracingService) Settings() types.TracingSettings {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.config.TracingSettings
+}
+
+func (ts *TracingService) currentExporter() trace.SpanExporter {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.exporter
+}
+
+// ratioSampler is a TraceIDRatioBased sampler, optionally parent-based, whose
+// ratio can change at runtime
+type ratioSampler struct {
+	current atomic.Value // samplerBox
+}
+
+// samplerBox keeps the concrete type stored in the atomic.Value constant
+type samplerBox struct {
+	trace.Sampler
+}
+
+func newRatioSampler(ratio float64, parentBased bool) *ratioSampler {
+	s := &ratioSampler{}
+	s.set(ratio, parentBased)
+	return s
+}
+
+func (s *ratioSampler) set(ratio float64, parentBased bool) {
+	sampler := trace.TraceIDRatioBased(ratio)
+	if parentBased {
+		sampler = trace.ParentBased(sampler)
+	}
+	s.current.Store(samplerBox{sampler})
+}
+
+func (s *ratioSampler) ShouldSample(parameters trace.SamplingParameters) trace.SamplingResult {
+	return s.current.Load().(samplerBox).ShouldSample(parameters)
+}
+
+func (s *ratioSampler) Description() string {
+	return s.current.Load().(samplerBox).Description()
+}
+
+// ServiceName returns the service.name resource attribute spans are exported with
+func (ts *TracingService) ServiceName() string {
+	return ts.config.ServiceName
+}
+
+// Flush exports all spans that are still buffered in the batcher
+func (ts *TracingService) Flush(ctx context.Context) error {
+	if ts.provider == nil {
+		return fmt.Errorf("tracer not initialized")
+	}
+	return ts.provider.ForceFlush(ctx)
+}
+
+// EmitSearchProbe emits a small trace tagged with argus.run_id so backends can
+// be searched for it, flushes it and returns its trace ID
+func (ts *TracingService) EmitSearchProbe(ctx context.Context, runID string) (string, error) {
+	if ts.tracer == nil {
+		return "", fmt.Errorf("tracer not initialized")
+	}
+
+	probeCtx, root := ts.tracer.Start(ctx, "argus.search_probe", oteltrace.WithSpanKind(oteltrace.SpanKindServer))
+	root.SetAttributes(
+		attribute.String("argus.run_id", runID),
+		attribute.String("operation.type", "search_probe"),
+	)
+
+	_, child := ts.tracer.Start(probeCtx, "argus.search_probe.child")
+	child.SetAttributes(attribute.String("argus.run_id", runID))
+	time.Sleep(5 * time.Millisecond)
+	child.End()
+	root.End()
+
+	if !root.SpanContext().IsSampled() {
+		return "", fmt.Errorf("probe trace was not sampled (sampling rate %.2f)", ts.Settings().SamplingRate)
+	}
+	if err := ts.Flush(ctx); err != nil {
+		return "", fmt.Errorf("flush probe trace: %w", err)
+	}
+	return root.SpanContext().TraceID().String(), nil
+}
+
+// Labels the exemplar probes are observed under
+const (
+	ExemplarProbeOperation = "exemplar_probe"
+	ExemplarProbeEndpoint  = "/exemplar-probe"
+)
+
+// EmitExemplarProbes emits count server spans and observes each one's
+// duration into the APM span and HTTP request histograms with its trace ID as
+// exemplar. A client keeps only the newest exemplar per bucket until the next
+// scrape, so durations are spread log-uniformly from 2ms to 4s to land in
+// different buckets. Unsampled spans are observed without an exemplar. The
+// bundled dashboards and alert rules leave the probe series out.
+func (ts *TracingService) EmitExemplarProbes(ctx context.Context, runID string, count int) ([]models.ExemplarProbe, error) {
+	if ts.tracer == nil {
+		return nil, fmt.Errorf("tracer not initialized")
+	}
+
+	spanHistogram := metrics.APMSpanDuration.WithLabelValues(ts.ServiceName(), ExemplarProbeOperation)
+	requestHistogram := metrics.HTTPRequestDuration.WithLabelValues("GET", ExemplarProbeEndpoint)
+
+	probes := []models.ExemplarProbe{}
+	for i := 0; i < count; i++ {
+		position := (float64(i) + rand.Float64()) / float64(count)
+		duration := time.Duration(0.002 * math.Pow(2000, position) * float64(time.Second))
+		end := time.Now()
+
+		_, span := ts.tracer.Start(ctx, "GET "+ExemplarProbeEndpoint,
+			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+			oteltrace.WithTimestamp(end.Add(-duration)),
+		)
+		span.SetAttributes(
+			attribute.String("argus.run_id", runID),
+			attribute.String("operation.type", ExemplarProbeOperation),
+			attribute.String("http.method", "GET"),
+			attribute.String("http.route", ExemplarProbeEndpoint),
+			attribute.Int("http.status_code", 200),
+		)
+		span.End(oteltrace.WithTimestamp(end))
+
+		var traceID, spanID string
+		if span.SpanContext().IsSampled() {
+			traceID, spanID = span.SpanContext().TraceID().String(), span.SpanContext().SpanID().String()
+			probes = append(probes, models.ExemplarProbe{TraceID: traceID, SpanID: spanID, Duration: duration})
+		}
+		metrics.ObserveWithTrace(spanHistogram, duration.Seconds(), traceID, spanID)
+		metrics.ObserveWithTrace(requestHistogram, duration.Seconds(), traceID, spanID)
+	}
+
+	if len(probes) == 0 {
+		return nil, fmt.Errorf("no probe span was sampled (sampling rate %.2f)", ts.Settings().SamplingRate)
+	}
+	if err := ts.Flush(ctx); err != nil {
+		return nil, fmt.Errorf("flush probe spans: %w", err)
+	}
+	return probes, nil
+}
+
+// sharedExporter lets short-lived tracer providers reuse the main exporter
+// without shutting it down when they are shut down themselves
+type sharedExporter struct {
+	trace.SpanExporter
+}
+
+func (sharedExporter) Shutdown(context.Context) error { return nil }
+
+// EmitTopology emits real spans for each scenario where every service gets its
+// own service.name resource and calls the next one with a client span, so
+// Tempo's metrics-generator can derive service-graph edges from them.
+// It returns the number of spans emitted.
+func (ts *TracingService) EmitTopology(ctx context.Context, scenarios []models.TraceScenario, runID string, iterations int) (int, error) {
+	exporter := ts.currentExporter()
+	if exporter == nil {
+		return 0, fmt.Errorf("tracer not initialized")
+	}
+
+	providers := make(map[string]*trace.TracerProvider)
+	tracerFor := func(service string) oteltrace.Tracer {
+		if tp, ok := providers[service]; ok {
+			return tp.Tracer(ts.config.ServiceName)
+		}
+		res := resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(service),
+			semconv.ServiceVersionKey.String(ts.config.ServiceVersion),
+		)
+		tp := trace.NewTracerProvider(
+			trace.WithBatcher(sharedExporter{exporter}),
+			trace.WithResource(res),
+			trace.WithSampler(trace.AlwaysSample()),
+		)
+		providers[service] = tp
+		return tp.Tracer(ts.config.ServiceName)
+	}
+
+	spans := 0
+	for i := 0; i < iterations; i++ {
+		for _, scenario := range scenarios {
+			spans += ts.emitChain(ctx, tracerFor, scenario, runID, 0)
+		}
+	}
+
+	var flushErr error
+	for _, tp := range providers {
+		if err := tp.ForceFlush(ctx); err != nil && flushErr == nil {
+			flushErr = fmt.Errorf("flush topology spans: %w", err)
+		}
+		_ = tp.Shutdown(ctx)
+	}
+	return spans, flushErr
+}
+
+// emitChain emits the server span for scenario.Services[index] and, when there
+// is a next hop, a client span calling it with the rest of the chain nested inside
+func (ts *TracingService) emitChain(ctx context.Context, tracerFor func(string) oteltrace.Tracer, scenario models.TraceScenario, runID string, index int) int {
+	service := scenario.Services[index]
+	attrs := []attribute.KeyValue{
+		attribute.String("argus.run_id", runID),
+		attribute.String("argus.scenario", scenario.Name),
+	}
+
+	serverCtx, server := tracerFor(service).Start(ctx, TopologySpanName(scenario, service),
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer), oteltrace.WithAttributes(attrs...))
+	defer server.End()
+	spans := 1
+
+	if index+1 < len(scenario.Services) {
+		next := scenario.Services[index+1]
+		clientCtx, client := tracerFor(service).Start(serverCtx, "call "+next,
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient), oteltrace.WithAttributes(attrs...))
+		spans++
+		spans += ts.emitChain(clientCtx, tracerFor, scenario, runID, index+1)
+		client.End()
+	}
+	return spans
+}
+
+// TopologySpanName is the name of the server span a service emits in a scenario
+func TopologySpanName(scenario models.TraceScenario, service string) string {
+	return scenario.Name + ":" + service
 }
 
 // GetResourceMetrics gets current resource metrics
@@ -182,10 +484,10 @@ func (ts *TracingService) LogAPMData(apmData models.APMData) {
 		status,
 	).Inc()
 
-	metrics.APMSpanDuration.WithLabelValues(
+	metrics.ObserveWithTrace(metrics.APMSpanDuration.WithLabelValues(
 		apmData.ServiceName,
 		apmData.OperationName,
-	).Observe(apmData.Duration.Seconds())
+	), apmData.Duration.Seconds(), apmData.TraceID, apmData.SpanID)
 
 	for _, dep := range apmData.Dependencies {
 		metrics.ServiceDependencyLatency.WithLabelValues(
@@ -2,14 +2,20 @@ package services
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 
 	"github.com/nahuelsantos/argus/internal/models"
+	"github.com/nahuelsantos/argus/internal/types"
 )
 
 func TestNewTracingService(t *testing.T) {
@@ -19,7 +25,8 @@ func TestNewTracingService(t *testing.T) {
 	assert.NotNil(t, ts.config)
 	assert.Equal(t, "argus", ts.config.ServiceName)
 	assert.NotEmpty(t, ts.config.ServiceVersion)
-	assert.Equal(t, "http://localhost:14268/api/traces", ts.config.JaegerEndpoint)
+	assert.Equal(t, "http://localhost:4318", ts.config.Exporter.Endpoint)
+	assert.Equal(t, "/v1/traces", ts.config.Exporter.URLPath)
 	assert.Equal(t, 1.0, ts.config.SamplingRate)
 }
 
@@ -556,3 +563,146 @@ func ExampleTracingService_CreateAPMData() {
 	_ = apmData.StatusCode    // 200
 	_ = apmData.Duration      // 150ms
 }
+
+func TestTracingService_EmitSearchProbe(t *testing.T) {
+	ts := NewTracingService()
+
+	_, err := ts.EmitSearchProbe(context.Background(), "run-1")
+	assert.Error(t, err, "probe requires an initialized tracer")
+
+	ts.InitTracer()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// No collector is listening in tests, so the flush fails after the span was recorded
+	_, err = ts.EmitSearchProbe(ctx, "run-1")
+	if err != nil {
+		assert.Contains(t, err.Error(), "flush probe trace")
+	}
+	assert.Equal(t, "argus", ts.ServiceName())
+}
+
+func TestTracingService_EmitExemplarProbes(t *testing.T) {
+	ts := NewTracingService()
+
+	_, err := ts.EmitExemplarProbes(context.Background(), "run-1", 3)
+	assert.Error(t, err, "probes require an initialized tracer")
+
+	exporter := tracetest.NewInMemoryExporter()
+	ts.provider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ts.tracer = ts.provider.Tracer("argus")
+
+	probes, err := ts.EmitExemplarProbes(context.Background(), "run-1", 4)
+	require.NoError(t, err)
+	require.Len(t, probes, 4)
+
+	recorded := exporter.GetSpans()
+	require.Len(t, recorded, 4)
+	for i, probe := range probes {
+		span := recorded[i]
+		assert.Equal(t, probe.TraceID, span.SpanContext.TraceID().String())
+		assert.Equal(t, probe.SpanID, span.SpanContext.SpanID().String())
+		assert.Equal(t, probe.Duration, span.EndTime.Sub(span.StartTime), "span lasts as long as the observation")
+		assert.Equal(t, "GET /exemplar-probe", span.Name)
+		assert.GreaterOrEqual(t, probe.Duration, 2*time.Millisecond)
+		assert.LessOrEqual(t, probe.Duration, 4*time.Second)
+		if i > 0 {
+			assert.Greater(t, probe.Duration, probes[i-1].Duration, "durations spread across buckets")
+		}
+	}
+}
+
+func TestTracingService_EmitTopology(t *testing.T) {
+	ts := NewTracingService()
+
+	_, err := ts.EmitTopology(context.Background(), CrossServiceScenarios, "run-1", 1)
+	assert.Error(t, err, "topology requires an initialized tracer")
+
+	exporter := tracetest.NewInMemoryExporter()
+	ts.exporter = exporter
+
+	scenario := models.TraceScenario{Name: "checkout", Services: []string{"frontend", "api", "database"}}
+	spans, err := ts.EmitTopology(context.Background(), []models.TraceScenario{scenario}, "run-1", 2)
+	require.NoError(t, err)
+
+	// Per iteration: three server spans plus two client spans
+	assert.Equal(t, 10, spans)
+	recorded := exporter.GetSpans()
+	require.Len(t, recorded, 10)
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range recorded {
+		byName[span.Name] = span
+	}
+
+	server := byName["checkout:api"]
+	assert.Equal(t, oteltrace.SpanKindServer, server.SpanKind)
+	serviceName, _ := server.Resource.Set().Value("service.name")
+	assert.Equal(t, "api", serviceName.AsString())
+
+	client := byName["call api"]
+	assert.Equal(t, oteltrace.SpanKindClient, client.SpanKind)
+	serviceName, _ = client.Resource.Set().Value("service.name")
+	assert.Equal(t, "frontend", serviceName.AsString())
+	assert.Equal(t, client.SpanContext.SpanID(), server.Parent.SpanID(), "server span is a child of the calling client span")
+}
+
+func TestTracingService_Configure(t *testing.T) {
+	type received struct {
+		path, tenant, encoding string
+	}
+	requests := make(chan received, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- received{r.URL.Path, r.Header.Get("X-Scope-OrgID"), r.Header.Get("Content-Encoding")}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	ts := NewTracingService()
+	assert.Error(t, ts.Configure(ts.config.TracingSettings), "configure requires an initialized tracer")
+	ts.InitTracer()
+
+	settings := ts.Settings()
+	settings.Exporter = types.OTLPExporterConfig{
+		Protocol:    types.OTLPProtocolHTTP,
+		Endpoint:    collector.URL,
+		URLPath:     "/otlp/v1/traces",
+		Headers:     map[string]string{"X-Scope-OrgID": "tenant-a"},
+		Compression: "gzip",
+	}
+	require.NoError(t, ts.Configure(settings))
+	assert.Equal(t, "tenant-a", ts.Settings().Exporter.Headers["X-Scope-OrgID"])
+
+	_, err := ts.EmitSearchProbe(context.Background(), "run-1")
+	require.NoError(t, err)
+
+	select {
+	case req := <-requests:
+		assert.Equal(t, "/otlp/v1/traces", req.path)
+		assert.Equal(t, "tenant-a", req.tenant)
+		assert.Equal(t, "gzip", req.encoding)
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector received no export")
+	}
+
+	t.Run("sampling rate applies at runtime", func(t *testing.T) {
+		settings.SamplingRate = 0
+		require.NoError(t, ts.Configure(settings))
+
+		_, err := ts.EmitSearchProbe(context.Background(), "run-2")
+		assert.ErrorContains(t, err, "not sampled")
+	})
+
+	t.Run("invalid settings are rejected and keep the current exporter", func(t *testing.T) {
+		invalid := settings
+		invalid.Exporter.Protocol = "http/json"
+		assert.Error(t, ts.Configure(invalid))
+		assert.Equal(t, types.OTLPProtocolHTTP, ts.Settings().Exporter.Protocol)
+	})
+
+	t.Run("grpc exporter with missing CA file fails", func(t *testing.T) {
+		grpcSettings := settings
+		grpcSettings.Exporter = types.OTLPExporterConfig{Protocol: types.OTLPProtocolGRPC, Endpoint: "collector:4317", CAFile: "/nonexistent/ca.pem"}
+		assert.ErrorContains(t, ts.Configure(grpcSettings), "read CA file")
+	})
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxCardinalitySeries caps the active series of one cardinality test
+const MaxCardinalitySeries = 1000000
+
+// MaxCardinalityDurationSeconds caps the run so the report is written before
+// the 15 minute limit long-running requests get
+const MaxCardinalityDurationSeconds = 600
+
+// CardinalityTest defines a cardinality stress run. Every series carries
+// Labels labels with Values values each, so Values^Labels series are active
+// at once. Every interval ChurnPercent of them is replaced by new series, as
+// are series older than LifetimeSeconds.
+type CardinalityTest struct {
+	Labels          int     `json:"labels"`
+	Values          int     `json:"values"`
+	ChurnPercent    float64 `json:"churn_percent"`
+	LifetimeSeconds int     `json:"lifetime_seconds"` // 0 keeps series until churned
+	IntervalSeconds int     `json:"interval_seconds"`
+	DurationSeconds int     `json:"duration_seconds"`
+	BatchSize       int     `json:"batch_size"` // series per remote write request
+
+	// Queries watched every interval; they default to Prometheus' own metrics
+	HeadSeriesQuery string `json:"head_series_query,omitempty"`
+	MemoryQuery     string `json:"memory_query,omitempty"`
+}
+
+// Series returns the number of series active at once
+func (c CardinalityTest) Series() int {
+	return int(math.Pow(float64(c.Values), float64(c.Labels)))
+}
+
+// Validate checks the test stays within the series cap and runs at least once
+func (c CardinalityTest) Validate() error {
+	if c.Labels < 1 || c.Labels > 10 {
+		return fmt.Errorf("labels must be between 1 and 10")
+	}
+	if c.Values < 1 {
+		return fmt.Errorf("values must be at least 1")
+	}
+	if math.Pow(float64(c.Values), float64(c.Labels)) > MaxCardinalitySeries {
+		return fmt.Errorf("values^labels must not exceed %d series", MaxCardinalitySeries)
+	}
+	if c.ChurnPercent < 0 || c.ChurnPercent > 100 {
+		return fmt.Errorf("churn_percent must be between 0 and 100")
+	}
+	if c.LifetimeSeconds < 0 {
+		return fmt.Errorf("lifetime_seconds must not be negative")
+	}
+	if c.IntervalSeconds < 1 {
+		return fmt.Errorf("interval_seconds must be at least 1")
+	}
+	if c.DurationSeconds < c.IntervalSeconds || c.DurationSeconds > MaxCardinalityDurationSeconds {
+		return fmt.Errorf("duration_seconds must be between interval_seconds and %d", MaxCardinalityDurationSeconds)
+	}
+	if c.BatchSize < 0 {
+		return fmt.Errorf("batch_size must not be negative")
+	}
+	return nil
+}
@@ -1,6 +1,11 @@
 package types
 
-import "os"
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+)
 
 // LGTMSettings represents the configuration for all LGTM stack services
 type LGTMSettings struct {
@@ -9,13 +14,252 @@ type LGTMSettings struct {
 	AlertManager ServiceConfig `json:"alertmanager"`
 	Loki         ServiceConfig `json:"loki"`
 	Tempo        ServiceConfig `json:"tempo"`
+
+	// RemoteWriteURL receives the cardinality test's series with the Prometheus
+	// credentials; it defaults to the Prometheus URL plus /api/v1/write
+	RemoteWriteURL string `json:"remote_write_url,omitempty"`
+
+	// OTELCollector is the OpenTelemetry Collector in front of the stack
+	OTELCollector CollectorConfig `json:"otel_collector"`
+
+	// Services are application health endpoints used by the service discovery test
+	Services []DiscoveryTarget `json:"services,omitempty"`
+
+	// Discovery providers find the scrape targets that the service discovery
+	// test health-checks and compares against Prometheus' active targets
+	Discovery []DiscoveryProvider `json:"discovery,omitempty"`
+
+	// ReverseProxy is the proxy whose routes the reverse proxy test sends requests through
+	ReverseProxy *ProxyConfig `json:"reverse_proxy,omitempty"`
+
+	// TLSTargets are the endpoints whose certificates the SSL monitoring test inspects
+	TLSTargets []TLSTarget `json:"tls_targets,omitempty"`
+
+	// Domains are probed by the domain health test, resolving names through
+	// DNSResolver (host:port) or the system resolver when it is empty
+	Domains     []DomainTarget `json:"domains,omitempty"`
+	DNSResolver string         `json:"dns_resolver,omitempty"`
+
+	// Tracing is applied to Argus' own trace exporter; nil keeps the current settings
+	Tracing *TracingSettings `json:"tracing,omitempty"`
+
+	// LogExport and MetricExport are applied to Argus' own OTLP log and metric export
+	LogExport    *LogExportSettings    `json:"log_export,omitempty"`
+	MetricExport *MetricExportSettings `json:"metric_export,omitempty"`
+
+	// LogSinks replace the sinks Argus writes logs to besides stdout; nil
+	// keeps the current sinks
+	LogSinks []LogSinkConfig `json:"log_sinks,omitempty"`
 }
 
 // ServiceConfig represents the configuration for a single service
 type ServiceConfig struct {
-	URL      string `json:"url"`
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
+	URL         string            `json:"url"`
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"` // used instead of basic auth when set
+	TenantID    string            `json:"tenant_id,omitempty"`    // sent as X-Scope-OrgID
+	Headers     map[string]string `json:"headers,omitempty"`      // sent with every request
+	TLS         *TLSConfig        `json:"tls,omitempty"`          // nil uses the system roots
+}
+
+// TLSConfig holds the client TLS settings for connecting to a service
+type TLSConfig struct {
+	CAFile             string `json:"ca_file,omitempty"`     // PEM bundle of trusted CAs instead of the system roots
+	CertFile           string `json:"cert_file,omitempty"`   // client certificate for mTLS
+	KeyFile            string `json:"key_file,omitempty"`    // client key for mTLS
+	ServerName         string `json:"server_name,omitempty"` // name verified against the certificate instead of the URL host
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// Validate checks that a client certificate and key are given together
+func (c *TLSConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// TenantHeader is the header Loki, Tempo and Mimir read the tenant from
+const TenantHeader = "X-Scope-OrgID"
+
+// CollectorConfig represents an OpenTelemetry Collector. URL points at its
+// internal telemetry (usually :8888); test traffic goes to OTLPEndpoint.
+type CollectorConfig struct {
+	ServiceConfig
+	OTLPEndpoint string `json:"otlp_endpoint"`
+	OTLPProtocol string `json:"otlp_protocol,omitempty"` // "http/protobuf" (default) or "grpc"
+}
+
+// DiscoveryTarget represents an application whose health endpoint is checked
+type DiscoveryTarget struct {
+	Name string `json:"name"`
+	ServiceConfig
+}
+
+// Discovery provider types
+const (
+	DiscoveryStatic = "static"
+	DiscoveryFileSD = "file_sd"
+	DiscoveryDNS    = "dns"
+)
+
+// DiscoveryProvider configures one source of scrape targets: a static list
+// of host:port addresses, Prometheus file_sd files (JSON or YAML, globs
+// allowed) or DNS SRV/A names. Discovered targets are health-checked at
+// Scheme://address HealthPath.
+type DiscoveryProvider struct {
+	Type       string            `json:"type"`           // "static", "file_sd" or "dns"
+	Name       string            `json:"name,omitempty"` // defaults to the type
+	Targets    []string          `json:"targets,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"` // added to static targets
+	Files      []string          `json:"files,omitempty"`
+	Names      []string          `json:"names,omitempty"`
+	RecordType string            `json:"record_type,omitempty"` // "SRV" (default) or "A"
+	Port       int               `json:"port,omitempty"`        // required for A records
+	Scheme     string            `json:"scheme,omitempty"`      // "http" (default) or "https"
+	HealthPath string            `json:"health_path,omitempty"` // defaults to /metrics
+	TLS        *TLSConfig        `json:"tls,omitempty"`
+}
+
+// Validate checks the provider has what its type needs
+func (p DiscoveryProvider) Validate() error {
+	switch p.Type {
+	case DiscoveryStatic:
+		if len(p.Targets) == 0 {
+			return fmt.Errorf("static discovery needs targets")
+		}
+	case DiscoveryFileSD:
+		if len(p.Files) == 0 {
+			return fmt.Errorf("file_sd discovery needs files")
+		}
+	case DiscoveryDNS:
+		if len(p.Names) == 0 {
+			return fmt.Errorf("dns discovery needs names")
+		}
+		switch p.RecordType {
+		case "", "SRV":
+		case "A":
+			if p.Port <= 0 || p.Port > 65535 {
+				return fmt.Errorf("dns discovery of A records needs a port")
+			}
+		default:
+			return fmt.Errorf("unsupported record_type %q (use SRV or A)", p.RecordType)
+		}
+	default:
+		return fmt.Errorf("unsupported discovery type %q (use static, file_sd or dns)", p.Type)
+	}
+	if p.Scheme != "" && p.Scheme != "http" && p.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q (use http or https)", p.Scheme)
+	}
+	return p.TLS.Validate()
+}
+
+// ProxyConfig represents a reverse proxy and the routes it should serve.
+// Requests are sent to Address (or TLSAddress for routes expecting TLS) with
+// each route's host, so no DNS entries are needed. TLS supplies the CA bundle
+// the proxy's certificates are verified against.
+type ProxyConfig struct {
+	Address    string       `json:"address"`               // host:port of the HTTP entrypoint
+	TLSAddress string       `json:"tls_address,omitempty"` // host:port of the HTTPS entrypoint
+	TLS        *TLSConfig   `json:"tls,omitempty"`
+	Routes     []ProxyRoute `json:"routes"`
+}
+
+// ProxyRoute represents one route and how to recognise the backend serving it:
+// by a response header naming the backend, or a marker in the response body
+type ProxyRoute struct {
+	Name             string   `json:"name,omitempty"` // defaults to host and path
+	Host             string   `json:"host"`
+	Path             string   `json:"path,omitempty"`           // defaults to /
+	BackendHeader    string   `json:"backend_header,omitempty"` // e.g. X-Backend
+	ExpectedBackends []string `json:"expected_backends,omitempty"`
+	BodyMarker       string   `json:"body_marker,omitempty"`
+	ExpectTLS        bool     `json:"expect_tls,omitempty"`
+	// EchoesHeaders marks backends that write the request headers they received
+	// into the body, like traefik/whoami, so header forwarding can be checked
+	EchoesHeaders bool `json:"echoes_headers,omitempty"`
+	Requests      int  `json:"requests,omitempty"` // sent to check load balancing, default 10
+}
+
+// Validate checks the proxy has an entrypoint for every route
+func (c ProxyConfig) Validate() error {
+	for _, route := range c.Routes {
+		if route.Host == "" {
+			return fmt.Errorf("every route needs a host")
+		}
+		if route.ExpectTLS && c.TLSAddress == "" {
+			return fmt.Errorf("route %s expects TLS but tls_address is not set", route.Host)
+		}
+		if !route.ExpectTLS && c.Address == "" {
+			return fmt.Errorf("route %s needs address to be set", route.Host)
+		}
+		if route.Requests < 0 || route.Requests > 1000 {
+			return fmt.Errorf("route %s: requests must be between 0 and 1000", route.Host)
+		}
+	}
+	return c.TLS.Validate()
+}
+
+// DomainTarget represents a URL probed by the domain health test
+type DomainTarget struct {
+	Name              string            `json:"name,omitempty"` // defaults to the URL host
+	URL               string            `json:"url"`
+	ExpectedStatus    []int             `json:"expected_status,omitempty"` // any 2xx when empty
+	BodyRegex         string            `json:"body_regex,omitempty"`
+	NoFollowRedirects bool              `json:"no_follow_redirects,omitempty"`
+	Headers           map[string]string `json:"headers,omitempty"`
+	TLS               *TLSConfig        `json:"tls,omitempty"`
+}
+
+// TLSTarget represents a TLS endpoint whose certificate chain is inspected.
+// TLS supplies the CA bundle the chain is verified against, the server name
+// and a client certificate for endpoints that require one.
+type TLSTarget struct {
+	Address string     `json:"address"` // host:port, port 443 when omitted
+	TLS     *TLSConfig `json:"tls,omitempty"`
+}
+
+// Apply sets the service's credentials, tenant and custom headers on a
+// request. Custom headers are applied last so they can override the others.
+func (c ServiceConfig) Apply(req *http.Request) {
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	if c.TenantID != "" {
+		req.Header.Set(TenantHeader, c.TenantID)
+	}
+	for key, value := range c.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// Exporter returns the OTLP settings for sending test traffic to the collector.
+// Credentials and tenant are sent the same way Apply sends them, with custom
+// headers applied last.
+func (c CollectorConfig) Exporter() OTLPExporterConfig {
+	protocol := c.OTLPProtocol
+	if protocol == "" {
+		protocol = OTLPProtocolHTTP
+	}
+	headers := make(map[string]string, len(c.Headers)+2)
+	if c.BearerToken != "" {
+		headers["Authorization"] = "Bearer " + c.BearerToken
+	} else if c.Username != "" {
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))
+	}
+	if c.TenantID != "" {
+		headers[TenantHeader] = c.TenantID
+	}
+	for key, value := range c.Headers {
+		headers[key] = value
+	}
+	return OTLPExporterConfig{Protocol: protocol, Endpoint: c.OTLPEndpoint, Headers: headers}
 }
 
 // getEnv returns environment variable or default value
@@ -38,15 +282,24 @@ func GetDefaults() *LGTMSettings {
 			URL:      getEnv("ARGUS_PROMETHEUS_URL", "http://localhost:9090"),
 			Username: getEnv("ARGUS_PROMETHEUS_USERNAME", ""),
 			Password: getEnv("ARGUS_PROMETHEUS_PASSWORD", ""),
+			TenantID: getEnv("ARGUS_PROMETHEUS_TENANT_ID", ""),
 		},
 		AlertManager: ServiceConfig{
 			URL: getEnv("ARGUS_ALERTMANAGER_URL", "http://localhost:9093"),
 		},
 		Loki: ServiceConfig{
-			URL: getEnv("ARGUS_LOKI_URL", "http://localhost:3100"),
+			URL:      getEnv("ARGUS_LOKI_URL", "http://localhost:3100"),
+			TenantID: getEnv("ARGUS_LOKI_TENANT_ID", ""),
 		},
 		Tempo: ServiceConfig{
-			URL: getEnv("ARGUS_TEMPO_URL", "http://localhost:3200"),
+			URL:      getEnv("ARGUS_TEMPO_URL", "http://localhost:3200"),
+			TenantID: getEnv("ARGUS_TEMPO_TENANT_ID", ""),
+		},
+		OTELCollector: CollectorConfig{
+			ServiceConfig: ServiceConfig{
+				URL: getEnv("ARGUS_OTEL_COLLECTOR_URL", "http://localhost:8888"),
+			},
+			OTLPEndpoint: getEnv("ARGUS_OTEL_COLLECTOR_OTLP_ENDPOINT", "http://localhost:4318"),
 		},
 	}
 }
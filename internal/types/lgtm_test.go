@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"net/http"
 	"os"
 	"testing"
 
@@ -148,24 +149,29 @@ func TestGetEnv(t *testing.T) {
 func TestGetDefaults(t *testing.T) {
 	// Save original env vars (both ARGUS_ and legacy)
 	originalEnvVars := map[string]string{
-		"ARGUS_GRAFANA_URL":         os.Getenv("ARGUS_GRAFANA_URL"),
-		"ARGUS_GRAFANA_USERNAME":    os.Getenv("ARGUS_GRAFANA_USERNAME"),
-		"ARGUS_GRAFANA_PASSWORD":    os.Getenv("ARGUS_GRAFANA_PASSWORD"),
-		"ARGUS_PROMETHEUS_URL":      os.Getenv("ARGUS_PROMETHEUS_URL"),
-		"ARGUS_PROMETHEUS_USERNAME": os.Getenv("ARGUS_PROMETHEUS_USERNAME"),
-		"ARGUS_PROMETHEUS_PASSWORD": os.Getenv("ARGUS_PROMETHEUS_PASSWORD"),
-		"ARGUS_ALERTMANAGER_URL":    os.Getenv("ARGUS_ALERTMANAGER_URL"),
-		"ARGUS_LOKI_URL":            os.Getenv("ARGUS_LOKI_URL"),
-		"ARGUS_TEMPO_URL":           os.Getenv("ARGUS_TEMPO_URL"),
-		"GRAFANA_URL":               os.Getenv("GRAFANA_URL"),
-		"GRAFANA_USERNAME":          os.Getenv("GRAFANA_USERNAME"),
-		"GRAFANA_PASSWORD":          os.Getenv("GRAFANA_PASSWORD"),
-		"PROMETHEUS_URL":            os.Getenv("PROMETHEUS_URL"),
-		"PROMETHEUS_USERNAME":       os.Getenv("PROMETHEUS_USERNAME"),
-		"PROMETHEUS_PASSWORD":       os.Getenv("PROMETHEUS_PASSWORD"),
-		"ALERTMANAGER_URL":          os.Getenv("ALERTMANAGER_URL"),
-		"LOKI_URL":                  os.Getenv("LOKI_URL"),
-		"TEMPO_URL":                 os.Getenv("TEMPO_URL"),
+		"ARGUS_GRAFANA_URL":                  os.Getenv("ARGUS_GRAFANA_URL"),
+		"ARGUS_GRAFANA_USERNAME":             os.Getenv("ARGUS_GRAFANA_USERNAME"),
+		"ARGUS_GRAFANA_PASSWORD":             os.Getenv("ARGUS_GRAFANA_PASSWORD"),
+		"ARGUS_PROMETHEUS_URL":               os.Getenv("ARGUS_PROMETHEUS_URL"),
+		"ARGUS_PROMETHEUS_USERNAME":          os.Getenv("ARGUS_PROMETHEUS_USERNAME"),
+		"ARGUS_PROMETHEUS_PASSWORD":          os.Getenv("ARGUS_PROMETHEUS_PASSWORD"),
+		"ARGUS_ALERTMANAGER_URL":             os.Getenv("ARGUS_ALERTMANAGER_URL"),
+		"ARGUS_LOKI_URL":                     os.Getenv("ARGUS_LOKI_URL"),
+		"ARGUS_TEMPO_URL":                    os.Getenv("ARGUS_TEMPO_URL"),
+		"ARGUS_PROMETHEUS_TENANT_ID":         os.Getenv("ARGUS_PROMETHEUS_TENANT_ID"),
+		"ARGUS_LOKI_TENANT_ID":               os.Getenv("ARGUS_LOKI_TENANT_ID"),
+		"ARGUS_TEMPO_TENANT_ID":              os.Getenv("ARGUS_TEMPO_TENANT_ID"),
+		"ARGUS_OTEL_COLLECTOR_URL":           os.Getenv("ARGUS_OTEL_COLLECTOR_URL"),
+		"ARGUS_OTEL_COLLECTOR_OTLP_ENDPOINT": os.Getenv("ARGUS_OTEL_COLLECTOR_OTLP_ENDPOINT"),
+		"GRAFANA_URL":                        os.Getenv("GRAFANA_URL"),
+		"GRAFANA_USERNAME":                   os.Getenv("GRAFANA_USERNAME"),
+		"GRAFANA_PASSWORD":                   os.Getenv("GRAFANA_PASSWORD"),
+		"PROMETHEUS_URL":                     os.Getenv("PROMETHEUS_URL"),
+		"PROMETHEUS_USERNAME":                os.Getenv("PROMETHEUS_USERNAME"),
+		"PROMETHEUS_PASSWORD":                os.Getenv("PROMETHEUS_PASSWORD"),
+		"ALERTMANAGER_URL":                   os.Getenv("ALERTMANAGER_URL"),
+		"LOKI_URL":                           os.Getenv("LOKI_URL"),
+		"TEMPO_URL":                          os.Getenv("TEMPO_URL"),
 	}
 
 	// Clean up function
@@ -208,6 +214,10 @@ func TestGetDefaults(t *testing.T) {
 			Tempo: ServiceConfig{
 				URL: "http://localhost:3200",
 			},
+			OTELCollector: CollectorConfig{
+				ServiceConfig: ServiceConfig{URL: "http://localhost:8888"},
+				OTLPEndpoint:  "http://localhost:4318",
+			},
 		}
 
 		assert.Equal(t, expected, settings)
@@ -216,15 +226,20 @@ func TestGetDefaults(t *testing.T) {
 	t.Run("uses ARGUS_ prefixed environment variables when set", func(t *testing.T) {
 		// Set specific ARGUS_ env vars
 		envVars := map[string]string{
-			"ARGUS_GRAFANA_URL":         "http://argus-grafana:3000",
-			"ARGUS_GRAFANA_USERNAME":    "argus-admin",
-			"ARGUS_GRAFANA_PASSWORD":    "argus-pass",
-			"ARGUS_PROMETHEUS_URL":      "http://argus-prometheus:9090",
-			"ARGUS_PROMETHEUS_USERNAME": "argus-prom-user",
-			"ARGUS_PROMETHEUS_PASSWORD": "argus-prom-pass",
-			"ARGUS_ALERTMANAGER_URL":    "http://argus-alertmanager:9093",
-			"ARGUS_LOKI_URL":            "http://argus-loki:3100",
-			"ARGUS_TEMPO_URL":           "http://argus-tempo:3200",
+			"ARGUS_GRAFANA_URL":                  "http://argus-grafana:3000",
+			"ARGUS_GRAFANA_USERNAME":             "argus-admin",
+			"ARGUS_GRAFANA_PASSWORD":             "argus-pass",
+			"ARGUS_PROMETHEUS_URL":               "http://argus-prometheus:9090",
+			"ARGUS_PROMETHEUS_USERNAME":          "argus-prom-user",
+			"ARGUS_PROMETHEUS_PASSWORD":          "argus-prom-pass",
+			"ARGUS_ALERTMANAGER_URL":             "http://argus-alertmanager:9093",
+			"ARGUS_LOKI_URL":                     "http://argus-loki:3100",
+			"ARGUS_TEMPO_URL":                    "http://argus-tempo:3200",
+			"ARGUS_PROMETHEUS_TENANT_ID":         "team-metrics",
+			"ARGUS_LOKI_TENANT_ID":               "team-logs",
+			"ARGUS_TEMPO_TENANT_ID":              "team-traces",
+			"ARGUS_OTEL_COLLECTOR_URL":           "http://argus-collector:8888",
+			"ARGUS_OTEL_COLLECTOR_OTLP_ENDPOINT": "argus-collector:4317",
 		}
 
 		for key, value := range envVars {
@@ -244,15 +259,22 @@ func TestGetDefaults(t *testing.T) {
 				URL:      "http://argus-prometheus:9090",
 				Username: "argus-prom-user",
 				Password: "argus-prom-pass",
+				TenantID: "team-metrics",
 			},
 			AlertManager: ServiceConfig{
 				URL: "http://argus-alertmanager:9093",
 			},
 			Loki: ServiceConfig{
-				URL: "http://argus-loki:3100",
+				URL:      "http://argus-loki:3100",
+				TenantID: "team-logs",
 			},
 			Tempo: ServiceConfig{
-				URL: "http://argus-tempo:3200",
+				URL:      "http://argus-tempo:3200",
+				TenantID: "team-traces",
+			},
+			OTELCollector: CollectorConfig{
+				ServiceConfig: ServiceConfig{URL: "http://argus-collector:8888"},
+				OTLPEndpoint:  "argus-collector:4317",
 			},
 		}
 
@@ -292,6 +314,10 @@ func TestGetDefaults(t *testing.T) {
 			Tempo: ServiceConfig{
 				URL: "http://localhost:3200", // default
 			},
+			OTELCollector: CollectorConfig{
+				ServiceConfig: ServiceConfig{URL: "http://localhost:8888"}, // default
+				OTLPEndpoint:  "http://localhost:4318",                     // default
+			},
 		}
 
 		assert.Equal(t, expected, settings)
@@ -357,3 +383,238 @@ func BenchmarkGetDefaults(b *testing.B) {
 		GetDefaults()
 	}
 }
+
+func TestServiceConfig_Apply(t *testing.T) {
+	config := ServiceConfig{
+		URL:      "http://prometheus:9090",
+		Username: "admin",
+		Password: "secret",
+		Headers:  map[string]string{"X-Scope-OrgID": "team-a"},
+	}
+
+	req, err := http.NewRequest("GET", config.URL, nil)
+	require.NoError(t, err)
+	config.Apply(req)
+
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "admin", username)
+	assert.Equal(t, "secret", password)
+	assert.Equal(t, "team-a", req.Header.Get("X-Scope-OrgID"))
+
+	// Without a username no credentials are sent
+	req, err = http.NewRequest("GET", config.URL, nil)
+	require.NoError(t, err)
+	ServiceConfig{URL: config.URL}.Apply(req)
+	_, _, ok = req.BasicAuth()
+	assert.False(t, ok)
+
+	// A bearer token replaces basic auth, and custom headers override the tenant
+	req, err = http.NewRequest("GET", config.URL, nil)
+	require.NoError(t, err)
+	ServiceConfig{
+		URL:         config.URL,
+		Username:    "admin",
+		BearerToken: "token",
+		TenantID:    "team-b",
+	}.Apply(req)
+	assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	assert.Equal(t, "team-b", req.Header.Get(TenantHeader))
+
+	config.TenantID = "team-b"
+	req, err = http.NewRequest("GET", config.URL, nil)
+	require.NoError(t, err)
+	config.Apply(req)
+	assert.Equal(t, "team-a", req.Header.Get(TenantHeader))
+}
+
+func TestCollectorConfig_Exporter(t *testing.T) {
+	collector := CollectorConfig{
+		ServiceConfig: ServiceConfig{URL: "http://collector:8888", Headers: map[string]string{"Authorization": "Bearer token"}},
+		OTLPEndpoint:  "http://collector:4318",
+	}
+
+	exporter := collector.Exporter()
+	assert.Equal(t, OTLPProtocolHTTP, exporter.Protocol)
+	assert.Equal(t, "http://collector:4318", exporter.Endpoint)
+	assert.Equal(t, "Bearer token", exporter.Headers["Authorization"])
+
+	collector.OTLPProtocol = OTLPProtocolGRPC
+	assert.Equal(t, OTLPProtocolGRPC, collector.Exporter().Protocol)
+}
+
+func TestCollectorConfig_ExporterCredentials(t *testing.T) {
+	collector := CollectorConfig{
+		ServiceConfig: ServiceConfig{URL: "http://collector:8888", BearerToken: "secret", TenantID: "team-a"},
+		OTLPEndpoint:  "http://collector:4318",
+	}
+
+	headers := collector.Exporter().Headers
+	assert.Equal(t, "Bearer secret", headers["Authorization"])
+	assert.Equal(t, "team-a", headers[TenantHeader])
+
+	collector.BearerToken = ""
+	collector.Username = "argus"
+	collector.Password = "pass"
+	assert.Equal(t, "Basic YXJndXM6cGFzcw==", collector.Exporter().Headers["Authorization"])
+
+	collector.Headers = map[string]string{TenantHeader: "team-b"}
+	assert.Equal(t, "team-b", collector.Exporter().Headers[TenantHeader])
+	assert.Len(t, collector.Headers, 1, "custom headers must not be modified")
+}
+
+func TestTLSConfig_Validate(t *testing.T) {
+	var unset *TLSConfig
+	assert.NoError(t, unset.Validate())
+	assert.NoError(t, (&TLSConfig{CAFile: "/etc/argus/ca.pem", ServerName: "loki.internal"}).Validate())
+	assert.NoError(t, (&TLSConfig{CertFile: "client.pem", KeyFile: "client-key.pem"}).Validate())
+	assert.Error(t, (&TLSConfig{CertFile: "client.pem"}).Validate())
+	assert.Error(t, (&TLSConfig{KeyFile: "client-key.pem"}).Validate())
+
+	var config ServiceConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"url":"https://loki:3100","tls":{"ca_file":"ca.pem","insecure_skip_verify":true}}`), &config))
+	assert.Equal(t, &TLSConfig{CAFile: "ca.pem", InsecureSkipVerify: true}, config.TLS)
+}
+
+func TestSchedule_Validate(t *testing.T) {
+	assert.NoError(t, Schedule{Check: "lgtm_status", IntervalSeconds: 60, JitterSeconds: 60}.Validate())
+	assert.EqualError(t, Schedule{IntervalSeconds: 60}.Validate(), "check is required")
+	assert.Error(t, Schedule{Check: "lgtm_status"}.Validate())
+	assert.Error(t, Schedule{Check: "lgtm_status", IntervalSeconds: 60, JitterSeconds: -1}.Validate())
+	assert.Error(t, Schedule{Check: "lgtm_status", IntervalSeconds: 60, TimeoutSeconds: -5}.Validate())
+
+	var schedule Schedule
+	require.NoError(t, json.Unmarshal([]byte(`{"check":"domain_probe","interval_seconds":30,"params":{"urls":"https://example.com"}}`), &schedule))
+	assert.Equal(t, "https://example.com", schedule.Params["urls"])
+	assert.False(t, schedule.Paused)
+}
+
+func TestProxyConfig_Validate(t *testing.T) {
+	assert.NoError(t, ProxyConfig{Address: "traefik:80", Routes: []ProxyRoute{{Host: "a.example.com"}}}.Validate())
+	assert.Error(t, ProxyConfig{Address: "traefik:80", Routes: []ProxyRoute{{Path: "/"}}}.Validate())
+	assert.Error(t, ProxyConfig{Address: "traefik:80", Routes: []ProxyRoute{{Host: "a.example.com", ExpectTLS: true}}}.Validate())
+	assert.Error(t, ProxyConfig{TLSAddress: "traefik:443", Routes: []ProxyRoute{{Host: "a.example.com"}}}.Validate())
+	assert.Error(t, ProxyConfig{Address: "traefik:80", Routes: []ProxyRoute{{Host: "a.example.com", Requests: 5000}}}.Validate())
+}
+
+func TestCardinalityTest_Validate(t *testing.T) {
+	valid := CardinalityTest{Labels: 3, Values: 100, ChurnPercent: 10, IntervalSeconds: 15, DurationSeconds: 60}
+	assert.NoError(t, valid.Validate())
+	assert.Equal(t, 1000000, valid.Series())
+
+	tooMany := valid
+	tooMany.Values = 101
+	assert.EqualError(t, tooMany.Validate(), "values^labels must not exceed 1000000 series")
+
+	short := valid
+	short.DurationSeconds = 10
+	assert.Error(t, short.Validate())
+
+	churn := valid
+	churn.ChurnPercent = -1
+	assert.Error(t, churn.Validate())
+}
+
+func TestMetricSeries_Validate(t *testing.T) {
+	for _, name := range PatternPresetNames() {
+		assert.NoError(t, MetricSeries{Name: name, Preset: name}.Validate(), name)
+	}
+
+	resolved, err := MetricSeries{Name: "orders", Preset: "counter_resets"}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "counter", resolved.Kind)
+
+	assert.EqualError(t, MetricSeries{Preset: "spiky"}.Validate(), "name is required")
+	assert.EqualError(t, MetricSeries{Name: "x"}.Validate(), "pattern or preset is required")
+	assert.EqualError(t, MetricSeries{Name: "x", Kind: "histogram", Preset: "spiky"}.Validate(), "kind must be gauge or counter")
+	assert.EqualError(t, MetricSeries{Name: "x", Pattern: []PatternComponent{{Type: PatternGaps, EverySeconds: 30}}}.Validate(),
+		"pattern[0] gaps: duration_seconds must be shorter than every_seconds")
+	assert.EqualError(t, MetricSeries{Name: "x", Pattern: []PatternComponent{{Type: PatternSpikes, Probability: 2}}}.Validate(),
+		"pattern[0] spikes: probability must be between 0 and 1")
+	assert.EqualError(t, MetricSeries{Name: "x", Pattern: []PatternComponent{{Type: "noise"}}}.Validate(),
+		"pattern[0] noise: unknown component type")
+}
+
+func TestLogFormatSpec_Validate(t *testing.T) {
+	for _, format := range LogFormats {
+		assert.NoError(t, LogFormatSpec{Format: format, Count: 10}.Validate(), format)
+	}
+	assert.NoError(t, LogFormatSpec{Format: LogFormatKlog, Count: 600, Rate: 1, Fields: map[string]FieldDistribution{
+		"level":       {Values: []string{"info", "error"}, Weights: []float64{9, 1}},
+		"duration_ms": {Mean: 120, StdDev: 30},
+	}}.Validate())
+
+	assert.EqualError(t, LogFormatSpec{Format: "json", Count: 1}.Validate(),
+		"format must be one of logfmt, syslog_rfc5424, syslog_rfc3164, common, combined, klog, cri, java_stacktrace, python_stacktrace, go_stacktrace")
+	assert.Error(t, LogFormatSpec{Format: LogFormatCRI}.Validate())
+	assert.Error(t, LogFormatSpec{Format: LogFormatCRI, Count: 1, Rate: -1}.Validate())
+	assert.EqualError(t, LogFormatSpec{Format: LogFormatCRI, Count: 601, Rate: 1}.Validate(), "count at this rate takes longer than 600 seconds")
+	assert.EqualError(t, LogFormatSpec{Format: LogFormatCRI, Count: 1, Fields: map[string]FieldDistribution{
+		"level": {Values: []string{"info"}, Weights: []float64{1, 2}},
+	}}.Validate(), "fields.level: weights must match values")
+
+	assert.Error(t, FieldDistribution{Values: []string{"a"}, Weights: []float64{0}}.Validate())
+	assert.Error(t, FieldDistribution{Min: 10, Max: 1}.Validate())
+	assert.Error(t, FieldDistribution{}.Validate())
+	assert.Error(t, FieldDistribution{Max: 1, Decimals: 12}.Validate())
+}
+
+func TestLogSinkConfig_Validate(t *testing.T) {
+	assert.NoError(t, ValidateLogSinks([]LogSinkConfig{
+		{Name: "tail", Type: LogSinkFile, Path: "/var/log/argus/argus.log", MaxSizeMB: 10},
+		{Name: "rsyslog", Type: LogSinkSyslog, Network: "tcp", Address: "rsyslog:514"},
+		{Name: "loki", Type: LogSinkLoki, Labels: map[string]string{"job": "argus"}},
+		{Name: "vector", Type: LogSinkUDP, Address: "vector:9000"},
+	}))
+
+	assert.EqualError(t, LogSinkConfig{Type: LogSinkFile, Path: "x"}.Validate(), "name is required")
+	assert.EqualError(t, LogSinkConfig{Name: "stdout", Type: LogSinkFile, Path: "x"}.Validate(), "name stdout is reserved")
+	assert.EqualError(t, LogSinkConfig{Name: "a", Type: "kafka"}.Validate(), "type must be one of file, syslog, loki, tcp or udp")
+	assert.EqualError(t, LogSinkConfig{Name: "a", Type: LogSinkFile}.Validate(), "path is required")
+	assert.Error(t, LogSinkConfig{Name: "a", Type: LogSinkTCP, Address: "collector"}.Validate())
+	assert.Error(t, LogSinkConfig{Name: "a", Type: LogSinkSyslog, Network: "tls", Address: "rsyslog:6514"}.Validate())
+	assert.Error(t, LogSinkConfig{Name: "a", Type: LogSinkSyslog, Address: "rsyslog:514", Facility: 24}.Validate())
+	assert.EqualError(t, LogSinkConfig{Name: "a", Type: LogSinkLoki, Labels: map[string]string{"service-name": "x"}}.Validate(), `label "service-name" is not a valid label name`)
+	assert.EqualError(t, ValidateLogSinks([]LogSinkConfig{{Name: "a", Type: LogSinkUDP, Address: "x:1"}, {Name: "a", Type: LogSinkTCP, Address: "x:1"}}),
+		"log_sinks[1]: name a is used twice")
+
+	os.Setenv("ARGUS_LOG_SINKS", `[{"name":"tail","type":"file","path":"/tmp/argus.log"}]`)
+	defer os.Unsetenv("ARGUS_LOG_SINKS")
+	sinks, err := LogSinksFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []LogSinkConfig{{Name: "tail", Type: LogSinkFile, Path: "/tmp/argus.log"}}, sinks)
+
+	os.Setenv("ARGUS_LOG_SINKS", `[{"name":"tail","type":"file"}]`)
+	_, err = LogSinksFromEnv()
+	assert.EqualError(t, err, "ARGUS_LOG_SINKS: log_sinks[0]: path is required")
+}
+
+func TestLogReplaySpec_Validate(t *testing.T) {
+	assert.NoError(t, LogReplaySpec{Path: "incident.log.gz", Sink: "loki", Labels: map[string]string{"incident": "inc-42"}, TimestampLayout: "rfc3339", Speed: 10, MaxGapSeconds: 5}.Validate())
+	assert.NoError(t, LogReplaySpec{Path: "app.log"}.Validate())
+
+	assert.EqualError(t, LogReplaySpec{}.Validate(), "path is required")
+	assert.EqualError(t, LogReplaySpec{Path: "a", TimestampLayout: "rfc3339", Speed: -1}.Validate(), "speed must not be negative")
+	assert.EqualError(t, LogReplaySpec{Path: "a", Speed: 2}.Validate(), "speed and max_gap_seconds need a timestamp_layout")
+	assert.EqualError(t, LogReplaySpec{Path: "a", Labels: map[string]string{"1x": "y"}}.Validate(), `label "1x" is not a valid label name`)
+
+	assert.Equal(t, "02/Jan/2006:15:04:05 -0700", LogReplaySpec{TimestampLayout: "common"}.Layout())
+	assert.Equal(t, "2006/01/02 15:04", LogReplaySpec{TimestampLayout: "2006/01/02 15:04"}.Layout())
+}
+
+func TestParsePIISpec(t *testing.T) {
+	spec, err := ParsePIISpec("true", "")
+	require.NoError(t, err)
+	assert.Equal(t, PIISpec{}, spec)
+
+	spec, err = ParsePIISpec("email, token", "0.25")
+	require.NoError(t, err)
+	assert.Equal(t, PIISpec{Types: []string{PIIEmail, PIIToken}, Rate: 0.25}, spec)
+
+	_, err = ParsePIISpec("ssn", "")
+	assert.EqualError(t, err, `pii type "ssn" must be one of email, credit_card, ip, token`)
+	_, err = ParsePIISpec("true", "2")
+	assert.EqualError(t, err, "pii_rate must be between 0 and 1")
+	_, err = ParsePIISpec("true", "often")
+	assert.EqualError(t, err, "pii_rate must be a number")
+}
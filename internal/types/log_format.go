@@ -0,0 +1,122 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Log formats the format generator writes
+const (
+	LogFormatLogfmt      = "logfmt"
+	LogFormatSyslog5424  = "syslog_rfc5424"
+	LogFormatSyslog3164  = "syslog_rfc3164"
+	LogFormatCommon      = "common"
+	LogFormatCombined    = "combined"
+	LogFormatKlog        = "klog"
+	LogFormatCRI         = "cri"
+	LogFormatJavaStack   = "java_stacktrace"
+	LogFormatPythonStack = "python_stacktrace"
+	LogFormatGoStack     = "go_stacktrace"
+)
+
+// Limits of one generator run
+const (
+	MaxLogFormatEntries    = 100000
+	MaxLogFormatRate       = 10000
+	MaxLogFormatRunSeconds = 600
+)
+
+// LogFormats lists the supported formats in order
+var LogFormats = []string{
+	LogFormatLogfmt, LogFormatSyslog5424, LogFormatSyslog3164, LogFormatCommon, LogFormatCombined,
+	LogFormatKlog, LogFormatCRI, LogFormatJavaStack, LogFormatPythonStack, LogFormatGoStack,
+}
+
+// LogFormatSpec describes a run of the log format generator. Entries are
+// written at Rate per second, or as fast as possible when Rate is 0; a
+// stack trace is one entry spanning several lines.
+type LogFormatSpec struct {
+	Format string                       `json:"format"`
+	Count  int                          `json:"count"`
+	Rate   float64                      `json:"rate,omitempty"`
+	Fields map[string]FieldDistribution `json:"fields,omitempty"` // overrides the default distribution per field
+	Seed   int64                        `json:"seed,omitempty"`   // makes the run repeatable
+}
+
+// FieldDistribution is how a field's values are drawn: one of Values (with
+// optional Weights), a normal distribution when StdDev is set, or a uniform
+// one between Min and Max. Numbers are rounded to Decimals places and kept
+// at or above Min.
+type FieldDistribution struct {
+	Values   []string  `json:"values,omitempty"`
+	Weights  []float64 `json:"weights,omitempty"`
+	Min      float64   `json:"min,omitempty"`
+	Max      float64   `json:"max,omitempty"`
+	Mean     float64   `json:"mean,omitempty"`
+	StdDev   float64   `json:"stddev,omitempty"`
+	Decimals int       `json:"decimals,omitempty"`
+}
+
+// IsLogFormat reports whether name is a supported format
+func IsLogFormat(name string) bool {
+	for _, format := range LogFormats {
+		if format == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the spec's format, volume and distributions
+func (s LogFormatSpec) Validate() error {
+	if !IsLogFormat(s.Format) {
+		return fmt.Errorf("format must be one of %s", strings.Join(LogFormats, ", "))
+	}
+	if s.Count < 1 || s.Count > MaxLogFormatEntries {
+		return fmt.Errorf("count must be between 1 and %d", MaxLogFormatEntries)
+	}
+	if s.Rate < 0 || s.Rate > MaxLogFormatRate {
+		return fmt.Errorf("rate must be between 0 and %d", MaxLogFormatRate)
+	}
+	if s.Rate > 0 && float64(s.Count)/s.Rate > MaxLogFormatRunSeconds {
+		return fmt.Errorf("count at this rate takes longer than %d seconds", MaxLogFormatRunSeconds)
+	}
+	for name, field := range s.Fields {
+		if err := field.Validate(); err != nil {
+			return fmt.Errorf("fields.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Validate checks that the distribution is well formed
+func (d FieldDistribution) Validate() error {
+	if len(d.Weights) > 0 && len(d.Weights) != len(d.Values) {
+		return fmt.Errorf("weights must match values")
+	}
+	total := 0.0
+	for _, weight := range d.Weights {
+		if weight < 0 {
+			return fmt.Errorf("weights must not be negative")
+		}
+		total += weight
+	}
+	if len(d.Weights) > 0 && total == 0 {
+		return fmt.Errorf("weights must not all be zero")
+	}
+	if len(d.Values) == 0 {
+		if d.StdDev < 0 {
+			return fmt.Errorf("stddev must not be negative")
+		}
+		if d.StdDev == 0 && d.Max < d.Min {
+			return fmt.Errorf("max must not be less than min")
+		}
+		if d.StdDev == 0 && d.Max == 0 && d.Min == 0 {
+			return fmt.Errorf("values, stddev or max is required")
+		}
+	}
+	if d.Decimals < 0 || d.Decimals > 9 {
+		return fmt.Errorf("decimals must be between 0 and 9")
+	}
+	return nil
+}
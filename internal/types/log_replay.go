@@ -0,0 +1,62 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReplayTimestampLayouts names common log timestamp layouts, so a replay can
+// say "common" instead of spelling out the Go layout
+var ReplayTimestampLayouts = map[string]string{
+	"rfc3339":  time.RFC3339Nano,
+	"datetime": "2006-01-02 15:04:05",
+	"common":   "02/Jan/2006:15:04:05 -0700",
+	"syslog":   time.Stamp,
+	"klog":     "0102 15:04:05.000000",
+}
+
+// LogReplaySpec describes a replay of a captured log file into a sink
+type LogReplaySpec struct {
+	Path   string            `json:"path"`             // plain or gzip-compressed log file
+	Sink   string            `json:"sink,omitempty"`   // defaults to stdout
+	Labels map[string]string `json:"labels,omitempty"` // stream labels when the sink is Loki
+
+	// TimestampLayout is a Go time layout, or a name from
+	// ReplayTimestampLayouts, of the timestamps in the file. They are
+	// rewritten relative to the start of the replay and paced by Speed;
+	// lines without one continue the entry before them. Without a layout
+	// every line is an entry and the file is replayed as fast as possible.
+	TimestampLayout string  `json:"timestamp_layout,omitempty"`
+	Speed           float64 `json:"speed,omitempty"`           // 1 keeps the original timing, 10 replays ten times faster
+	MaxGapSeconds   float64 `json:"max_gap_seconds,omitempty"` // caps the pause between two entries
+}
+
+// Layout returns the Go layout of the spec's timestamps, or "" when timing is not replayed
+func (s LogReplaySpec) Layout() string {
+	if layout, ok := ReplayTimestampLayouts[s.TimestampLayout]; ok {
+		return layout
+	}
+	return s.TimestampLayout
+}
+
+// Validate checks the replay spec
+func (s LogReplaySpec) Validate() error {
+	if s.Path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if s.Speed < 0 {
+		return fmt.Errorf("speed must not be negative")
+	}
+	if s.MaxGapSeconds < 0 {
+		return fmt.Errorf("max_gap_seconds must not be negative")
+	}
+	if s.TimestampLayout == "" && (s.Speed > 0 || s.MaxGapSeconds > 0) {
+		return fmt.Errorf("speed and max_gap_seconds need a timestamp_layout")
+	}
+	for name := range s.Labels {
+		if !isLabelName(name) {
+			return fmt.Errorf("label %q is not a valid label name", name)
+		}
+	}
+	return nil
+}
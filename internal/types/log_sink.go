@@ -0,0 +1,150 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Log sink types
+const (
+	LogSinkStdout = "stdout"
+	LogSinkFile   = "file"
+	LogSinkSyslog = "syslog"
+	LogSinkLoki   = "loki"
+	LogSinkTCP    = "tcp"
+	LogSinkUDP    = "udp"
+)
+
+// LogSinkConfig describes one destination for log lines besides stdout.
+// Every configured sink receives Argus' own logs, and generator endpoints can
+// write to a single sink by name.
+type LogSinkConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // file, syslog, loki, tcp or udp
+
+	// File sinks write to Path, rotating it when it exceeds MaxSizeMB and
+	// keeping MaxBackups rotated files as Path.1, Path.2, ...
+	Path       string `json:"path,omitempty"`
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`
+	MaxBackups int    `json:"max_backups,omitempty"`
+
+	// Syslog, TCP and UDP sinks send to Address (host:port); syslog sends
+	// RFC 5424 messages over Network "udp" or "tcp" (octet-counted framing)
+	Address  string `json:"address,omitempty"`
+	Network  string `json:"network,omitempty"`
+	Facility int    `json:"facility,omitempty"` // 0-23, local0 (16) by default
+	AppName  string `json:"app_name,omitempty"`
+
+	// Loki sinks push to Loki, which is the settings' Loki when nil, as
+	// streams labelled with Labels. Entries are sent every BatchWaitMs or
+	// once BatchSize are queued, and a failed push is retried MaxRetries
+	// times with backoff.
+	Loki        *ServiceConfig    `json:"loki,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	BatchSize   int               `json:"batch_size,omitempty"`
+	BatchWaitMs int               `json:"batch_wait_ms,omitempty"`
+	MaxRetries  int               `json:"max_retries,omitempty"`
+}
+
+// Validate checks the fields the sink's type needs
+func (c LogSinkConfig) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Name == LogSinkStdout {
+		return fmt.Errorf("name %s is reserved", LogSinkStdout)
+	}
+
+	switch c.Type {
+	case LogSinkFile:
+		if c.Path == "" {
+			return fmt.Errorf("path is required")
+		}
+		if c.MaxSizeMB < 0 || c.MaxBackups < 0 {
+			return fmt.Errorf("max_size_mb and max_backups must not be negative")
+		}
+	case LogSinkSyslog:
+		if c.Network != "" && c.Network != "udp" && c.Network != "tcp" {
+			return fmt.Errorf("network must be udp or tcp")
+		}
+		if c.Facility < 0 || c.Facility > 23 {
+			return fmt.Errorf("facility must be between 0 and 23")
+		}
+		return validateSinkAddress(c.Address)
+	case LogSinkTCP, LogSinkUDP:
+		return validateSinkAddress(c.Address)
+	case LogSinkLoki:
+		if c.Loki != nil && c.Loki.URL == "" {
+			return fmt.Errorf("loki.url is required")
+		}
+		if c.BatchSize < 0 || c.BatchWaitMs < 0 || c.MaxRetries < 0 {
+			return fmt.Errorf("batch_size, batch_wait_ms and max_retries must not be negative")
+		}
+		for name := range c.Labels {
+			if !isLabelName(name) {
+				return fmt.Errorf("label %q is not a valid label name", name)
+			}
+		}
+	default:
+		return fmt.Errorf("type must be one of %s, %s, %s, %s or %s", LogSinkFile, LogSinkSyslog, LogSinkLoki, LogSinkTCP, LogSinkUDP)
+	}
+	return nil
+}
+
+// ValidateLogSinks checks every sink and that their names are unique
+func ValidateLogSinks(sinks []LogSinkConfig) error {
+	names := make(map[string]bool)
+	for i, sink := range sinks {
+		if err := sink.Validate(); err != nil {
+			return fmt.Errorf("log_sinks[%d]: %w", i, err)
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("log_sinks[%d]: name %s is used twice", i, sink.Name)
+		}
+		names[sink.Name] = true
+	}
+	return nil
+}
+
+// LogSinksFromEnv reads the sinks configured at startup from ARGUS_LOG_SINKS,
+// a JSON array of LogSinkConfig
+func LogSinksFromEnv() ([]LogSinkConfig, error) {
+	value := strings.TrimSpace(os.Getenv("ARGUS_LOG_SINKS"))
+	if value == "" {
+		return nil, nil
+	}
+	var sinks []LogSinkConfig
+	if err := json.Unmarshal([]byte(value), &sinks); err != nil {
+		return nil, fmt.Errorf("ARGUS_LOG_SINKS: %w", err)
+	}
+	if err := ValidateLogSinks(sinks); err != nil {
+		return nil, fmt.Errorf("ARGUS_LOG_SINKS: %w", err)
+	}
+	return sinks, nil
+}
+
+func validateSinkAddress(address string) error {
+	if address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return fmt.Errorf("address must be host:port: %w", err)
+	}
+	return nil
+}
+
+func isLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,191 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Pattern component types
+const (
+	PatternConstant     = "constant"
+	PatternSine         = "sine"
+	PatternTrend        = "trend"
+	PatternRandomWalk   = "random_walk"
+	PatternStep         = "step"
+	PatternSpikes       = "spikes"
+	PatternGaps         = "gaps"
+	PatternCounterReset = "counter_reset"
+)
+
+// MetricSeries defines a generated series. A gauge's value is the sum of its
+// pattern's components; a counter takes that sum as its rate per second.
+// Preset names one of PatternPresets and is used when Pattern is empty.
+type MetricSeries struct {
+	Name    string             `json:"name"`
+	Kind    string             `json:"kind,omitempty"` // "gauge" (default) or "counter"
+	Preset  string             `json:"preset,omitempty"`
+	Pattern []PatternComponent `json:"pattern,omitempty"`
+	Seed    int64              `json:"seed,omitempty"` // makes random components repeatable
+}
+
+// PatternComponent is one function of a series' pattern. Components add up,
+// except gaps, which hide the series, and counter_reset, which zeroes a
+// counter. Each type reads these fields:
+//
+//	constant       value
+//	sine           amplitude, period_seconds, phase_seconds
+//	trend          value (change per hour)
+//	random_walk    value (standard deviation per second)
+//	step           value (change) from at_seconds, again every every_seconds
+//	spikes         value (height) with probability per duration_seconds window (default 15)
+//	gaps           every_seconds, or probability per window; lasting duration_seconds (default 60)
+//	counter_reset  every_seconds, or probability per duration_seconds window (default 60)
+type PatternComponent struct {
+	Type            string  `json:"type"`
+	Value           float64 `json:"value,omitempty"`
+	Amplitude       float64 `json:"amplitude,omitempty"`
+	PeriodSeconds   float64 `json:"period_seconds,omitempty"`
+	PhaseSeconds    float64 `json:"phase_seconds,omitempty"`
+	AtSeconds       float64 `json:"at_seconds,omitempty"`
+	EverySeconds    float64 `json:"every_seconds,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	Probability     float64 `json:"probability,omitempty"`
+}
+
+// PatternPresets are ready-made patterns that series can select by name
+var PatternPresets = map[string]MetricSeries{
+	"seasonal": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 100},
+		{Type: PatternSine, Amplitude: 40, PeriodSeconds: 3600},
+		{Type: PatternRandomWalk, Value: 0.5},
+	}},
+	"daily": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 500},
+		{Type: PatternSine, Amplitude: 300, PeriodSeconds: 86400, PhaseSeconds: -21600},
+		{Type: PatternSine, Amplitude: 50, PeriodSeconds: 3600},
+		{Type: PatternTrend, Value: 2},
+	}},
+	"trend": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 50},
+		{Type: PatternTrend, Value: 30},
+	}},
+	"random_walk": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 100},
+		{Type: PatternRandomWalk, Value: 1},
+	}},
+	"step": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 100},
+		{Type: PatternStep, Value: 150, AtSeconds: 300},
+	}},
+	"spiky": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 20},
+		{Type: PatternRandomWalk, Value: 0.2},
+		{Type: PatternSpikes, Value: 200, Probability: 0.05, DurationSeconds: 30},
+	}},
+	"gappy": {Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 10},
+		{Type: PatternSine, Amplitude: 5, PeriodSeconds: 600},
+		{Type: PatternGaps, EverySeconds: 600, DurationSeconds: 120},
+	}},
+	"counter_resets": {Kind: "counter", Pattern: []PatternComponent{
+		{Type: PatternConstant, Value: 5},
+		{Type: PatternSine, Amplitude: 3, PeriodSeconds: 900},
+		{Type: PatternCounterReset, EverySeconds: 900},
+	}},
+}
+
+// PatternPresetNames returns the preset names in order
+func PatternPresetNames() []string {
+	names := make([]string, 0, len(PatternPresets))
+	for name := range PatternPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the series with its preset's kind and pattern filled in
+func (s MetricSeries) Resolve() (MetricSeries, error) {
+	if len(s.Pattern) > 0 || s.Preset == "" {
+		return s, nil
+	}
+	preset, ok := PatternPresets[s.Preset]
+	if !ok {
+		return s, fmt.Errorf("unknown preset %q", s.Preset)
+	}
+	s.Pattern = preset.Pattern
+	if s.Kind == "" {
+		s.Kind = preset.Kind
+	}
+	return s, nil
+}
+
+// Validate checks the series after its preset is resolved
+func (s MetricSeries) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if s.Kind != "" && s.Kind != "gauge" && s.Kind != "counter" {
+		return fmt.Errorf("kind must be gauge or counter")
+	}
+	resolved, err := s.Resolve()
+	if err != nil {
+		return err
+	}
+	if len(resolved.Pattern) == 0 {
+		return fmt.Errorf("pattern or preset is required")
+	}
+	for i, c := range resolved.Pattern {
+		if err := c.validate(resolved.Kind); err != nil {
+			return fmt.Errorf("pattern[%d] %s: %w", i, c.Type, err)
+		}
+	}
+	return nil
+}
+
+// Window returns the component's duration_seconds, or fallback when unset
+func (c PatternComponent) Window(fallback float64) float64 {
+	if c.DurationSeconds > 0 {
+		return c.DurationSeconds
+	}
+	return fallback
+}
+
+// validate checks a component's fields for its type
+func (c PatternComponent) validate(kind string) error {
+	if c.Probability < 0 || c.Probability > 1 {
+		return fmt.Errorf("probability must be between 0 and 1")
+	}
+	if c.EverySeconds < 0 || c.DurationSeconds < 0 {
+		return fmt.Errorf("every_seconds and duration_seconds must not be negative")
+	}
+
+	switch c.Type {
+	case PatternConstant, PatternTrend, PatternStep, PatternSpikes:
+	case PatternSine:
+		if c.PeriodSeconds <= 0 {
+			return fmt.Errorf("period_seconds must be positive")
+		}
+	case PatternRandomWalk:
+		if c.Value < 0 {
+			return fmt.Errorf("value must not be negative")
+		}
+	case PatternGaps:
+		if c.EverySeconds == 0 && c.Probability == 0 {
+			return fmt.Errorf("every_seconds or probability is required")
+		}
+		if c.EverySeconds > 0 && c.Window(60) >= c.EverySeconds {
+			return fmt.Errorf("duration_seconds must be shorter than every_seconds")
+		}
+	case PatternCounterReset:
+		if kind != "counter" {
+			return fmt.Errorf("only counters can reset")
+		}
+		if c.EverySeconds == 0 && c.Probability == 0 {
+			return fmt.Errorf("every_seconds or probability is required")
+		}
+	default:
+		return fmt.Errorf("unknown component type")
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Supported OTLP exporter protocols
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// OTLPExporterConfig represents the connection settings of an OTLP exporter
+type OTLPExporterConfig struct {
+	Protocol    string            `json:"protocol"`              // "http/protobuf" or "grpc"
+	Endpoint    string            `json:"endpoint"`              // http(s)://host:port or host:port
+	URLPath     string            `json:"url_path,omitempty"`    // HTTP only, e.g. /v1/traces
+	Headers     map[string]string `json:"headers,omitempty"`     // e.g. X-Scope-OrgID, Authorization
+	Insecure    bool              `json:"insecure"`              // plaintext instead of TLS
+	CAFile      string            `json:"ca_file,omitempty"`     // PEM bundle to verify the collector
+	CertFile    string            `json:"cert_file,omitempty"`   // client certificate for mTLS
+	KeyFile     string            `json:"key_file,omitempty"`    // client key for mTLS
+	Compression string            `json:"compression,omitempty"` // "gzip" or "none"
+	TimeoutMs   int               `json:"timeout_ms,omitempty"`
+}
+
+// BatchConfig represents the batch span processor parameters
+type BatchConfig struct {
+	MaxQueueSize       int `json:"max_queue_size"`
+	MaxExportBatchSize int `json:"max_export_batch_size"`
+	ScheduleDelayMs    int `json:"schedule_delay_ms"`
+	ExportTimeoutMs    int `json:"export_timeout_ms"`
+}
+
+// TracingSettings represents how Argus exports its own traces
+type TracingSettings struct {
+	Exporter     OTLPExporterConfig `json:"exporter"`
+	SamplingRate float64            `json:"sampling_rate"`
+	ParentBased  bool               `json:"parent_based,omitempty"` // follow the sampling decision of a remote parent span
+	Batch        BatchConfig        `json:"batch"`
+}
+
+// UnmarshalJSON defaults a missing sampling_rate to 1, since 0 would drop every span
+func (s *TracingSettings) UnmarshalJSON(data []byte) error {
+	type plain TracingSettings
+	settings := plain{SamplingRate: 1}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	*s = TracingSettings(settings)
+	return nil
+}
+
+// LogExportSettings represents how Argus exports its own log records
+type LogExportSettings struct {
+	Enabled  bool               `json:"enabled"`
+	Exporter OTLPExporterConfig `json:"exporter"`
+	Batch    BatchConfig        `json:"batch"`
+}
+
+// MetricExportSettings represents how Argus pushes its own metrics
+type MetricExportSettings struct {
+	Enabled    bool               `json:"enabled"`
+	Exporter   OTLPExporterConfig `json:"exporter"`
+	IntervalMs int                `json:"interval_ms"`
+}
+
+// Target splits the endpoint into the host:port the exporter dials, the URL
+// path to post to and whether the connection is plaintext. A scheme in the
+// endpoint wins over the Insecure flag; a path in it wins over URLPath.
+func (c OTLPExporterConfig) Target() (string, string, bool, error) {
+	if c.Endpoint == "" {
+		return "", "", false, fmt.Errorf("endpoint is required")
+	}
+	if !strings.Contains(c.Endpoint, "://") {
+		return c.Endpoint, c.URLPath, c.Insecure, nil
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", "", false, fmt.Errorf("invalid endpoint %q: %w", c.Endpoint, err)
+	}
+	if u.Host == "" {
+		return "", "", false, fmt.Errorf("invalid endpoint %q: missing host", c.Endpoint)
+	}
+
+	path := c.URLPath
+	if u.Path != "" && u.Path != "/" {
+		path = u.Path
+	}
+	switch u.Scheme {
+	case "http":
+		return u.Host, path, true, nil
+	case "https":
+		return u.Host, path, false, nil
+	default:
+		return "", "", false, fmt.Errorf("invalid endpoint %q: unsupported scheme %s", c.Endpoint, u.Scheme)
+	}
+}
+
+// RedactedValue replaces secrets in settings responses. Saving it back keeps
+// the secret in effect.
+const RedactedValue = "[REDACTED]"
+
+// Redacted returns a copy of the exporter settings with the header values,
+// which usually carry tokens, replaced by RedactedValue
+func (c OTLPExporterConfig) Redacted() OTLPExporterConfig {
+	if len(c.Headers) == 0 {
+		return c
+	}
+	headers := make(map[string]string, len(c.Headers))
+	for name := range c.Headers {
+		headers[name] = RedactedValue
+	}
+	c.Headers = headers
+	return c
+}
+
+// RestoreRedacted puts the current value back into headers that were saved
+// as RedactedValue, dropping those that have no current value
+func (c *OTLPExporterConfig) RestoreRedacted(current OTLPExporterConfig) {
+	for name, value := range c.Headers {
+		if value != RedactedValue {
+			continue
+		}
+		if previous, ok := current.Headers[name]; ok {
+			c.Headers[name] = previous
+		} else {
+			delete(c.Headers, name)
+		}
+	}
+}
+
+// Validate checks the exporter settings for values the exporters cannot use
+func (c OTLPExporterConfig) Validate() error {
+	if c.Protocol != OTLPProtocolHTTP && c.Protocol != OTLPProtocolGRPC {
+		return fmt.Errorf("unsupported protocol %q (use %s or %s)", c.Protocol, OTLPProtocolHTTP, OTLPProtocolGRPC)
+	}
+	if _, _, _, err := c.Target(); err != nil {
+		return err
+	}
+	if c.Compression != "" && c.Compression != "none" && c.Compression != "gzip" {
+		return fmt.Errorf("unsupported compression %q (use gzip or none)", c.Compression)
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if c.TimeoutMs < 0 {
+		return fmt.Errorf("timeout_ms must not be negative")
+	}
+	return nil
+}
+
+// Validate checks the tracing settings
+func (s TracingSettings) Validate() error {
+	if err := s.Exporter.Validate(); err != nil {
+		return err
+	}
+	if s.SamplingRate < 0 || s.SamplingRate > 1 {
+		return fmt.Errorf("sampling_rate must be between 0 and 1")
+	}
+	if s.Batch.MaxQueueSize < 0 || s.Batch.MaxExportBatchSize < 0 || s.Batch.ScheduleDelayMs < 0 || s.Batch.ExportTimeoutMs < 0 {
+		return fmt.Errorf("batch parameters must not be negative")
+	}
+	if s.Batch.MaxExportBatchSize > s.Batch.MaxQueueSize && s.Batch.MaxQueueSize > 0 {
+		return fmt.Errorf("max_export_batch_size must not exceed max_queue_size")
+	}
+	return nil
+}
+
+// Validate checks the log export settings. The exporter is only checked when
+// export is enabled, so a disabled exporter may keep an incomplete endpoint.
+func (s LogExportSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if err := s.Exporter.Validate(); err != nil {
+		return err
+	}
+	if s.Batch.MaxQueueSize < 0 || s.Batch.MaxExportBatchSize < 0 || s.Batch.ScheduleDelayMs < 0 || s.Batch.ExportTimeoutMs < 0 {
+		return fmt.Errorf("batch parameters must not be negative")
+	}
+	if s.Batch.MaxExportBatchSize > s.Batch.MaxQueueSize && s.Batch.MaxQueueSize > 0 {
+		return fmt.Errorf("max_export_batch_size must not exceed max_queue_size")
+	}
+	return nil
+}
+
+// Validate checks the metric export settings
+func (s MetricExportSettings) Validate() error {
+	if !s.Enabled {
+		return nil
+	}
+	if err := s.Exporter.Validate(); err != nil {
+		return err
+	}
+	if s.IntervalMs < 0 {
+		return fmt.Errorf("interval_ms must not be negative")
+	}
+	return nil
+}
+
+// OTLPExporterFromEnv builds exporter settings for one signal ("TRACES",
+// "LOGS" or "METRICS") from the standard OTEL_EXPORTER_OTLP_* variables.
+// Signal-specific variables win over the generic ones and, as in the spec,
+// a signal-specific endpoint is used as-is while the generic endpoint gets
+// the signal path appended for HTTP.
+func OTLPExporterFromEnv(signal, signalPath string) OTLPExporterConfig {
+	lookup := func(name string) string {
+		if value := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_" + name); value != "" {
+			return value
+		}
+		return os.Getenv("OTEL_EXPORTER_OTLP_" + name)
+	}
+
+	config := OTLPExporterConfig{
+		Protocol:    getEnv("OTEL_EXPORTER_OTLP_"+signal+"_PROTOCOL", getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", OTLPProtocolHTTP)),
+		Headers:     parseOTLPHeaders(lookup("HEADERS")),
+		CAFile:      lookup("CERTIFICATE"),
+		CertFile:    lookup("CLIENT_CERTIFICATE"),
+		KeyFile:     lookup("CLIENT_KEY"),
+		Compression: lookup("COMPRESSION"),
+		TimeoutMs:   envInt(lookup("TIMEOUT"), 10000),
+	}
+	config.Insecure, _ = strconv.ParseBool(lookup("INSECURE"))
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_" + signal + "_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+		return config
+	}
+
+	config.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if config.Protocol == OTLPProtocolGRPC {
+		if config.Endpoint == "" {
+			config.Endpoint = "http://localhost:4317"
+		}
+		return config
+	}
+
+	if config.Endpoint == "" {
+		config.Endpoint = "http://localhost:4318"
+	}
+	// The generic endpoint is a base URL; append the signal path to it
+	if u, err := url.Parse(config.Endpoint); err == nil && u.Scheme != "" {
+		config.URLPath = strings.TrimRight(u.Path, "/") + signalPath
+		u.Path = ""
+		config.Endpoint = u.String()
+	} else {
+		config.URLPath = signalPath
+	}
+	return config
+}
+
+// TracingSettingsFromEnv returns the trace export settings from the standard
+// OpenTelemetry environment variables, defaulting to OTLP/HTTP on localhost
+func TracingSettingsFromEnv() TracingSettings {
+	samplingRate, parentBased := samplerFromEnv()
+
+	return TracingSettings{
+		Exporter:     OTLPExporterFromEnv("TRACES", "/v1/traces"),
+		SamplingRate: samplingRate,
+		ParentBased:  parentBased,
+		Batch: BatchConfig{
+			MaxQueueSize:       envInt(os.Getenv("OTEL_BSP_MAX_QUEUE_SIZE"), 2048),
+			MaxExportBatchSize: envInt(os.Getenv("OTEL_BSP_MAX_EXPORT_BATCH_SIZE"), 512),
+			ScheduleDelayMs:    envInt(os.Getenv("OTEL_BSP_SCHEDULE_DELAY"), 5000),
+			ExportTimeoutMs:    envInt(os.Getenv("OTEL_BSP_EXPORT_TIMEOUT"), 30000),
+		},
+	}
+}
+
+// samplerFromEnv maps OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG to a
+// sampling rate and whether a remote parent's decision is followed. Unknown
+// samplers fall back to sampling every trace, as the SDKs do.
+func samplerFromEnv() (float64, bool) {
+	ratio := 1.0
+	if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
+		if parsed, err := strconv.ParseFloat(arg, 64); err == nil && parsed >= 0 && parsed <= 1 {
+			ratio = parsed
+		}
+	}
+
+	switch os.Getenv("OTEL_TRACES_SAMPLER") {
+	case "always_on":
+		return 1, false
+	case "always_off":
+		return 0, false
+	case "parentbased_always_on":
+		return 1, true
+	case "parentbased_always_off":
+		return 0, true
+	case "parentbased_traceidratio":
+		return ratio, true
+	case "", "traceidratio":
+		return ratio, false
+	default:
+		return 1, false
+	}
+}
+
+// LogExportSettingsFromEnv returns the log export settings. Export stays off
+// unless OTEL_LOGS_EXPORTER=otlp, since Argus logs to stdout by default.
+func LogExportSettingsFromEnv() LogExportSettings {
+	return LogExportSettings{
+		Enabled:  os.Getenv("OTEL_LOGS_EXPORTER") == "otlp",
+		Exporter: OTLPExporterFromEnv("LOGS", "/v1/logs"),
+		Batch: BatchConfig{
+			MaxQueueSize:       envInt(os.Getenv("OTEL_BLRP_MAX_QUEUE_SIZE"), 2048),
+			MaxExportBatchSize: envInt(os.Getenv("OTEL_BLRP_MAX_EXPORT_BATCH_SIZE"), 512),
+			ScheduleDelayMs:    envInt(os.Getenv("OTEL_BLRP_SCHEDULE_DELAY"), 1000),
+			ExportTimeoutMs:    envInt(os.Getenv("OTEL_BLRP_EXPORT_TIMEOUT"), 30000),
+		},
+	}
+}
+
+// MetricExportSettingsFromEnv returns the metric export settings. Export stays
+// off unless OTEL_METRICS_EXPORTER=otlp, since /metrics is scraped by default.
+func MetricExportSettingsFromEnv() MetricExportSettings {
+	return MetricExportSettings{
+		Enabled:    os.Getenv("OTEL_METRICS_EXPORTER") == "otlp",
+		Exporter:   OTLPExporterFromEnv("METRICS", "/v1/metrics"),
+		IntervalMs: envInt(os.Getenv("OTEL_METRIC_EXPORT_INTERVAL"), 60000),
+	}
+}
+
+// parseOTLPHeaders parses the k1=v1,k2=v2 format of OTEL_EXPORTER_OTLP_HEADERS
+func parseOTLPHeaders(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		if key != "" {
+			headers[key] = value
+		}
+	}
+	return headers
+}
+
+func envInt(raw string, defaultValue int) int {
+	if value, err := strconv.Atoi(raw); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
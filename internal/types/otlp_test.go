@@ -0,0 +1,225 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTLPExporterConfig_Target(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       OTLPExporterConfig
+		wantHostPort string
+		wantPath     string
+		wantInsecure bool
+		wantErr      bool
+	}{
+		{
+			name:         "http URL is plaintext",
+			config:       OTLPExporterConfig{Endpoint: "http://otel-collector:4318", URLPath: "/v1/traces"},
+			wantHostPort: "otel-collector:4318",
+			wantPath:     "/v1/traces",
+			wantInsecure: true,
+		},
+		{
+			name:         "https URL path wins over url_path",
+			config:       OTLPExporterConfig{Endpoint: "https://tempo.example.com/otlp/v1/traces", URLPath: "/v1/traces", Insecure: true},
+			wantHostPort: "tempo.example.com",
+			wantPath:     "/otlp/v1/traces",
+		},
+		{
+			name:         "bare host:port keeps the insecure flag",
+			config:       OTLPExporterConfig{Endpoint: "localhost:4317", Insecure: true},
+			wantHostPort: "localhost:4317",
+			wantInsecure: true,
+		},
+		{
+			name:    "missing endpoint",
+			config:  OTLPExporterConfig{},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			config:  OTLPExporterConfig{Endpoint: "ftp://collector:4318"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostPort, path, insecure, err := tt.config.Target()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHostPort, hostPort)
+			assert.Equal(t, tt.wantPath, path)
+			assert.Equal(t, tt.wantInsecure, insecure)
+		})
+	}
+}
+
+func TestTracingSettings_Validate(t *testing.T) {
+	valid := TracingSettingsFromEnv()
+	assert.NoError(t, valid.Validate())
+
+	invalid := map[string]func(s *TracingSettings){
+		"protocol":      func(s *TracingSettings) { s.Exporter.Protocol = "http/json" },
+		"compression":   func(s *TracingSettings) { s.Exporter.Compression = "zstd" },
+		"client cert":   func(s *TracingSettings) { s.Exporter.CertFile = "client.pem" },
+		"sampling rate": func(s *TracingSettings) { s.SamplingRate = 1.5 },
+		"batch size":    func(s *TracingSettings) { s.Batch.MaxExportBatchSize = s.Batch.MaxQueueSize + 1 },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			settings := TracingSettingsFromEnv()
+			mutate(&settings)
+			assert.Error(t, settings.Validate())
+		})
+	}
+}
+
+func TestOTLPExporterFromEnv(t *testing.T) {
+	t.Run("generic endpoint gets the signal path", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/base/")
+		t.Setenv("OTEL_EXPORTER_OTLP_TRACES_INSECURE", "true")
+		t.Setenv("OTEL_EXPORTER_OTLP_COMPRESSION", "gzip")
+
+		config := OTLPExporterFromEnv("TRACES", "/v1/traces")
+
+		assert.Equal(t, OTLPProtocolHTTP, config.Protocol)
+		assert.Equal(t, "http://collector:4318", config.Endpoint)
+		assert.Equal(t, "/base/v1/traces", config.URLPath)
+		assert.True(t, config.Insecure)
+		assert.Equal(t, "gzip", config.Compression)
+		assert.Equal(t, 10000, config.TimeoutMs)
+	})
+
+	t.Run("signal endpoint is used as-is", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+		t.Setenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT", "http://loki:3100/otlp/v1/logs")
+		t.Setenv("OTEL_EXPORTER_OTLP_LOGS_CERTIFICATE", "/etc/ssl/ca.pem")
+
+		config := OTLPExporterFromEnv("LOGS", "/v1/logs")
+
+		assert.Equal(t, "http://loki:3100/otlp/v1/logs", config.Endpoint)
+		assert.Empty(t, config.URLPath)
+		assert.Equal(t, "/etc/ssl/ca.pem", config.CAFile)
+	})
+
+	t.Run("grpc defaults to port 4317", func(t *testing.T) {
+		t.Setenv("OTEL_EXPORTER_OTLP_METRICS_PROTOCOL", "grpc")
+
+		config := OTLPExporterFromEnv("METRICS", "/v1/metrics")
+
+		assert.Equal(t, OTLPProtocolGRPC, config.Protocol)
+		assert.Equal(t, "http://localhost:4317", config.Endpoint)
+	})
+}
+
+func TestSignalExportSettingsFromEnv(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		logs := LogExportSettingsFromEnv()
+		metrics := MetricExportSettingsFromEnv()
+
+		assert.False(t, logs.Enabled)
+		assert.False(t, metrics.Enabled)
+		assert.Equal(t, "/v1/logs", logs.Exporter.URLPath)
+		assert.Equal(t, 1000, logs.Batch.ScheduleDelayMs)
+		assert.Equal(t, "/v1/metrics", metrics.Exporter.URLPath)
+		assert.Equal(t, 60000, metrics.IntervalMs)
+	})
+
+	t.Run("enabled through OTEL_*_EXPORTER", func(t *testing.T) {
+		t.Setenv("OTEL_LOGS_EXPORTER", "otlp")
+		t.Setenv("OTEL_METRICS_EXPORTER", "otlp")
+		t.Setenv("OTEL_BLRP_MAX_EXPORT_BATCH_SIZE", "100")
+		t.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "15000")
+		t.Setenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT", "http://mimir:9009/otlp/v1/metrics")
+
+		logs := LogExportSettingsFromEnv()
+		metrics := MetricExportSettingsFromEnv()
+
+		assert.True(t, logs.Enabled)
+		assert.Equal(t, 100, logs.Batch.MaxExportBatchSize)
+		assert.NoError(t, logs.Validate())
+		assert.True(t, metrics.Enabled)
+		assert.Equal(t, 15000, metrics.IntervalMs)
+		assert.Equal(t, "http://mimir:9009/otlp/v1/metrics", metrics.Exporter.Endpoint)
+		assert.NoError(t, metrics.Validate())
+	})
+
+	t.Run("exporter only validated when enabled", func(t *testing.T) {
+		logs := LogExportSettings{Exporter: OTLPExporterConfig{Protocol: "http/json"}}
+		assert.NoError(t, logs.Validate())
+		logs.Enabled = true
+		assert.Error(t, logs.Validate())
+
+		metrics := MetricExportSettings{Enabled: true, Exporter: OTLPExporterFromEnv("METRICS", "/v1/metrics"), IntervalMs: -1}
+		assert.Error(t, metrics.Validate())
+	})
+}
+
+func TestParseOTLPHeaders(t *testing.T) {
+	assert.Nil(t, parseOTLPHeaders(""))
+	assert.Equal(t, map[string]string{"api-key": "a=b", "X-Scope-OrgID": "team"},
+		parseOTLPHeaders(" api-key = a%3Db , X-Scope-OrgID=team,broken"))
+}
+
+func TestTracingSettings_UnmarshalJSON(t *testing.T) {
+	var settings TracingSettings
+	require.NoError(t, json.Unmarshal([]byte(`{"exporter":{"protocol":"grpc","endpoint":"localhost:4317"}}`), &settings))
+	assert.Equal(t, 1.0, settings.SamplingRate, "a missing rate keeps every span")
+
+	require.NoError(t, json.Unmarshal([]byte(`{"sampling_rate":0}`), &settings))
+	assert.Equal(t, 0.0, settings.SamplingRate)
+	assert.Empty(t, settings.Exporter.Endpoint)
+}
+
+func TestTracingSettingsFromEnv_Sampler(t *testing.T) {
+	tests := []struct {
+		sampler     string
+		arg         string
+		rate        float64
+		parentBased bool
+	}{
+		{"", "0.25", 0.25, false},
+		{"traceidratio", "0.25", 0.25, false},
+		{"always_on", "0.25", 1, false},
+		{"always_off", "", 0, false},
+		{"parentbased_always_on", "", 1, true},
+		{"parentbased_always_off", "", 0, true},
+		{"parentbased_traceidratio", "0.1", 0.1, true},
+		{"parentbased_traceidratio", "2", 1, true},
+		{"jaeger_remote", "0.25", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sampler+"/"+tt.arg, func(t *testing.T) {
+			t.Setenv("OTEL_TRACES_SAMPLER", tt.sampler)
+			t.Setenv("OTEL_TRACES_SAMPLER_ARG", tt.arg)
+
+			settings := TracingSettingsFromEnv()
+			assert.Equal(t, tt.rate, settings.SamplingRate)
+			assert.Equal(t, tt.parentBased, settings.ParentBased)
+		})
+	}
+}
+
+func TestOTLPExporterConfig_Redacted(t *testing.T) {
+	config := OTLPExporterConfig{Endpoint: "localhost:4317", Headers: map[string]string{"Authorization": "Bearer abc", "X-Scope-OrgID": "team"}}
+
+	redacted := config.Redacted()
+	assert.Equal(t, map[string]string{"Authorization": RedactedValue, "X-Scope-OrgID": RedactedValue}, redacted.Headers)
+	assert.Equal(t, "Bearer abc", config.Headers["Authorization"], "the original is not changed")
+
+	// A redacted value saved back keeps the current one; new values win
+	redacted.Headers["X-Scope-OrgID"] = "team-b"
+	redacted.Headers["X-Extra"] = RedactedValue
+	redacted.RestoreRedacted(config)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer abc", "X-Scope-OrgID": "team-b"}, redacted.Headers)
+}
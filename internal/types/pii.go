@@ -0,0 +1,64 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kinds of synthetic PII the log generators can embed
+const (
+	PIIEmail      = "email"
+	PIICreditCard = "credit_card"
+	PIIIP         = "ip"
+	PIIToken      = "token"
+)
+
+// PIITypes lists the PII kinds in order
+var PIITypes = []string{PIIEmail, PIICreditCard, PIIIP, PIIToken}
+
+// PIISpec makes a log generator embed fake PII for testing redaction
+// pipelines. Each line gets each of Types with probability Rate, so some
+// lines stay clean.
+type PIISpec struct {
+	Types []string `json:"types,omitempty"` // all kinds when empty
+	Rate  float64  `json:"rate,omitempty"`  // 0.5 when 0
+}
+
+// ParsePIISpec reads the pii and pii_rate request parameters: pii is "true"
+// for every kind or a comma-separated list of kinds
+func ParsePIISpec(pii, rate string) (PIISpec, error) {
+	var spec PIISpec
+	if pii != "true" {
+		for _, kind := range strings.Split(pii, ",") {
+			spec.Types = append(spec.Types, strings.TrimSpace(kind))
+		}
+	}
+	if rate != "" {
+		if _, err := fmt.Sscan(rate, &spec.Rate); err != nil {
+			return spec, fmt.Errorf("pii_rate must be a number")
+		}
+	}
+	return spec, spec.Validate()
+}
+
+// Validate checks the kinds and rate
+func (s PIISpec) Validate() error {
+	for _, kind := range s.Types {
+		if !isPIIType(kind) {
+			return fmt.Errorf("pii type %q must be one of %s", kind, strings.Join(PIITypes, ", "))
+		}
+	}
+	if s.Rate < 0 || s.Rate > 1 {
+		return fmt.Errorf("pii_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+func isPIIType(name string) bool {
+	for _, kind := range PIITypes {
+		if kind == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package types
+
+import "fmt"
+
+// Schedule defines a check that the scheduler runs every interval, delayed by
+// a random jitter so that several schedules do not hit the stack at once
+type Schedule struct {
+	ID              string            `json:"id"`
+	Check           string            `json:"check"`
+	Profile         string            `json:"profile"`
+	IntervalSeconds int               `json:"interval_seconds"`
+	JitterSeconds   int               `json:"jitter_seconds"`
+	TimeoutSeconds  int               `json:"timeout_seconds"` // defaults to the interval
+	Params          map[string]string `json:"params,omitempty"`
+	Paused          bool              `json:"paused"`
+}
+
+// Validate checks the schedule
+func (s Schedule) Validate() error {
+	if s.Check == "" {
+		return fmt.Errorf("check is required")
+	}
+	if s.IntervalSeconds < 1 {
+		return fmt.Errorf("interval_seconds must be at least 1")
+	}
+	if s.JitterSeconds < 0 || s.JitterSeconds > s.IntervalSeconds {
+		return fmt.Errorf("jitter_seconds must be between 0 and interval_seconds")
+	}
+	if s.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds must not be negative")
+	}
+	return nil
+}
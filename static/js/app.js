@@ -589,6 +589,10 @@ window.ArgusApp = {
             },
             tempo: {
                 url: 'http://localhost:3200'
+            },
+            otel_collector: {
+                url: 'http://localhost:8888',
+                otlp_endpoint: 'http://localhost:4318'
             }
         };
 
@@ -606,6 +610,8 @@ window.ArgusApp = {
         
         document.getElementById('loki-url').value = settings.loki?.url || defaultSettings.loki.url;
         document.getElementById('tempo-url').value = settings.tempo?.url || defaultSettings.tempo.url;
+        document.getElementById('otel-collector-url').value = settings.otel_collector?.url || defaultSettings.otel_collector.url;
+        document.getElementById('otel-collector-otlp-endpoint').value = settings.otel_collector?.otlp_endpoint || defaultSettings.otel_collector.otlp_endpoint;
         
         return settings;
     },
@@ -627,6 +633,10 @@ window.ArgusApp = {
             },
             tempo: {
                 url: document.getElementById('tempo-url').value
+            },
+            otel_collector: {
+                url: document.getElementById('otel-collector-url').value,
+                otlp_endpoint: document.getElementById('otel-collector-otlp-endpoint').value
             }
         };
 
@@ -715,6 +725,10 @@ window.ArgusApp = {
             },
             tempo: {
                 url: document.getElementById('tempo-url').value
+            },
+            otel_collector: {
+                url: document.getElementById('otel-collector-url').value,
+                otlp_endpoint: document.getElementById('otel-collector-otlp-endpoint').value
             }
         };
     }